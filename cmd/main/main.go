@@ -118,6 +118,8 @@ func main() {
 	routes.DefineUserFcmTokenRoutes(e, auth, roleMiddlewareProvider)
 	routes.DefinePomodoroRoutes(e, auth, roleMiddlewareProvider)
 	routes.DefineQuizAnswerRoutes(e, auth, roleMiddlewareProvider)
+	routes.DefineGradebookRoutes(e, auth, roleMiddlewareProvider)
+	routes.DefineSurveyRoutes(e, auth, roleMiddlewareProvider)
 	routes.DefineParentalConsentRoutes(e)
 	defer func(MQConn config.AmqpConnection) {
 		err := MQConn.Close()
@@ -10,8 +10,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"io"
 	"time"
+	"zeppelin/internal/domain"
 )
 
+// UploadTeacherQuizVersion uploads the immutable copy of a quiz version; attempts are graded against this key
+func UploadTeacherQuizVersion(courseID, contentID string, version int, jsonBytes []byte) error {
+	key := fmt.Sprintf("focused/%s/quiz/teacher/%s/v%d.json", courseID, contentID, version)
+	return UploadJSONToR2(key, jsonBytes)
+}
+
 func UploadTeacherQuiz(courseID, contentID string, jsonBytes []byte) error {
 	// Upload the teacher version (unchanged JSON)
 	teacherKey := fmt.Sprintf("focused/%s/quiz/teacher/%s.json", courseID, contentID)
@@ -31,32 +38,57 @@ func UploadTeacherQuiz(courseID, contentID string, jsonBytes []byte) error {
 		return fmt.Errorf("questions field is not an array")
 	}
 
-	// Remove correctAnswer and correctAnswers from each question
+	// Remove the answer key fields from each question
 	for _, q := range questions {
 		question, ok := q.(map[string]interface{})
 		if !ok {
 			return fmt.Errorf("question is not a map")
 		}
-		delete(question, "correctAnswer")
-		delete(question, "correctAnswers")
+		// In ordering questions the teacher's option order is the answer
+		if question["type"] == "ordering" {
+			question["options"] = domain.ScrambleOrderingOptions(stringList(question["options"]), stringList(question["correctAnswers"]))
+		}
+		for _, field := range domain.AnswerKeyFields {
+			delete(question, field)
+		}
 	}
 
+	// Question pools are drawn per student when the attempt starts, never exposed as a whole
+	delete(quiz, "pools")
+
 	// Marshal the modified JSON back to bytes
 	studentJSON, err := json.Marshal(quiz)
 	if err != nil {
 		return fmt.Errorf("error marshaling student JSON: %w", err)
 	}
 
-	// Upload the student version (without correctAnswer/correctAnswers)
+	// Upload the student version (without the answer key)
 	studentKey := fmt.Sprintf("focused/%s/quiz/student/%s.json", courseID, contentID)
 	return UploadJSONToR2(studentKey, studentJSON)
 }
 
+// stringList converts a decoded JSON array into a string slice, skipping non-string items
+func stringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if text, ok := item.(string); ok {
+			list = append(list, text)
+		}
+	}
+	return list
+}
+
 func UploadTeacherText(courseID, contentID string, jsonBytes []byte) error {
 	key := fmt.Sprintf("focused/%s/text/teacher/%s.json", courseID, contentID)
 	return UploadJSONToR2(key, jsonBytes)
 }
 
+func UploadTeacherSurvey(courseID, contentID string, jsonBytes []byte) error {
+	key := fmt.Sprintf("focused/%s/survey/teacher/%s.json", courseID, contentID)
+	return UploadJSONToR2(key, jsonBytes)
+}
+
 func GetR2Object(bucketName, objectKey string) ([]byte, error) {
 	if R2Client == nil {
 		return nil, errors.New("R2 client not initialized for get object")
@@ -127,3 +159,65 @@ func UploadJSONToR2(key string, jsonBytes []byte) error {
 
 	return err
 }
+
+// DeleteR2Prefix borra del bucket todos los objetos cuya clave empieza con prefix
+func DeleteR2Prefix(prefix string) error {
+	if R2Client == nil {
+		return errors.New("R2 client not initialized for delete")
+	}
+
+	keys, err := ListR2Keys(prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		_, err := R2Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: aws.String("zeppelin"),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete R2 object %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ListR2Keys devuelve las claves de todos los objetos bajo un prefijo
+func ListR2Keys(prefix string) ([]string, error) {
+	if R2Client == nil {
+		return nil, errors.New("R2 client not initialized for list")
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(R2Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String("zeppelin"),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list R2 objects under %s: %w", prefix, err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
+
+// CopyR2Object copia un objeto dentro del bucket
+func CopyR2Object(sourceKey, targetKey string) error {
+	if R2Client == nil {
+		return errors.New("R2 client not initialized for copy")
+	}
+
+	_, err := R2Client.CopyObject(context.TODO(), &s3.CopyObjectInput{
+		Bucket:     aws.String("zeppelin"),
+		CopySource: aws.String("zeppelin/" + sourceKey),
+		Key:        aws.String(targetKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy R2 object %s to %s: %w", sourceKey, targetKey, err)
+	}
+	return nil
+}
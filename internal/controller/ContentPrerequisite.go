@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetPrerequisites reemplaza los prerrequisitos de una sección del profesor
+func (c *CourseContentController) SetPrerequisites() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.SetPrerequisitesInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		if err := c.Repo.SetPrerequisites(input, userID); err != nil {
+			return reorderError(e, err)
+		}
+		return ReturnWriteResponse(e, nil, map[string]string{"message": "Prerrequisitos actualizados"})
+	}
+}
+
+// GetPrerequisites devuelve los prerrequisitos de una sección del profesor
+func (c *CourseContentController) GetPrerequisites() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		prerequisites, err := c.Repo.GetPrerequisites(e.Param("contentId"), userID)
+		if err != nil {
+			return reorderError(e, err)
+		}
+		return ReturnReadResponse(e, nil, prerequisites)
+	}
+}
+
+// contentLockedError responde 403 con los prerrequisitos que faltan
+func contentLockedError(locks []domain.ContentLock) error {
+	return echo.NewHTTPError(http.StatusForbidden, map[string]interface{}{
+		"message":      fmt.Sprintf("El contenido está bloqueado: faltan %d prerrequisitos", len(locks)),
+		"lock_reasons": locks,
+	}).SetInternal(domain.ErrContentLocked)
+}
+
+// checkContentUnlocked devuelve contentLockedError si el estudiante todavía no cumple los prerrequisitos
+// del contenido. Lo usan todas las rutas que hacen avanzar un contenido (estado, quizzes y encuestas);
+// el error se devuelve tal cual desde el handler.
+func checkContentUnlocked(repo domain.CourseContentRepo, contentID, userID string) error {
+	locks, err := repo.GetContentLocks(contentID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al verificar los prerrequisitos: %v", err))
+	}
+	if len(locks) > 0 {
+		return contentLockedError(locks)
+	}
+	return nil
+}
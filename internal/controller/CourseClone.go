@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CloneStorageKey cambia en una clave o URL de R2 el curso y los contenidos originales por los de la copia.
+// Solo se reemplazan segmentos completos de la ruta: focused/<curso>/ y <contenido> o <contenido>.json.
+func CloneStorageKey(clone domain.CourseClone, key string) string {
+	segments := strings.Split(key, "/")
+	sourceCourse := strconv.Itoa(clone.SourceCourseID)
+	for i, segment := range segments {
+		if segment == "focused" && i+1 < len(segments) && segments[i+1] == sourceCourse {
+			segments[i+1] = strconv.Itoa(clone.CourseID)
+			continue
+		}
+		if newID, ok := clone.ContentIDs[segment]; ok {
+			segments[i] = newID
+		} else if newID, ok := clone.ContentIDs[strings.TrimSuffix(segment, ".json")]; ok {
+			segments[i] = newID + ".json"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isAttemptStorageKey indica si la clave guarda datos de un intento de un estudiante
+func isAttemptStorageKey(key string) bool {
+	return strings.Contains(key, "/quiz/variant/") || strings.Contains(key, "/quiz/live/") || strings.Contains(key, "/quiz/answer/")
+}
+
+// CloneCourse duplica un curso del profesor con un QR nuevo, sus módulos, secciones y archivos en R2.
+// Con exclude_enrollments y exclude_attempts se omiten las inscripciones y los intentos.
+func (c *CourseController) CloneCourse() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		courseID, err := strconv.Atoi(e.Param("courseId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "courseId inválido")
+		}
+		var input domain.CloneCourseInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		clone, err := c.ContentRepo.CloneCourse(domain.CloneCourseRequest{
+			CloneCourseInput: input,
+			SourceCourseID:   courseID,
+			TeacherID:        userID,
+			QRCode:           generateQRCode(),
+		})
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		withAttempts := !input.ExcludeEnrollments && !input.ExcludeAttempts
+		body := map[string]interface{}{
+			"message":     "Curso duplicado",
+			"course_id":   clone.CourseID,
+			"qr_code":     clone.QRCode,
+			"modules":     len(clone.ModuleIDs),
+			"sections":    len(clone.ContentIDs),
+			"enrollments": clone.Enrollments,
+			"attempts":    clone.Attempts,
+		}
+		if storageErrors := c.copyCourseStorage(e, clone, withAttempts); len(storageErrors) > 0 {
+			body["storage_errors"] = storageErrors
+		}
+		return ReturnWriteResponse(e, nil, body)
+	}
+}
+
+// copyCourseStorage copia a las claves de la copia los objetos del curso original y los referenciados
+// por las URLs reescritas. Devuelve las claves que no se pudieron copiar.
+func (c *CourseController) copyCourseStorage(e echo.Context, clone domain.CourseClone, withAttempts bool) []string {
+	copies := make(map[string]string)
+	prefix := fmt.Sprintf("focused/%d/", clone.SourceCourseID)
+	keys, err := c.ListStorageFunc(prefix)
+	if err != nil {
+		e.Logger().Errorf("Failed to list R2 objects under %s: %v", prefix, err)
+		return []string{prefix}
+	}
+	for _, key := range keys {
+		if !withAttempts && isAttemptStorageKey(key) {
+			continue
+		}
+		copies[key] = CloneStorageKey(clone, key)
+	}
+	// Las respuestas de los estudiantes no siempre están bajo la carpeta del curso
+	for oldURL, newURL := range clone.URLs {
+		copies[r2KeyFromURL(oldURL)] = r2KeyFromURL(newURL)
+	}
+
+	sources := make([]string, 0, len(copies))
+	for source := range copies {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var failed []string
+	for _, source := range sources {
+		target := copies[source]
+		if target == source {
+			continue
+		}
+		if err := c.CopyStorageFunc(source, target); err != nil {
+			e.Logger().Errorf("Failed to copy R2 object %s to %s: %v", source, target, err)
+			failed = append(failed, source)
+		}
+	}
+	return failed
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,12 +12,16 @@ import (
 )
 
 type CourseContentController struct {
-	Repo                 domain.CourseContentRepo
-	RepoAssigment        domain.AssignmentRepo
-	RepoCourse           domain.CourseRepo
-	GeneratePresignedURL func(bucket, key string) (string, error)
-	UploadTextFunc       func(courseID, contentID string, json []byte) error
-	UploadQuizFunc       func(courseID, contentID string, jsonBytes []byte) error
+	Repo                  domain.CourseContentRepo
+	RepoAssigment         domain.AssignmentRepo
+	RepoCourse            domain.CourseRepo
+	GeneratePresignedURL  func(bucket, key string) (string, error)
+	UploadTextFunc        func(courseID, contentID string, json []byte) error
+	UploadQuizFunc        func(courseID, contentID string, jsonBytes []byte) error
+	UploadQuizVersionFunc func(courseID, contentID string, version int, jsonBytes []byte) error
+	UploadSurveyFunc      func(courseID, contentID string, jsonBytes []byte) error
+	DeleteStorageFunc     func(prefix string) error
+	QuizVersionRepo       domain.QuizVersionRepo
 }
 
 func (c *CourseContentController) GetCourseContentTeacher() echo.HandlerFunc {
@@ -58,6 +63,8 @@ func (c *CourseContentController) GetCourseContentTeacher() echo.HandlerFunc {
 						key = fmt.Sprintf("focused/%d/text/teacher/%s.json", courseID, detail.ContentID)
 					case 3: // Text
 						key = fmt.Sprintf("focused/%d/quiz/teacher/%s.json", courseID, detail.ContentID)
+					case 4: // Encuesta
+						key = fmt.Sprintf("focused/%d/survey/teacher/%s.json", courseID, detail.ContentID)
 					default:
 						continue
 					}
@@ -118,7 +125,8 @@ func (c *CourseContentController) GetCourseContentForStudent() echo.HandlerFunc
 
 		for i, content := range data {
 			for j, detail := range content.Details {
-				if detail.ContentID == "" {
+				// Un contenido bloqueado no recibe URL firmada hasta cumplir sus prerrequisitos
+				if detail.ContentID == "" || detail.Locked {
 					continue
 				}
 
@@ -128,6 +136,8 @@ func (c *CourseContentController) GetCourseContentForStudent() echo.HandlerFunc
 					key = fmt.Sprintf("focused/%d/text/teacher/%s.json", courseID, detail.ContentID)
 				case 3: // Text
 					key = fmt.Sprintf("focused/%d/quiz/student/%s.json", courseID, detail.ContentID)
+				case 4: // Encuesta (no tiene clave de respuestas, el estudiante ve la misma copia)
+					key = fmt.Sprintf("focused/%d/survey/teacher/%s.json", courseID, detail.ContentID)
 				default:
 					continue
 				}
@@ -206,6 +216,19 @@ func (c *CourseContentController) UpdateContent() echo.HandlerFunc {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "content_id requerido"), nil)
 		}
 
+		userID := e.Get("user_id").(string)
+		if _, err := c.RepoCourse.GetCourseByTeacherAndCourseID(userID, input.CourseID); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor"), nil)
+		}
+		// Las versiones y los objetos de R2 se escriben bajo input.CourseID, así que el contenido debe ser de ese curso
+		contentCourseID, err := c.Repo.GetCourseIDByContentID(input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", input.ContentID)), nil)
+		}
+		if contentCourseID != input.CourseID {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "El contenido no pertenece a este curso"), nil)
+		}
+
 		contentTypeID, err := c.Repo.GetContentTypeID(input.ContentID)
 		if err != nil {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, err.Error()), nil)
@@ -229,17 +252,28 @@ func (c *CourseContentController) UpdateContent() echo.HandlerFunc {
 			}
 		case 3: // Quiz
 			if input.JsonData != nil {
-				courseIDStr := strconv.Itoa(input.CourseID)
-				err = c.UploadQuizFunc(courseIDStr, input.ContentID, input.JsonData)
+				// Validar el quiz antes de escribir cualquier cosa en R2
+				if fieldErrors := ValidateTeacherQuiz(input.JsonData); fieldErrors != nil {
+					return quizDefinitionError(fieldErrors)
+				}
+				unsignedURL, _, err = c.publishTeacherQuiz(input.CourseID, input.ContentID, input.JsonData)
 				if err != nil {
-					return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, "error al subir quiz a R2"), nil)
+					return ReturnWriteResponse(e, err, nil)
 				}
-				// Generate unsigned URL for quiz (teacher version)
-				accountID := os.Getenv("R2_ACCOUNT_ID")
-				unsignedURL = fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/%s/quiz/teacher/%s.json",
-					accountID, courseIDStr, input.ContentID) // Fixed: use ContentID instead of CourseID
 				input.Url = unsignedURL
 			}
+		case 4: // Encuesta
+			if input.JsonData != nil {
+				if fieldErrors := ValidateSurvey(input.JsonData); fieldErrors != nil {
+					return definitionError("Error on survey definition", fieldErrors)
+				}
+				courseIDStr := strconv.Itoa(input.CourseID)
+				if err := c.UploadSurveyFunc(courseIDStr, input.ContentID, input.JsonData); err != nil {
+					return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, "error al subir encuesta a R2"), nil)
+				}
+				input.Url = fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/%s/survey/teacher/%s.json",
+					os.Getenv("R2_ACCOUNT_ID"), courseIDStr, input.ContentID)
+			}
 		case 1:
 			input.Url = input.VideoID
 		default:
@@ -254,6 +288,53 @@ func (c *CourseContentController) UpdateContent() echo.HandlerFunc {
 		return ReturnWriteResponse(e, nil, map[string]string{"message": "Contenido actualizado"})
 	}
 }
+
+// quizDefinitionError devuelve los errores de validación del quiz por campo
+func quizDefinitionError(fieldErrors map[string]string) error {
+	return definitionError("Error on quiz definition", fieldErrors)
+}
+
+// definitionError devuelve un 400 con los errores de validación por campo
+func definitionError(message string, fieldErrors map[string]string) error {
+	return echo.NewHTTPError(http.StatusBadRequest, struct {
+		Message string            `json:"message"`
+		Body    map[string]string `json:"body"`
+	}{Message: message,
+		Body: fieldErrors,
+	})
+}
+
+// publishTeacherQuiz sube una nueva versión del quiz a R2 (copias del profesor y del estudiante) y la registra.
+// Devuelve la URL sin firmar de la copia del profesor y el número de versión.
+func (c *CourseContentController) publishTeacherQuiz(courseID int, contentID string, jsonBytes []byte) (string, int, error) {
+	courseIDStr := strconv.Itoa(courseID)
+	accountID := os.Getenv("R2_ACCOUNT_ID")
+	// Cada edición crea una versión inmutable; los intentos quedan fijados a la versión que se respondió.
+	// Dentro de la transacción solo se sube v<n>.json: si falla, la próxima versión reutiliza esa clave.
+	var uploadErr error
+	version, err := c.QuizVersionRepo.CreateQuizVersion(contentID, func(version int) (string, error) {
+		if uploadErr = c.UploadQuizVersionFunc(courseIDStr, contentID, version, jsonBytes); uploadErr != nil {
+			return "", uploadErr
+		}
+		return fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/%s/quiz/teacher/%s/v%d.json",
+			accountID, courseIDStr, contentID, version), nil
+	})
+	if uploadErr != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "error al subir quiz a R2")
+	}
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "error al registrar la versión del quiz")
+	}
+	// Las copias vigentes del profesor y del estudiante se actualizan solo con la versión ya registrada
+	if err := c.UploadQuizFunc(courseIDStr, contentID, jsonBytes); err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "error al subir quiz a R2")
+	}
+	// Generate unsigned URL for quiz (teacher version)
+	unsignedURL := fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/%s/quiz/teacher/%s.json",
+		accountID, courseIDStr, contentID)
+	return unsignedURL, version.Version, nil
+}
+
 func (c *CourseContentController) UpdateContentStatus() echo.HandlerFunc {
 	return func(e echo.Context) error {
 		var input domain.UpdateContentStatusInput
@@ -282,6 +363,150 @@ func (c *CourseContentController) UpdateModuleTitle() echo.HandlerFunc {
 	}
 }
 
+// ReorderModules cambia el orden de los módulos de un curso
+func (c *CourseContentController) ReorderModules() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		var input domain.ReorderModulesInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		if _, err := c.RepoCourse.GetCourseByTeacherAndCourseID(userID, input.CourseID); err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor")
+		}
+
+		if err := c.Repo.ReorderModules(input.CourseID, input.CourseContentIDs); err != nil {
+			return reorderError(e, err)
+		}
+		return ReturnWriteResponse(e, nil, map[string]string{"message": "Módulos reordenados"})
+	}
+}
+
+// ReorderSections cambia el orden de las secciones de un módulo y mueve a él las secciones
+// de otros módulos del curso que vengan en la lista
+func (c *CourseContentController) ReorderSections() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		var input domain.ReorderSectionsInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		if err := c.Repo.VerifyModuleOwnership(input.CourseContentID, userID); err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+
+		if err := c.Repo.ReorderSections(input.CourseContentID, input.ContentIDs); err != nil {
+			return reorderError(e, err)
+		}
+		return ReturnWriteResponse(e, nil, map[string]string{"message": "Secciones reordenadas"})
+	}
+}
+
+// reorderError devuelve el detalle de los órdenes inválidos; el resto de errores pasa por ReturnWriteResponse
+func reorderError(e echo.Context, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrValidationFailed):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrResourceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	default:
+		return ReturnWriteResponse(e, err, nil)
+	}
+}
+
+// DeleteSection borra una sección y sus archivos en R2. Si la sección tiene intentos de quiz
+// responde 409 con la cantidad, salvo que se confirme con ?force=true.
+func (c *CourseContentController) DeleteSection() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		contentID := e.Param("contentId")
+		force := e.QueryParam("force") == "true"
+
+		deleted, err := c.Repo.DeleteSection(contentID, userID, force)
+		if err != nil {
+			return deleteContentError(e, deleted, err)
+		}
+		return ReturnWriteResponse(e, nil, c.cleanupContentStorage(e, deleted, "Sección eliminada"))
+	}
+}
+
+// DeleteModule borra un módulo con todas sus secciones; la confirmación funciona igual que en DeleteSection
+func (c *CourseContentController) DeleteModule() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		courseContentID, err := strconv.Atoi(e.Param("courseContentId"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "course_content_id inválido")
+		}
+		force := e.QueryParam("force") == "true"
+
+		deleted, err := c.Repo.DeleteModule(courseContentID, userID, force)
+		if err != nil {
+			return deleteContentError(e, deleted, err)
+		}
+		return ReturnWriteResponse(e, nil, c.cleanupContentStorage(e, deleted, "Módulo eliminado"))
+	}
+}
+
+func deleteContentError(e echo.Context, deleted domain.DeletedContent, err error) error {
+	if errors.Is(err, domain.ErrContentHasAttempts) {
+		return echo.NewHTTPError(http.StatusConflict, map[string]interface{}{
+			"message":     fmt.Sprintf("El contenido tiene %d intentos de quiz; confirme con force=true para borrarlos", deleted.Attempts),
+			"attempts":    deleted.Attempts,
+			"content_ids": deleted.ContentIDs,
+		})
+	}
+	return ReturnWriteResponse(e, err, nil)
+}
+
+// contentStorageKeys son los prefijos en R2 de los archivos de un contenido. Los videos no tienen archivo:
+// su url es el ID del video.
+func contentStorageKeys(courseID int, contentID string) []string {
+	return []string{
+		fmt.Sprintf("focused/%d/quiz/teacher/%s.json", courseID, contentID),
+		fmt.Sprintf("focused/%d/quiz/teacher/%s/", courseID, contentID), // versiones
+		fmt.Sprintf("focused/%d/quiz/student/%s.json", courseID, contentID),
+		fmt.Sprintf("focused/%d/quiz/variant/%s/", courseID, contentID),
+		fmt.Sprintf("focused/%d/quiz/live/%s/", courseID, contentID),
+		fmt.Sprintf("focused/%d/text/teacher/%s.json", courseID, contentID),
+		fmt.Sprintf("focused/%d/survey/teacher/%s.json", courseID, contentID),
+	}
+}
+
+// cleanupContentStorage borra de R2 los archivos del contenido ya eliminado de la base de datos.
+// Un fallo en R2 no revierte el borrado: se informa en storage_errors.
+func (c *CourseContentController) cleanupContentStorage(e echo.Context, deleted domain.DeletedContent, message string) map[string]interface{} {
+	body := map[string]interface{}{
+		"message":          message,
+		"content_ids":      deleted.ContentIDs,
+		"deleted_attempts": deleted.Attempts,
+	}
+	if c.DeleteStorageFunc == nil {
+		return body
+	}
+	var prefixes []string
+	for _, contentID := range deleted.ContentIDs {
+		prefixes = append(prefixes, contentStorageKeys(deleted.CourseID, contentID)...)
+	}
+	// Las respuestas de los estudiantes no están bajo los prefijos del curso: se borra el archivo de cada intento
+	for _, answerURL := range deleted.AnswerURLs {
+		prefixes = append(prefixes, r2KeyFromURL(answerURL))
+	}
+	var storageErrors []string
+	for _, prefix := range prefixes {
+		if err := c.DeleteStorageFunc(prefix); err != nil {
+			e.Logger().Errorf("Failed to delete R2 objects under %s: %v", prefix, err)
+			storageErrors = append(storageErrors, prefix)
+		}
+	}
+	if len(storageErrors) > 0 {
+		body["storage_errors"] = storageErrors
+	}
+	return body
+}
+
 func (c *CourseContentController) UpdateUserContentStatus(statusID int) echo.HandlerFunc {
 	return func(e echo.Context) error {
 		userID := e.Get("user_id").(string)
@@ -291,6 +516,11 @@ func (c *CourseContentController) UpdateUserContentStatus(statusID int) echo.Han
 			return err
 		}
 
+		// Un contenido bloqueado no avanza hasta cumplir sus prerrequisitos
+		if err := checkContentUnlocked(c.Repo, input.ContentID, userID); err != nil {
+			return err
+		}
+
 		err := c.Repo.UpdateUserContentStatus(userID, input.ContentID, statusID)
 		if err != nil {
 			return ReturnWriteResponse(e, err, nil)
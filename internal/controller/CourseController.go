@@ -9,7 +9,10 @@ import (
 )
 
 type CourseController struct {
-	Repo domain.CourseRepo
+	Repo            domain.CourseRepo
+	ContentRepo     domain.CourseContentRepo
+	ListStorageFunc func(prefix string) ([]string, error)
+	CopyStorageFunc func(sourceKey, targetKey string) error
 }
 
 func (c *CourseController) CreateCourse() echo.HandlerFunc {
@@ -0,0 +1,474 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type GradebookController struct {
+	GradebookRepo     domain.GradebookRepo
+	CourseRepo        domain.CourseRepo
+	AssignmentRepo    domain.AssignmentRepo
+	CourseContentRepo domain.CourseContentRepo
+	QuizRepo          domain.QuizRepository
+}
+
+// defaultLetterScale se usa cuando el profesor no definió una escala propia
+var defaultLetterScale = []domain.LetterGrade{
+	{Letter: "A", MinPercent: 90},
+	{Letter: "B", MinPercent: 80},
+	{Letter: "C", MinPercent: 70},
+	{Letter: "D", MinPercent: 60},
+	{Letter: "F", MinPercent: 0},
+}
+
+// defaultGradebook es la configuración de un curso sin libro de calificaciones guardado:
+// mejor intento, aprobado con 60 % y todos los quizzes en una sola categoría
+func defaultGradebook(courseID int) domain.Gradebook {
+	return domain.Gradebook{
+		CourseID:      courseID,
+		AttemptPolicy: domain.AttemptPolicyBest,
+		PassThreshold: 60,
+		Categories:    []domain.GradebookCategory{},
+		LetterScale:   defaultLetterScale,
+	}
+}
+
+// teacherCourseID valida que el curso de la ruta pertenezca al profesor autenticado
+func (c *GradebookController) teacherCourseID(e echo.Context) (int, error) {
+	userID := e.Get("user_id").(string)
+	courseID, err := strconv.Atoi(e.Param("courseId"))
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido")
+	}
+	if _, err := c.CourseRepo.GetCourseByTeacherAndCourseID(userID, courseID); err != nil {
+		return 0, echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor")
+	}
+	return courseID, nil
+}
+
+func (c *GradebookController) loadGradebook(courseID int) (domain.Gradebook, error) {
+	gradebook, err := c.GradebookRepo.GetGradebook(courseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultGradebook(courseID), nil
+	}
+	if err != nil {
+		return domain.Gradebook{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el libro de calificaciones: %v", err))
+	}
+	if len(gradebook.LetterScale) == 0 {
+		gradebook.LetterScale = defaultLetterScale
+	}
+	return gradebook, nil
+}
+
+// courseQuizColumns lista los quizzes del curso en el orden de módulos y secciones
+func (c *GradebookController) courseQuizColumns(courseID int) ([]domain.GradebookColumn, error) {
+	modules, err := c.CourseContentRepo.GetContentByCourse(courseID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el contenido del curso: %v", err))
+	}
+	sort.SliceStable(modules, func(i, j int) bool { return modules[i].ModuleIndex < modules[j].ModuleIndex })
+
+	columns := []domain.GradebookColumn{}
+	for _, module := range modules {
+		details := append([]domain.Content(nil), module.Details...)
+		sort.SliceStable(details, func(i, j int) bool { return details[i].SectionIndex < details[j].SectionIndex })
+		for _, content := range details {
+			if content.ContentTypeID != 3 {
+				continue
+			}
+			columns = append(columns, domain.GradebookColumn{
+				ContentID: content.ContentID,
+				Title:     content.Title,
+				Module:    module.Module,
+			})
+		}
+	}
+	return columns, nil
+}
+
+// GetGradebookSettings devuelve las categorías, la política de intentos y la escala del curso
+func (c *GradebookController) GetGradebookSettings() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		courseID, err := c.teacherCourseID(e)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		gradebook, err := c.loadGradebook(courseID)
+		return ReturnReadResponse(e, err, gradebook)
+	}
+}
+
+// UpdateGradebookSettings reemplaza la configuración del libro de calificaciones del curso
+func (c *GradebookController) UpdateGradebookSettings() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		courseID, err := c.teacherCourseID(e)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		var input domain.GradebookInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		columns, err := c.courseQuizColumns(courseID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		if errorMap := validateGradebookInput(input, columns); errorMap != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, struct {
+				Message string            `json:"message"`
+				Body    map[string]string `json:"body"`
+			}{Message: "Error on gradebook settings", Body: errorMap})
+		}
+
+		gradebook := domain.Gradebook{
+			CourseID:      courseID,
+			AttemptPolicy: input.AttemptPolicy,
+			PassThreshold: input.PassThreshold,
+			Categories:    input.Categories,
+			LetterScale:   input.LetterScale,
+		}
+		if gradebook.Categories == nil {
+			gradebook.Categories = []domain.GradebookCategory{}
+		}
+		if err := c.GradebookRepo.SaveGradebook(gradebook); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar el libro de calificaciones: %v", err)), nil)
+		}
+		return ReturnWriteResponse(e, nil, gradebook)
+	}
+}
+
+// validateGradebookInput revisa que las categorías referencien quizzes del curso sin repetirlos
+// y que la escala de letras cubra desde 0%
+func validateGradebookInput(input domain.GradebookInput, columns []domain.GradebookColumn) map[string]string {
+	errorMap := make(map[string]string)
+
+	quizzes := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		quizzes[column.ContentID] = true
+	}
+
+	seenCategories := make(map[string]bool)
+	assigned := make(map[string]string)
+	for i, category := range input.Categories {
+		path := fmt.Sprintf("categories[%d]", i)
+		if seenCategories[category.ID] {
+			errorMap[path+".id"] = fmt.Sprintf("Duplicated category id %q", category.ID)
+		}
+		seenCategories[category.ID] = true
+
+		if category.Source == domain.CategorySourceProgress {
+			if len(category.ContentIDs) > 0 {
+				errorMap[path+".content_ids"] = "Progress categories cannot contain quizzes"
+			}
+			continue
+		}
+		for j, contentID := range category.ContentIDs {
+			field := fmt.Sprintf("%s.content_ids[%d]", path, j)
+			if !quizzes[contentID] {
+				errorMap[field] = fmt.Sprintf("Quiz %q does not belong to this course", contentID)
+			} else if previous, ok := assigned[contentID]; ok {
+				errorMap[field] = fmt.Sprintf("Quiz %q is already in category %q", contentID, previous)
+			}
+			assigned[contentID] = category.ID
+		}
+	}
+
+	seenLetters := make(map[string]bool)
+	hasFloor := false
+	for i, grade := range input.LetterScale {
+		if seenLetters[grade.Letter] {
+			errorMap[fmt.Sprintf("letter_scale[%d].letter", i)] = fmt.Sprintf("Duplicated letter %q", grade.Letter)
+		}
+		seenLetters[grade.Letter] = true
+		hasFloor = hasFloor || grade.MinPercent == 0
+	}
+	// Sin una letra desde 0% los promedios bajos se quedarían sin letra
+	if len(input.LetterScale) > 0 && !hasFloor {
+		errorMap["letter_scale"] = "Letter scale must include a grade with min_percent 0"
+	}
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+// buildReport reúne la configuración, los quizzes, los estudiantes, los intentos y el avance del curso
+func (c *GradebookController) buildReport(courseID int) (domain.GradebookReport, error) {
+	gradebook, err := c.loadGradebook(courseID)
+	if err != nil {
+		return domain.GradebookReport{}, err
+	}
+	columns, err := c.courseQuizColumns(courseID)
+	if err != nil {
+		return domain.GradebookReport{}, err
+	}
+	students, err := c.AssignmentRepo.GetStudentsByCourse(courseID)
+	if err != nil {
+		return domain.GradebookReport{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener los estudiantes del curso: %v", err))
+	}
+	attempts, err := c.QuizRepo.GetQuizAttemptsByCourse(courseID)
+	if err != nil {
+		return domain.GradebookReport{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err))
+	}
+	progress, err := c.GradebookRepo.GetCourseProgress(courseID)
+	if err != nil {
+		return domain.GradebookReport{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el avance del curso: %v", err))
+	}
+	return BuildGradebook(gradebook, columns, students, attempts, progress), nil
+}
+
+// GetGradebook devuelve la matriz de estudiantes por quizzes con la nota final de cada estudiante
+func (c *GradebookController) GetGradebook() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		courseID, err := c.teacherCourseID(e)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		report, err := c.buildReport(courseID)
+		return ReturnReadResponse(e, err, report)
+	}
+}
+
+// ExportGradebook descarga el libro de calificaciones como CSV
+func (c *GradebookController) ExportGradebook() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		courseID, err := c.teacherCourseID(e)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		report, err := c.buildReport(courseID)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		csvBytes, err := GradebookCSV(report)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al generar el CSV: %v", err)), nil)
+		}
+		e.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"gradebook-%d.csv\"", courseID))
+		return e.Blob(http.StatusOK, "text/csv; charset=utf-8", csvBytes)
+	}
+}
+
+// BuildGradebook calcula la nota de cada estudiante por quiz, por categoría y final.
+// Los quizzes sin intentos enviados no cuentan en el promedio de su categoría, y las categorías
+// sin notas no cuentan en la nota final: los pesos se reparten entre las que sí tienen.
+func BuildGradebook(gradebook domain.Gradebook, columns []domain.GradebookColumn, students []domain.AssignmentWithStudent, attempts []domain.QuizAttemptView, progress []domain.StudentCourseProgress) domain.GradebookReport {
+	categories := gradebook.Categories
+	if len(categories) == 0 {
+		all := make([]string, 0, len(columns))
+		for _, column := range columns {
+			all = append(all, column.ContentID)
+		}
+		categories = []domain.GradebookCategory{{ID: "quizzes", Name: "Quizzes", Weight: 100, ContentIDs: all}}
+	}
+
+	categoryByQuiz := make(map[string]string)
+	for _, category := range categories {
+		for _, contentID := range category.ContentIDs {
+			categoryByQuiz[contentID] = category.ID
+		}
+	}
+	for i := range columns {
+		columns[i].CategoryID = categoryByQuiz[columns[i].ContentID]
+	}
+
+	attemptsByStudent := make(map[string]map[string][]domain.QuizAttemptView)
+	for _, attempt := range attempts {
+		if attempt.EndTime.IsZero() || attempt.Grade == nil || attempt.TotalPoints == nil || *attempt.TotalPoints <= 0 {
+			continue
+		}
+		if attemptsByStudent[attempt.UserID] == nil {
+			attemptsByStudent[attempt.UserID] = make(map[string][]domain.QuizAttemptView)
+		}
+		attemptsByStudent[attempt.UserID][attempt.ContentID] = append(attemptsByStudent[attempt.UserID][attempt.ContentID], attempt)
+	}
+
+	progressByStudent := make(map[string]float64)
+	for _, p := range progress {
+		progressByStudent[p.UserID] = p.CompletionPercentage
+	}
+
+	scale := append([]domain.LetterGrade(nil), gradebook.LetterScale...)
+	sort.SliceStable(scale, func(i, j int) bool { return scale[i].MinPercent > scale[j].MinPercent })
+
+	rows := make([]domain.GradebookRow, 0, len(students))
+	for _, student := range students {
+		row := domain.GradebookRow{
+			UserID:     student.UserID,
+			Name:       student.Name,
+			Lastname:   student.Lastname,
+			Email:      student.Email,
+			Quizzes:    make(map[string]domain.GradebookCell, len(columns)),
+			Categories: make(map[string]*float64, len(categories)),
+			Progress:   progressByStudent[student.UserID],
+		}
+		for _, column := range columns {
+			row.Quizzes[column.ContentID] = gradebookCell(gradebook.AttemptPolicy, attemptsByStudent[student.UserID][column.ContentID])
+		}
+
+		var weighted, weights float64
+		for _, category := range categories {
+			var score *float64
+			if category.Source == domain.CategorySourceProgress {
+				value := row.Progress
+				score = &value
+			} else {
+				score = averagePercent(category.ContentIDs, row.Quizzes)
+			}
+			row.Categories[category.ID] = score
+			if score != nil {
+				weighted += *score * category.Weight
+				weights += category.Weight
+			}
+		}
+		if weights > 0 {
+			final := weighted / weights
+			row.Final = &final
+			row.Letter = letterFor(scale, final)
+			row.Passed = final >= gradebook.PassThreshold
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Lastname != rows[j].Lastname {
+			return rows[i].Lastname < rows[j].Lastname
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	settings := gradebook
+	settings.Categories = categories
+	return domain.GradebookReport{Settings: settings, Columns: columns, Rows: rows}
+}
+
+// gradebookCell combina los intentos de un estudiante en un quiz según la política
+func gradebookCell(policy string, attempts []domain.QuizAttemptView) domain.GradebookCell {
+	cell := domain.GradebookCell{Attempts: len(attempts)}
+	if len(attempts) == 0 {
+		return cell
+	}
+	percent := func(attempt domain.QuizAttemptView) float64 {
+		return *attempt.Grade / float64(*attempt.TotalPoints) * 100
+	}
+	pending := func(attempt domain.QuizAttemptView) bool {
+		return attempt.NeedsReview && attempt.ReviewedAt == nil
+	}
+
+	var value float64
+	switch policy {
+	case domain.AttemptPolicyLatest:
+		latest := attempts[0]
+		for _, attempt := range attempts[1:] {
+			if attempt.EndTime.After(latest.EndTime) {
+				latest = attempt
+			}
+		}
+		value = percent(latest)
+		cell.NeedsReview = pending(latest)
+	case domain.AttemptPolicyAverage:
+		for _, attempt := range attempts {
+			value += percent(attempt)
+			cell.NeedsReview = cell.NeedsReview || pending(attempt)
+		}
+		value /= float64(len(attempts))
+	default:
+		for i, attempt := range attempts {
+			if p := percent(attempt); i == 0 || p > value {
+				value = p
+			}
+			cell.NeedsReview = cell.NeedsReview || pending(attempt)
+		}
+	}
+	cell.Percent = &value
+	return cell
+}
+
+func averagePercent(contentIDs []string, cells map[string]domain.GradebookCell) *float64 {
+	var sum float64
+	var count int
+	for _, contentID := range contentIDs {
+		if cell, ok := cells[contentID]; ok && cell.Percent != nil {
+			sum += *cell.Percent
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	average := sum / float64(count)
+	return &average
+}
+
+// letterFor devuelve la letra de la escala (ordenada de mayor a menor) para un porcentaje
+func letterFor(scale []domain.LetterGrade, percent float64) string {
+	for _, grade := range scale {
+		if percent >= grade.MinPercent {
+			return grade.Letter
+		}
+	}
+	return ""
+}
+
+// GradebookCSV genera el CSV con una fila por estudiante y una columna por quiz y por categoría
+func GradebookCSV(report domain.GradebookReport) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"user_id", "lastname", "name", "email"}
+	for _, column := range report.Columns {
+		header = append(header, csvSafeCell(column.Title))
+	}
+	for _, category := range report.Settings.Categories {
+		header = append(header, csvSafeCell(category.Name))
+	}
+	header = append(header, "progress", "final", "letter", "passed")
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, row := range report.Rows {
+		record := []string{csvSafeCell(row.UserID), csvSafeCell(row.Lastname), csvSafeCell(row.Name), csvSafeCell(row.Email)}
+		for _, column := range report.Columns {
+			record = append(record, formatPercent(row.Quizzes[column.ContentID].Percent))
+		}
+		for _, category := range report.Settings.Categories {
+			record = append(record, formatPercent(row.Categories[category.ID]))
+		}
+		progress := row.Progress
+		record = append(record, formatPercent(&progress), formatPercent(row.Final), csvSafeCell(row.Letter), strconv.FormatBool(row.Passed))
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func formatPercent(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
+}
+
+// csvSafeCell antepone una comilla a los textos que una hoja de cálculo interpretaría como fórmula
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
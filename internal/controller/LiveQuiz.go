@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"zeppelin/internal/domain"
+)
+
+// LiveQuizSession es un quiz en vivo: el profesor envía una pregunta a la vez a todos los
+// estudiantes conectados al curso y las respuestas se califican al recibirse.
+type LiveQuizSession struct {
+	mu            sync.Mutex
+	CourseID      int
+	ContentID     string
+	TeacherID     string
+	Quiz          domain.TeacherQuiz
+	QuizURL       string
+	QuizVersionID *int
+	StartedAt     time.Time
+	current       int  // índice de la pregunta actual; -1 antes de la primera
+	open          bool // la pregunta actual acepta respuestas
+	ended         bool
+	openedAt      time.Time
+	participants  map[string]*liveParticipant
+}
+
+type liveParticipant struct {
+	name         string
+	answers      map[string]interface{}
+	grades       map[string]domain.QuestionGrade
+	responseTime time.Duration // suma del tiempo que tardó en responder; desempata el ranking
+}
+
+// NewLiveQuizSession crea la sesión en vivo de un quiz; todavía no hay ninguna pregunta abierta
+func NewLiveQuizSession(courseID int, contentID, teacherID string, quiz domain.TeacherQuiz, startedAt time.Time) *LiveQuizSession {
+	return &LiveQuizSession{
+		CourseID:     courseID,
+		ContentID:    contentID,
+		TeacherID:    teacherID,
+		Quiz:         quiz,
+		StartedAt:    startedAt,
+		current:      -1,
+		participants: make(map[string]*liveParticipant),
+	}
+}
+
+// liveQuizSupported indica si todos los estudiantes pueden ver las mismas preguntas del quiz
+func liveQuizSupported(quiz domain.TeacherQuiz) error {
+	if len(quiz.Questions) == 0 {
+		return errors.New("el quiz no tiene preguntas")
+	}
+	if len(quiz.Pools) > 0 {
+		return errors.New("los quizzes con bancos de preguntas no se pueden usar en vivo")
+	}
+	for _, question := range quiz.Questions {
+		if question.Type == "calculated" {
+			return errors.New("los quizzes con preguntas calculated no se pueden usar en vivo")
+		}
+	}
+	return nil
+}
+
+// NextQuestion cierra la pregunta actual y abre la siguiente
+func (s *LiveQuizSession) NextQuestion(now time.Time) (domain.TeacherQuizQuestion, error) {
+	if s.ended {
+		return domain.TeacherQuizQuestion{}, errors.New("el quiz en vivo ya terminó")
+	}
+	if s.current+1 >= len(s.Quiz.Questions) {
+		return domain.TeacherQuizQuestion{}, errors.New("no quedan preguntas")
+	}
+	s.current++
+	s.open = true
+	s.openedAt = now
+	return s.Quiz.Questions[s.current], nil
+}
+
+// CloseQuestion deja de aceptar respuestas para la pregunta actual
+func (s *LiveQuizSession) CloseQuestion() (domain.TeacherQuizQuestion, error) {
+	if !s.open {
+		return domain.TeacherQuizQuestion{}, errors.New("no hay una pregunta abierta")
+	}
+	s.open = false
+	return s.Quiz.Questions[s.current], nil
+}
+
+// CurrentQuestion devuelve la pregunta actual y si acepta respuestas
+func (s *LiveQuizSession) CurrentQuestion() (domain.TeacherQuizQuestion, int, bool) {
+	if s.current < 0 {
+		return domain.TeacherQuizQuestion{}, -1, false
+	}
+	return s.Quiz.Questions[s.current], s.current, s.open
+}
+
+// SubmitAnswer califica la respuesta de un estudiante a la pregunta abierta. Solo cuenta la primera respuesta.
+func (s *LiveQuizSession) SubmitAnswer(userID, name, questionID string, answer interface{}, now time.Time) (domain.QuestionGrade, error) {
+	if s.ended {
+		return domain.QuestionGrade{}, errors.New("el quiz en vivo ya terminó")
+	}
+	if !s.open {
+		return domain.QuestionGrade{}, errors.New("la pregunta no acepta respuestas")
+	}
+	question := s.Quiz.Questions[s.current]
+	if questionID != question.ID {
+		return domain.QuestionGrade{}, fmt.Errorf("la pregunta %s no es la pregunta actual", questionID)
+	}
+	participant, ok := s.participants[userID]
+	if !ok {
+		participant = &liveParticipant{
+			name:    name,
+			answers: make(map[string]interface{}),
+			grades:  make(map[string]domain.QuestionGrade),
+		}
+		s.participants[userID] = participant
+	}
+	if _, answered := participant.answers[questionID]; answered {
+		return domain.QuestionGrade{}, errors.New("ya respondió esta pregunta")
+	}
+	if answer == nil {
+		return domain.QuestionGrade{}, errors.New("la respuesta está vacía")
+	}
+	if questionType, ok := LookupQuestionType(question.Type); ok {
+		if err := questionType.ValidateAnswer(question, answer); err != nil {
+			return domain.QuestionGrade{}, err
+		}
+	}
+
+	grade := gradeQuestion(question, answer)
+	participant.answers[questionID] = answer
+	participant.grades[questionID] = grade
+	participant.responseTime += now.Sub(s.openedAt)
+	return grade, nil
+}
+
+// Answered devuelve cuántos estudiantes respondieron la pregunta actual
+func (s *LiveQuizSession) Answered() int {
+	question, index, _ := s.CurrentQuestion()
+	if index < 0 {
+		return 0
+	}
+	count := 0
+	for _, participant := range s.participants {
+		if _, ok := participant.answers[question.ID]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// Leaderboard ordena a los estudiantes por puntos; a igual puntaje gana quien respondió más rápido
+func (s *LiveQuizSession) Leaderboard() []domain.LiveQuizLeaderboardEntry {
+	type ranked struct {
+		entry        domain.LiveQuizLeaderboardEntry
+		responseTime time.Duration
+	}
+	rows := make([]ranked, 0, len(s.participants))
+	for userID, participant := range s.participants {
+		entry := domain.LiveQuizLeaderboardEntry{UserID: userID, Name: participant.name, Answered: len(participant.answers)}
+		for _, grade := range participant.grades {
+			entry.Score += grade.Earned
+			if grade.IsCorrect {
+				entry.Correct++
+			}
+		}
+		rows = append(rows, ranked{entry: entry, responseTime: participant.responseTime})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].entry.Score != rows[j].entry.Score {
+			return rows[i].entry.Score > rows[j].entry.Score
+		}
+		if rows[i].responseTime != rows[j].responseTime {
+			return rows[i].responseTime < rows[j].responseTime
+		}
+		return rows[i].entry.UserID < rows[j].entry.UserID
+	})
+
+	leaderboard := make([]domain.LiveQuizLeaderboardEntry, len(rows))
+	for i, row := range rows {
+		row.entry.Rank = i + 1
+		leaderboard[i] = row.entry
+	}
+	return leaderboard
+}
+
+// Histogram cuenta las respuestas de la pregunta actual: por opción en las preguntas de opciones
+// y por resultado (correct, incorrect, pending) en las demás
+func (s *LiveQuizSession) Histogram() []domain.LiveQuizBucket {
+	question, index, _ := s.CurrentQuestion()
+	if index < 0 {
+		return nil
+	}
+
+	var labels []string
+	switch question.Type {
+	case "multiple", "checkbox":
+		labels = question.Options
+	case "boolean":
+		labels = []string{"true", "false"}
+	default:
+		labels = []string{"correct", "incorrect", "pending"}
+	}
+	counts := make(map[string]int, len(labels))
+	for _, participant := range s.participants {
+		answer, ok := participant.answers[question.ID]
+		if !ok {
+			continue
+		}
+		switch question.Type {
+		case "multiple":
+			if choice, ok := answer.(string); ok {
+				counts[choice]++
+			}
+		case "checkbox":
+			choices, _ := toStringSlice(answer)
+			for _, choice := range choices {
+				counts[choice]++
+			}
+		case "boolean":
+			value, _ := parseBoolAnswer(answer)
+			counts[fmt.Sprintf("%t", value)]++
+		default:
+			grade := participant.grades[question.ID]
+			switch {
+			case grade.NeedsReview:
+				counts["pending"]++
+			case grade.IsCorrect:
+				counts["correct"]++
+			default:
+				counts["incorrect"]++
+			}
+		}
+	}
+
+	histogram := make([]domain.LiveQuizBucket, len(labels))
+	for i, label := range labels {
+		histogram[i] = domain.LiveQuizBucket{Label: label, Count: counts[label]}
+	}
+	return histogram
+}
+
+// askedQuestions devuelve el quiz reducido a las preguntas que se llegaron a enviar
+func (s *LiveQuizSession) askedQuestions() domain.TeacherQuiz {
+	asked := s.Quiz
+	asked.Questions = s.Quiz.Questions[:s.current+1]
+	return asked
+}
+
+// liveStudentQuestion quita la clave de respuestas de una pregunta antes de enviarla a los estudiantes
+func liveStudentQuestion(question domain.TeacherQuizQuestion) domain.TeacherQuizQuestion {
+	return studentQuizCopy(domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{question}}).Questions[0]
+}
+
+// PersistLiveQuiz termina la sesión y guarda un intento en quiz_answer por cada estudiante que respondió,
+// calificado sobre las preguntas que se enviaron. Devuelve cuántos intentos se guardaron.
+func (c *QuizController) PersistLiveQuiz(session *LiveQuizSession, now time.Time) (int, error) {
+	session.open = false
+	session.ended = true
+	if session.current < 0 || len(session.participants) == 0 {
+		return 0, nil
+	}
+
+	// Las preguntas enviadas se guardan como la variante de los intentos para que los reportes
+	// y la recalificación usen exactamente lo que vieron los estudiantes
+	accountID := os.Getenv("R2_ACCOUNT_ID")
+	asked := session.askedQuestions()
+	variantKey := fmt.Sprintf("focused/%d/quiz/variant/%s/live-%d.json", session.CourseID, session.ContentID, session.StartedAt.Unix())
+	if err := c.uploadQuizVariant(variantKey, asked); err != nil {
+		return 0, err
+	}
+	variantURL := fmt.Sprintf("https://%s.r2.cloudflarestorage.com/%s", accountID, variantKey)
+
+	userIDs := make([]string, 0, len(session.participants))
+	for userID := range session.participants {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	saved := 0
+	var failures []string
+	for _, userID := range userIDs {
+		if err := c.saveLiveAttempt(session, asked, variantURL, userID, now); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", userID, err))
+			continue
+		}
+		saved++
+	}
+	if len(failures) > 0 {
+		return saved, fmt.Errorf("no se guardaron %d intentos: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return saved, nil
+}
+
+func (c *QuizController) saveLiveAttempt(session *LiveQuizSession, asked domain.TeacherQuiz, variantURL, userID string, now time.Time) error {
+	answers := session.participants[userID].answers
+	answersBytes, err := json.Marshal(answers)
+	if err != nil {
+		return fmt.Errorf("error al serializar respuestas: %w", err)
+	}
+	answersKey := fmt.Sprintf("focused/%d/quiz/live/%s/%d/%s.json", session.CourseID, session.ContentID, session.StartedAt.Unix(), userID)
+	if err := c.UploadStudentAnswers(answersKey, answersBytes); err != nil {
+		return fmt.Errorf("error al subir respuestas a R2: %w", err)
+	}
+
+	gradeResult := c.GradeQuiz(asked, answers)
+	score, totalPoints := gradeResult.Score, gradeResult.TotalPoints
+	var reviewedAt *time.Time
+	if !gradeResult.NeedsReview {
+		reviewedAt = &now
+	}
+	attempt := domain.QuizAnswer{
+		ContentID:     session.ContentID,
+		UserID:        userID,
+		StartTime:     session.StartedAt,
+		EndTime:       &now,
+		Grade:         &score,
+		RawGrade:      &score,
+		ReviewedAt:    reviewedAt,
+		QuizURL:       session.QuizURL,
+		QuizVersionID: session.QuizVersionID,
+		VariantURL:    variantURL,
+		QuizAnswerURL: fmt.Sprintf("https://%s.r2.cloudflarestorage.com/%s", os.Getenv("R2_ACCOUNT_ID"), answersKey),
+		TotalPoints:   &totalPoints,
+	}
+	quizAnswerID, err := c.QuizRepo.SaveQuizAttempt(attempt, 0)
+	if err != nil {
+		return fmt.Errorf("error al guardar el intento: %w", err)
+	}
+	if err := c.QuizRepo.SaveQuizAnswerItems(buildAnswerItems(quizAnswerID, gradeResult, answers, now)); err != nil {
+		return fmt.Errorf("error al guardar las calificaciones por pregunta: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// analysisGroupRatio es la proporción de estudiantes en los grupos superior e inferior
+const analysisGroupRatio = 0.27
+
+// GetQuizItemAnalysis devuelve la dificultad, la discriminación y la frecuencia de distractores
+// de cada pregunta de un quiz. Solo se considera el primer intento enviado de cada estudiante.
+func (c *QuizController) GetQuizItemAnalysis() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		contentID := e.Param("contentId")
+		courseID, err := strconv.Atoi(e.Param("courseId"))
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido"), nil)
+		}
+
+		Url, quizCourseID, err := c.teacherQuizCourse(userID, contentID)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		if quizCourseID != courseID {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("el quiz %s no pertenece al curso %d", contentID, courseID)), nil)
+		}
+		Url, _, err = c.currentQuizVersion(contentID, Url)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		quiz, err := c.loadTeacherQuiz(Url)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+
+		courseAttempts, err := c.QuizRepo.GetQuizAttemptsByCourse(courseID)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
+		}
+		attempts := firstSubmittedAttempts(courseAttempts, contentID)
+
+		attemptIDs := make([]int, 0, len(attempts))
+		for _, attempt := range attempts {
+			attemptIDs = append(attemptIDs, attempt.QuizAnswerID)
+		}
+		items, err := c.QuizRepo.FindQuizAnswerItemsByAttempts(attemptIDs)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las calificaciones por pregunta: %v", err)), nil)
+		}
+		itemsByAttempt := make(map[int][]domain.QuizAnswerItem)
+		for _, item := range items {
+			itemsByAttempt[item.QuizAnswerID] = append(itemsByAttempt[item.QuizAnswerID], item)
+		}
+
+		// Los intentos anteriores a quiz_answer_item se califican a partir de las respuestas guardadas en R2,
+		// contra la versión (o variante) del quiz que respondió el estudiante
+		attemptQuizzes := make(map[string]domain.TeacherQuiz)
+		for _, attempt := range attempts {
+			if _, ok := itemsByAttempt[attempt.QuizAnswerID]; ok {
+				continue
+			}
+			quizAnswer, err := c.QuizRepo.FindQuizAttemptByID(attempt.QuizAnswerID)
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el intento %d: %v", attempt.QuizAnswerID, err)), nil)
+			}
+			attemptURL := attemptQuizURL(quizAnswer)
+			attemptQuiz, ok := attemptQuizzes[attemptURL]
+			if !ok {
+				if attemptQuiz, err = c.loadTeacherQuiz(attemptURL); err != nil {
+					return ReturnReadResponse(e, err, nil)
+				}
+				attemptQuizzes[attemptURL] = attemptQuiz
+			}
+			answersBytes, err := c.GetTeacherQuizContent("zeppelin", r2KeyFromURL(attempt.QuizAnswerURL))
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener respuestas del estudiante desde R2: %v", err)), nil)
+			}
+			var answers map[string]interface{}
+			if err := json.Unmarshal(answersBytes, &answers); err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear respuestas del estudiante: %v", err)), nil)
+			}
+			result := c.GradeQuiz(domain.TeacherQuiz{Questions: attemptQuiz.Questions}, answers)
+			itemsByAttempt[attempt.QuizAnswerID] = buildAnswerItems(attempt.QuizAnswerID, result, answers, attempt.EndTime)
+		}
+
+		analysis := AnalyzeQuizItems(quiz, attempts, itemsByAttempt)
+		analysis.ContentID = contentID
+		analysis.CourseID = courseID
+		return ReturnReadResponse(e, nil, analysis)
+	}
+}
+
+// firstSubmittedAttempts devuelve el primer intento enviado de cada estudiante para un quiz
+func firstSubmittedAttempts(attempts []domain.QuizAttemptView, contentID string) []domain.QuizAttemptView {
+	first := make(map[string]domain.QuizAttemptView)
+	for _, attempt := range attempts {
+		if attempt.ContentID != contentID || attempt.EndTime.IsZero() {
+			continue
+		}
+		if current, ok := first[attempt.UserID]; !ok || attempt.StartTime.Before(current.StartTime) {
+			first[attempt.UserID] = attempt
+		}
+	}
+	result := make([]domain.QuizAttemptView, 0, len(first))
+	for _, attempt := range first {
+		result = append(result, attempt)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].QuizAnswerID < result[j].QuizAnswerID })
+	return result
+}
+
+// AnalyzeQuizItems calcula las estadísticas por pregunta a partir de las calificaciones por pregunta
+// de cada intento. La discriminación compara el 27 % de intentos con mejor nota contra el 27 % peor.
+func AnalyzeQuizItems(quiz domain.TeacherQuiz, attempts []domain.QuizAttemptView, items map[int][]domain.QuizAnswerItem) domain.QuizItemAnalysis {
+	analysis := domain.QuizItemAnalysis{
+		QuizTitle: quiz.Title,
+		Attempts:  len(attempts),
+		Questions: []domain.QuestionAnalysis{},
+	}
+	if len(attempts) == 0 {
+		return analysis
+	}
+
+	// Nota relativa de cada intento para formar los grupos
+	ranked := make([]domain.QuizAttemptView, len(attempts))
+	copy(ranked, attempts)
+	fractions := make(map[int]float64, len(attempts))
+	var totalScore, totalSeconds float64
+	for _, attempt := range attempts {
+		fractions[attempt.QuizAnswerID] = attemptFraction(attempt, items[attempt.QuizAnswerID])
+		if attempt.Grade != nil {
+			totalScore += *attempt.Grade
+		}
+		totalSeconds += attempt.EndTime.Sub(attempt.StartTime).Seconds()
+	}
+	analysis.AverageScore = totalScore / float64(len(attempts))
+	analysis.AverageTimeSeconds = totalSeconds / float64(len(attempts))
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return fractions[ranked[i].QuizAnswerID] > fractions[ranked[j].QuizAnswerID]
+	})
+
+	upper := make(map[int]bool)
+	lower := make(map[int]bool)
+	if len(ranked) >= 2 {
+		analysis.GroupSize = max(1, int(math.Round(analysisGroupRatio*float64(len(ranked)))))
+		for i := 0; i < analysis.GroupSize; i++ {
+			upper[ranked[i].QuizAnswerID] = true
+			lower[ranked[len(ranked)-1-i].QuizAnswerID] = true
+		}
+	}
+
+	for _, question := range quizQuestions(quiz) {
+		analysis.Questions = append(analysis.Questions, analyzeQuestion(question, attempts, items, upper, lower))
+	}
+	return analysis
+}
+
+func analyzeQuestion(question domain.TeacherQuizQuestion, attempts []domain.QuizAttemptView, items map[int][]domain.QuizAnswerItem, upper, lower map[int]bool) domain.QuestionAnalysis {
+	stats := domain.QuestionAnalysis{
+		QuestionID: question.ID,
+		Question:   question.Question,
+		Type:       question.Type,
+		Points:     question.Points,
+	}
+
+	countsByOption := make(map[string]int)
+	var sum, upperSum, lowerSum float64
+	var seen, upperCount, lowerCount int
+	for _, attempt := range attempts {
+		item, ok := findAnswerItem(items[attempt.QuizAnswerID], question.ID)
+		if !ok {
+			continue // la pregunta no le tocó en su variante
+		}
+		seen++
+
+		var answer interface{}
+		if item.Answer != "" {
+			_ = json.Unmarshal([]byte(item.Answer), &answer)
+		}
+		if answer == nil || answer == "" {
+			stats.Omitted++
+		}
+		if selected, ok := answer.(string); ok {
+			countsByOption[selected]++
+		} else if selected, err := toStringSlice(answer); err == nil {
+			for _, option := range selected {
+				countsByOption[option]++
+			}
+		}
+
+		if item.Score == nil {
+			stats.Pending++
+			continue
+		}
+		stats.Responses++
+		if item.IsCorrect != nil && *item.IsCorrect {
+			stats.Correct++
+		}
+		fraction := itemFraction(item)
+		sum += fraction
+		if upper[attempt.QuizAnswerID] {
+			upperSum += fraction
+			upperCount++
+		}
+		if lower[attempt.QuizAnswerID] {
+			lowerSum += fraction
+			lowerCount++
+		}
+	}
+
+	if stats.Responses > 0 {
+		difficulty := sum / float64(stats.Responses)
+		stats.Difficulty = &difficulty
+	}
+	if upperCount > 0 && lowerCount > 0 {
+		discrimination := upperSum/float64(upperCount) - lowerSum/float64(lowerCount)
+		stats.Discrimination = &discrimination
+	}
+
+	if question.Type == "multiple" || question.Type == "checkbox" {
+		correct := question.CorrectAnswers
+		if question.Type == "multiple" {
+			if answer, ok := question.CorrectAnswer.(string); ok {
+				correct = []string{answer}
+			}
+		}
+		for _, option := range question.Options {
+			distractor := domain.DistractorStat{
+				Option:    option,
+				IsCorrect: containsString(correct, option),
+				Count:     countsByOption[option],
+			}
+			if seen > 0 {
+				distractor.Ratio = float64(distractor.Count) / float64(seen)
+			}
+			stats.Distractors = append(stats.Distractors, distractor)
+		}
+	}
+	return stats
+}
+
+func findAnswerItem(items []domain.QuizAnswerItem, questionID string) (domain.QuizAnswerItem, bool) {
+	for _, item := range items {
+		if item.QuestionID == questionID {
+			return item, true
+		}
+	}
+	return domain.QuizAnswerItem{}, false
+}
+
+// itemFraction es la fracción de puntos obtenida en una pregunta calificada
+func itemFraction(item domain.QuizAnswerItem) float64 {
+	if item.Points > 0 {
+		return *item.Score / float64(item.Points)
+	}
+	if item.IsCorrect != nil && *item.IsCorrect {
+		return 1
+	}
+	return 0
+}
+
+// attemptFraction es la nota del intento sobre el total de puntos
+func attemptFraction(attempt domain.QuizAttemptView, items []domain.QuizAnswerItem) float64 {
+	if attempt.Grade != nil && attempt.TotalPoints != nil && *attempt.TotalPoints > 0 {
+		return *attempt.Grade / float64(*attempt.TotalPoints)
+	}
+	var earned, points float64
+	for _, item := range items {
+		points += float64(item.Points)
+		if item.Score != nil {
+			earned += *item.Score
+		}
+	}
+	if points == 0 {
+		return 0
+	}
+	return earned / points
+}
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"reflect"
@@ -13,10 +15,15 @@ import (
 	"zeppelin/internal/domain"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type QuizController struct {
 	QuizRepo              domain.QuizRepository
+	QuizVersionRepo       domain.QuizVersionRepo
+	DueDateRepo           domain.DueDateRepo
+	AnswerReleaseRepo     domain.AnswerReleaseRepo
+	SubmissionKeyRepo     domain.SubmissionKeyRepo
 	CourseContentRepo     domain.CourseContentRepo
 	AssignmentRepo        domain.AssignmentRepo
 	CourseRepo            domain.CourseRepo
@@ -24,6 +31,264 @@ type QuizController struct {
 	UploadStudentAnswers  func(key string, data []byte) error
 	GetTeacherQuizContent func(bucket, key string) ([]byte, error)
 	GeneratePresignedURL  func(bucket, key string) (string, error)
+	RegradeJobs           *RegradeJobManager
+	QuizCache             *QuizVersionCache // opcional: sin caché cada consulta descarga el quiz de R2
+}
+
+// quizSubmitGrace tolera la latencia de red al comparar contra el tiempo límite y el cierre del quiz
+const quizSubmitGrace = 30 * time.Second
+
+// r2KeyFromURL elimina el prefijo de la URL de R2 para obtener la key del objeto
+func r2KeyFromURL(url string) string {
+	return strings.Replace(url, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", os.Getenv("R2_ACCOUNT_ID")), "", 1)
+}
+
+// courseIDFromQuizURL extrae el courseID de una URL con formato .../focused/<courseID>/...
+func courseIDFromQuizURL(url string) (int, error) {
+	parts := strings.SplitN(url, "/focused/", 2)
+	if len(parts) < 2 {
+		return 0, fmt.Errorf("URL malformada")
+	}
+	subparts := strings.Split(parts[1], "/")
+	return strconv.Atoi(subparts[0])
+}
+
+// resolveQuizContent valida que el contenido sea un quiz del curso del estudiante y devuelve su URL
+func (c *QuizController) resolveQuizContent(userID, contentID string) (string, int, error) {
+	Url, err := c.CourseContentRepo.GetUrlByContentID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", contentID))
+	}
+	courseIDInt, err := courseIDFromQuizURL(Url)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "URL malformada")
+	}
+
+	// Verificar que el estudiante está asignado a este curso
+	_, err = c.AssignmentRepo.GetAssignmentsByStudentAndCourse(userID, courseIDInt)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusForbidden, "Este estudiante no está asignado a este curso")
+	}
+
+	// Verificar que el ContentID es realmente un quiz
+	contentTypeID, err := c.CourseContentRepo.GetContentTypeID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener tipo de contenido: %v", err))
+	}
+	if contentTypeID != 3 { // Asumiendo que 3 es el ID para Quiz
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "el content_id no corresponde a un quiz")
+	}
+	return Url, courseIDInt, nil
+}
+
+// attemptQuizURL devuelve la URL del quiz contra el que se califica un intento: su variante, si la tiene
+func attemptQuizURL(attempt domain.QuizAnswer) string {
+	if attempt.VariantURL != "" {
+		return attempt.VariantURL
+	}
+	return attempt.QuizURL
+}
+
+// currentQuizVersion devuelve la URL de la versión vigente del quiz y su ID.
+// Los quizzes subidos antes del versionado no tienen versiones: se usa la URL del contenido.
+func (c *QuizController) currentQuizVersion(contentID, contentURL string) (string, *int, error) {
+	version, err := c.QuizVersionRepo.GetLatestQuizVersion(contentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return contentURL, nil, nil
+		}
+		return "", nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la versión del quiz: %v", err))
+	}
+	return version.QuizURL, &version.QuizVersionID, nil
+}
+
+// loadTeacherQuiz descarga y parsea el quiz del profesor desde R2
+func (c *QuizController) loadTeacherQuiz(url string) (domain.TeacherQuiz, error) {
+	var teacherQuiz domain.TeacherQuiz
+	teacherQuizBytes, err := c.GetTeacherQuizContent("zeppelin", r2KeyFromURL(url))
+	if err != nil {
+		return teacherQuiz, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el quiz del profesor desde R2: %s", err.Error()))
+	}
+	if err := json.Unmarshal(teacherQuizBytes, &teacherQuiz); err != nil {
+		return teacherQuiz, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear el quiz del profesor: %s", err.Error()))
+	}
+	return teacherQuiz, nil
+}
+
+// checkQuizWindow verifica que el quiz esté abierto en el instante indicado
+func checkQuizWindow(settings domain.QuizSettings, now time.Time) error {
+	if settings.OpensAt != nil && now.Before(*settings.OpensAt) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("el quiz abre el %s", settings.OpensAt.Format(time.RFC3339)))
+	}
+	if settings.ClosesAt != nil && now.After(settings.ClosesAt.Add(quizSubmitGrace)) {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("el quiz cerró el %s", settings.ClosesAt.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// attemptDeadline devuelve el instante límite de entrega de un intento, o nil si no hay tiempo límite
+func attemptDeadline(settings domain.QuizSettings, startTime time.Time) *time.Time {
+	if settings.TimeLimitMinutes <= 0 {
+		return nil
+	}
+	deadline := startTime.Add(time.Duration(settings.TimeLimitMinutes) * time.Minute)
+	return &deadline
+}
+
+// checkAttemptsLeft verifica que el estudiante no haya agotado sus intentos
+func (c *QuizController) checkAttemptsLeft(userID, contentID string, settings domain.QuizSettings) (int64, error) {
+	used, err := c.QuizRepo.CountQuizAttempts(userID, contentID)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al contar intentos: %v", err))
+	}
+	if settings.MaxAttempts > 0 && used >= int64(settings.MaxAttempts) {
+		return used, maxAttemptsError(settings)
+	}
+	return used, nil
+}
+
+// submittedAttempts descarta los intentos que aún no se han enviado
+func submittedAttempts(attempts []domain.QuizAttemptView) []domain.QuizAttemptView {
+	submitted := make([]domain.QuizAttemptView, 0, len(attempts))
+	for _, attempt := range attempts {
+		if !attempt.EndTime.IsZero() {
+			submitted = append(submitted, attempt)
+		}
+	}
+	return submitted
+}
+
+func maxAttemptsError(settings domain.QuizSettings) error {
+	return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("se alcanzó el máximo de %d intentos", settings.MaxAttempts))
+}
+
+// StartQuizAttempt registra el inicio de un intento con la hora del servidor.
+// Si ya existe un intento en curso dentro del tiempo límite, lo devuelve en lugar de crear otro.
+func (c *QuizController) StartQuizAttempt() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.StartQuizAttemptInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		Url, courseID, err := c.resolveQuizContent(userID, input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		if err := checkContentUnlocked(c.CourseContentRepo, input.ContentID, userID); err != nil {
+			return err
+		}
+		Url, quizVersionID, err := c.currentQuizVersion(input.ContentID, Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		teacherQuiz, err := c.loadTeacherQuiz(Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		settings := teacherQuiz.Settings
+
+		now := time.Now()
+		if err := checkQuizWindow(settings, now); err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		// Reanudar un intento en curso que aún no ha vencido
+		openAttempt, err := c.QuizRepo.FindOpenQuizAttempt(userID, input.ContentID)
+		if err == nil {
+			deadline := attemptDeadline(settings, openAttempt.StartTime)
+			if deadline == nil || now.Before(deadline.Add(quizSubmitGrace)) {
+				response := map[string]interface{}{
+					"message":        "Intento de quiz en curso",
+					"quiz_answer_id": openAttempt.QuizAnswerID,
+					"start_time":     openAttempt.StartTime,
+					"expires_at":     deadline,
+					"settings":       settings,
+					"answers":        openAttempt.DraftAnswers, // respuestas guardadas automáticamente
+					"autosaved_at":   openAttempt.AutosavedAt,
+				}
+				if openAttempt.VariantURL != "" {
+					variant, err := c.loadTeacherQuiz(openAttempt.VariantURL)
+					if err != nil {
+						return ReturnWriteResponse(e, err, nil)
+					}
+					response["quiz"] = studentQuizCopy(variant)
+				}
+				return ReturnWriteResponse(e, nil, response)
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al buscar intento en curso: %v", err)), nil)
+		}
+
+		// Con la política reject no se pueden iniciar intentos después de la fecha de entrega
+		late, err := c.lateSubmission(input.ContentID, userID, now)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		used, err := c.checkAttemptsLeft(userID, input.ContentID, settings)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		attempt := domain.QuizAnswer{
+			ContentID:     input.ContentID,
+			UserID:        userID,
+			StartTime:     now,
+			QuizURL:       Url,
+			QuizVersionID: quizVersionID,
+		}
+
+		// Generar y guardar la variante del estudiante (bancos de preguntas, barajado y variables)
+		var studentQuiz *domain.TeacherQuiz
+		if quizHasVariants(teacherQuiz) {
+			seed := newVariantSeed()
+			variant := BuildQuizVariant(teacherQuiz, seed)
+			variantKey := fmt.Sprintf("focused/%d/quiz/variant/%s/%s-%d.json", courseID, input.ContentID, userID, seed)
+			if err := c.uploadQuizVariant(variantKey, variant); err != nil {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, err.Error()), nil)
+			}
+			attempt.Seed = &seed
+			attempt.VariableValues = variableValues(variant)
+			attempt.VariantURL = fmt.Sprintf("https://%s.r2.cloudflarestorage.com/%s", os.Getenv("R2_ACCOUNT_ID"), variantKey)
+			student := studentQuizCopy(variant)
+			studentQuiz = &student
+		}
+
+		// El repositorio vuelve a contar los intentos al insertar, por si hubo otro inicio simultáneo
+		quizAnswerID, err := c.QuizRepo.StartQuizAttempt(attempt, settings.MaxAttempts)
+		if errors.Is(err, domain.ErrMaxAttemptsReached) {
+			return ReturnWriteResponse(e, maxAttemptsError(settings), nil)
+		}
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al iniciar el intento del quiz: %s", err.Error())), nil)
+		}
+
+		var attemptsRemaining *int
+		if settings.MaxAttempts > 0 {
+			remaining := settings.MaxAttempts - int(used) - 1
+			attemptsRemaining = &remaining
+		}
+
+		response := map[string]interface{}{
+			"message":            "Intento de quiz iniciado",
+			"quiz_answer_id":     quizAnswerID,
+			"start_time":         now,
+			"expires_at":         attemptDeadline(settings, now),
+			"attempts_remaining": attemptsRemaining,
+			"settings":           settings,
+		}
+		if late != nil {
+			response["due_at"] = late.DueAt
+		}
+		if studentQuiz != nil {
+			response["quiz"] = studentQuiz
+		}
+		return ReturnWriteResponse(e, nil, response)
+	}
 }
 
 func (c *QuizController) SubmitQuiz() echo.HandlerFunc {
@@ -35,44 +300,108 @@ func (c *QuizController) SubmitQuiz() echo.HandlerFunc {
 			return err
 		}
 
-		Url, err := c.CourseContentRepo.GetUrlByContentID(input.ContentID) // Necesitarás implementar GetContentByContentID
+		// 0. Un reintento con la misma Idempotency-Key devuelve la respuesta original sin volver a calificar
+		idempotencyKey := e.Request().Header.Get(idempotencyKeyHeader)
+		completed := false
+		if idempotencyKey != "" {
+			replay, err := c.reserveSubmission(userID, idempotencyKey, input.ContentID)
+			if err != nil {
+				return ReturnWriteResponse(e, err, nil)
+			}
+			if replay != nil {
+				e.Response().Header().Set("Idempotent-Replayed", "true")
+				return ReturnWriteResponse(e, nil, replay)
+			}
+			defer func() {
+				if !completed {
+					c.releaseSubmission(userID, idempotencyKey)
+				}
+			}()
+		}
+
+		// 1. Validar contenido, asignación al curso y tipo de contenido
+		Url, _, err := c.resolveQuizContent(userID, input.ContentID)
 		if err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", input.ContentID)), nil)
+			return ReturnWriteResponse(e, err, nil)
 		}
-		//Imprimer el URL
-		parts := strings.SplitN(Url, "/focused/", 2)
-		if len(parts) < 2 {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, "URL malformada"), nil)
+		if err := checkContentUnlocked(c.CourseContentRepo, input.ContentID, userID); err != nil {
+			return err
 		}
-		subparts := strings.Split(parts[1], "/")
-		courseIDInt, err := strconv.Atoi(subparts[0])
 
-		// 1. Verificar que el estudiante está asignado a este curso
-		_, err = c.AssignmentRepo.GetAssignmentsByStudentAndCourse(userID, courseIDInt)
-		if err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "Este estudiante no está asignado a este curso"), nil)
+		// 2. Obtener el intento iniciado (si existe); el quiz se califica contra la URL (o variante) registrada al iniciarlo
+		now := time.Now()
+		var quizAttempt domain.QuizAnswer
+		started := input.QuizAnswerID != 0
+		if started {
+			quizAttempt, err = c.QuizRepo.FindQuizAttemptByID(input.QuizAnswerID)
+			if err != nil || quizAttempt.UserID != userID || quizAttempt.ContentID != input.ContentID {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("intento de quiz con ID %d no encontrado", input.QuizAnswerID)), nil)
+			}
+			if quizAttempt.EndTime != nil {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusConflict, "este intento ya fue enviado"), nil)
+			}
+			Url = attemptQuizURL(quizAttempt)
+		} else {
+			var quizVersionID *int
+			Url, quizVersionID, err = c.currentQuizVersion(input.ContentID, Url)
+			if err != nil {
+				return ReturnWriteResponse(e, err, nil)
+			}
+			quizAttempt.QuizVersionID = quizVersionID
 		}
 
-		// 2. Verificar que el ContentID es realmente un quiz
-		contentTypeID, err := c.CourseContentRepo.GetContentTypeID(input.ContentID)
+		// 3. Obtener el quiz del profesor desde R2
+		teacherQuiz, err := c.loadTeacherQuiz(Url)
 		if err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener tipo de contenido: %v", err)), nil)
+			return ReturnWriteResponse(e, err, nil)
+		}
+		settings := teacherQuiz.Settings
+
+		// 4. Aplicar ventana de apertura, intentos y tiempo límite con la hora del servidor
+		if err := checkQuizWindow(settings, now); err != nil {
+			return ReturnWriteResponse(e, err, nil)
 		}
-		if contentTypeID != 3 { // Asumiendo que 3 es el ID para Quiz
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "el content_id no corresponde a un quiz"), nil)
+		if started {
+			if deadline := attemptDeadline(settings, quizAttempt.StartTime); deadline != nil && now.After(deadline.Add(quizSubmitGrace)) {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("el tiempo límite del intento venció el %s", deadline.Format(time.RFC3339))), nil)
+			}
+		} else {
+			if settings.TimeLimitMinutes > 0 {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "este quiz tiene tiempo límite: inicie el intento con /quiz/start"), nil)
+			}
+			if quizHasVariants(teacherQuiz) {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "este quiz genera una versión por estudiante: inicie el intento con /quiz/start"), nil)
+			}
+			if _, err := c.checkAttemptsLeft(userID, input.ContentID, settings); err != nil {
+				return ReturnWriteResponse(e, err, nil)
+			}
+			quizAttempt = domain.QuizAnswer{
+				ContentID:     input.ContentID,
+				UserID:        userID,
+				StartTime:     now,
+				QuizURL:       Url,
+				QuizVersionID: quizAttempt.QuizVersionID,
+			}
 		}
 
-		// 3. Serializar las respuestas del estudiante a JSON bytes
+		// 4.1 Evaluar la fecha de entrega (y la prórroga del estudiante)
+		late, err := c.lateSubmission(input.ContentID, userID, now)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		// 5. Serializar las respuestas del estudiante a JSON bytes; solo se guardan las de preguntas del quiz
+		input.Answers = quizQuestionAnswers(teacherQuiz, input.Answers)
 		studentAnswersJSONBytes, err := json.Marshal(input.Answers)
 		if err != nil {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al serializar respuestas del estudiante: %s", err.Error())), nil)
 		}
 
-		// 4. Subir el JSON de respuestas del estudiante a R2
-		// Ruta: focused/el accountID/quiz/answer/id de student/id de contentid.json
-		// Ajustamos la key para seguir tu convención de ruta
+		// 6. Subir el JSON de respuestas del estudiante a R2
+		// Ruta: focused/el accountID/quiz/answer/id de student/id de contentid/inicio del intento.json
+		// Cada intento tiene su propio archivo para que los reintentos no sobrescriban los anteriores
 		accountID := os.Getenv("R2_ACCOUNT_ID") // Obtener el AccountID
-		studentAnswersKey := fmt.Sprintf("focused/%s/quiz/answer/%s/%s.json", accountID, userID, input.ContentID)
+		studentAnswersKey := fmt.Sprintf("focused/%s/quiz/answer/%s/%s/%d.json", accountID, userID, input.ContentID, quizAttempt.StartTime.UnixMilli())
 		err = c.UploadStudentAnswers(studentAnswersKey, studentAnswersJSONBytes) // Usa la función exportada
 		if err != nil {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al subir respuestas del estudiante a R2: %s", err.Error())), nil)
@@ -81,64 +410,85 @@ func (c *QuizController) SubmitQuiz() echo.HandlerFunc {
 		// Genera la URL del archivo subido
 		studentAnswersURL := fmt.Sprintf("https://%s.r2.cloudflarestorage.com/%s", accountID, studentAnswersKey) // URL directa
 
-		// Obtener el contenido del archivo del quiz del profesor desde R2
-		teacherQuizBytes, err := c.GetTeacherQuizContent("zeppelin", strings.Replace(Url, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1)) // Eliminar el prefijo de la URL para obtener la key
-		if err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el quiz del profesor desde R2: %s", err.Error())), nil)
+		// 7. Calificar el quiz
+		gradeResult := c.GradeQuiz(teacherQuiz, input.Answers)
+		rawScore, totalPoints := gradeResult.Score, gradeResult.TotalPoints
+		score := rawScore
+		if late != nil {
+			score = applyLatePenalty(rawScore, late.PenaltyPercent)
+			quizAttempt.DueAt = &late.DueAt
+			quizAttempt.LateMinutes = late.LateMinutes
+			quizAttempt.LatePenalty = late.PenaltyPercent
 		}
 
-		var teacherQuiz domain.TeacherQuiz // Usar el struct definido en domain
-		if err := json.Unmarshal(teacherQuizBytes, &teacherQuiz); err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear el quiz del profesor: %s", err.Error())), nil)
+		// 8. Determinar el estado de revisión (`reviewed_at`)
+		var reviewedAt *time.Time
+		if !gradeResult.NeedsReview {
+			reviewedAt = &now
 		}
 
-		// 6. Calificar el quiz
-		score, totalPoints := c.GradeQuiz(teacherQuiz, input.Answers)
-
-		// 7. Determinar el estado de revisión (`reviewed_at`)
-		needsReview := false
-		for _, question := range teacherQuiz.Questions {
-			if question.Type == "text" {
-				needsReview = true
-				break
-			}
+		// 9. Completar el registro del intento de quiz
+		quizAttempt.EndTime = &now
+		quizAttempt.Grade = &score // Usamos el puntero para el grade
+		quizAttempt.RawGrade = &rawScore
+		quizAttempt.ReviewedAt = reviewedAt
+		quizAttempt.QuizAnswerURL = studentAnswersURL // URL del archivo de respuestas del estudiante en R2
+		quizAttempt.TotalPoints = &totalPoints        // Usamos el puntero para total_points
+		quizAttempt.DraftAnswers = nil                // las respuestas en curso ya no se necesitan
+
+		// 10. Guardar el intento de quiz en la base de datos
+		if started {
+			err = c.QuizRepo.UpdateQuizAttempt(quizAttempt)
+		} else {
+			quizAttempt.QuizAnswerID, err = c.QuizRepo.SaveQuizAttempt(quizAttempt, settings.MaxAttempts)
 		}
-
-		var reviewedAt *time.Time
-		if !needsReview {
-			now := time.Now()
-			reviewedAt = &now
+		if errors.Is(err, domain.ErrMaxAttemptsReached) {
+			return ReturnWriteResponse(e, maxAttemptsError(settings), nil)
+		}
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar el intento del quiz: %s", err.Error())), nil)
 		}
 
-		// 8. Crear el registro del intento de quiz en la base de datos
-		quizAttempt := domain.QuizAnswer{ // Usar el struct definido en domain
-			ContentID:     input.ContentID,
-			UserID:        userID,
-			StartTime:     input.StartTime,
-			EndTime:       input.EndTime,
-			Grade:         &score, // Usamos el puntero para el grade
-			ReviewedAt:    reviewedAt,
-			QuizURL:       Url,               // URL del quiz del profesor
-			QuizAnswerURL: studentAnswersURL, // URL del archivo de respuestas del estudiante en R2
-			TotalPoints:   &totalPoints,      // Usamos el puntero para total_points
+		// 10.1 Guardar la calificación de cada pregunta. El intento ya quedó enviado: un error aquí no
+		// debe liberar la Idempotency-Key (el reintento chocaría con "ya fue enviado"), así que se
+		// registra y el intento se trata como uno anterior a quiz_answer_item (se recalcula desde R2).
+		if err := c.QuizRepo.SaveQuizAnswerItems(buildAnswerItems(quizAttempt.QuizAnswerID, gradeResult, input.Answers, now)); err != nil {
+			log.Printf("error al guardar las calificaciones por pregunta del intento %d: %v", quizAttempt.QuizAnswerID, err)
 		}
 
-		// 9. Guardar el intento de quiz en la base de datos
-		err = c.QuizRepo.SaveQuizAttempt(quizAttempt) // Llama al método del QuizRepository
+		// 11. La clave de respuestas solo se devuelve si la política del quiz lo permite
+		released, err := c.answersReleased(input.ContentID, settings, true, now)
 		if err != nil {
-			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar el intento del quiz: %s", err.Error())), nil)
+			// Ante la duda no se publica la clave; el intento ya está guardado
+			log.Printf("error al evaluar la publicación de respuestas del intento %d: %v", quizAttempt.QuizAnswerID, err)
+			released = false
+		}
+		quizResponse := studentQuizCopy(teacherQuiz)
+		if released {
+			quizResponse = teacherQuiz
 		}
 
-		// 11. Devolver la puntuación al estudiante
-		return ReturnWriteResponse(e, nil, map[string]interface{}{
+		// 12. Devolver la puntuación al estudiante
+		response := map[string]interface{}{
 			"message":             "Quiz calificado exitosamente",
 			"score":               score,
+			"raw_score":           rawScore,
+			"late":                late,
 			"total_points":        totalPoints,
 			"quiz_answer_id":      quizAttempt.QuizAnswerID, // Devolver el ID del intento guardado
 			"student_answers_url": studentAnswersURL,        // Devolver la URL de las respuestas guardadas
 			"reviewed_at":         reviewedAt,               // Incluir el timestamp de revisión
-			"quizTeacherResponse": teacherQuiz,              // Devolver la URL del quiz del profesor
-		})
+			"start_time":          quizAttempt.StartTime,
+			"end_time":            now,
+			"questions":           gradeResult.Questions, // Desglose de puntos por pregunta
+			"quizTeacherResponse": quizResponse,          // Quiz con la clave y las explicaciones solo si ya se publicaron
+			"answers_released":    released,
+		}
+		if idempotencyKey != "" {
+			c.completeSubmission(userID, idempotencyKey, quizAttempt.QuizAnswerID, response)
+			completed = true
+		}
+		return ReturnWriteResponse(e, nil, response)
 	}
 }
 
@@ -185,7 +535,7 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 		log.Printf("Parsed student answers keys: %v", reflect.ValueOf(studentAnswers).MapKeys())
 
 		// 4. Validar que la pregunta existe y es de tipo texto
-		teacherQuizBytes, err := c.GetTeacherQuizContent("zeppelin", strings.Replace(quizAttempt.QuizURL, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1))
+		teacherQuizBytes, err := c.GetTeacherQuizContent("zeppelin", strings.Replace(attemptQuizURL(quizAttempt), fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1))
 		if err != nil {
 			log.Printf("Error fetching teacher quiz from R2: %v", err)
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el quiz del profesor desde R2: %s", err.Error())), nil)
@@ -198,6 +548,7 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 		}
 
 		var questionPoints int
+		var questionRubric []domain.RubricCriterion
 		var questionFound bool
 		for _, question := range teacherQuiz.Questions {
 			if question.ID == input.QuestionID {
@@ -206,6 +557,7 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 					return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "la pregunta no es de tipo texto"), nil)
 				}
 				questionPoints = question.Points
+				questionRubric = question.Rubric
 				questionFound = true
 				break
 			}
@@ -215,6 +567,17 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("pregunta con ID %s no encontrada", input.QuestionID)), nil)
 		}
 
+		// Con rúbrica, los puntos se calculan a partir de los niveles elegidos
+		var rubricScores []domain.RubricScore
+		if len(questionRubric) > 0 {
+			rubricScores, input.PointsAwarded, err = GradeRubric(questionRubric, input.RubricSelections)
+			if err != nil {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, err.Error()), nil)
+			}
+		} else if len(input.RubricSelections) > 0 {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "la pregunta no tiene rúbrica"), nil)
+		}
+
 		// 5. Validar que los puntos asignados no excedan los puntos máximos
 		if input.PointsAwarded > float64(questionPoints) {
 			log.Printf("Points awarded %f exceed max points %d for question %s", input.PointsAwarded, questionPoints, input.QuestionID)
@@ -245,6 +608,7 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 				"value":          answerValue,
 				"is_correct":     input.IsCorrect,
 				"points_awarded": input.PointsAwarded,
+				"rubric":         rubricScores,
 			},
 		}
 		extraReview = append(extraReview, reviewEntry)
@@ -269,18 +633,69 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 		}
 		log.Printf("Calculated total points: %d", totalPoints)
 
-		// 10. Actualizar el score sumando points_awarded al grade actual
-		currentScore := 0.0
-		if quizAttempt.Grade != nil {
-			currentScore = *quizAttempt.Grade
+		// 10. Registrar la revisión en quiz_answer_item y recalcular el score
+		now := time.Now()
+		reviewerID, _ := e.Get("user_id").(string)
+		items, err := c.QuizRepo.FindQuizAnswerItems(quizAttempt.QuizAnswerID)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las calificaciones por pregunta: %s", err.Error())), nil)
+		}
+		reviewedItem := domain.QuizAnswerItem{
+			QuizAnswerID: quizAttempt.QuizAnswerID,
+			QuestionID:   input.QuestionID,
+			QuestionType: "text",
+			Answer:       encodeAnswer(answerValue),
+			Points:       questionPoints,
+			Score:        &input.PointsAwarded,
+			IsCorrect:    &input.IsCorrect,
+			ReviewedAt:   &now,
+			Feedback:     input.Feedback,
+			Rubric:       rubricScores,
+		}
+		if reviewerID != "" {
+			reviewedItem.ReviewerID = &reviewerID
 		}
-		newScore := currentScore + input.PointsAwarded
-		log.Printf("Current score: %f, Adding %f points, New score: %f", currentScore, input.PointsAwarded, newScore)
 
-		// 11. Actualizar el quiz attempt en la base de datos
+		if len(items) == 0 {
+			// Intento anterior a quiz_answer_item: se registran primero todas las preguntas calificando sus respuestas
+			items = buildAnswerItems(quizAttempt.QuizAnswerID, c.GradeQuiz(teacherQuiz, studentAnswers), studentAnswers, now)
+		}
+
+		// El score es la suma de los ítems, así que revisar dos veces la misma pregunta no duplica puntos
+		var newScore float64
+		var reviewedAt *time.Time
+		found := false
+		pending := false
+		for i := range items {
+			if items[i].QuestionID == input.QuestionID {
+				reviewedItem.QuizAnswerItemID = items[i].QuizAnswerItemID
+				items[i] = reviewedItem
+				found = true
+			}
+			if items[i].Score != nil {
+				newScore += *items[i].Score
+			}
+			pending = pending || items[i].NeedsReview
+		}
+		if !found {
+			items = append(items, reviewedItem)
+			newScore += input.PointsAwarded
+		}
+		if !pending {
+			reviewedAt = &now
+		}
+		log.Printf("New score for quiz attempt %d: %f", quizAttempt.QuizAnswerID, newScore)
+
+		if err := c.QuizRepo.SaveQuizAnswerItems(items); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar las calificaciones por pregunta: %s", err.Error())), nil)
+		}
+
+		// 11. Actualizar el quiz attempt en la base de datos; el descuento por entrega tardía se mantiene
+		rawScore := newScore
+		newScore = applyLatePenalty(rawScore, quizAttempt.LatePenalty)
+		quizAttempt.RawGrade = &rawScore
 		quizAttempt.Grade = &newScore
-		now := time.Now()
-		quizAttempt.ReviewedAt = &now
+		quizAttempt.ReviewedAt = reviewedAt
 		quizAttempt.TotalPoints = &totalPoints
 		err = c.QuizRepo.UpdateQuizAttempt(quizAttempt)
 		if err != nil {
@@ -296,127 +711,141 @@ func (c *QuizController) ReviewTextAnswer() echo.HandlerFunc {
 			"question_id":    input.QuestionID,
 			"score":          newScore,
 			"total_points":   totalPoints,
-			"reviewed_at":    now,
+			"points_awarded": input.PointsAwarded,
+			"rubric":         rubricScores,
+			"reviewed_at":    reviewedAt,
+			"feedback":       input.Feedback,
 		})
 	}
 }
 
-func (c *QuizController) GradeQuiz(teacherQuiz domain.TeacherQuiz, studentAnswers map[string]interface{}) (float64, int) {
-	earnedPoints := 0.0
-	totalPoints := 0
+func (c *QuizController) GradeQuiz(teacherQuiz domain.TeacherQuiz, studentAnswers map[string]interface{}) domain.QuizGradeResult {
+	result := domain.QuizGradeResult{Questions: make([]domain.QuestionGrade, 0, len(teacherQuiz.Questions))}
 
 	for _, question := range teacherQuiz.Questions {
-		totalPoints += question.Points
-		studentAnswer := studentAnswers[question.ID]
-
-		switch question.Type {
-		case "text":
-			// Para preguntas de texto, usamos points_awarded si existe
-			if answerMap, ok := studentAnswer.(map[string]interface{}); ok {
-				if points, ok := answerMap["points_awarded"].(float64); ok {
-					earnedPoints += points
-				}
-			}
-		case "multiple":
-			correctAnswer := question.CorrectAnswer.(string)
-			studentAnswerStr, ok := studentAnswer.(string)
-			if ok && studentAnswerStr == correctAnswer {
-				earnedPoints += float64(question.Points)
-			}
-		case "checkbox":
-			correctAnswers := question.CorrectAnswers
-			studentSelectedInterface, ok := studentAnswer.([]interface{})
-			if !ok {
-				continue
-			}
-
-			studentSelectedStrings := make([]string, 0, len(studentSelectedInterface))
-			validStudentAnswers := true
-			for i, v := range studentSelectedInterface {
-				str, typeOk := v.(string)
-				if !typeOk {
-					fmt.Printf("DEBUG CHECKBOX: Elemento %d de studentAnswer no es string. Es: %T\n", i, v)
-					validStudentAnswers = false
-					break
-				}
-				studentSelectedStrings = append(studentSelectedStrings, str)
-			}
+		grade := gradeQuestion(question, studentAnswers[question.ID])
+		result.TotalPoints += question.Points
+		result.Score += grade.Earned
+		if grade.NeedsReview {
+			result.NeedsReview = true
+		}
+		result.Questions = append(result.Questions, grade)
+	}
 
-			if !validStudentAnswers {
-				continue
-			}
+	fmt.Printf("\n--- FIN CALIFICACIÓN ---\n")
+	fmt.Printf("Puntos Totales Ganados: %f, Puntos Totales Posibles: %d\n", result.Score, result.TotalPoints)
+	return result
+}
 
-			if len(studentSelectedStrings) == len(correctAnswers) {
-				correctMap := make(map[string]bool)
-				for _, ans := range correctAnswers {
-					correctMap[ans] = true
-				}
-				studentMap := make(map[string]bool)
-				for _, ans := range studentSelectedStrings {
-					studentMap[ans] = true
-				}
+// gradeQuestion califica una pregunta usando el tipo registrado y devuelve su desglose
+func gradeQuestion(question domain.TeacherQuizQuestion, studentAnswer interface{}) domain.QuestionGrade {
+	grade := domain.QuestionGrade{QuestionID: question.ID, Type: question.Type, Points: question.Points}
 
-				isCorrect := true
-				if len(correctMap) != len(studentMap) {
-					isCorrect = false
-				} else {
-					for key := range correctMap {
-						if _, found := studentMap[key]; !found {
-							isCorrect = false
-							break
-						}
-					}
-				}
+	questionType, ok := LookupQuestionType(question.Type)
+	if !ok {
+		// Tipo desconocido: se deja para revisión manual en vez de calificarlo con cero
+		grade.NeedsReview = true
+		return grade
+	}
+	if studentAnswer == nil {
+		// Sin respuesta: cero puntos, salvo las de texto que siguen pendientes de revisión
+		grade.NeedsReview = question.Type == "text"
+		return grade
+	}
+	if err := questionType.ValidateAnswer(question, studentAnswer); err != nil {
+		// Respuesta con formato inválido: cero puntos
+		return grade
+	}
 
-				if isCorrect {
-					earnedPoints += float64(question.Points)
-				}
-			}
-		case "boolean":
-			var expectedBoolValue bool
-			isCorrectAnswerParsable := true
-
-			if val, ok := question.CorrectAnswer.(bool); ok {
-				expectedBoolValue = val
-			} else if valStr, ok := question.CorrectAnswer.(string); ok {
-				lowerValStr := strings.ToLower(valStr)
-				if lowerValStr == "true" || lowerValStr == "verdadero" {
-					expectedBoolValue = true
-				} else if lowerValStr == "false" || lowerValStr == "falso" {
-					expectedBoolValue = false
-				} else {
-					isCorrectAnswerParsable = false
-				}
-			} else {
-				isCorrectAnswerParsable = false
-			}
+	fraction, needsReview := questionType.Grade(question, studentAnswer)
+	grade.NeedsReview = needsReview
+	grade.Earned = fraction * float64(question.Points)
+	grade.IsCorrect = !needsReview && fraction >= 1
+	return grade
+}
 
-			if !isCorrectAnswerParsable {
-				continue
-			}
+// buildAnswerItems convierte el desglose de la calificación en filas de quiz_answer_item
+func buildAnswerItems(quizAnswerID int, result domain.QuizGradeResult, answers map[string]interface{}, now time.Time) []domain.QuizAnswerItem {
+	items := make([]domain.QuizAnswerItem, 0, len(result.Questions))
+	for _, grade := range result.Questions {
+		item := domain.QuizAnswerItem{
+			QuizAnswerID: quizAnswerID,
+			QuestionID:   grade.QuestionID,
+			QuestionType: grade.Type,
+			Answer:       encodeAnswer(answers[grade.QuestionID]),
+			Points:       grade.Points,
+			NeedsReview:  grade.NeedsReview,
+		}
+		if !grade.NeedsReview {
+			earned, isCorrect := grade.Earned, grade.IsCorrect
+			item.Score = &earned
+			item.IsCorrect = &isCorrect
+			item.ReviewedAt = &now
+		}
+		items = append(items, item)
+	}
+	return items
+}
 
-			studentAnswerBool, typeOk := studentAnswer.(bool)
-			if !typeOk {
-				if studentAnswerStr, okStr := studentAnswer.(string); okStr {
-					lowerStudentStr := strings.ToLower(studentAnswerStr)
-					if lowerStudentStr == "true" || lowerStudentStr == "verdadero" {
-						studentAnswerBool = true
-						typeOk = true
-					} else if lowerStudentStr == "false" || lowerStudentStr == "falso" {
-						studentAnswerBool = false
-						typeOk = true
-					}
-				}
-			}
-			if typeOk && studentAnswerBool == expectedBoolValue {
-				earnedPoints += float64(question.Points)
-			}
+// quizQuestionAnswers descarta las claves que no son IDs de preguntas del quiz respondido
+func quizQuestionAnswers(teacherQuiz domain.TeacherQuiz, answers map[string]interface{}) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(answers))
+	for _, question := range teacherQuiz.Questions {
+		if answer, ok := answers[question.ID]; ok {
+			filtered[question.ID] = answer
+		}
+	}
+	return filtered
+}
+
+// encodeAnswer serializa la respuesta del estudiante para guardarla en la base de datos
+func encodeAnswer(answer interface{}) string {
+	if answer == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(answer)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// scoreFraction calcula la fracción de puntos obtenida según la política de puntuación,
+// dados los aciertos, las selecciones incorrectas y el número de respuestas correctas esperadas.
+func scoreFraction(policy string, hits, wrong, expected int) float64 {
+	if expected == 0 {
+		if wrong == 0 {
+			return 1
 		}
+		return 0
 	}
+	switch policy {
+	case domain.ScoringProportional:
+		return float64(hits) / float64(max(expected, hits+wrong))
+	case domain.ScoringRightMinusWrong:
+		return math.Max(0, float64(hits-wrong)/float64(expected))
+	default:
+		if hits == expected && wrong == 0 {
+			return 1
+		}
+		return 0
+	}
+}
 
-	fmt.Printf("\n--- FIN CALIFICACIÓN ---\n")
-	fmt.Printf("Puntos Totales Ganados: %f, Puntos Totales Posibles: %d\n", earnedPoints, totalPoints)
-	return earnedPoints, totalPoints
+// parseBoolAnswer interpreta un bool o las cadenas true/false/verdadero/falso
+func parseBoolAnswer(value interface{}) (bool, bool) {
+	if val, ok := value.(bool); ok {
+		return val, true
+	}
+	if valStr, ok := value.(string); ok {
+		switch strings.ToLower(valStr) {
+		case "true", "verdadero":
+			return true, true
+		case "false", "falso":
+			return false, true
+		}
+	}
+	return false, false
 }
 
 // GetQuizzesByCourse reestructurado
@@ -441,6 +870,7 @@ func (c *QuizController) GetQuizzesByCourse() echo.HandlerFunc {
 		if err != nil {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
 		}
+		attempts = submittedAttempts(attempts) // los intentos en curso no tienen nota ni hora de envío
 
 		if len(attempts) == 0 {
 			return ReturnWriteResponse(e, nil, domain.CourseQuizResponse{
@@ -486,6 +916,13 @@ func (c *QuizController) GetQuizzesByCourse() echo.HandlerFunc {
 				QuizAnswerURL:   attempt.QuizAnswerURL,
 				StartTime:       attempt.StartTime,
 				EndTime:         attempt.EndTime,
+				QuizVersionID:   attempt.QuizVersionID,
+				QuizVersion:     attempt.QuizVersion,
+				RawGrade:        attempt.RawGrade,
+				DueAt:           attempt.DueAt,
+				Late:            attempt.LateMinutes > 0,
+				LateMinutes:     attempt.LateMinutes,
+				LatePenalty:     attempt.LatePenalty,
 			})
 		}
 
@@ -563,6 +1000,7 @@ func (c *QuizController) GetQuizzesByStudent() echo.HandlerFunc {
 		if err != nil {
 			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
 		}
+		attempts = submittedAttempts(attempts) // los intentos en curso no tienen nota ni hora de envío
 
 		if len(attempts) == 0 {
 			return ReturnWriteResponse(e, nil, domain.StudentCoursesQuizResponse{
@@ -574,6 +1012,20 @@ func (c *QuizController) GetQuizzesByStudent() echo.HandlerFunc {
 			})
 		}
 
+		// Calificación por pregunta (incluye el desglose de rúbrica y la retroalimentación)
+		attemptIDs := make([]int, 0, len(attempts))
+		for _, attempt := range attempts {
+			attemptIDs = append(attemptIDs, attempt.QuizAnswerID)
+		}
+		items, err := c.QuizRepo.FindQuizAnswerItemsByAttempts(attemptIDs)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las calificaciones por pregunta: %v", err)), nil)
+		}
+		itemsByAttempt := make(map[int][]domain.QuizAnswerItem)
+		for _, item := range items {
+			itemsByAttempt[item.QuizAnswerID] = append(itemsByAttempt[item.QuizAnswerID], item)
+		}
+
 		// Agrupar por curso y quiz
 		courseMap := make(map[int]map[string]*domain.Quiz)
 		courseInfo := make(map[int]struct {
@@ -627,6 +1079,15 @@ func (c *QuizController) GetQuizzesByStudent() echo.HandlerFunc {
 					QuizAnswerURL:   attempt.QuizAnswerURL,
 					StartTime:       attempt.StartTime,
 					EndTime:         attempt.EndTime,
+					QuizVersionID:   attempt.QuizVersionID,
+					QuizVersion:     attempt.QuizVersion,
+					RawGrade:        attempt.RawGrade,
+					DueAt:           attempt.DueAt,
+					Late:            attempt.LateMinutes > 0,
+					LateMinutes:     attempt.LateMinutes,
+					LatePenalty:     attempt.LatePenalty,
+					Questions:       itemsByAttempt[attempt.QuizAnswerID],
+					QuizURL:         attempt.VariantURL, // se reemplaza por la URL firmada más abajo
 				},
 			)
 		}
@@ -656,15 +1117,41 @@ func (c *QuizController) GetQuizzesByStudent() echo.HandlerFunc {
 			Courses:  courses,
 		}
 
-		// ... resto del código para firmar URLs permanece igual
-		// Mapear claves únicas para firmar
+		// Mapear claves únicas para firmar. Mientras la política del quiz no publique las respuestas,
+		// se firma la copia del estudiante (sin la clave) en lugar del quiz del profesor y se ocultan
+		// los aciertos, puntos y comentarios de cada pregunta.
+		now := time.Now()
 		quizKeys := make(map[string]string) // ContentID -> quizKey
 		answerKeys := make(map[int]string)  // QuizAnswerID -> answerKey
-		for _, course := range response.Courses {
-			for _, quiz := range course.Quizzes {
-				quizKeys[quiz.ContentID] = strings.Replace(quiz.QuizURL, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1)
-				for _, attempt := range quiz.Attempts {
+		variantKeys := make(map[int]string) // QuizAnswerID -> variantKey
+		for i, course := range response.Courses {
+			for j, quiz := range course.Quizzes {
+				teacherQuiz, err := c.loadCurrentQuiz(quiz.ContentID, quiz.QuizURL)
+				if err != nil {
+					return ReturnReadResponse(e, err, nil)
+				}
+				released, err := c.answersReleased(quiz.ContentID, teacherQuiz.Settings, true, now)
+				if err != nil {
+					return ReturnReadResponse(e, err, nil)
+				}
+				if released {
+					quizKeys[quiz.ContentID] = strings.Replace(quiz.QuizURL, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1)
+				} else {
+					quizKeys[quiz.ContentID] = fmt.Sprintf("focused/%d/quiz/student/%s.json", course.CourseID, quiz.ContentID)
+				}
+				response.Courses[i].Quizzes[j].AnswersReleased = released
+				for k, attempt := range quiz.Attempts {
 					answerKeys[attempt.QuizAnswerID] = strings.Replace(attempt.QuizAnswerURL, fmt.Sprintf("https://%s.r2.cloudflarestorage.com/", accountID), "", 1)
+					// Con bancos de preguntas o variables, el estudiante ve la variante que respondió
+					if attempt.QuizURL != "" {
+						variantKeys[attempt.QuizAnswerID] = r2KeyFromURL(attempt.QuizURL)
+						if !released {
+							variantKeys[attempt.QuizAnswerID] = studentVariantKey(variantKeys[attempt.QuizAnswerID])
+						}
+					}
+					if !released {
+						response.Courses[i].Quizzes[j].Attempts[k].Questions = withheldItemGrades(attempt.Questions)
+					}
 				}
 			}
 		}
@@ -701,6 +1188,37 @@ func (c *QuizController) GetQuizzesByStudent() echo.HandlerFunc {
 			}
 		}
 
+		// Firmar las variantes de cada intento
+		for quizAnswerID, variantKey := range variantKeys {
+			signedURL, err := c.GeneratePresignedURL("zeppelin", variantKey)
+			if err != nil {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al generar URL firmada para la variante del intento %d: %v", quizAnswerID, err)), nil)
+			}
+			for i, course := range response.Courses {
+				for j, quiz := range course.Quizzes {
+					for k, attempt := range quiz.Attempts {
+						if attempt.QuizAnswerID == quizAnswerID {
+							response.Courses[i].Quizzes[j].Attempts[k].QuizURL = signedURL
+						}
+					}
+				}
+			}
+		}
+
 		return ReturnReadResponse(e, nil, response)
 	}
 }
+
+// withheldItemGrades quita de la calificación por pregunta los aciertos, los puntos y los comentarios,
+// que no se muestran al estudiante mientras la política del quiz no publique las respuestas
+func withheldItemGrades(items []domain.QuizAnswerItem) []domain.QuizAnswerItem {
+	withheld := make([]domain.QuizAnswerItem, len(items))
+	for i, item := range items {
+		item.Score = nil
+		item.IsCorrect = nil
+		item.Feedback = ""
+		item.Rubric = nil
+		withheld[i] = item
+	}
+	return withheld
+}
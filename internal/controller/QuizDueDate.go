@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// EvaluateLateSubmission calcula el retraso y el descuento de una entrega según la política del contenido.
+// dueAt es la fecha vigente para el estudiante (la prórroga, si tiene). Con la política reject,
+// una entrega fuera del periodo de gracia devuelve error.
+func EvaluateLateSubmission(dueDate domain.ContentDueDate, dueAt time.Time, submittedAt time.Time) (domain.LateSubmission, error) {
+	result := domain.LateSubmission{DueAt: dueAt}
+	if !submittedAt.After(dueAt.Add(time.Duration(dueDate.GraceMinutes) * time.Minute)) {
+		return result, nil
+	}
+
+	lateness := submittedAt.Sub(dueAt)
+	result.Late = true
+	result.LateMinutes = int(math.Ceil(lateness.Minutes()))
+
+	switch dueDate.LatePolicy {
+	case domain.LatePolicyReject:
+		return result, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("la fecha de entrega venció el %s", dueAt.Format(time.RFC3339)))
+	case domain.LatePolicyFlat:
+		result.PenaltyPercent = dueDate.PenaltyPercent
+	case domain.LatePolicyPerDay:
+		days := math.Ceil(lateness.Hours() / 24)
+		maxPenalty := dueDate.MaxPenaltyPercent
+		if maxPenalty == 0 {
+			maxPenalty = 100
+		}
+		result.PenaltyPercent = math.Min(days*dueDate.PenaltyPercent, maxPenalty)
+	}
+	result.PenaltyPercent = math.Min(result.PenaltyPercent, 100)
+	return result, nil
+}
+
+// applyLatePenalty descuenta de la nota el porcentaje por entrega tardía
+func applyLatePenalty(score, penaltyPercent float64) float64 {
+	if penaltyPercent <= 0 {
+		return score
+	}
+	return score * (1 - penaltyPercent/100)
+}
+
+// lateSubmission evalúa una entrega contra la fecha del contenido y la prórroga del estudiante.
+// Devuelve nil si el contenido no tiene fecha de entrega.
+func (c *QuizController) lateSubmission(contentID, userID string, submittedAt time.Time) (*domain.LateSubmission, error) {
+	dueDate, err := c.DueDateRepo.GetDueDate(contentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la fecha de entrega: %v", err))
+	}
+
+	dueAt := dueDate.DueAt
+	extension, err := c.DueDateRepo.GetDueDateExtension(contentID, userID)
+	if err == nil {
+		dueAt = extension.DueAt
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la prórroga del estudiante: %v", err))
+	}
+
+	result, err := EvaluateLateSubmission(dueDate, dueAt, submittedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetDueDate define la fecha de entrega de un quiz y su política de entregas tardías
+func (c *QuizController) SetDueDate() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.DueDateInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+		if _, _, err := c.teacherQuizCourse(userID, input.ContentID); err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		dueDate := domain.ContentDueDate{
+			ContentID:         input.ContentID,
+			DueAt:             input.DueAt,
+			LatePolicy:        input.LatePolicy,
+			PenaltyPercent:    input.PenaltyPercent,
+			MaxPenaltyPercent: input.MaxPenaltyPercent,
+			GraceMinutes:      input.GraceMinutes,
+		}
+		if err := c.DueDateRepo.SaveDueDate(dueDate); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar la fecha de entrega: %v", err)), nil)
+		}
+		return ReturnWriteResponse(e, nil, dueDate)
+	}
+}
+
+// GrantDueDateExtension otorga a un estudiante una fecha de entrega distinta para un quiz
+func (c *QuizController) GrantDueDateExtension() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.DueDateExtensionInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+		_, courseID, err := c.teacherQuizCourse(userID, input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		if _, err := c.AssignmentRepo.GetAssignmentsByStudentAndCourse(input.UserID, courseID); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "Este estudiante no está asignado a este curso"), nil)
+		}
+
+		extension := domain.DueDateExtension{
+			ContentID: input.ContentID,
+			UserID:    input.UserID,
+			DueAt:     input.DueAt,
+		}
+		if err := c.DueDateRepo.SaveDueDateExtension(extension); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar la prórroga: %v", err)), nil)
+		}
+		return ReturnWriteResponse(e, nil, extension)
+	}
+}
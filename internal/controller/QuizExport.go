@@ -0,0 +1,471 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+const qtiNamespace = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+
+// teacherExportQuiz valida que el quiz de la ruta pertenezca al curso y al profesor y carga su versión vigente
+func (c *QuizController) teacherExportQuiz(e echo.Context) (domain.TeacherQuiz, int, error) {
+	userID := e.Get("user_id").(string)
+	contentID := e.Param("contentId")
+	courseID, err := strconv.Atoi(e.Param("courseId"))
+	if err != nil {
+		return domain.TeacherQuiz{}, 0, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido")
+	}
+
+	Url, quizCourseID, err := c.teacherQuizCourse(userID, contentID)
+	if err != nil {
+		return domain.TeacherQuiz{}, 0, err
+	}
+	if quizCourseID != courseID {
+		return domain.TeacherQuiz{}, 0, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("el quiz %s no pertenece al curso %d", contentID, courseID))
+	}
+	Url, _, err = c.currentQuizVersion(contentID, Url)
+	if err != nil {
+		return domain.TeacherQuiz{}, 0, err
+	}
+	quiz, err := c.loadTeacherQuiz(Url)
+	if err != nil {
+		return domain.TeacherQuiz{}, 0, err
+	}
+	return quiz, courseID, nil
+}
+
+// ExportQuizQTI descarga la versión vigente del quiz como paquete de contenido IMS QTI 2.1 (zip)
+func (c *QuizController) ExportQuizQTI() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		quiz, _, err := c.teacherExportQuiz(e)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		contentID := e.Param("contentId")
+		packageBytes, err := QuizQTIPackage(contentID, quiz)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al generar el paquete QTI: %v", err)), nil)
+		}
+		e.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"quiz-%s-qti.zip\"", contentID))
+		return e.Blob(http.StatusOK, "application/zip", packageBytes)
+	}
+}
+
+// ExportQuizAttempts descarga como CSV todos los intentos enviados del quiz, con la respuesta y el puntaje
+// de cada pregunta. Los intentos anteriores a quiz_answer_item toman las respuestas de R2 y no tienen puntaje por pregunta.
+func (c *QuizController) ExportQuizAttempts() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		quiz, courseID, err := c.teacherExportQuiz(e)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		contentID := e.Param("contentId")
+
+		courseAttempts, err := c.QuizRepo.GetQuizAttemptsByCourse(courseID)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
+		}
+		var attempts []domain.QuizAttemptView
+		for _, attempt := range courseAttempts {
+			if attempt.ContentID == contentID && !attempt.EndTime.IsZero() {
+				attempts = append(attempts, attempt)
+			}
+		}
+		sort.Slice(attempts, func(i, j int) bool {
+			if attempts[i].StudentLastname != attempts[j].StudentLastname {
+				return attempts[i].StudentLastname < attempts[j].StudentLastname
+			}
+			if attempts[i].StudentName != attempts[j].StudentName {
+				return attempts[i].StudentName < attempts[j].StudentName
+			}
+			return attempts[i].StartTime.Before(attempts[j].StartTime)
+		})
+
+		attemptIDs := make([]int, 0, len(attempts))
+		for _, attempt := range attempts {
+			attemptIDs = append(attemptIDs, attempt.QuizAnswerID)
+		}
+		items, err := c.QuizRepo.FindQuizAnswerItemsByAttempts(attemptIDs)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las calificaciones por pregunta: %v", err)), nil)
+		}
+		itemsByAttempt := make(map[int]map[string]domain.QuizAnswerItem)
+		for _, item := range items {
+			if itemsByAttempt[item.QuizAnswerID] == nil {
+				itemsByAttempt[item.QuizAnswerID] = make(map[string]domain.QuizAnswerItem)
+			}
+			itemsByAttempt[item.QuizAnswerID][item.QuestionID] = item
+		}
+
+		for _, attempt := range attempts {
+			if _, ok := itemsByAttempt[attempt.QuizAnswerID]; ok {
+				continue
+			}
+			answersBytes, err := c.GetTeacherQuizContent("zeppelin", r2KeyFromURL(attempt.QuizAnswerURL))
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener respuestas del estudiante desde R2: %v", err)), nil)
+			}
+			var answers map[string]interface{}
+			if err := json.Unmarshal(answersBytes, &answers); err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear respuestas del estudiante: %v", err)), nil)
+			}
+			legacy := make(map[string]domain.QuizAnswerItem, len(answers))
+			for questionID, answer := range answers {
+				legacy[questionID] = domain.QuizAnswerItem{QuestionID: questionID, Answer: encodeAnswer(answer)}
+			}
+			itemsByAttempt[attempt.QuizAnswerID] = legacy
+		}
+
+		csvBytes, err := QuizAttemptsCSV(quiz, attempts, itemsByAttempt)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al generar el CSV: %v", err)), nil)
+		}
+		e.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"quiz-%s-attempts.csv\"", contentID))
+		return e.Blob(http.StatusOK, "text/csv; charset=utf-8", csvBytes)
+	}
+}
+
+// QuizAttemptsCSV genera el CSV con una fila por intento y dos columnas (respuesta y puntaje) por pregunta.
+// Las preguntas siguen el orden del quiz; las que ya no existen en la versión vigente van al final.
+func QuizAttemptsCSV(quiz domain.TeacherQuiz, attempts []domain.QuizAttemptView, itemsByAttempt map[int]map[string]domain.QuizAnswerItem) ([]byte, error) {
+	var questionIDs []string
+	known := make(map[string]bool)
+	for _, question := range quizQuestions(quiz) {
+		questionIDs = append(questionIDs, question.ID)
+		known[question.ID] = true
+	}
+	var removed []string
+	for _, items := range itemsByAttempt {
+		for questionID := range items {
+			if !known[questionID] {
+				known[questionID] = true
+				removed = append(removed, questionID)
+			}
+		}
+	}
+	sort.Strings(removed)
+	questionIDs = append(questionIDs, removed...)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"quiz_answer_id", "user_id", "lastname", "name", "email", "quiz_version", "start_time", "end_time",
+		"late_minutes", "late_penalty", "raw_grade", "grade", "total_points", "needs_review"}
+	for _, questionID := range questionIDs {
+		header = append(header, csvSafeCell(questionID+"_response"), csvSafeCell(questionID+"_score"))
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, attempt := range attempts {
+		version := ""
+		if attempt.QuizVersion != nil {
+			version = strconv.Itoa(*attempt.QuizVersion)
+		}
+		totalPoints := ""
+		if attempt.TotalPoints != nil {
+			totalPoints = strconv.Itoa(*attempt.TotalPoints)
+		}
+		record := []string{
+			strconv.Itoa(attempt.QuizAnswerID), csvSafeCell(attempt.UserID), csvSafeCell(attempt.StudentLastname),
+			csvSafeCell(attempt.StudentName), csvSafeCell(attempt.StudentEmail),
+			version, attempt.StartTime.Format(time.RFC3339), attempt.EndTime.Format(time.RFC3339),
+			strconv.Itoa(attempt.LateMinutes), strconv.FormatFloat(attempt.LatePenalty, 'f', 2, 64),
+			formatPercent(attempt.RawGrade), formatPercent(attempt.Grade), totalPoints, strconv.FormatBool(attempt.NeedsReview),
+		}
+		for _, questionID := range questionIDs {
+			item, ok := itemsByAttempt[attempt.QuizAnswerID][questionID]
+			if !ok {
+				record = append(record, "", "")
+				continue
+			}
+			record = append(record, csvSafeCell(formatExportedAnswer(item.Answer)), formatPercent(item.Score))
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// formatExportedAnswer convierte una respuesta serializada en JSON en texto legible para una celda
+func formatExportedAnswer(encoded string) string {
+	var answer interface{}
+	if encoded == "" || json.Unmarshal([]byte(encoded), &answer) != nil {
+		return encoded
+	}
+	switch v := answer.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, part := range v {
+			parts = append(parts, fmt.Sprint(part))
+		}
+		return strings.Join(parts, "; ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(v))
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s -> %v", key, v[key]))
+		}
+		return strings.Join(parts, "; ")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(answer)
+}
+
+// QuizQTIPackage genera un paquete IMS QTI 2.1 con un assessmentItem por pregunta, un assessmentTest
+// (los bancos de preguntas quedan como secciones con selección aleatoria) y el imsmanifest.xml
+func QuizQTIPackage(contentID string, quiz domain.TeacherQuiz) ([]byte, error) {
+	files := map[string]string{}
+	var itemHrefs []string
+	itemHref := func(question domain.TeacherQuizQuestion) string {
+		href := fmt.Sprintf("items/%s.xml", question.ID)
+		files[href] = qtiAssessmentItem(question)
+		itemHrefs = append(itemHrefs, href)
+		return href
+	}
+
+	var test strings.Builder
+	fmt.Fprintf(&test, `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentTest xmlns="%s" identifier="%s" title="%s">
+  <testPart identifier="part" navigationMode="nonlinear" submissionMode="simultaneous">
+    <assessmentSection identifier="main" title="%s" visible="true">
+`, qtiNamespace, qtiEscape(contentID), qtiEscape(quiz.Title), qtiEscape(quiz.Title))
+	for _, question := range quiz.Questions {
+		fmt.Fprintf(&test, "      <assessmentItemRef identifier=\"%s\" href=\"%s\"/>\n", qtiEscape(question.ID), itemHref(question))
+	}
+	for _, pool := range quiz.Pools {
+		fmt.Fprintf(&test, "      <assessmentSection identifier=\"%s\" title=\"%s\" visible=\"false\">\n        <selection select=\"%d\"/>\n",
+			qtiEscape(pool.ID), qtiEscape(pool.ID), pool.Draw)
+		for _, question := range pool.Questions {
+			fmt.Fprintf(&test, "        <assessmentItemRef identifier=\"%s\" href=\"%s\"/>\n", qtiEscape(question.ID), itemHref(question))
+		}
+		test.WriteString("      </assessmentSection>\n")
+	}
+	test.WriteString("    </assessmentSection>\n  </testPart>\n</assessmentTest>\n")
+	files["test.xml"] = test.String()
+
+	var manifest strings.Builder
+	fmt.Fprintf(&manifest, `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" identifier="manifest-%s">
+  <metadata><schema>QTIv2.1 Package</schema><schemaversion>1.0.0</schemaversion></metadata>
+  <organizations/>
+  <resources>
+    <resource identifier="test" type="imsqti_test_xmlv2p1" href="test.xml"><file href="test.xml"/></resource>
+`, qtiEscape(contentID))
+	for _, href := range itemHrefs {
+		fmt.Fprintf(&manifest, "    <resource identifier=\"%s\" type=\"imsqti_item_xmlv2p1\" href=\"%s\"><file href=\"%s\"/></resource>\n",
+			qtiEscape(strings.TrimSuffix(strings.TrimPrefix(href, "items/"), ".xml")), href, href)
+	}
+	manifest.WriteString("  </resources>\n</manifest>\n")
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	names := []string{"imsmanifest.xml", "test.xml"}
+	files["imsmanifest.xml"] = manifest.String()
+	names = append(names, itemHrefs...)
+	for _, name := range names {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qtiAssessmentItem traduce una pregunta a un assessmentItem. Los tipos sin equivalente directo
+// se exportan como respuesta abierta para no perder el enunciado.
+func qtiAssessmentItem(question domain.TeacherQuizQuestion) string {
+	var declaration, interaction, processing string
+	choiceIDs := func(prefix string, labels []string) map[string]string {
+		ids := make(map[string]string, len(labels))
+		for i, label := range labels {
+			ids[label] = fmt.Sprintf("%s%d", prefix, i+1)
+		}
+		return ids
+	}
+	simpleChoices := func(element string, labels []string, ids map[string]string, indent string) string {
+		var b strings.Builder
+		for _, label := range labels {
+			fmt.Fprintf(&b, "%s<%s identifier=\"%s\">%s</%s>\n", indent, element, ids[label], qtiEscape(label), element)
+		}
+		return b.String()
+	}
+	correctValues := func(values []string) string {
+		var b strings.Builder
+		b.WriteString("<correctResponse>")
+		for _, value := range values {
+			fmt.Fprintf(&b, "<value>%s</value>", qtiEscape(value))
+		}
+		b.WriteString("</correctResponse>")
+		return b.String()
+	}
+	matchCorrect := `<responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"/>`
+
+	switch question.Type {
+	case "multiple", "checkbox", "boolean":
+		options := question.Options
+		var correct []string
+		cardinality, maxChoices := "single", 1
+		switch question.Type {
+		case "multiple":
+			correctAnswer, _ := question.CorrectAnswer.(string)
+			correct = []string{correctAnswer}
+		case "checkbox":
+			correct = question.CorrectAnswers
+			cardinality, maxChoices = "multiple", 0
+		case "boolean":
+			options = []string{"Verdadero", "Falso"}
+			if value, _ := parseBoolAnswer(question.CorrectAnswer); value {
+				correct = []string{"Verdadero"}
+			} else {
+				correct = []string{"Falso"}
+			}
+		}
+		ids := choiceIDs("C", options)
+		correctIDs := make([]string, 0, len(correct))
+		for _, label := range correct {
+			correctIDs = append(correctIDs, ids[label])
+		}
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="%s" baseType="identifier">%s</responseDeclaration>`,
+			cardinality, correctValues(correctIDs))
+		interaction = fmt.Sprintf("<choiceInteraction responseIdentifier=\"RESPONSE\" shuffle=\"false\" maxChoices=\"%d\">\n      <prompt>%s</prompt>\n%s    </choiceInteraction>",
+			maxChoices, qtiEscape(question.Question), simpleChoices("simpleChoice", options, ids, "      "))
+		processing = matchCorrect
+	case "ordering":
+		ids := choiceIDs("C", question.Options)
+		correctIDs := make([]string, 0, len(question.CorrectAnswers))
+		for _, label := range question.CorrectAnswers {
+			correctIDs = append(correctIDs, ids[label])
+		}
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="ordered" baseType="identifier">%s</responseDeclaration>`,
+			correctValues(correctIDs))
+		interaction = fmt.Sprintf("<orderInteraction responseIdentifier=\"RESPONSE\" shuffle=\"true\">\n      <prompt>%s</prompt>\n%s    </orderInteraction>",
+			qtiEscape(question.Question), simpleChoices("simpleChoice", question.Options, ids, "      "))
+		processing = matchCorrect
+	case "matching":
+		items := question.Items
+		if len(items) == 0 {
+			for item := range question.CorrectPairs {
+				items = append(items, item)
+			}
+			sort.Strings(items)
+		}
+		options := question.Options
+		if len(options) == 0 {
+			for _, item := range items {
+				if option := question.CorrectPairs[item]; !containsString(options, option) {
+					options = append(options, option)
+				}
+			}
+		}
+		itemIDs, optionIDs := choiceIDs("I", items), choiceIDs("O", options)
+		var pairs []string
+		for _, item := range items {
+			if option, ok := question.CorrectPairs[item]; ok {
+				pairs = append(pairs, itemIDs[item]+" "+optionIDs[option])
+			}
+		}
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="directedPair">%s</responseDeclaration>`,
+			correctValues(pairs))
+		interaction = fmt.Sprintf("<matchInteraction responseIdentifier=\"RESPONSE\" shuffle=\"false\" maxAssociations=\"%d\">\n      <prompt>%s</prompt>\n      <simpleMatchSet>\n%s      </simpleMatchSet>\n      <simpleMatchSet>\n%s      </simpleMatchSet>\n    </matchInteraction>",
+			len(items), qtiEscape(question.Question),
+			simpleChoices("simpleAssociableChoice", items, itemIDs, "        "),
+			simpleChoices("simpleAssociableChoice", options, optionIDs, "        "))
+		processing = matchCorrect
+	case "short":
+		accepted := shortAcceptedAnswers(question)
+		var mapping strings.Builder
+		mapping.WriteString(`<mapping defaultValue="0">`)
+		for _, answer := range accepted {
+			fmt.Fprintf(&mapping, `<mapEntry mapKey="%s" mappedValue="%d" caseSensitive="false"/>`, qtiEscape(answer), question.Points)
+		}
+		mapping.WriteString("</mapping>")
+		correct := ""
+		if len(accepted) > 0 {
+			correct = correctValues(accepted[:1])
+		}
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">%s%s</responseDeclaration>`,
+			correct, mapping.String())
+		interaction = fmt.Sprintf("<p>%s <textEntryInteraction responseIdentifier=\"RESPONSE\"%s/></p>",
+			qtiEscape(question.Question), qtiPatternMask(question.Pattern))
+		processing = `<responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"/>`
+	case "numeric":
+		value, _ := parseNumber(question.CorrectAnswer)
+		declaration = fmt.Sprintf(`<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="float">%s</responseDeclaration>`,
+			correctValues([]string{strconv.FormatFloat(value, 'f', -1, 64)}))
+		interaction = fmt.Sprintf("<p>%s <textEntryInteraction responseIdentifier=\"RESPONSE\"/></p>", qtiEscape(question.Question))
+		mode, tolerance := "absolute", question.Tolerance
+		if question.ToleranceType == "relative" {
+			// QTI expresa la tolerancia relativa en porcentaje
+			mode, tolerance = "relative", question.Tolerance*100
+		}
+		toleranceText := strconv.FormatFloat(tolerance, 'f', -1, 64)
+		processing = fmt.Sprintf(`<responseProcessing><responseCondition><responseIf><equal toleranceMode="%s" tolerance="%s %s"><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></equal><setOutcomeValue identifier="SCORE"><baseValue baseType="float">%d</baseValue></setOutcomeValue></responseIf></responseCondition></responseProcessing>`,
+			mode, toleranceText, toleranceText, question.Points)
+	default:
+		declaration = `<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string"/>`
+		interaction = fmt.Sprintf("<extendedTextInteraction responseIdentifier=\"RESPONSE\">\n      <prompt>%s</prompt>\n    </extendedTextInteraction>",
+			qtiEscape(question.Question))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="%s" identifier="%s" title="%s" adaptive="false" timeDependent="false">
+  %s
+  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float" normalMaximum="%d"><defaultValue><value>0</value></defaultValue></outcomeDeclaration>
+`, qtiNamespace, qtiEscape(question.ID), qtiEscape(question.ID), declaration, question.Points)
+	if question.Explanation != "" {
+		b.WriteString(`  <outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier"/>` + "\n")
+	}
+	fmt.Fprintf(&b, "  <itemBody>\n    %s\n  </itemBody>\n", interaction)
+	if processing != "" {
+		fmt.Fprintf(&b, "  %s\n", processing)
+	}
+	if question.Explanation != "" {
+		fmt.Fprintf(&b, "  <modalFeedback outcomeIdentifier=\"FEEDBACK\" identifier=\"EXPLANATION\" showHide=\"hide\">%s</modalFeedback>\n",
+			qtiEscape(question.Explanation))
+	}
+	b.WriteString("</assessmentItem>\n")
+	return b.String()
+}
+
+func qtiPatternMask(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+	return fmt.Sprintf(` patternMask="%s"`, qtiEscape(pattern))
+}
+
+func qtiEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,354 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxFormulaLength = 500
+	maxFormulaDepth  = 50
+)
+
+// Formula es una expresión aritmética ya analizada. Solo admite números, variables, + - * / ^,
+// paréntesis y un conjunto cerrado de funciones: no hay forma de ejecutar otra cosa al evaluarla.
+type Formula struct {
+	root      formulaNode
+	variables []string
+}
+
+type formulaNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+type variableNode string
+
+type unaryNode struct {
+	operand formulaNode
+}
+
+type binaryNode struct {
+	op          byte
+	left, right formulaNode
+}
+
+type callNode struct {
+	name string
+	arg  formulaNode
+}
+
+var formulaConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var formulaFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+}
+
+func (n numberNode) eval(map[string]float64) (float64, error) { return float64(n), nil }
+
+func (n variableNode) eval(values map[string]float64) (float64, error) {
+	value, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("la variable %q no tiene valor", string(n))
+	}
+	return value, nil
+}
+
+func (n unaryNode) eval(values map[string]float64) (float64, error) {
+	value, err := n.operand.eval(values)
+	return -value, err
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, errors.New("división por cero")
+		}
+		return left / right, nil
+	case '^':
+		return math.Pow(left, right), nil
+	}
+	return 0, fmt.Errorf("operador desconocido %q", n.op)
+}
+
+func (n callNode) eval(values map[string]float64) (float64, error) {
+	arg, err := n.arg.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	return formulaFunctions[n.name](arg), nil
+}
+
+// ParseFormula analiza una fórmula como "sqrt({a}^2 + b^2) / 2". Las variables se escriben
+// por su nombre, con o sin llaves, igual que en el enunciado.
+func ParseFormula(source string) (*Formula, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, errors.New("la fórmula está vacía")
+	}
+	if len(source) > maxFormulaLength {
+		return nil, fmt.Errorf("la fórmula no puede superar los %d caracteres", maxFormulaLength)
+	}
+	p := &formulaParser{source: source, variables: map[string]bool{}}
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.source) {
+		return nil, fmt.Errorf("carácter inesperado %q en la posición %d", p.source[p.pos], p.pos+1)
+	}
+
+	variables := make([]string, 0, len(p.variables))
+	for name := range p.variables {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return &Formula{root: root, variables: variables}, nil
+}
+
+// Variables devuelve, ordenados, los nombres de las variables que usa la fórmula
+func (f *Formula) Variables() []string {
+	return f.variables
+}
+
+// Evaluate calcula la fórmula con los valores dados; falla si falta una variable o el resultado no es finito
+func (f *Formula) Evaluate(values map[string]float64) (float64, error) {
+	result, err := f.root.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, errors.New("el resultado no es un número finito")
+	}
+	return result, nil
+}
+
+// formulaParser es un analizador descendente recursivo:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = "-" unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | name | "{" name "}" | name "(" expression ")" | "(" expression ")"
+type formulaParser struct {
+	source    string
+	pos       int
+	variables map[string]bool
+}
+
+func (p *formulaParser) skipSpaces() {
+	for p.pos < len(p.source) && (p.source[p.pos] == ' ' || p.source[p.pos] == '\t' || p.source[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *formulaParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.source) {
+		return 0
+	}
+	return p.source[p.pos]
+}
+
+func (p *formulaParser) parseExpression(depth int) (formulaNode, error) {
+	if depth > maxFormulaDepth {
+		return nil, errors.New("la fórmula está anidada demasiado")
+	}
+	left, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseTerm(depth int) (formulaNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseUnary(depth int) (formulaNode, error) {
+	if depth > maxFormulaDepth {
+		return nil, errors.New("la fórmula está anidada demasiado")
+	}
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{operand: operand}, nil
+	}
+	if p.peek() == '+' {
+		p.pos++
+		return p.parseUnary(depth + 1)
+	}
+	return p.parsePower(depth)
+}
+
+func (p *formulaParser) parsePower(depth int) (formulaNode, error) {
+	base, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == '^' {
+		p.pos++
+		// ^ es asociativo a la derecha: 2^3^2 = 2^(3^2)
+		exponent, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *formulaParser) parsePrimary(depth int) (formulaNode, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, errors.New("la fórmula termina de forma inesperada")
+	case c == '(':
+		p.pos++
+		inner, err := p.parseExpression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errors.New("falta cerrar un paréntesis")
+		}
+		p.pos++
+		return inner, nil
+	case c == '{':
+		p.pos++
+		name := p.readName()
+		if name == "" || p.peek() != '}' {
+			return nil, fmt.Errorf("variable mal escrita en la posición %d", p.pos+1)
+		}
+		p.pos++
+		p.variables[name] = true
+		return variableNode(name), nil
+	case c == '.' || c >= '0' && c <= '9':
+		return p.readNumber()
+	case isFormulaNameStart(rune(c)):
+		name := p.readName()
+		if p.peek() == '(' {
+			if _, ok := formulaFunctions[name]; !ok {
+				return nil, fmt.Errorf("función desconocida %q", name)
+			}
+			p.pos++
+			arg, err := p.parseExpression(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ')' {
+				return nil, fmt.Errorf("falta cerrar el paréntesis de %s", name)
+			}
+			p.pos++
+			return callNode{name: name, arg: arg}, nil
+		}
+		if value, ok := formulaConstants[name]; ok {
+			return numberNode(value), nil
+		}
+		p.variables[name] = true
+		return variableNode(name), nil
+	}
+	return nil, fmt.Errorf("carácter inesperado %q en la posición %d", c, p.pos+1)
+}
+
+func (p *formulaParser) readName() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.source) && isFormulaNamePart(rune(p.source[p.pos])) {
+		p.pos++
+	}
+	return p.source[start:p.pos]
+}
+
+func (p *formulaParser) readNumber() (formulaNode, error) {
+	start := p.pos
+	for p.pos < len(p.source) && (p.source[p.pos] == '.' || p.source[p.pos] >= '0' && p.source[p.pos] <= '9') {
+		p.pos++
+	}
+	// notación científica: 6.02e23, 1e-3
+	if p.pos < len(p.source) && (p.source[p.pos] == 'e' || p.source[p.pos] == 'E') {
+		end := p.pos + 1
+		if end < len(p.source) && (p.source[end] == '+' || p.source[end] == '-') {
+			end++
+		}
+		if end < len(p.source) && p.source[end] >= '0' && p.source[end] <= '9' {
+			for end < len(p.source) && p.source[end] >= '0' && p.source[end] <= '9' {
+				end++
+			}
+			p.pos = end
+		}
+	}
+	value, err := strconv.ParseFloat(p.source[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("número inválido %q", p.source[start:p.pos])
+	}
+	return numberNode(value), nil
+}
+
+func isFormulaNameStart(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+func isFormulaNamePart(r rune) bool {
+	return isFormulaNameStart(r) || r >= '0' && r <= '9'
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// importedQuestion es una pregunta leída del archivo de origen; Reason no vacío indica que no se puede importar
+type importedQuestion struct {
+	Name       string
+	SourceType string
+	Question   domain.TeacherQuizQuestion
+	Reason     string
+}
+
+func unsupportedQuestion(name, sourceType, reason string) importedQuestion {
+	return importedQuestion{Name: name, SourceType: sourceType, Reason: reason}
+}
+
+// ConvertImportedQuiz convierte un archivo Moodle XML, GIFT o QTI 2.1 en un quiz del profesor.
+// Las preguntas que no se pueden representar se devuelven como issues en lugar de abortar la importación.
+func ConvertImportedQuiz(format string, data []byte) (domain.TeacherQuiz, []domain.QuizImportIssue, error) {
+	var (
+		questions []importedQuestion
+		err       error
+	)
+	switch format {
+	case domain.QuizImportMoodleXML:
+		questions, err = parseMoodleXML(data)
+	case domain.QuizImportGIFT:
+		questions, err = parseGIFT(data)
+	case domain.QuizImportQTI:
+		questions, err = parseQTI(data)
+	default:
+		return domain.TeacherQuiz{}, nil, fmt.Errorf("formato de importación desconocido %q", format)
+	}
+	if err != nil {
+		return domain.TeacherQuiz{}, nil, err
+	}
+
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{}}
+	issues := []domain.QuizImportIssue{}
+	seenIDs := make(map[string]string)
+	for i, imported := range questions {
+		issue := domain.QuizImportIssue{Position: i + 1, Name: imported.Name, Type: imported.SourceType, Reason: imported.Reason}
+		if issue.Reason == "" {
+			question := imported.Question
+			question.ID = fmt.Sprintf("q%d", len(quiz.Questions)+1)
+			if question.Points == 0 {
+				question.Points = 1
+			}
+			issue.Reason = importedQuestionError(question, seenIDs)
+			if issue.Reason == "" {
+				quiz.Questions = append(quiz.Questions, question)
+				continue
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return quiz, issues, nil
+}
+
+// importedQuestionError aplica a la pregunta convertida la misma validación que al guardar un quiz
+func importedQuestionError(question domain.TeacherQuizQuestion, seenIDs map[string]string) string {
+	seen := make(map[string]bool)
+	for _, option := range question.Options {
+		if seen[option] {
+			return fmt.Sprintf("la opción %q está repetida", option)
+		}
+		seen[option] = true
+	}
+
+	errorMap := make(map[string]string)
+	validateQuizQuestion("question", question, seenIDs, errorMap)
+	if len(errorMap) == 0 {
+		return ""
+	}
+	fields := make([]string, 0, len(errorMap))
+	for field := range errorMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return errorMap[fields[0]]
+}
+
+// importedPoints redondea la puntuación de origen, que suele tener decimales; por defecto vale 1
+func importedPoints(value string) int {
+	points, ok := parseNumber(value)
+	if !ok || points <= 0 {
+		return 1
+	}
+	return int(math.Max(1, math.Round(points)))
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6])[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlToText reduce un fragmento HTML a texto plano: quita etiquetas, decodifica entidades y colapsa espacios
+func htmlToText(s string) string {
+	s = htmlBreakPattern.ReplaceAllString(s, " ")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// ImportQuiz convierte un archivo de otra plataforma en el quiz de un contenido y lo publica como una nueva versión
+func (c *CourseContentController) ImportQuiz() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.QuizImportInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		if _, err := c.RepoCourse.GetCourseByTeacherAndCourseID(userID, input.CourseID); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor"), nil)
+		}
+		// El curso se deduce del contenido: no basta con ser dueño del curso indicado en la petición
+		contentCourseID, err := c.Repo.GetCourseIDByContentID(input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", input.ContentID)), nil)
+		}
+		if contentCourseID != input.CourseID {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "El contenido no pertenece a este curso"), nil)
+		}
+		contentTypeID, err := c.Repo.GetContentTypeID(input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, err.Error()), nil)
+		}
+		if contentTypeID != 3 {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, "El contenido no es un quiz"), nil)
+		}
+
+		quiz, issues, err := ConvertImportedQuiz(input.Format, []byte(input.Data))
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("archivo inválido: %v", err)), nil)
+		}
+		quiz.Title = input.Title
+		result := domain.QuizImportResult{
+			Quiz:        quiz,
+			Imported:    len(quiz.Questions),
+			Unsupported: issues,
+			DryRun:      input.DryRun,
+		}
+		if len(quiz.Questions) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, struct {
+				Message string                   `json:"message"`
+				Body    []domain.QuizImportIssue `json:"body"`
+			}{Message: "El archivo no contiene preguntas compatibles",
+				Body: issues,
+			})
+		}
+		if input.DryRun {
+			return ReturnWriteResponse(e, nil, result)
+		}
+
+		jsonBytes, err := json.Marshal(quiz)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, "error al serializar el quiz"), nil)
+		}
+		// Se valida como cualquier quiz editado a mano antes de escribir en R2
+		if fieldErrors := ValidateTeacherQuiz(jsonBytes); fieldErrors != nil {
+			return quizDefinitionError(fieldErrors)
+		}
+		unsignedURL, version, err := c.publishTeacherQuiz(input.CourseID, input.ContentID, jsonBytes)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		err = c.Repo.UpdateContent(domain.UpdateContentInput{
+			ContentID: input.ContentID,
+			CourseID:  input.CourseID,
+			Title:     input.Title,
+			Url:       unsignedURL,
+		})
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, "error al actualizar el contenido"), nil)
+		}
+
+		result.Version = version
+		return ReturnWriteResponse(e, nil, result)
+	}
+}
@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"zeppelin/internal/domain"
+)
+
+// parseGIFT lee el formato de texto GIFT de Moodle: una pregunta por bloque, separadas por líneas en blanco
+func parseGIFT(data []byte) ([]importedQuestion, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var (
+		questions []importedQuestion
+		block     []string
+	)
+	flush := func() error {
+		if len(block) == 0 {
+			return nil
+		}
+		source := strings.TrimSpace(strings.Join(block, "\n"))
+		block = nil
+		if strings.HasPrefix(source, "$CATEGORY:") {
+			return nil
+		}
+		question, err := convertGIFTQuestion(source)
+		if err != nil {
+			return err
+		}
+		questions = append(questions, question)
+		return nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if trimmed == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func convertGIFTQuestion(source string) (importedQuestion, error) {
+	var name string
+	if strings.HasPrefix(source, "::") {
+		end := indexUnescaped(source[2:], "::")
+		if end < 0 {
+			return importedQuestion{}, fmt.Errorf("título sin cerrar en %q", giftExcerpt(source))
+		}
+		name = giftUnescape(strings.TrimSpace(source[2 : end+2]))
+		source = strings.TrimSpace(source[end+4:])
+	}
+
+	open := indexUnescaped(source, "{")
+	if open < 0 {
+		return unsupportedQuestion(name, "description", "las descripciones no son preguntas"), nil
+	}
+	closeIndex := indexUnescaped(source[open:], "}")
+	if closeIndex < 0 {
+		return importedQuestion{}, fmt.Errorf("respuestas sin cerrar en %q", giftExcerpt(source))
+	}
+	closeIndex += open
+
+	before := strings.TrimSpace(source[:open])
+	after := strings.TrimSpace(source[closeIndex+1:])
+	body := strings.TrimSpace(source[open+1 : closeIndex])
+
+	// Una pregunta con texto después de las respuestas es de "palabra faltante"
+	questionText := before
+	if after != "" {
+		questionText = before + " _____ " + after
+	}
+	question := domain.TeacherQuizQuestion{Question: giftText(questionText), Points: 1}
+
+	// La retroalimentación general va al final del bloque de respuestas, después de ####
+	if feedback := strings.Index(body, "####"); feedback >= 0 {
+		question.Explanation = giftText(body[feedback+4:])
+		body = strings.TrimSpace(body[:feedback])
+	}
+
+	switch {
+	case body == "":
+		question.Type = "text"
+		return importedQuestion{Name: name, SourceType: "essay", Question: question}, nil
+	case strings.HasPrefix(body, "#"):
+		return convertGIFTNumeric(name, question, body[1:])
+	}
+
+	answer, _ := splitGIFTFeedback(body)
+	switch strings.ToUpper(strings.TrimSpace(answer)) {
+	case "T", "TRUE":
+		question.Type = "boolean"
+		question.CorrectAnswer = true
+		return importedQuestion{Name: name, SourceType: "truefalse", Question: question}, nil
+	case "F", "FALSE":
+		question.Type = "boolean"
+		question.CorrectAnswer = false
+		return importedQuestion{Name: name, SourceType: "truefalse", Question: question}, nil
+	}
+
+	answers := splitGIFTAnswers(body)
+	if len(answers) == 0 {
+		return unsupportedQuestion(name, "unknown", "no se reconoce el bloque de respuestas"), nil
+	}
+
+	hasWrong, weighted, matching := false, false, false
+	for _, answer := range answers {
+		hasWrong = hasWrong || answer.Marker == '~'
+		weighted = weighted || answer.HasWeight
+		matching = matching || indexUnescaped(answer.Text, "->") >= 0
+	}
+
+	switch {
+	case matching:
+		question.Type = "matching"
+		question.CorrectPairs = map[string]string{}
+		for _, answer := range answers {
+			arrow := indexUnescaped(answer.Text, "->")
+			if answer.Marker != '=' || arrow < 0 {
+				return unsupportedQuestion(name, "matching", "cada par debe tener la forma =item -> opción"), nil
+			}
+			item := giftText(answer.Text[:arrow])
+			option := giftText(answer.Text[arrow+2:])
+			if !containsString(question.Options, option) {
+				question.Options = append(question.Options, option)
+			}
+			// Los pares sin item son distractores
+			if item == "" {
+				continue
+			}
+			question.Items = append(question.Items, item)
+			question.CorrectPairs[item] = option
+		}
+		return importedQuestion{Name: name, SourceType: "matching", Question: question}, nil
+	case !hasWrong:
+		// Solo respuestas correctas: respuesta corta
+		question.Type = "short"
+		for _, answer := range answers {
+			if !answer.HasWeight || answer.Weight == 100 {
+				question.AcceptedAnswers = append(question.AcceptedAnswers, giftText(answer.Text))
+			}
+		}
+		return importedQuestion{Name: name, SourceType: "shortanswer", Question: question}, nil
+	}
+
+	question.OptionExplanations = map[string]string{}
+	var correct []string
+	for _, answer := range answers {
+		option := giftText(answer.Text)
+		question.Options = append(question.Options, option)
+		if answer.Feedback != "" {
+			question.OptionExplanations[option] = answer.Feedback
+		}
+		if answer.Marker == '=' || answer.HasWeight && answer.Weight > 0 {
+			correct = append(correct, option)
+		}
+	}
+	if len(question.OptionExplanations) == 0 {
+		question.OptionExplanations = nil
+	}
+
+	// Con pesos (~%50%) la pregunta admite varias respuestas correctas
+	if weighted {
+		question.Type = "checkbox"
+		question.CorrectAnswers = correct
+		question.Scoring = domain.ScoringProportional
+		return importedQuestion{Name: name, SourceType: "multichoice", Question: question}, nil
+	}
+	if len(correct) != 1 {
+		return unsupportedQuestion(name, "multichoice", "opción única con más de una respuesta correcta"), nil
+	}
+	question.Type = "multiple"
+	question.CorrectAnswer = correct[0]
+	return importedQuestion{Name: name, SourceType: "multichoice", Question: question}, nil
+}
+
+// convertGIFTNumeric interpreta {#valor}, {#valor:tolerancia}, {#mín..máx} y {#=valor:tolerancia =%50%...}
+func convertGIFTNumeric(name string, question domain.TeacherQuizQuestion, body string) (importedQuestion, error) {
+	question.Type = "numeric"
+	spec, _ := splitGIFTFeedback(strings.TrimSpace(body))
+	if answers := splitGIFTAnswers(body); len(answers) > 0 {
+		spec = ""
+		for _, answer := range answers {
+			if answer.Marker == '=' && (!answer.HasWeight || answer.Weight == 100) {
+				spec = answer.Text
+				break
+			}
+		}
+	}
+	spec = strings.TrimSpace(spec)
+
+	var value, tolerance float64
+	var err error
+	if bounds := strings.SplitN(spec, "..", 2); len(bounds) == 2 {
+		low, errLow := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
+		high, errHigh := strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
+		if errLow != nil || errHigh != nil {
+			return unsupportedQuestion(name, "numerical", fmt.Sprintf("rango numérico inválido %q", spec)), nil
+		}
+		value, tolerance = (low+high)/2, math.Abs(high-low)/2
+	} else {
+		parts := strings.SplitN(spec, ":", 2)
+		if value, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+			return unsupportedQuestion(name, "numerical", fmt.Sprintf("valor numérico inválido %q", spec)), nil
+		}
+		if len(parts) == 2 {
+			if tolerance, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+				return unsupportedQuestion(name, "numerical", fmt.Sprintf("tolerancia inválida %q", spec)), nil
+			}
+		}
+	}
+	question.CorrectAnswer = value
+	question.Tolerance = math.Abs(tolerance)
+	return importedQuestion{Name: name, SourceType: "numerical", Question: question}, nil
+}
+
+type giftAnswer struct {
+	Marker    byte // '=' correcta, '~' incorrecta
+	Text      string
+	Feedback  string
+	HasWeight bool
+	Weight    float64
+}
+
+// splitGIFTAnswers separa el bloque de respuestas en sus alternativas =... y ~...
+func splitGIFTAnswers(body string) []giftAnswer {
+	var answers []giftAnswer
+	start := -1
+	for i := 0; i <= len(body); i++ {
+		atMarker := i < len(body) && (body[i] == '=' || body[i] == '~') && !isEscaped(body, i)
+		if !atMarker && i < len(body) {
+			continue
+		}
+		if start >= 0 {
+			answers = append(answers, newGIFTAnswer(body[start], body[start+1:i]))
+		}
+		start = i
+	}
+	return answers
+}
+
+func newGIFTAnswer(marker byte, raw string) giftAnswer {
+	text, feedback := splitGIFTFeedback(strings.TrimSpace(raw))
+	answer := giftAnswer{Marker: marker, Text: text, Feedback: giftText(feedback)}
+	if strings.HasPrefix(answer.Text, "%") {
+		if end := strings.Index(answer.Text[1:], "%"); end >= 0 {
+			if weight, err := strconv.ParseFloat(answer.Text[1:end+1], 64); err == nil {
+				answer.HasWeight = true
+				answer.Weight = weight
+				answer.Text = strings.TrimSpace(answer.Text[end+2:])
+			}
+		}
+	}
+	return answer
+}
+
+// splitGIFTFeedback separa el texto de una respuesta de su retroalimentación (#...)
+func splitGIFTFeedback(raw string) (string, string) {
+	if hash := indexUnescaped(raw, "#"); hash >= 0 {
+		return strings.TrimSpace(raw[:hash]), strings.TrimSpace(raw[hash+1:])
+	}
+	return strings.TrimSpace(raw), ""
+}
+
+// giftText quita el prefijo de formato ([html], [plain], [markdown]) y los escapes de GIFT
+func giftText(raw string) string {
+	raw = strings.TrimSpace(raw)
+	isHTML := false
+	for _, prefix := range []string{"[html]", "[plain]", "[moodle]", "[markdown]"} {
+		if strings.HasPrefix(raw, prefix) {
+			isHTML = prefix == "[html]"
+			raw = raw[len(prefix):]
+			break
+		}
+	}
+	raw = giftUnescape(raw)
+	if isHTML {
+		return htmlToText(raw)
+	}
+	return strings.Join(strings.Fields(raw), " ")
+}
+
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`~=#{}:\n`, s[i+1]) >= 0 {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// indexUnescaped busca sep ignorando las apariciones escapadas con barra invertida
+func indexUnescaped(s, sep string) int {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], sep)
+		if i < 0 {
+			return -1
+		}
+		if !isEscaped(s, offset+i) {
+			return offset + i
+		}
+		offset += i + 1
+	}
+}
+
+func isEscaped(s string, i int) bool {
+	backslashes := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+func giftExcerpt(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+	return s
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"zeppelin/internal/domain"
+)
+
+type moodleQuiz struct {
+	Questions []moodleQuestion `xml:"question"`
+}
+
+type moodleText struct {
+	Format string `xml:"format,attr"`
+	Text   string `xml:"text"`
+}
+
+type moodleQuestion struct {
+	Type            string              `xml:"type,attr"`
+	Name            moodleText          `xml:"name"`
+	QuestionText    moodleText          `xml:"questiontext"`
+	GeneralFeedback moodleText          `xml:"generalfeedback"`
+	DefaultGrade    string              `xml:"defaultgrade"`
+	Single          string              `xml:"single"`
+	Answers         []moodleAnswer      `xml:"answer"`
+	Subquestions    []moodleSubquestion `xml:"subquestion"`
+}
+
+type moodleAnswer struct {
+	Fraction  string     `xml:"fraction,attr"`
+	Format    string     `xml:"format,attr"`
+	Text      string     `xml:"text"`
+	Feedback  moodleText `xml:"feedback"`
+	Tolerance string     `xml:"tolerance"`
+}
+
+type moodleSubquestion struct {
+	Format string     `xml:"format,attr"`
+	Text   string     `xml:"text"`
+	Answer moodleText `xml:"answer"`
+}
+
+// parseMoodleXML lee el formato de exportación "Moodle XML" del banco de preguntas
+func parseMoodleXML(data []byte) ([]importedQuestion, error) {
+	var quiz moodleQuiz
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&quiz); err != nil {
+		return nil, fmt.Errorf("XML de Moodle inválido: %w", err)
+	}
+
+	var questions []importedQuestion
+	for _, source := range quiz.Questions {
+		// Las categorías organizan el banco de preguntas de Moodle; no son preguntas
+		if source.Type == "category" {
+			continue
+		}
+		questions = append(questions, convertMoodleQuestion(source))
+	}
+	return questions, nil
+}
+
+func convertMoodleQuestion(source moodleQuestion) importedQuestion {
+	name := strings.TrimSpace(source.Name.Text)
+	question := domain.TeacherQuizQuestion{
+		Question:    moodleToText(source.QuestionText),
+		Points:      importedPoints(source.DefaultGrade),
+		Explanation: moodleToText(source.GeneralFeedback),
+	}
+
+	switch source.Type {
+	case "multichoice":
+		question.OptionExplanations = map[string]string{}
+		var correct []string
+		partial := false
+		for _, answer := range source.Answers {
+			option := moodleToText(moodleText{Format: answer.Format, Text: answer.Text})
+			question.Options = append(question.Options, option)
+			if feedback := moodleToText(answer.Feedback); feedback != "" {
+				question.OptionExplanations[option] = feedback
+			}
+			fraction := moodleFraction(answer.Fraction)
+			if fraction > 0 {
+				correct = append(correct, option)
+			}
+			if fraction > 0 && fraction < 100 {
+				partial = true
+			}
+		}
+		if len(question.OptionExplanations) == 0 {
+			question.OptionExplanations = nil
+		}
+		if strings.TrimSpace(source.Single) == "false" {
+			question.Type = "checkbox"
+			question.CorrectAnswers = correct
+			question.Scoring = domain.ScoringProportional
+			break
+		}
+		if len(correct) != 1 || partial {
+			return unsupportedQuestion(name, source.Type, "opción única con más de una respuesta puntuada o con crédito parcial")
+		}
+		question.Type = "multiple"
+		question.CorrectAnswer = correct[0]
+	case "truefalse":
+		question.Type = "boolean"
+		for _, answer := range source.Answers {
+			if moodleFraction(answer.Fraction) == 100 {
+				question.CorrectAnswer = strings.TrimSpace(answer.Text)
+			}
+		}
+	case "shortanswer":
+		question.Type = "short"
+		var patterns []string
+		for _, answer := range source.Answers {
+			if moodleFraction(answer.Fraction) != 100 {
+				continue
+			}
+			text := strings.TrimSpace(answer.Text)
+			if strings.Contains(text, "*") {
+				patterns = append(patterns, moodleWildcardPattern(text))
+			} else {
+				question.AcceptedAnswers = append(question.AcceptedAnswers, text)
+			}
+		}
+		if len(patterns) > 0 {
+			question.Pattern = "(?i)^(" + strings.Join(patterns, "|") + ")$"
+		}
+	case "numerical":
+		question.Type = "numeric"
+		for _, answer := range source.Answers {
+			if moodleFraction(answer.Fraction) != 100 {
+				continue
+			}
+			value, ok := parseNumber(answer.Text)
+			if !ok {
+				return unsupportedQuestion(name, source.Type, "la respuesta correcta no es un número")
+			}
+			question.CorrectAnswer = value
+			if tolerance, ok := parseNumber(answer.Tolerance); ok {
+				question.Tolerance = math.Abs(tolerance)
+			}
+			break
+		}
+	case "matching":
+		question.Type = "matching"
+		question.CorrectPairs = map[string]string{}
+		for _, sub := range source.Subquestions {
+			option := strings.TrimSpace(sub.Answer.Text)
+			if !containsString(question.Options, option) {
+				question.Options = append(question.Options, option)
+			}
+			// Las subpreguntas sin texto son distractores: solo agregan opciones
+			item := moodleToText(moodleText{Format: sub.Format, Text: sub.Text})
+			if item == "" {
+				continue
+			}
+			question.Items = append(question.Items, item)
+			question.CorrectPairs[item] = option
+		}
+	case "ordering":
+		// En el tipo ordering de Moodle las respuestas se exportan en el orden correcto
+		question.Type = "ordering"
+		for _, answer := range source.Answers {
+			item := moodleToText(moodleText{Format: answer.Format, Text: answer.Text})
+			question.Options = append(question.Options, item)
+			question.CorrectAnswers = append(question.CorrectAnswers, item)
+		}
+	case "essay":
+		question.Type = "text"
+	case "description":
+		return unsupportedQuestion(name, source.Type, "las descripciones no son preguntas")
+	default:
+		return unsupportedQuestion(name, source.Type, fmt.Sprintf("tipo de pregunta %q no soportado", source.Type))
+	}
+
+	return importedQuestion{Name: name, SourceType: source.Type, Question: question}
+}
+
+// moodleToText convierte un texto de Moodle a texto plano según su formato
+func moodleToText(text moodleText) string {
+	if text.Format == "html" || text.Format == "" && strings.Contains(text.Text, "<") {
+		return htmlToText(text.Text)
+	}
+	return strings.TrimSpace(text.Text)
+}
+
+func moodleFraction(value string) float64 {
+	fraction, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return fraction
+}
+
+// moodleWildcardPattern traduce el comodín * de las respuestas cortas de Moodle a una expresión regular
+func moodleWildcardPattern(answer string) string {
+	parts := strings.Split(answer, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return strings.Join(parts, ".*")
+}
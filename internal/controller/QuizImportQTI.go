@@ -0,0 +1,399 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"zeppelin/internal/domain"
+)
+
+// qtiNode es un elemento XML con sus hijos en orden; los nodos de texto tienen Name vacío
+type qtiNode struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*qtiNode
+	Text     string
+}
+
+func (n *qtiNode) child(name string) *qtiNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *qtiNode) children(name string) []*qtiNode {
+	var result []*qtiNode
+	for _, child := range n.Children {
+		if child.Name == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// find recorre el árbol en profundidad y devuelve los elementos que cumplen match, sin entrar en ellos
+func (n *qtiNode) find(match func(*qtiNode) bool) []*qtiNode {
+	var result []*qtiNode
+	for _, child := range n.Children {
+		if child.Name == "" {
+			continue
+		}
+		if match(child) {
+			result = append(result, child)
+			continue
+		}
+		result = append(result, child.find(match)...)
+	}
+	return result
+}
+
+// text concatena el texto del elemento, omitiendo los subárboles para los que skip devuelve true
+func (n *qtiNode) text(skip func(*qtiNode) bool) string {
+	var parts []string
+	var walk func(node *qtiNode)
+	walk = func(node *qtiNode) {
+		for _, child := range node.Children {
+			if child.Name == "" {
+				parts = append(parts, child.Text)
+			} else if skip == nil || !skip(child) {
+				walk(child)
+				parts = append(parts, " ")
+			}
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(strings.Join(parts, "")), " ")
+}
+
+func parseQTINode(data []byte) (*qtiNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Los paquetes QTI pueden declarar entidades HTML (&nbsp;) que encoding/xml no conoce
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	root := &qtiNode{}
+	stack := []*qtiNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &qtiNode{Name: t.Name.Local, Attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.Children = append(parent.Children, &qtiNode{Text: string(t)})
+		}
+	}
+	return root, nil
+}
+
+// parseQTI acepta un documento XML con uno o más assessmentItem, o un paquete de contenido QTI (zip) en base64
+func parseQTI(data []byte) ([]importedQuestion, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '<' {
+		archive, err := base64.StdEncoding.DecodeString(string(trimmed))
+		if err != nil {
+			return nil, errors.New("se esperaba XML o un paquete zip en base64")
+		}
+		return parseQTIPackage(archive)
+	}
+
+	root, err := parseQTINode(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("XML de QTI inválido: %w", err)
+	}
+	items := root.find(func(n *qtiNode) bool { return n.Name == "assessmentItem" })
+	if len(items) == 0 {
+		return nil, errors.New("el documento no contiene ningún assessmentItem")
+	}
+	questions := make([]importedQuestion, 0, len(items))
+	for _, item := range items {
+		questions = append(questions, convertQTIItem(item))
+	}
+	return questions, nil
+}
+
+// parseQTIPackage lee los assessmentItem de un paquete zip en el orden del manifiesto
+func parseQTIPackage(archive []byte) ([]importedQuestion, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("paquete QTI inválido: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	var names []string
+	for _, file := range reader.File {
+		if strings.HasSuffix(strings.ToLower(file.Name), ".xml") {
+			files[file.Name] = file
+			names = append(names, file.Name)
+		}
+	}
+	sort.Strings(names)
+
+	// El manifiesto define el orden de las preguntas; sin él se usa el orden alfabético
+	if manifest, ok := files["imsmanifest.xml"]; ok {
+		content, err := readZipFile(manifest)
+		if err != nil {
+			return nil, err
+		}
+		root, err := parseQTINode(content)
+		if err != nil {
+			return nil, fmt.Errorf("imsmanifest.xml inválido: %w", err)
+		}
+		var ordered []string
+		for _, resource := range root.find(func(n *qtiNode) bool { return n.Name == "resource" }) {
+			if href := resource.Attrs["href"]; files[href] != nil && !containsString(ordered, href) {
+				ordered = append(ordered, href)
+			}
+		}
+		if len(ordered) > 0 {
+			names = ordered
+		}
+	}
+
+	var questions []importedQuestion
+	for _, name := range names {
+		if name == "imsmanifest.xml" {
+			continue
+		}
+		content, err := readZipFile(files[name])
+		if err != nil {
+			return nil, err
+		}
+		root, err := parseQTINode(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: XML de QTI inválido: %w", name, err)
+		}
+		for _, item := range root.find(func(n *qtiNode) bool { return n.Name == "assessmentItem" }) {
+			questions = append(questions, convertQTIItem(item))
+		}
+	}
+	if len(questions) == 0 {
+		return nil, errors.New("el paquete no contiene ningún assessmentItem")
+	}
+	return questions, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+var qtiSupportedInteractions = map[string]bool{
+	"choiceInteraction":       true,
+	"orderInteraction":        true,
+	"matchInteraction":        true,
+	"textEntryInteraction":    true,
+	"extendedTextInteraction": true,
+}
+
+func isQTIInteraction(n *qtiNode) bool {
+	return strings.HasSuffix(n.Name, "Interaction")
+}
+
+func convertQTIItem(item *qtiNode) importedQuestion {
+	name := item.Attrs["title"]
+	if name == "" {
+		name = item.Attrs["identifier"]
+	}
+	body := item.child("itemBody")
+	if body == nil {
+		return unsupportedQuestion(name, "", "el item no tiene itemBody")
+	}
+
+	interactions := body.find(isQTIInteraction)
+	if len(interactions) == 0 {
+		return unsupportedQuestion(name, "", "el item no tiene ninguna interacción")
+	}
+	interaction := interactions[0]
+	if len(interactions) > 1 {
+		return unsupportedQuestion(name, interaction.Name, "los items con varias interacciones no están soportados")
+	}
+	if !qtiSupportedInteractions[interaction.Name] {
+		return unsupportedQuestion(name, interaction.Name, fmt.Sprintf("la interacción %s no está soportada", interaction.Name))
+	}
+
+	declaration := qtiResponseDeclaration(item, interaction.Attrs["responseIdentifier"])
+	if declaration == nil && interaction.Name != "extendedTextInteraction" {
+		return unsupportedQuestion(name, interaction.Name, "falta el responseDeclaration de la interacción")
+	}
+	correct := qtiCorrectValues(declaration)
+
+	// El enunciado es el texto del itemBody fuera de la interacción más el prompt de la interacción
+	questionText := body.text(isQTIInteraction)
+	if prompt := interaction.child("prompt"); prompt != nil {
+		questionText = strings.TrimSpace(questionText + " " + prompt.text(nil))
+	}
+	question := domain.TeacherQuizQuestion{
+		Question: questionText,
+		Points:   qtiMaxScore(item),
+	}
+
+	switch interaction.Name {
+	case "choiceInteraction":
+		labels := qtiChoiceLabels(interaction.children("simpleChoice"))
+		for _, choice := range interaction.children("simpleChoice") {
+			question.Options = append(question.Options, labels[choice.Attrs["identifier"]])
+		}
+		var answers []string
+		for _, value := range correct {
+			label, ok := labels[value]
+			if !ok {
+				return unsupportedQuestion(name, interaction.Name, fmt.Sprintf("la respuesta correcta %q no es una opción", value))
+			}
+			answers = append(answers, label)
+		}
+		if declaration.Attrs["cardinality"] == "multiple" {
+			question.Type = "checkbox"
+			question.CorrectAnswers = answers
+			question.Scoring = domain.ScoringProportional
+			break
+		}
+		if len(answers) != 1 {
+			return unsupportedQuestion(name, interaction.Name, "opción única sin exactamente una respuesta correcta")
+		}
+		question.Type = "multiple"
+		question.CorrectAnswer = answers[0]
+	case "orderInteraction":
+		labels := qtiChoiceLabels(interaction.children("simpleChoice"))
+		question.Type = "ordering"
+		for _, choice := range interaction.children("simpleChoice") {
+			question.Options = append(question.Options, labels[choice.Attrs["identifier"]])
+		}
+		for _, value := range correct {
+			question.CorrectAnswers = append(question.CorrectAnswers, labels[value])
+		}
+	case "matchInteraction":
+		sets := interaction.children("simpleMatchSet")
+		if len(sets) != 2 {
+			return unsupportedQuestion(name, interaction.Name, "se esperaban dos simpleMatchSet")
+		}
+		itemLabels := qtiChoiceLabels(sets[0].children("simpleAssociableChoice"))
+		optionLabels := qtiChoiceLabels(sets[1].children("simpleAssociableChoice"))
+		question.Type = "matching"
+		question.CorrectPairs = map[string]string{}
+		for _, choice := range sets[0].children("simpleAssociableChoice") {
+			question.Items = append(question.Items, itemLabels[choice.Attrs["identifier"]])
+		}
+		for _, choice := range sets[1].children("simpleAssociableChoice") {
+			question.Options = append(question.Options, optionLabels[choice.Attrs["identifier"]])
+		}
+		for _, value := range correct {
+			pair := strings.Fields(value)
+			if len(pair) != 2 || itemLabels[pair[0]] == "" || optionLabels[pair[1]] == "" {
+				return unsupportedQuestion(name, interaction.Name, fmt.Sprintf("par correcto inválido %q", value))
+			}
+			question.CorrectPairs[itemLabels[pair[0]]] = optionLabels[pair[1]]
+		}
+	case "textEntryInteraction":
+		switch declaration.Attrs["baseType"] {
+		case "float", "integer":
+			if len(correct) == 0 {
+				return unsupportedQuestion(name, interaction.Name, "falta la respuesta correcta")
+			}
+			value, ok := parseNumber(correct[0])
+			if !ok {
+				return unsupportedQuestion(name, interaction.Name, fmt.Sprintf("la respuesta correcta %q no es un número", correct[0]))
+			}
+			question.Type = "numeric"
+			question.CorrectAnswer = value
+		default:
+			question.Type = "short"
+			question.AcceptedAnswers = correct
+			// Las respuestas alternativas con puntuación completa suelen venir en el mapping
+			if mapping := declaration.child("mapping"); mapping != nil {
+				for _, entry := range mapping.children("mapEntry") {
+					if key := entry.Attrs["mapKey"]; key != "" && !containsString(question.AcceptedAnswers, key) && qtiFullCredit(entry, question.Points) {
+						question.AcceptedAnswers = append(question.AcceptedAnswers, key)
+					}
+				}
+			}
+		}
+	case "extendedTextInteraction":
+		question.Type = "text"
+	}
+
+	return importedQuestion{Name: name, SourceType: interaction.Name, Question: question}
+}
+
+func qtiResponseDeclaration(item *qtiNode, identifier string) *qtiNode {
+	for _, declaration := range item.children("responseDeclaration") {
+		if declaration.Attrs["identifier"] == identifier {
+			return declaration
+		}
+	}
+	return nil
+}
+
+func qtiCorrectValues(declaration *qtiNode) []string {
+	if declaration == nil {
+		return nil
+	}
+	correctResponse := declaration.child("correctResponse")
+	if correctResponse == nil {
+		return nil
+	}
+	var values []string
+	for _, value := range correctResponse.children("value") {
+		values = append(values, value.text(nil))
+	}
+	return values
+}
+
+// qtiChoiceLabels asocia el identificador de cada opción con su texto
+func qtiChoiceLabels(choices []*qtiNode) map[string]string {
+	labels := make(map[string]string, len(choices))
+	for _, choice := range choices {
+		labels[choice.Attrs["identifier"]] = choice.text(func(n *qtiNode) bool { return n.Name == "feedbackInline" })
+	}
+	return labels
+}
+
+// qtiMaxScore toma la puntuación máxima declarada en el outcome SCORE o MAXSCORE; por defecto 1
+func qtiMaxScore(item *qtiNode) int {
+	for _, outcome := range item.children("outcomeDeclaration") {
+		switch outcome.Attrs["identifier"] {
+		case "MAXSCORE":
+			if value := outcome.child("defaultValue"); value != nil && value.child("value") != nil {
+				return importedPoints(value.child("value").text(nil))
+			}
+		case "SCORE":
+			if normalMaximum := outcome.Attrs["normalMaximum"]; normalMaximum != "" {
+				return importedPoints(normalMaximum)
+			}
+		}
+	}
+	return 1
+}
+
+func qtiFullCredit(entry *qtiNode, points int) bool {
+	value, ok := parseNumber(entry.Attrs["mappedValue"])
+	return ok && value >= float64(points)
+}
@@ -0,0 +1,492 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"zeppelin/internal/domain"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// QuestionType agrupa la lógica de un tipo de pregunta: cómo se valida su clave de respuestas,
+// qué forma debe tener la respuesta del estudiante y cómo se califica.
+type QuestionType interface {
+	// ValidateKey verifica que la pregunta del profesor tenga una clave de respuestas utilizable
+	ValidateKey(question domain.TeacherQuizQuestion) error
+	// ValidateAnswer verifica la forma de la respuesta del estudiante (nunca recibe nil)
+	ValidateAnswer(question domain.TeacherQuizQuestion, answer interface{}) error
+	// Grade devuelve la fracción de los puntos obtenida (0 a 1) y si requiere revisión manual
+	Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool)
+}
+
+// QuestionKeyError indica qué campo de la clave de respuestas es inválido
+type QuestionKeyError struct {
+	Field   string
+	Message string
+}
+
+func (e *QuestionKeyError) Error() string {
+	return e.Message
+}
+
+func keyError(field, message string) error {
+	return &QuestionKeyError{Field: field, Message: message}
+}
+
+var questionTypes = map[string]QuestionType{}
+
+// RegisterQuestionType registra (o reemplaza) la implementación de un tipo de pregunta
+func RegisterQuestionType(name string, questionType QuestionType) {
+	questionTypes[name] = questionType
+}
+
+// LookupQuestionType devuelve la implementación registrada para un tipo de pregunta
+func LookupQuestionType(name string) (QuestionType, bool) {
+	questionType, ok := questionTypes[name]
+	return questionType, ok
+}
+
+func init() {
+	RegisterQuestionType("text", textQuestion{})
+	RegisterQuestionType("multiple", multipleQuestion{})
+	RegisterQuestionType("checkbox", checkboxQuestion{})
+	RegisterQuestionType("boolean", booleanQuestion{})
+	RegisterQuestionType("numeric", numericQuestion{})
+	RegisterQuestionType("short", shortQuestion{})
+	RegisterQuestionType("ordering", orderingQuestion{})
+	RegisterQuestionType("matching", matchingQuestion{})
+	RegisterQuestionType("calculated", calculatedQuestion{})
+}
+
+// textQuestion: respuesta abierta calificada manualmente por el profesor
+type textQuestion struct{}
+
+func (textQuestion) ValidateKey(domain.TeacherQuizQuestion) error { return nil }
+
+func (textQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := answer.(string); !ok {
+		return errors.New("la respuesta debe ser texto")
+	}
+	return nil
+}
+
+// Grade nunca asigna puntos: la nota sale de la revisión del profesor, no de la respuesta del estudiante
+func (textQuestion) Grade(domain.TeacherQuizQuestion, interface{}) (float64, bool) {
+	return 0, true
+}
+
+// multipleQuestion: una única opción correcta
+type multipleQuestion struct{}
+
+func (multipleQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if len(question.Options) < 2 {
+		return keyError("options", "options debe tener al menos dos opciones")
+	}
+	correctAnswer, ok := question.CorrectAnswer.(string)
+	if !ok || correctAnswer == "" {
+		return keyError("correctAnswer", "correctAnswer debe ser un texto no vacío")
+	}
+	if !containsString(question.Options, correctAnswer) {
+		return keyError("correctAnswer", fmt.Sprintf("correctAnswer %q no está entre las opciones", correctAnswer))
+	}
+	return nil
+}
+
+func (multipleQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := answer.(string); !ok {
+		return errors.New("la respuesta debe ser una opción")
+	}
+	return nil
+}
+
+func (multipleQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	correctAnswer, _ := question.CorrectAnswer.(string)
+	if answer.(string) == correctAnswer {
+		return 1, false
+	}
+	return 0, false
+}
+
+// checkboxQuestion: varias opciones correctas, con política de puntuación configurable
+type checkboxQuestion struct{}
+
+func (checkboxQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if len(question.Options) < 2 {
+		return keyError("options", "options debe tener al menos dos opciones")
+	}
+	if len(question.CorrectAnswers) == 0 {
+		return keyError("correctAnswers", "correctAnswers debe tener al menos una opción")
+	}
+	for _, ans := range question.CorrectAnswers {
+		if !containsString(question.Options, ans) {
+			return keyError("correctAnswers", fmt.Sprintf("correctAnswers contiene %q, que no está entre las opciones", ans))
+		}
+	}
+	return nil
+}
+
+func (checkboxQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	_, err := toStringSlice(answer)
+	return err
+}
+
+func (checkboxQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	selected, _ := toStringSlice(answer)
+	studentMap := make(map[string]bool)
+	for _, ans := range selected {
+		studentMap[ans] = true
+	}
+	correctMap := make(map[string]bool)
+	for _, ans := range question.CorrectAnswers {
+		correctMap[ans] = true
+	}
+	hits, wrong := 0, 0
+	for ans := range studentMap {
+		if correctMap[ans] {
+			hits++
+		} else {
+			wrong++
+		}
+	}
+	return scoreFraction(question.Scoring, hits, wrong, len(correctMap)), false
+}
+
+// booleanQuestion: verdadero o falso
+type booleanQuestion struct{}
+
+func (booleanQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if _, ok := parseBoolAnswer(question.CorrectAnswer); !ok {
+		return keyError("correctAnswer", "correctAnswer debe ser true/false o verdadero/falso")
+	}
+	return nil
+}
+
+func (booleanQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := parseBoolAnswer(answer); !ok {
+		return errors.New("la respuesta debe ser verdadero o falso")
+	}
+	return nil
+}
+
+func (booleanQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	expected, ok := parseBoolAnswer(question.CorrectAnswer)
+	if !ok {
+		return 0, false
+	}
+	if studentAnswer, _ := parseBoolAnswer(answer); studentAnswer == expected {
+		return 1, false
+	}
+	return 0, false
+}
+
+// numericQuestion: valor numérico con tolerancia absoluta o relativa
+type numericQuestion struct{}
+
+func (numericQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if _, ok := parseNumber(question.CorrectAnswer); !ok {
+		return keyError("correctAnswer", "correctAnswer debe ser un número")
+	}
+	if question.Tolerance < 0 {
+		return keyError("tolerance", "tolerance no puede ser negativa")
+	}
+	if question.ToleranceType != "" && question.ToleranceType != "absolute" && question.ToleranceType != "relative" {
+		return keyError("toleranceType", "toleranceType debe ser absolute o relative")
+	}
+	return nil
+}
+
+func (numericQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := parseNumber(answer); !ok {
+		return errors.New("la respuesta debe ser un número")
+	}
+	return nil
+}
+
+func (numericQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	expected, ok := parseNumber(question.CorrectAnswer)
+	if !ok {
+		return 0, false
+	}
+	studentAnswer, _ := parseNumber(answer)
+	if withinTolerance(studentAnswer, expected, question.Tolerance, question.ToleranceType) {
+		return 1, false
+	}
+	return 0, false
+}
+
+// calculatedQuestion: la respuesta correcta sale de evaluar la fórmula con los valores sorteados al estudiante
+type calculatedQuestion struct{}
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (calculatedQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	formula, err := ParseFormula(question.Formula)
+	if err != nil {
+		return keyError("formula", fmt.Sprintf("formula inválida: %v", err))
+	}
+	if len(question.Variables) == 0 {
+		return keyError("variables", "se requiere al menos una variable")
+	}
+	declared := make(map[string]bool, len(question.Variables))
+	for _, variable := range question.Variables {
+		switch {
+		case !variableNamePattern.MatchString(variable.Name):
+			return keyError("variables", fmt.Sprintf("nombre de variable inválido %q", variable.Name))
+		case declared[variable.Name]:
+			return keyError("variables", fmt.Sprintf("la variable %q está repetida", variable.Name))
+		case variable.Min > variable.Max:
+			return keyError("variables", fmt.Sprintf("la variable %q tiene min mayor que max", variable.Name))
+		case variable.Decimals < 0 || variable.Decimals > 10:
+			return keyError("variables", fmt.Sprintf("decimals de la variable %q debe estar entre 0 y 10", variable.Name))
+		}
+		declared[variable.Name] = true
+	}
+	for _, name := range formula.Variables() {
+		if !declared[name] {
+			return keyError("formula", fmt.Sprintf("la variable %q no está declarada en variables", name))
+		}
+	}
+	if question.Tolerance < 0 {
+		return keyError("tolerance", "tolerance no puede ser negativa")
+	}
+	if question.ToleranceType != "" && question.ToleranceType != "absolute" && question.ToleranceType != "relative" {
+		return keyError("toleranceType", "toleranceType debe ser absolute o relative")
+	}
+	// Se comprueba con el punto medio de los rangos que la fórmula se pueda evaluar
+	midpoints := make(map[string]float64, len(question.Variables))
+	for _, variable := range question.Variables {
+		midpoints[variable.Name] = roundTo((variable.Min+variable.Max)/2, variable.Decimals)
+	}
+	if _, err := formula.Evaluate(midpoints); err != nil {
+		return keyError("formula", fmt.Sprintf("la fórmula no se puede evaluar: %v", err))
+	}
+	return nil
+}
+
+func (calculatedQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := parseNumber(answer); !ok {
+		return errors.New("la respuesta debe ser un número")
+	}
+	return nil
+}
+
+func (calculatedQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	expected, err := calculatedAnswer(question)
+	if err != nil {
+		// Sin valores válidos no se puede calificar automáticamente
+		return 0, true
+	}
+	studentAnswer, _ := parseNumber(answer)
+	if withinTolerance(studentAnswer, expected, question.Tolerance, question.ToleranceType) {
+		return 1, false
+	}
+	return 0, false
+}
+
+// calculatedAnswer evalúa la fórmula de la pregunta con los valores sorteados al estudiante
+func calculatedAnswer(question domain.TeacherQuizQuestion) (float64, error) {
+	formula, err := ParseFormula(question.Formula)
+	if err != nil {
+		return 0, err
+	}
+	return formula.Evaluate(question.Values)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
+
+// shortQuestion: respuesta corta comparada sin distinguir mayúsculas ni tildes
+type shortQuestion struct{}
+
+func (shortQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if len(shortAcceptedAnswers(question)) == 0 && question.Pattern == "" {
+		return keyError("acceptedAnswers", "se requiere acceptedAnswers, correctAnswer o pattern")
+	}
+	if question.Pattern != "" {
+		if _, err := regexp.Compile(question.Pattern); err != nil {
+			return keyError("pattern", fmt.Sprintf("pattern no es una expresión regular válida: %v", err))
+		}
+	}
+	return nil
+}
+
+func (shortQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	if _, ok := answer.(string); !ok {
+		return errors.New("la respuesta debe ser texto")
+	}
+	return nil
+}
+
+func (shortQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	raw := strings.TrimSpace(answer.(string))
+	normalized := NormalizeText(raw)
+	for _, accepted := range shortAcceptedAnswers(question) {
+		if NormalizeText(accepted) == normalized {
+			return 1, false
+		}
+	}
+	if question.Pattern != "" {
+		if re, err := regexp.Compile(question.Pattern); err == nil && (re.MatchString(raw) || re.MatchString(normalized)) {
+			return 1, false
+		}
+	}
+	return 0, false
+}
+
+func shortAcceptedAnswers(question domain.TeacherQuizQuestion) []string {
+	accepted := append([]string(nil), question.AcceptedAnswers...)
+	if correctAnswer, ok := question.CorrectAnswer.(string); ok && correctAnswer != "" {
+		accepted = append(accepted, correctAnswer)
+	}
+	return accepted
+}
+
+// orderingQuestion: ordenar las opciones; correctAnswers contiene el orden correcto
+type orderingQuestion struct{}
+
+func (orderingQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if len(question.CorrectAnswers) < 2 {
+		return keyError("correctAnswers", "correctAnswers debe tener al menos dos elementos en el orden correcto")
+	}
+	if len(question.Options) != len(question.CorrectAnswers) {
+		return keyError("options", "options debe contener los mismos elementos que correctAnswers")
+	}
+	for _, item := range question.CorrectAnswers {
+		if !containsString(question.Options, item) {
+			return keyError("correctAnswers", fmt.Sprintf("correctAnswers contiene %q, que no está entre las opciones", item))
+		}
+	}
+	return nil
+}
+
+func (orderingQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	_, err := toStringSlice(answer)
+	return err
+}
+
+func (orderingQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	ordered, _ := toStringSlice(answer)
+	hits, wrong := 0, 0
+	for i, item := range question.CorrectAnswers {
+		if i < len(ordered) && ordered[i] == item {
+			hits++
+		} else {
+			wrong++
+		}
+	}
+	return scoreFraction(question.Scoring, hits, wrong, len(question.CorrectAnswers)), false
+}
+
+// matchingQuestion: relacionar cada item con una opción; correctPairs contiene item -> opción
+type matchingQuestion struct{}
+
+func (matchingQuestion) ValidateKey(question domain.TeacherQuizQuestion) error {
+	if len(question.CorrectPairs) == 0 {
+		return keyError("correctPairs", "correctPairs debe tener al menos un par")
+	}
+	for item, option := range question.CorrectPairs {
+		if len(question.Items) > 0 && !containsString(question.Items, item) {
+			return keyError("correctPairs", fmt.Sprintf("correctPairs contiene el item %q, que no está en items", item))
+		}
+		if len(question.Options) > 0 && !containsString(question.Options, option) {
+			return keyError("correctPairs", fmt.Sprintf("correctPairs contiene la opción %q, que no está entre las opciones", option))
+		}
+	}
+	return nil
+}
+
+func (matchingQuestion) ValidateAnswer(_ domain.TeacherQuizQuestion, answer interface{}) error {
+	pairs, ok := answer.(map[string]interface{})
+	if !ok {
+		return errors.New("la respuesta debe ser un objeto item -> opción")
+	}
+	for item, option := range pairs {
+		if _, ok := option.(string); !ok {
+			return fmt.Errorf("la opción elegida para %q debe ser texto", item)
+		}
+	}
+	return nil
+}
+
+func (matchingQuestion) Grade(question domain.TeacherQuizQuestion, answer interface{}) (float64, bool) {
+	pairs := answer.(map[string]interface{})
+	hits, wrong := 0, 0
+	for item, option := range pairs {
+		if expected, ok := question.CorrectPairs[item]; ok && option.(string) == expected {
+			hits++
+		} else {
+			wrong++
+		}
+	}
+	return scoreFraction(question.Scoring, hits, wrong, len(question.CorrectPairs)), false
+}
+
+// NormalizeText pasa a minúsculas, elimina tildes y colapsa espacios para comparar respuestas
+func NormalizeText(s string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(stripAccents, s)
+	if err != nil {
+		result = s
+	}
+	return strings.Join(strings.Fields(strings.ToLower(result)), " ")
+}
+
+// parseNumber acepta números JSON o textos numéricos (con punto o coma decimal)
+func parseNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(v), ",", ".", 1), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+func withinTolerance(value, expected, tolerance float64, toleranceType string) bool {
+	allowed := tolerance
+	if toleranceType == "relative" {
+		allowed = tolerance * math.Abs(expected)
+	}
+	// margen mínimo para errores de redondeo en coma flotante
+	return math.Abs(value-expected) <= allowed+1e-9
+}
+
+func toStringSlice(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("la respuesta debe ser una lista")
+	}
+	result := make([]string, 0, len(list))
+	for i, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("el elemento %d de la respuesta debe ser texto", i)
+		}
+		result = append(result, str)
+	}
+	return result, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,395 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// regradeJobTTL es el tiempo que se conservan en memoria los trabajos terminados
+const regradeJobTTL = 24 * time.Hour
+
+// RegradeJobManager guarda en memoria los trabajos de recalificación y su progreso
+type RegradeJobManager struct {
+	jobs  map[string]*domain.RegradeJob
+	mutex sync.Mutex
+}
+
+func NewRegradeJobManager() *RegradeJobManager {
+	return &RegradeJobManager{jobs: make(map[string]*domain.RegradeJob)}
+}
+
+// create registra un trabajo nuevo; falla si ya hay uno en curso para el mismo quiz
+func (m *RegradeJobManager) create(job *domain.RegradeJob) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, existing := range m.jobs {
+		if existing.FinishedAt != nil && time.Since(*existing.FinishedAt) > regradeJobTTL {
+			delete(m.jobs, id)
+			continue
+		}
+		if existing.ContentID == job.ContentID && existing.Status == domain.RegradeStatusRunning {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("ya hay una recalificación en curso para este quiz (%s)", existing.JobID))
+		}
+	}
+	m.jobs[job.JobID] = job
+	return nil
+}
+
+// Get devuelve una copia del trabajo para poder leerla sin bloquear al worker
+func (m *RegradeJobManager) Get(jobID string) (domain.RegradeJob, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[jobID]
+	if !ok {
+		return domain.RegradeJob{}, false
+	}
+	snapshot := *job
+	snapshot.Changes = append([]domain.RegradeChange(nil), job.Changes...)
+	snapshot.Failures = append([]domain.RegradeFailure(nil), job.Failures...)
+	snapshot.Skipped = append([]int(nil), job.Skipped...)
+	return snapshot, true
+}
+
+// update aplica fn al trabajo bajo el mutex
+func (m *RegradeJobManager) update(jobID string, fn func(job *domain.RegradeJob)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if job, ok := m.jobs[jobID]; ok {
+		fn(job)
+	}
+}
+
+// transition cambia el estado del trabajo solo si está en el estado esperado
+func (m *RegradeJobManager) transition(jobID, from, to string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	job, ok := m.jobs[jobID]
+	if !ok || job.Status != from {
+		return false
+	}
+	job.Status = to
+	return true
+}
+
+// teacherQuizCourse verifica que el contenido sea un quiz de un curso del profesor y devuelve el courseID
+func (c *QuizController) teacherQuizCourse(teacherID, contentID string) (string, int, error) {
+	Url, err := c.CourseContentRepo.GetUrlByContentID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", contentID))
+	}
+	courseID, err := courseIDFromQuizURL(Url)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "URL malformada")
+	}
+	if _, err := c.CourseRepo.GetCourseByTeacherAndCourseID(teacherID, courseID); err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor")
+	}
+	contentTypeID, err := c.CourseContentRepo.GetContentTypeID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener tipo de contenido: %v", err))
+	}
+	if contentTypeID != 3 {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "el content_id no corresponde a un quiz")
+	}
+	return Url, courseID, nil
+}
+
+// StartRegrade lanza en segundo plano la recalificación de todos los intentos enviados de un quiz
+// con la clave de respuestas vigente. El resultado queda como reporte hasta que se confirme.
+func (c *QuizController) StartRegrade() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.RegradeInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		Url, courseID, err := c.teacherQuizCourse(userID, input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		Url, _, err = c.currentQuizVersion(input.ContentID, Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		latestQuiz, err := c.loadTeacherQuiz(Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		attempts, err := c.QuizRepo.FindSubmittedQuizAttemptsByContent(input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
+		}
+
+		job := &domain.RegradeJob{
+			JobID:     GenerateUID(),
+			ContentID: input.ContentID,
+			CourseID:  courseID,
+			TeacherID: userID,
+			Status:    domain.RegradeStatusRunning,
+			Total:     len(attempts),
+			CreatedAt: time.Now(),
+		}
+		if err := c.RegradeJobs.create(job); err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		go c.runRegrade(job.JobID, latestQuiz, attempts)
+
+		return ReturnWriteResponse(e, nil, map[string]interface{}{
+			"message": "Recalificación iniciada",
+			"job_id":  job.JobID,
+			"total":   job.Total,
+		})
+	}
+}
+
+// GetRegradeJob devuelve el progreso y el reporte de diferencias de una recalificación
+func (c *QuizController) GetRegradeJob() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		job, ok := c.RegradeJobs.Get(e.Param("jobId"))
+		if !ok || job.TeacherID != userID {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusNotFound, "recalificación no encontrada"), nil)
+		}
+		return ReturnReadResponse(e, nil, job)
+	}
+}
+
+// CommitRegrade guarda las calificaciones nuevas del reporte. Los intentos cuya nota cambió
+// después de calcular el reporte (p. ej. por una revisión manual) se omiten.
+func (c *QuizController) CommitRegrade() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		jobID := e.Param("jobId")
+
+		job, ok := c.RegradeJobs.Get(jobID)
+		if !ok || job.TeacherID != userID {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, "recalificación no encontrada"), nil)
+		}
+		if !c.RegradeJobs.transition(jobID, domain.RegradeStatusReady, domain.RegradeStatusCommitted) {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("la recalificación no se puede confirmar en estado %s", job.Status)), nil)
+		}
+
+		now := time.Now()
+		committed := 0
+		var skipped []int
+		var failures []domain.RegradeFailure
+		for _, change := range job.Changes {
+			attempt, err := c.QuizRepo.FindQuizAttemptByID(change.QuizAnswerID)
+			if err != nil {
+				failures = append(failures, domain.RegradeFailure{QuizAnswerID: change.QuizAnswerID, Error: err.Error()})
+				continue
+			}
+			if !sameGrade(attempt.Grade, change.OldGrade) {
+				skipped = append(skipped, change.QuizAnswerID)
+				continue
+			}
+
+			newGrade, newRawGrade, newTotal := change.NewGrade, change.NewRawGrade, change.NewTotalPoints
+			attempt.Grade = &newGrade
+			attempt.RawGrade = &newRawGrade
+			attempt.TotalPoints = &newTotal
+			if change.NeedsReview {
+				attempt.ReviewedAt = nil
+			} else if attempt.ReviewedAt == nil {
+				attempt.ReviewedAt = &now
+			}
+			if err := c.QuizRepo.UpdateQuizAttempt(attempt); err != nil {
+				failures = append(failures, domain.RegradeFailure{QuizAnswerID: change.QuizAnswerID, Error: err.Error()})
+				continue
+			}
+			if err := c.saveRegradedItems(change, now); err != nil {
+				failures = append(failures, domain.RegradeFailure{QuizAnswerID: change.QuizAnswerID, Error: err.Error()})
+				continue
+			}
+			committed++
+		}
+
+		c.RegradeJobs.update(jobID, func(job *domain.RegradeJob) {
+			job.Committed = committed
+			job.Skipped = skipped
+			job.Failures = append(job.Failures, failures...)
+			job.CommittedAt = &now
+		})
+
+		return ReturnWriteResponse(e, nil, map[string]interface{}{
+			"message":   "Recalificación confirmada",
+			"job_id":    jobID,
+			"committed": committed,
+			"skipped":   skipped,
+			"failures":  failures,
+		})
+	}
+}
+
+// saveRegradedItems actualiza las calificaciones por pregunta de un intento recalificado,
+// sin tocar las preguntas revisadas manualmente por un profesor
+func (c *QuizController) saveRegradedItems(change domain.RegradeChange, now time.Time) error {
+	existing, err := c.QuizRepo.FindQuizAnswerItems(change.QuizAnswerID)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]domain.QuizAnswerItem, len(existing))
+	for _, item := range existing {
+		previous[item.QuestionID] = item
+	}
+
+	items := buildAnswerItems(change.QuizAnswerID, domain.QuizGradeResult{Questions: change.Questions}, nil, now)
+	for i, item := range items {
+		old, ok := previous[item.QuestionID]
+		if !ok {
+			continue
+		}
+		if old.ReviewerID != nil {
+			items[i] = old
+			continue
+		}
+		items[i].QuizAnswerItemID = old.QuizAnswerItemID
+		items[i].Answer = old.Answer
+	}
+	return c.QuizRepo.SaveQuizAnswerItems(items)
+}
+
+// runRegrade recalifica los intentos uno a uno y va publicando el progreso en el trabajo
+func (c *QuizController) runRegrade(jobID string, latestQuiz domain.TeacherQuiz, attempts []domain.QuizAnswer) {
+	defer func() {
+		if r := recover(); r != nil {
+			finishedAt := time.Now()
+			c.RegradeJobs.update(jobID, func(job *domain.RegradeJob) {
+				job.Status = domain.RegradeStatusFailed
+				job.Error = fmt.Sprintf("%v", r)
+				job.FinishedAt = &finishedAt
+			})
+		}
+	}()
+
+	latestQuestions := questionsByID(latestQuiz)
+	for _, attempt := range attempts {
+		change, err := c.regradeAttempt(latestQuestions, attempt)
+		c.RegradeJobs.update(jobID, func(job *domain.RegradeJob) {
+			job.Processed++
+			switch {
+			case err != nil:
+				job.Failures = append(job.Failures, domain.RegradeFailure{QuizAnswerID: attempt.QuizAnswerID, Error: err.Error()})
+			case change == nil:
+				job.Unchanged++
+			default:
+				job.Changes = append(job.Changes, *change)
+			}
+		})
+	}
+
+	finishedAt := time.Now()
+	c.RegradeJobs.update(jobID, func(job *domain.RegradeJob) {
+		job.Status = domain.RegradeStatusReady
+		job.FinishedAt = &finishedAt
+	})
+}
+
+// regradeAttempt califica un intento con la clave vigente. Devuelve nil si la nota no cambia.
+func (c *QuizController) regradeAttempt(latestQuestions map[string]domain.TeacherQuizQuestion, attempt domain.QuizAnswer) (*domain.RegradeChange, error) {
+	// Las preguntas que vio el estudiante salen de su versión o variante; la clave, de la versión vigente
+	attemptQuiz, err := c.loadTeacherQuiz(attemptQuizURL(attempt))
+	if err != nil {
+		return nil, err
+	}
+	for i, question := range attemptQuiz.Questions {
+		if latest, ok := latestQuestions[question.ID]; ok {
+			if latest.Type == "calculated" && question.Values != nil {
+				// Se conservan los valores sorteados al estudiante y el enunciado que vio
+				latest.Values = question.Values
+				latest.Question = question.Question
+			}
+			attemptQuiz.Questions[i] = latest
+		}
+	}
+
+	answersBytes, err := c.GetTeacherQuizContent("zeppelin", r2KeyFromURL(attempt.QuizAnswerURL))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener respuestas del estudiante desde R2: %w", err)
+	}
+	var answers map[string]interface{}
+	if err := json.Unmarshal(answersBytes, &answers); err != nil {
+		return nil, fmt.Errorf("error al parsear respuestas del estudiante: %w", err)
+	}
+	items, err := c.QuizRepo.FindQuizAnswerItems(attempt.QuizAnswerID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las calificaciones por pregunta: %w", err)
+	}
+
+	result := applyManualReviews(c.GradeQuiz(attemptQuiz, answers), items)
+	newGrade := applyLatePenalty(result.Score, attempt.LatePenalty)
+	if sameGrade(attempt.Grade, &newGrade) && attempt.TotalPoints != nil && *attempt.TotalPoints == result.TotalPoints {
+		return nil, nil
+	}
+	return &domain.RegradeChange{
+		QuizAnswerID:   attempt.QuizAnswerID,
+		UserID:         attempt.UserID,
+		OldGrade:       attempt.Grade,
+		NewGrade:       newGrade,
+		NewRawGrade:    result.Score,
+		OldTotalPoints: attempt.TotalPoints,
+		NewTotalPoints: result.TotalPoints,
+		NeedsReview:    result.NeedsReview,
+		Questions:      result.Questions,
+	}, nil
+}
+
+// applyManualReviews reemplaza la calificación de las preguntas revisadas por un profesor
+// con la registrada en quiz_answer_item, para que la recalificación conserve esos puntos.
+func applyManualReviews(result domain.QuizGradeResult, items []domain.QuizAnswerItem) domain.QuizGradeResult {
+	reviewed := make(map[string]domain.QuizAnswerItem, len(items))
+	for _, item := range items {
+		if item.ReviewerID != nil && item.Score != nil {
+			reviewed[item.QuestionID] = item
+		}
+	}
+
+	result.Score = 0
+	result.NeedsReview = false
+	for i, grade := range result.Questions {
+		if item, ok := reviewed[grade.QuestionID]; ok {
+			grade.Earned = *item.Score
+			grade.IsCorrect = item.IsCorrect != nil && *item.IsCorrect
+			grade.NeedsReview = false
+			result.Questions[i] = grade
+		}
+		result.Score += grade.Earned
+		result.NeedsReview = result.NeedsReview || grade.NeedsReview
+	}
+	return result
+}
+
+// questionsByID indexa las preguntas de un quiz, incluidas las de sus bancos
+func questionsByID(quiz domain.TeacherQuiz) map[string]domain.TeacherQuizQuestion {
+	questions := make(map[string]domain.TeacherQuizQuestion)
+	for _, question := range quiz.Questions {
+		questions[question.ID] = question
+	}
+	for _, pool := range quiz.Pools {
+		for _, question := range pool.Questions {
+			questions[question.ID] = question
+		}
+	}
+	return questions
+}
+
+func sameGrade(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return math.Abs(*a-*b) < 1e-9
+}
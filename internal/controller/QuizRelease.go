@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// AnswersReleased indica si la política del quiz permite mostrar al estudiante las respuestas
+// correctas y las explicaciones. released indica si el profesor ya las publicó.
+func AnswersReleased(settings domain.QuizSettings, released, submitted bool, now time.Time) bool {
+	switch settings.ReleaseAnswers {
+	case domain.ReleaseAnswersAfterSubmission:
+		return submitted
+	case domain.ReleaseAnswersAfterClose:
+		return settings.ClosesAt != nil && now.After(*settings.ClosesAt)
+	case domain.ReleaseAnswersAfterRelease:
+		return released
+	default:
+		return false
+	}
+}
+
+// quizVersionCacheSize limita cuántas versiones de quizzes se guardan en memoria
+const quizVersionCacheSize = 512
+
+// QuizVersionCache guarda en memoria los quizzes del profesor por URL de versión.
+// Las versiones son inmutables, así que no hace falta invalidarlas.
+type QuizVersionCache struct {
+	quizzes map[string]domain.TeacherQuiz
+	mutex   sync.RWMutex
+}
+
+func NewQuizVersionCache() *QuizVersionCache {
+	return &QuizVersionCache{quizzes: make(map[string]domain.TeacherQuiz)}
+}
+
+func (m *QuizVersionCache) get(url string) (domain.TeacherQuiz, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	quiz, ok := m.quizzes[url]
+	return quiz, ok
+}
+
+func (m *QuizVersionCache) put(url string, quiz domain.TeacherQuiz) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.quizzes) >= quizVersionCacheSize {
+		m.quizzes = make(map[string]domain.TeacherQuiz)
+	}
+	m.quizzes[url] = quiz
+}
+
+// loadCurrentQuiz obtiene la versión vigente del quiz, usando la caché cuando el quiz está versionado
+func (c *QuizController) loadCurrentQuiz(contentID, contentURL string) (domain.TeacherQuiz, error) {
+	url, versionID, err := c.currentQuizVersion(contentID, contentURL)
+	if err != nil {
+		return domain.TeacherQuiz{}, err
+	}
+	// Los quizzes sin versiones se sobrescriben en R2 al editarlos: no se pueden guardar en caché
+	if versionID == nil || c.QuizCache == nil {
+		return c.loadTeacherQuiz(url)
+	}
+	if quiz, ok := c.QuizCache.get(url); ok {
+		return quiz, nil
+	}
+	quiz, err := c.loadTeacherQuiz(url)
+	if err != nil {
+		return domain.TeacherQuiz{}, err
+	}
+	c.QuizCache.put(url, quiz)
+	return quiz, nil
+}
+
+// answersReleased evalúa la política de publicación de respuestas de un quiz.
+// Solo consulta la publicación del profesor cuando la política la requiere.
+func (c *QuizController) answersReleased(contentID string, settings domain.QuizSettings, submitted bool, now time.Time) (bool, error) {
+	released := false
+	if settings.ReleaseAnswers == domain.ReleaseAnswersAfterRelease {
+		_, err := c.AnswerReleaseRepo.GetAnswerRelease(contentID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la publicación de respuestas: %v", err))
+		}
+		released = err == nil
+	}
+	return AnswersReleased(settings, released, submitted, now), nil
+}
+
+// ReleaseQuizAnswers publica (o retira) las respuestas de un quiz con la política after_release
+func (c *QuizController) ReleaseQuizAnswers() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.QuizAnswerReleaseInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+		Url, _, err := c.teacherQuizCourse(userID, input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		Url, _, err = c.currentQuizVersion(input.ContentID, Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		quiz, err := c.loadTeacherQuiz(Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		if quiz.Settings.ReleaseAnswers != domain.ReleaseAnswersAfterRelease {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("el quiz no publica sus respuestas manualmente (releaseAnswers debe ser %q)", domain.ReleaseAnswersAfterRelease)), nil)
+		}
+
+		if !input.Released {
+			if err := c.AnswerReleaseRepo.DeleteAnswerRelease(input.ContentID); err != nil {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al retirar la publicación de respuestas: %v", err)), nil)
+			}
+			return ReturnWriteResponse(e, nil, map[string]interface{}{
+				"content_id": input.ContentID,
+				"released":   false,
+			})
+		}
+
+		release := domain.QuizAnswerRelease{
+			ContentID:  input.ContentID,
+			ReleasedBy: userID,
+			ReleasedAt: time.Now(),
+		}
+		if err := c.AnswerReleaseRepo.SaveAnswerRelease(release); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al publicar las respuestas: %v", err)), nil)
+		}
+		return ReturnWriteResponse(e, nil, release)
+	}
+}
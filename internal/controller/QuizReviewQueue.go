@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultReviewQueueLimit = 20
+	maxReviewQueueLimit     = 100
+)
+
+// GetReviewQueue lista las preguntas de texto pendientes de revisión en todos los cursos del profesor,
+// de la más antigua a la más reciente. Filtros: course_id, content_id, user_id. Con anonymous=true
+// se oculta la identidad del estudiante. next_cursor devuelve la página (o con limit=1, el ítem) siguiente.
+func (c *QuizController) GetReviewQueue() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		filter := domain.ReviewQueueFilter{
+			TeacherID: userID,
+			ContentID: e.QueryParam("content_id"),
+			UserID:    e.QueryParam("user_id"),
+			Limit:     defaultReviewQueueLimit,
+		}
+		if value := e.QueryParam("course_id"); value != "" {
+			courseID, err := strconv.Atoi(value)
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido"), nil)
+			}
+			filter.CourseID = courseID
+		}
+		if value := e.QueryParam("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > maxReviewQueueLimit {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit debe estar entre 1 y %d", maxReviewQueueLimit)), nil)
+			}
+			filter.Limit = limit
+		}
+		anonymous := false
+		if value := e.QueryParam("anonymous"); value != "" {
+			var err error
+			if anonymous, err = strconv.ParseBool(value); err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "anonymous debe ser true o false"), nil)
+			}
+		}
+		if cursor := e.QueryParam("cursor"); cursor != "" {
+			endTime, itemID, err := decodeReviewCursor(cursor)
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "cursor inválido"), nil)
+			}
+			filter.AfterEndTime = &endTime
+			filter.AfterItemID = itemID
+		}
+
+		// Se pide un ítem de más para saber si hay una página siguiente
+		limit := filter.Limit
+		filter.Limit++
+		rows, err := c.QuizRepo.FindPendingReviewItems(filter)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la cola de revisión: %v", err)), nil)
+		}
+		page := domain.ReviewQueuePage{Items: []domain.ReviewQueueItem{}, Anonymous: anonymous}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			page.NextCursor = encodeReviewCursor(last.EndTime, last.QuizAnswerItemID)
+		}
+
+		// El enunciado y la rúbrica salen del quiz con el que se calificó cada intento
+		quizzes := make(map[string]map[string]domain.TeacherQuizQuestion)
+		for _, row := range rows {
+			quizURL := row.QuizURL
+			if row.VariantURL != "" {
+				quizURL = row.VariantURL
+			}
+			questions, ok := quizzes[quizURL]
+			if !ok {
+				quiz, err := c.loadTeacherQuiz(quizURL)
+				if err != nil {
+					return ReturnReadResponse(e, err, nil)
+				}
+				questions = questionsByID(quiz)
+				quizzes[quizURL] = questions
+			}
+			question := questions[row.QuestionID]
+
+			item := domain.ReviewQueueItem{
+				QuizAnswerItemID: row.QuizAnswerItemID,
+				QuizAnswerID:     row.QuizAnswerID,
+				QuestionID:       row.QuestionID,
+				Question:         question.Question,
+				Points:           row.Points,
+				Rubric:           question.Rubric,
+				Answer:           decodeTextAnswer(row.Answer),
+				SubmittedAt:      row.EndTime,
+				CourseID:         row.CourseID,
+				CourseTitle:      row.CourseTitle,
+				ContentID:        row.ContentID,
+				QuizTitle:        row.QuizTitle,
+			}
+			if anonymous {
+				item.StudentLabel = anonymousStudentLabel(row.ContentID, row.UserID)
+			} else {
+				item.StudentLabel = strings.TrimSpace(row.StudentName + " " + row.StudentLastname)
+				item.UserID = row.UserID
+				item.StudentName = row.StudentName
+				item.StudentLastname = row.StudentLastname
+				item.StudentEmail = row.StudentEmail
+			}
+			page.Items = append(page.Items, item)
+		}
+		return ReturnReadResponse(e, nil, page)
+	}
+}
+
+// decodeTextAnswer devuelve el texto de una respuesta guardada como JSON en quiz_answer_item
+func decodeTextAnswer(answer string) string {
+	var text string
+	if err := json.Unmarshal([]byte(answer), &text); err != nil {
+		return answer
+	}
+	return text
+}
+
+// anonymousStudentLabel es un alias estable del estudiante dentro de un quiz. Cambia entre quizzes
+// para que no se pueda seguir a un estudiante de un quiz a otro.
+func anonymousStudentLabel(contentID, userID string) string {
+	sum := sha256.Sum256([]byte(contentID + ":" + userID))
+	return "Estudiante " + hex.EncodeToString(sum[:4])
+}
+
+// encodeReviewCursor codifica la posición del último ítem devuelto (hora de envío e ID del ítem)
+func encodeReviewCursor(endTime time.Time, itemID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", endTime.UnixNano(), itemID)))
+}
+
+func decodeReviewCursor(cursor string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("cursor malformado")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	itemID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.Unix(0, nanos).UTC(), itemID, nil
+}
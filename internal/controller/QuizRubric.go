@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"zeppelin/internal/domain"
+)
+
+// rubricMaxPoints suma el nivel más alto de cada criterio
+func rubricMaxPoints(rubric []domain.RubricCriterion) float64 {
+	total := 0.0
+	for _, criterion := range rubric {
+		total += criterionMaxPoints(criterion)
+	}
+	return total
+}
+
+func criterionMaxPoints(criterion domain.RubricCriterion) float64 {
+	maxPoints := 0.0
+	for _, level := range criterion.Levels {
+		maxPoints = math.Max(maxPoints, level.Points)
+	}
+	return maxPoints
+}
+
+// GradeRubric calcula los puntos de una respuesta a partir del nivel elegido en cada criterio.
+// Todos los criterios deben tener un nivel y no se aceptan criterios desconocidos.
+func GradeRubric(rubric []domain.RubricCriterion, selections map[string]string) ([]domain.RubricScore, float64, error) {
+	known := make(map[string]bool, len(rubric))
+	scores := make([]domain.RubricScore, 0, len(rubric))
+	total := 0.0
+	for _, criterion := range rubric {
+		known[criterion.ID] = true
+		levelID, ok := selections[criterion.ID]
+		if !ok {
+			return nil, 0, fmt.Errorf("falta el nivel del criterio %s", criterion.ID)
+		}
+		var level *domain.RubricLevel
+		for i := range criterion.Levels {
+			if criterion.Levels[i].ID == levelID {
+				level = &criterion.Levels[i]
+				break
+			}
+		}
+		if level == nil {
+			return nil, 0, fmt.Errorf("el nivel %s no existe en el criterio %s", levelID, criterion.ID)
+		}
+		scores = append(scores, domain.RubricScore{
+			CriterionID: criterion.ID,
+			Criterion:   criterion.Title,
+			LevelID:     level.ID,
+			Level:       level.Title,
+			Points:      level.Points,
+			MaxPoints:   criterionMaxPoints(criterion),
+		})
+		total += level.Points
+	}
+	for criterionID := range selections {
+		if !known[criterionID] {
+			return nil, 0, fmt.Errorf("el criterio %s no existe en la rúbrica", criterionID)
+		}
+	}
+	return scores, total, nil
+}
+
+// validateRubric revisa la rúbrica de una pregunta de texto y agrega los errores por campo
+func validateRubric(path string, question domain.TeacherQuizQuestion, errorMap map[string]string) {
+	if len(question.Rubric) == 0 {
+		return
+	}
+	if question.Type != "text" {
+		errorMap[path+".rubric"] = "Rubrics are only supported on text questions"
+		return
+	}
+
+	seenCriteria := make(map[string]bool)
+	for i, criterion := range question.Rubric {
+		criterionPath := fmt.Sprintf("%s.rubric[%d]", path, i)
+		if criterion.ID == "" {
+			errorMap[criterionPath+".id"] = "This field is required"
+		} else if seenCriteria[criterion.ID] {
+			errorMap[criterionPath+".id"] = fmt.Sprintf("Duplicated criterion id %q", criterion.ID)
+		}
+		seenCriteria[criterion.ID] = true
+		if criterion.Title == "" {
+			errorMap[criterionPath+".title"] = "This field is required"
+		}
+		if len(criterion.Levels) == 0 {
+			errorMap[criterionPath+".levels"] = "This field is required"
+		}
+
+		seenLevels := make(map[string]bool)
+		for j, level := range criterion.Levels {
+			levelPath := fmt.Sprintf("%s.levels[%d]", criterionPath, j)
+			if level.ID == "" {
+				errorMap[levelPath+".id"] = "This field is required"
+			} else if seenLevels[level.ID] {
+				errorMap[levelPath+".id"] = fmt.Sprintf("Duplicated level id %q", level.ID)
+			}
+			seenLevels[level.ID] = true
+			if level.Points < 0 {
+				errorMap[levelPath+".points"] = "Must be zero or positive"
+			}
+		}
+	}
+
+	if maxPoints := rubricMaxPoints(question.Rubric); maxPoints != float64(question.Points) {
+		errorMap[path+".rubric"] = fmt.Sprintf("The highest levels add up to %g points, but the question is worth %d", maxPoints, question.Points)
+	}
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSimilarityThreshold = 0.5
+	similarityShingleSize      = 3 // palabras por shingle
+	similarityMinWords         = 5 // las respuestas más cortas se parecen por azar y no se comparan
+	similarityBands            = 64
+	similarityRowsPerBand      = 2 // 64 bandas de 2 filas: casi no se pierden pares con similitud >= 0.3
+	similarityHashes           = similarityBands * similarityRowsPerBand
+)
+
+// GetQuizSimilarity compara las respuestas de todos los estudiantes a cada pregunta de texto de un quiz
+// y devuelve los pares con similitud mayor o igual al umbral (?threshold=, 0.5 por defecto).
+// Se usa el último intento enviado de cada estudiante.
+func (c *QuizController) GetQuizSimilarity() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		contentID := e.Param("contentId")
+		courseID, err := strconv.Atoi(e.Param("courseId"))
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido"), nil)
+		}
+		threshold := defaultSimilarityThreshold
+		if value := e.QueryParam("threshold"); value != "" {
+			threshold, err = strconv.ParseFloat(value, 64)
+			if err != nil || threshold <= 0 || threshold > 1 {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "threshold debe ser un número mayor que 0 y menor o igual a 1"), nil)
+			}
+		}
+
+		Url, quizCourseID, err := c.teacherQuizCourse(userID, contentID)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		if quizCourseID != courseID {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("el quiz %s no pertenece al curso %d", contentID, courseID)), nil)
+		}
+		Url, _, err = c.currentQuizVersion(contentID, Url)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		quiz, err := c.loadTeacherQuiz(Url)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+
+		courseAttempts, err := c.QuizRepo.GetQuizAttemptsByCourse(courseID)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener intentos de quiz: %v", err)), nil)
+		}
+		attempts := latestSubmittedAttempts(courseAttempts, contentID)
+
+		attemptIDs := make([]int, 0, len(attempts))
+		for _, attempt := range attempts {
+			attemptIDs = append(attemptIDs, attempt.QuizAnswerID)
+		}
+		items, err := c.QuizRepo.FindQuizAnswerItemsByAttempts(attemptIDs)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las calificaciones por pregunta: %v", err)), nil)
+		}
+		answersByAttempt := make(map[int]map[string]string)
+		for _, item := range items {
+			var text string
+			if err := json.Unmarshal([]byte(item.Answer), &text); err != nil {
+				continue
+			}
+			if answersByAttempt[item.QuizAnswerID] == nil {
+				answersByAttempt[item.QuizAnswerID] = make(map[string]string)
+			}
+			answersByAttempt[item.QuizAnswerID][item.QuestionID] = text
+		}
+
+		// Los intentos anteriores a quiz_answer_item se leen de las respuestas guardadas en R2
+		for _, attempt := range attempts {
+			if _, ok := answersByAttempt[attempt.QuizAnswerID]; ok {
+				continue
+			}
+			answersBytes, err := c.GetTeacherQuizContent("zeppelin", r2KeyFromURL(attempt.QuizAnswerURL))
+			if err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener respuestas del estudiante desde R2: %v", err)), nil)
+			}
+			var answers map[string]interface{}
+			if err := json.Unmarshal(answersBytes, &answers); err != nil {
+				return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear respuestas del estudiante: %v", err)), nil)
+			}
+			texts := make(map[string]string)
+			for questionID, answer := range answers {
+				if text, ok := answer.(string); ok {
+					texts[questionID] = text
+				}
+			}
+			answersByAttempt[attempt.QuizAnswerID] = texts
+		}
+
+		report := domain.QuizSimilarityReport{
+			ContentID: contentID,
+			CourseID:  courseID,
+			QuizTitle: quiz.Title,
+			Threshold: threshold,
+			Attempts:  len(attempts),
+			Questions: []domain.QuestionSimilarity{},
+		}
+		for _, question := range quizQuestions(quiz) {
+			if question.Type != "text" {
+				continue
+			}
+			answers := make([]domain.SimilarityAnswer, 0, len(attempts))
+			for _, attempt := range attempts {
+				text, ok := answersByAttempt[attempt.QuizAnswerID][question.ID]
+				if !ok {
+					continue
+				}
+				answers = append(answers, domain.SimilarityAnswer{
+					QuizAnswerID:    attempt.QuizAnswerID,
+					UserID:          attempt.UserID,
+					StudentName:     attempt.StudentName,
+					StudentLastname: attempt.StudentLastname,
+					Text:            text,
+				})
+			}
+			similarity := FindSimilarAnswers(answers, threshold)
+			similarity.QuestionID = question.ID
+			similarity.Question = question.Question
+			report.Questions = append(report.Questions, similarity)
+		}
+		return ReturnReadResponse(e, nil, report)
+	}
+}
+
+// latestSubmittedAttempts devuelve el último intento enviado de cada estudiante para un quiz
+func latestSubmittedAttempts(attempts []domain.QuizAttemptView, contentID string) []domain.QuizAttemptView {
+	latest := make(map[string]domain.QuizAttemptView)
+	for _, attempt := range attempts {
+		if attempt.ContentID != contentID || attempt.EndTime.IsZero() {
+			continue
+		}
+		if current, ok := latest[attempt.UserID]; !ok || attempt.StartTime.After(current.StartTime) {
+			latest[attempt.UserID] = attempt
+		}
+	}
+	result := make([]domain.QuizAttemptView, 0, len(latest))
+	for _, attempt := range latest {
+		result = append(result, attempt)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].QuizAnswerID < result[j].QuizAnswerID })
+	return result
+}
+
+// quizQuestions devuelve las preguntas del quiz seguidas de las de sus bancos
+func quizQuestions(quiz domain.TeacherQuiz) []domain.TeacherQuizQuestion {
+	questions := make([]domain.TeacherQuizQuestion, 0, len(quiz.Questions))
+	questions = append(questions, quiz.Questions...)
+	for _, pool := range quiz.Pools {
+		questions = append(questions, pool.Questions...)
+	}
+	return questions
+}
+
+// FindSimilarAnswers compara las respuestas de una pregunta y devuelve los pares con similitud de Jaccard
+// mayor o igual al umbral, de mayor a menor. Los candidatos se obtienen con MinHash y bandas (LSH),
+// y su similitud se calcula de forma exacta sobre los shingles de palabras normalizadas.
+func FindSimilarAnswers(answers []domain.SimilarityAnswer, threshold float64) domain.QuestionSimilarity {
+	result := domain.QuestionSimilarity{Pairs: []domain.SimilarityPair{}}
+
+	type shingled struct {
+		answer    domain.SimilarityAnswer
+		shingles  map[uint64]struct{}
+		signature []uint64
+	}
+	compared := make([]shingled, 0, len(answers))
+	for _, answer := range answers {
+		words := normalizeAnswerWords(answer.Text)
+		if len(words) < similarityMinWords {
+			result.Skipped++
+			continue
+		}
+		shingles := answerShingles(words)
+		compared = append(compared, shingled{answer: answer, shingles: shingles, signature: minHashSignature(shingles)})
+	}
+	result.Compared = len(compared)
+
+	// Dos respuestas son candidatas si coinciden en todas las filas de al menos una banda
+	candidates := make(map[[2]int]bool)
+	for band := 0; band < similarityBands; band++ {
+		buckets := make(map[string][]int)
+		for i, entry := range compared {
+			rows := entry.signature[band*similarityRowsPerBand : (band+1)*similarityRowsPerBand]
+			key := fmt.Sprint(rows)
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, bucket := range buckets {
+			for a := 0; a < len(bucket); a++ {
+				for b := a + 1; b < len(bucket); b++ {
+					candidates[[2]int{bucket[a], bucket[b]}] = true
+				}
+			}
+		}
+	}
+
+	for pair := range candidates {
+		first, second := compared[pair[0]], compared[pair[1]]
+		shared := 0
+		for shingle := range first.shingles {
+			if _, ok := second.shingles[shingle]; ok {
+				shared++
+			}
+		}
+		score := float64(shared) / float64(len(first.shingles)+len(second.shingles)-shared)
+		if score < threshold {
+			continue
+		}
+		result.Pairs = append(result.Pairs, domain.SimilarityPair{
+			First:          first.answer,
+			Second:         second.answer,
+			Score:          score,
+			SharedShingles: shared,
+		})
+	}
+	sort.Slice(result.Pairs, func(i, j int) bool {
+		if result.Pairs[i].Score != result.Pairs[j].Score {
+			return result.Pairs[i].Score > result.Pairs[j].Score
+		}
+		if result.Pairs[i].First.QuizAnswerID != result.Pairs[j].First.QuizAnswerID {
+			return result.Pairs[i].First.QuizAnswerID < result.Pairs[j].First.QuizAnswerID
+		}
+		return result.Pairs[i].Second.QuizAnswerID < result.Pairs[j].Second.QuizAnswerID
+	})
+	return result
+}
+
+// normalizeAnswerWords pasa el texto a minúsculas sin tildes ni puntuación y lo separa en palabras
+func normalizeAnswerWords(text string) []string {
+	return strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// answerShingles devuelve el hash de cada secuencia de similarityShingleSize palabras consecutivas
+func answerShingles(words []string) map[uint64]struct{} {
+	shingles := make(map[uint64]struct{})
+	size := similarityShingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		shingles[h.Sum64()] = struct{}{}
+	}
+	return shingles
+}
+
+// minHashSignature calcula la firma MinHash de un conjunto de shingles. Cada función de hash
+// se obtiene mezclando el hash del shingle con una semilla fija, así las firmas son comparables.
+func minHashSignature(shingles map[uint64]struct{}) []uint64 {
+	signature := make([]uint64, similarityHashes)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+	for shingle := range shingles {
+		for i := range signature {
+			if h := mixHash(shingle ^ mixHash(uint64(i)+1)); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// mixHash es el finalizador de splitmix64
+func mixHash(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// idempotencyKeyHeader es la cabecera con la que el cliente identifica un envío para poder reintentarlo
+const idempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+// AutosaveQuiz guarda las respuestas en curso de un intento iniciado con /quiz/start.
+// Se recuperan al reanudar el intento. El tiempo límite se aplica al enviar, no aquí.
+func (c *QuizController) AutosaveQuiz() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.AutosaveQuizInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		attempt, err := c.QuizRepo.FindQuizAttemptByID(input.QuizAnswerID)
+		if err != nil || attempt.UserID != userID {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("intento de quiz con ID %d no encontrado", input.QuizAnswerID)), nil)
+		}
+		if attempt.EndTime != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusConflict, "este intento ya fue enviado"), nil)
+		}
+		if err := checkContentUnlocked(c.CourseContentRepo, attempt.ContentID, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := c.QuizRepo.SaveQuizDraft(attempt.QuizAnswerID, input.Answers, now); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusConflict, "este intento ya fue enviado"), nil)
+			}
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar las respuestas en curso: %v", err)), nil)
+		}
+		return ReturnWriteResponse(e, nil, map[string]interface{}{
+			"message":        "Respuestas guardadas",
+			"quiz_answer_id": attempt.QuizAnswerID,
+			"autosaved_at":   now,
+		})
+	}
+}
+
+// reserveSubmission registra la Idempotency-Key de un envío. Si la clave ya se usó devuelve la
+// respuesta original (replay != nil) o un error si el envío original aún se está procesando.
+func (c *QuizController) reserveSubmission(userID, idempotencyKey, contentID string) (json.RawMessage, error) {
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("la cabecera %s no puede superar %d caracteres", idempotencyKeyHeader, maxIdempotencyKeyLength))
+	}
+	err := c.SubmissionKeyRepo.ReserveSubmissionKey(domain.QuizSubmissionKey{
+		UserID:         userID,
+		IdempotencyKey: idempotencyKey,
+		ContentID:      contentID,
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al registrar el envío: %v", err))
+	}
+
+	previous, err := c.SubmissionKeyRepo.GetSubmissionKey(userID, idempotencyKey)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener el envío original: %v", err))
+	}
+	if previous.ContentID != contentID {
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("la %s ya se usó para otro quiz", idempotencyKeyHeader))
+	}
+	if previous.Response == "" {
+		return nil, echo.NewHTTPError(http.StatusConflict, "el envío original todavía se está procesando")
+	}
+	return json.RawMessage(previous.Response), nil
+}
+
+// completeSubmission guarda la respuesta de un envío con Idempotency-Key para devolverla en los reintentos
+func (c *QuizController) completeSubmission(userID, idempotencyKey string, quizAnswerID int, response interface{}) {
+	responseBytes, err := json.Marshal(response)
+	if err == nil {
+		err = c.SubmissionKeyRepo.CompleteSubmissionKey(domain.QuizSubmissionKey{
+			UserID:         userID,
+			IdempotencyKey: idempotencyKey,
+			QuizAnswerID:   &quizAnswerID,
+			Response:       string(responseBytes),
+		})
+	}
+	if err != nil {
+		// El intento ya se guardó: se libera la clave en lugar de dejarla "en proceso" para siempre
+		log.Printf("error al guardar la respuesta del envío %s: %v", idempotencyKey, err)
+		c.releaseSubmission(userID, idempotencyKey)
+	}
+}
+
+// releaseSubmission libera la clave de un envío que falló para que el cliente pueda reintentarlo
+func (c *QuizController) releaseSubmission(userID, idempotencyKey string) {
+	if err := c.SubmissionKeyRepo.DeleteSubmissionKey(userID, idempotencyKey); err != nil {
+		log.Printf("error al liberar la clave de envío %s: %v", idempotencyKey, err)
+	}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"zeppelin/internal/domain"
+)
+
+var jsonIndexPattern = regexp.MustCompile(`\.(\d+)`)
+
+// ValidateTeacherQuiz valida la estructura y la semántica de un quiz del profesor.
+// Devuelve los errores por campo (p. ej. "questions[2].correctAnswer") o nil si el quiz es válido.
+func ValidateTeacherQuiz(jsonBytes []byte) map[string]string {
+	errorMap := make(map[string]string)
+
+	var quiz domain.TeacherQuiz
+	if err := json.Unmarshal(jsonBytes, &quiz); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			// encoding/json reporta "questions.0.points"; se normaliza a "questions[0].points"
+			field := jsonIndexPattern.ReplaceAllString(typeErr.Field, "[$1]")
+			errorMap[field] = fmt.Sprintf("Invalid type: expected %s", typeErr.Type.String())
+		} else {
+			errorMap["json_data"] = "Invalid quiz JSON"
+		}
+		return errorMap
+	}
+
+	if len(quiz.Questions) == 0 && len(quiz.Pools) == 0 {
+		errorMap["questions"] = "This field is required"
+	}
+
+	validateQuizSettings(quiz.Settings, errorMap)
+
+	seenIDs := make(map[string]string)
+	for i, question := range quiz.Questions {
+		validateQuizQuestion(fmt.Sprintf("questions[%d]", i), question, seenIDs, errorMap)
+	}
+
+	seenPools := make(map[string]bool)
+	for i, pool := range quiz.Pools {
+		path := fmt.Sprintf("pools[%d]", i)
+		if pool.ID == "" {
+			errorMap[path+".id"] = "This field is required"
+		} else if seenPools[pool.ID] {
+			errorMap[path+".id"] = fmt.Sprintf("Duplicated pool id %q", pool.ID)
+		}
+		seenPools[pool.ID] = true
+
+		if len(pool.Questions) == 0 {
+			errorMap[path+".questions"] = "This field is required"
+		}
+		if pool.Draw < 1 || pool.Draw > len(pool.Questions) {
+			errorMap[path+".draw"] = fmt.Sprintf("Must be between 1 and %d", len(pool.Questions))
+		}
+		for j, question := range pool.Questions {
+			validateQuizQuestion(fmt.Sprintf("%s.questions[%d]", path, j), question, seenIDs, errorMap)
+		}
+	}
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+func validateQuizSettings(settings domain.QuizSettings, errorMap map[string]string) {
+	if settings.MaxAttempts < 0 {
+		errorMap["settings.maxAttempts"] = "Must be zero (unlimited) or positive"
+	}
+	if settings.TimeLimitMinutes < 0 {
+		errorMap["settings.timeLimitMinutes"] = "Must be zero (unlimited) or positive"
+	}
+	if settings.OpensAt != nil && settings.ClosesAt != nil && !settings.ClosesAt.After(*settings.OpensAt) {
+		errorMap["settings.closesAt"] = "Must be after opensAt"
+	}
+	switch settings.ReleaseAnswers {
+	case "", domain.ReleaseAnswersNever, domain.ReleaseAnswersAfterSubmission, domain.ReleaseAnswersAfterRelease:
+	case domain.ReleaseAnswersAfterClose:
+		if settings.ClosesAt == nil {
+			errorMap["settings.closesAt"] = "Required when releaseAnswers is after_close"
+		}
+	default:
+		errorMap["settings.releaseAnswers"] = "Invalid value"
+	}
+}
+
+func validateQuizQuestion(path string, question domain.TeacherQuizQuestion, seenIDs map[string]string, errorMap map[string]string) {
+	if question.ID == "" {
+		errorMap[path+".id"] = "This field is required"
+	} else if previous, ok := seenIDs[question.ID]; ok {
+		errorMap[path+".id"] = fmt.Sprintf("Duplicated question id %q (also used in %s)", question.ID, previous)
+	} else {
+		seenIDs[question.ID] = path
+	}
+
+	if question.Question == "" {
+		errorMap[path+".question"] = "This field is required"
+	}
+	if question.Points < 0 {
+		errorMap[path+".points"] = "Must be zero or positive"
+	}
+	switch question.Scoring {
+	case "", domain.ScoringAllOrNothing, domain.ScoringProportional, domain.ScoringRightMinusWrong:
+	default:
+		errorMap[path+".scoring"] = "Invalid value"
+	}
+
+	validateRubric(path, question, errorMap)
+
+	for option := range question.OptionExplanations {
+		if !containsString(question.Options, option) {
+			errorMap[path+".optionExplanations"] = fmt.Sprintf("Unknown option %q", option)
+		}
+	}
+
+	if question.Type == "" {
+		errorMap[path+".type"] = "This field is required"
+		return
+	}
+	questionType, ok := LookupQuestionType(question.Type)
+	if !ok {
+		errorMap[path+".type"] = fmt.Sprintf("Unknown question type %q", question.Type)
+		return
+	}
+	if err := questionType.ValidateKey(question); err != nil {
+		var keyErr *QuestionKeyError
+		if errors.As(err, &keyErr) {
+			errorMap[path+"."+keyErr.Field] = keyErr.Message
+		} else {
+			errorMap[path] = err.Error()
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"zeppelin/internal/domain"
+)
+
+// maxVariableDraws es cuántas veces se vuelven a sortear los valores de una pregunta calculated
+// cuando la fórmula no se puede evaluar con ellos (por ejemplo, una división por cero)
+const maxVariableDraws = 20
+
+// quizHasVariants indica si cada estudiante debe recibir su propia versión del quiz
+func quizHasVariants(quiz domain.TeacherQuiz) bool {
+	if len(quiz.Pools) > 0 || quiz.Settings.ShuffleQuestions || quiz.Settings.ShuffleOptions {
+		return true
+	}
+	for _, question := range quiz.Questions {
+		if question.Type == "calculated" {
+			return true
+		}
+	}
+	return false
+}
+
+// newVariantSeed genera una semilla aleatoria para la variante de un intento
+func newVariantSeed() int64 {
+	var b [8]byte
+	if _, err := cryptorand.Read(b[:]); err != nil {
+		return rand.Int63()
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1)
+}
+
+// studentVariantKey es la clave de la copia sin respuestas de una variante
+func studentVariantKey(variantKey string) string {
+	return strings.TrimSuffix(variantKey, ".json") + "-student.json"
+}
+
+// uploadQuizVariant sube la variante con la clave, contra la que se califica el intento, y una copia
+// sin la clave que es la que ve el estudiante mientras las respuestas no se publican
+func (c *QuizController) uploadQuizVariant(variantKey string, variant domain.TeacherQuiz) error {
+	variantBytes, err := json.Marshal(variant)
+	if err != nil {
+		return fmt.Errorf("error al serializar la variante del quiz: %w", err)
+	}
+	if err := c.UploadStudentAnswers(variantKey, variantBytes); err != nil {
+		return fmt.Errorf("error al subir la variante del quiz a R2: %w", err)
+	}
+	studentBytes, err := json.Marshal(studentQuizCopy(variant))
+	if err != nil {
+		return fmt.Errorf("error al serializar la variante del quiz: %w", err)
+	}
+	if err := c.UploadStudentAnswers(studentVariantKey(variantKey), studentBytes); err != nil {
+		return fmt.Errorf("error al subir la variante del quiz a R2: %w", err)
+	}
+	return nil
+}
+
+// BuildQuizVariant genera la versión del quiz para un estudiante: sortea las preguntas de cada banco
+// y baraja preguntas y opciones según la configuración. Con la misma semilla el resultado es el mismo.
+func BuildQuizVariant(quiz domain.TeacherQuiz, seed int64) domain.TeacherQuiz {
+	rng := rand.New(rand.NewSource(seed))
+
+	questions := make([]domain.TeacherQuizQuestion, 0, len(quiz.Questions))
+	questions = append(questions, quiz.Questions...)
+	for _, pool := range quiz.Pools {
+		drawn := make([]domain.TeacherQuizQuestion, len(pool.Questions))
+		copy(drawn, pool.Questions)
+		rng.Shuffle(len(drawn), func(i, j int) { drawn[i], drawn[j] = drawn[j], drawn[i] })
+		if pool.Draw > 0 && pool.Draw < len(drawn) {
+			drawn = drawn[:pool.Draw]
+		}
+		questions = append(questions, drawn...)
+	}
+
+	if quiz.Settings.ShuffleQuestions {
+		rng.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	}
+	if quiz.Settings.ShuffleOptions {
+		for i := range questions {
+			// Las claves referencian el texto de las opciones, no su posición, así que barajar no altera la calificación
+			options := make([]string, len(questions[i].Options))
+			copy(options, questions[i].Options)
+			rng.Shuffle(len(options), func(a, b int) { options[a], options[b] = options[b], options[a] })
+			questions[i].Options = options
+		}
+	}
+
+	for i := range questions {
+		if questions[i].Type == "calculated" {
+			drawVariableValues(&questions[i], rng)
+		}
+	}
+
+	variant := quiz
+	variant.Questions = questions
+	variant.Pools = nil
+	return variant
+}
+
+// drawVariableValues sortea los valores de una pregunta calculated y los sustituye en el enunciado
+func drawVariableValues(question *domain.TeacherQuizQuestion, rng *rand.Rand) {
+	formula, _ := ParseFormula(question.Formula)
+	var values map[string]float64
+	for draw := 0; draw < maxVariableDraws; draw++ {
+		values = make(map[string]float64, len(question.Variables))
+		for _, variable := range question.Variables {
+			values[variable.Name] = roundTo(variable.Min+rng.Float64()*(variable.Max-variable.Min), variable.Decimals)
+		}
+		if formula == nil {
+			break
+		}
+		if _, err := formula.Evaluate(values); err == nil {
+			break
+		}
+	}
+	question.Values = values
+
+	replacements := make([]string, 0, 2*len(question.Variables))
+	for _, variable := range question.Variables {
+		replacements = append(replacements, "{"+variable.Name+"}", strconv.FormatFloat(values[variable.Name], 'f', variable.Decimals, 64))
+	}
+	question.Question = strings.NewReplacer(replacements...).Replace(question.Question)
+}
+
+// variableValues reúne los valores sorteados de las preguntas calculated de una variante
+func variableValues(quiz domain.TeacherQuiz) map[string]map[string]float64 {
+	var values map[string]map[string]float64
+	for _, question := range quiz.Questions {
+		if question.Type != "calculated" {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]map[string]float64)
+		}
+		values[question.ID] = question.Values
+	}
+	return values
+}
+
+// studentQuizCopy elimina la clave de respuestas de un quiz antes de enviarlo al estudiante.
+// Las opciones de las preguntas ordering siempre se barajan, porque su orden es la respuesta.
+func studentQuizCopy(quiz domain.TeacherQuiz) domain.TeacherQuiz {
+	student := quiz
+	student.Pools = nil
+	student.Questions = make([]domain.TeacherQuizQuestion, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		if q.Type == "ordering" {
+			q.Options = domain.ScrambleOrderingOptions(q.Options, q.CorrectAnswers)
+		}
+		q.CorrectAnswer = nil
+		q.CorrectAnswers = nil
+		q.CorrectPairs = nil
+		q.AcceptedAnswers = nil
+		q.Pattern = ""
+		q.Tolerance = 0
+		q.ToleranceType = ""
+		q.Explanation = ""
+		q.OptionExplanations = nil
+		q.Formula = ""
+		q.Variables = nil
+		student.Questions[i] = q
+	}
+	return student
+}
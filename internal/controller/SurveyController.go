@@ -0,0 +1,441 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSurveyOpenAnswerLength = 2000
+	maxSurveyLikertScale      = 10
+	surveyTopWords            = 20
+)
+
+// surveyStopWords son palabras frecuentes que no aportan a la frecuencia de palabras de las respuestas abiertas
+var surveyStopWords = map[string]bool{
+	"que": true, "los": true, "las": true, "del": true, "por": true, "con": true, "una": true, "para": true,
+	"como": true, "mas": true, "pero": true, "sus": true, "les": true, "muy": true, "sin": true, "sobre": true,
+	"este": true, "esta": true, "esto": true, "todo": true, "tambien": true, "fue": true, "era": true, "son": true,
+	"hay": true, "nos": true, "porque": true, "cuando": true, "donde": true, "the": true, "and": true,
+}
+
+type SurveyController struct {
+	SurveyRepo        domain.SurveyRepo
+	CourseContentRepo domain.CourseContentRepo
+	CourseRepo        domain.CourseRepo
+	AssignmentRepo    domain.AssignmentRepo
+	GetSurveyContent  func(bucket, key string) ([]byte, error)
+}
+
+// ValidateSurvey valida la definición de una encuesta del profesor.
+// Devuelve los errores por campo (p. ej. "questions[1].options") o nil si la encuesta es válida.
+func ValidateSurvey(jsonBytes []byte) map[string]string {
+	errorMap := make(map[string]string)
+
+	var survey domain.Survey
+	if err := json.Unmarshal(jsonBytes, &survey); err != nil {
+		errorMap["json_data"] = "Invalid survey JSON"
+		return errorMap
+	}
+	if len(survey.Questions) == 0 {
+		errorMap["questions"] = "This field is required"
+	}
+
+	seenIDs := make(map[string]bool)
+	for i, question := range survey.Questions {
+		path := fmt.Sprintf("questions[%d]", i)
+		if question.ID == "" {
+			errorMap[path+".id"] = "This field is required"
+		} else if seenIDs[question.ID] {
+			errorMap[path+".id"] = fmt.Sprintf("Duplicated question id %q", question.ID)
+		}
+		seenIDs[question.ID] = true
+		if strings.TrimSpace(question.Question) == "" {
+			errorMap[path+".question"] = "This field is required"
+		}
+
+		switch question.Type {
+		case domain.SurveyQuestionLikert:
+			scale := likertScale(question)
+			if scale < 2 || scale > maxSurveyLikertScale {
+				errorMap[path+".scale"] = fmt.Sprintf("Must be between 2 and %d", maxSurveyLikertScale)
+			}
+			if len(question.Labels) > 0 && len(question.Labels) != scale {
+				errorMap[path+".labels"] = fmt.Sprintf("Must have %d labels", scale)
+			}
+		case domain.SurveyQuestionMultiple:
+			if len(question.Options) < 2 {
+				errorMap[path+".options"] = "Must have at least 2 options"
+			}
+			seenOptions := make(map[string]bool)
+			for _, option := range question.Options {
+				if seenOptions[option] {
+					errorMap[path+".options"] = fmt.Sprintf("Duplicated option %q", option)
+				}
+				seenOptions[option] = true
+			}
+		case domain.SurveyQuestionOpen:
+		default:
+			errorMap[path+".type"] = "Must be one of likert, multiple, open"
+		}
+	}
+
+	if len(errorMap) == 0 {
+		return nil
+	}
+	return errorMap
+}
+
+func likertScale(question domain.SurveyQuestion) int {
+	if question.Scale == 0 {
+		return domain.DefaultLikertScale
+	}
+	return question.Scale
+}
+
+// validateSurveyAnswers verifica las respuestas de un estudiante y devuelve las respuestas normalizadas
+func validateSurveyAnswers(survey domain.Survey, answers map[string]interface{}) (map[string]interface{}, map[string]string) {
+	errorMap := make(map[string]string)
+	cleaned := make(map[string]interface{})
+
+	questionsByID := make(map[string]domain.SurveyQuestion, len(survey.Questions))
+	for _, question := range survey.Questions {
+		questionsByID[question.ID] = question
+	}
+	for questionID := range answers {
+		if _, ok := questionsByID[questionID]; !ok {
+			errorMap["answers."+questionID] = "Unknown question"
+		}
+	}
+
+	for _, question := range survey.Questions {
+		field := "answers." + question.ID
+		answer, ok := answers[question.ID]
+		if text, isText := answer.(string); isText && strings.TrimSpace(text) == "" {
+			ok = false
+		}
+		if list, isList := answer.([]interface{}); isList && len(list) == 0 {
+			ok = false
+		}
+		if !ok || answer == nil {
+			if question.Required {
+				errorMap[field] = "This field is required"
+			}
+			continue
+		}
+
+		switch question.Type {
+		case domain.SurveyQuestionLikert:
+			value, isNumber := parseNumber(answer)
+			if !isNumber || value != math.Trunc(value) || value < 1 || int(value) > likertScale(question) {
+				errorMap[field] = fmt.Sprintf("Must be an integer between 1 and %d", likertScale(question))
+				continue
+			}
+			cleaned[question.ID] = int(value)
+		case domain.SurveyQuestionMultiple:
+			choices, err := surveyChoices(question, answer)
+			if err != nil {
+				errorMap[field] = err.Error()
+				continue
+			}
+			if question.AllowMultiple {
+				cleaned[question.ID] = choices
+			} else {
+				cleaned[question.ID] = choices[0]
+			}
+		case domain.SurveyQuestionOpen:
+			text, isText := answer.(string)
+			if !isText {
+				errorMap[field] = "Must be text"
+				continue
+			}
+			text = strings.TrimSpace(text)
+			if utf8.RuneCountInString(text) > maxSurveyOpenAnswerLength {
+				errorMap[field] = fmt.Sprintf("Must be at most %d characters", maxSurveyOpenAnswerLength)
+				continue
+			}
+			cleaned[question.ID] = text
+		}
+	}
+
+	if len(errorMap) == 0 {
+		return cleaned, nil
+	}
+	return nil, errorMap
+}
+
+// surveyChoices devuelve las opciones elegidas en una pregunta multiple
+func surveyChoices(question domain.SurveyQuestion, answer interface{}) ([]string, error) {
+	var choices []string
+	if choice, ok := answer.(string); ok {
+		choices = []string{choice}
+	} else if question.AllowMultiple {
+		list, err := toStringSlice(answer)
+		if err != nil {
+			return nil, err
+		}
+		choices = list
+	} else {
+		return nil, errors.New("Must be one of the options")
+	}
+
+	seen := make(map[string]bool, len(choices))
+	for _, choice := range choices {
+		if !containsString(question.Options, choice) {
+			return nil, fmt.Errorf("Unknown option %q", choice)
+		}
+		if seen[choice] {
+			return nil, fmt.Errorf("Duplicated option %q", choice)
+		}
+		seen[choice] = true
+	}
+	return choices, nil
+}
+
+// SurveyResultsFor agrega las respuestas de una encuesta: conteos por opción, media de las escalas
+// Likert y frecuencia de palabras de las respuestas abiertas
+func SurveyResultsFor(survey domain.Survey, responses []domain.SurveyResponse) domain.SurveyResults {
+	results := domain.SurveyResults{
+		Title:     survey.Title,
+		Anonymous: survey.Anonymous,
+		Responses: len(responses),
+		Questions: make([]domain.SurveyQuestionResult, 0, len(survey.Questions)),
+	}
+
+	for _, question := range survey.Questions {
+		result := domain.SurveyQuestionResult{QuestionID: question.ID, Question: question.Question, Type: question.Type}
+		switch question.Type {
+		case domain.SurveyQuestionLikert:
+			scale := likertScale(question)
+			counts := make([]int, scale)
+			sum := 0
+			for _, response := range responses {
+				value, ok := parseNumber(response.Answers[question.ID])
+				if !ok || value < 1 || int(value) > scale {
+					continue
+				}
+				counts[int(value)-1]++
+				sum += int(value)
+				result.Answered++
+			}
+			for point, count := range counts {
+				option := strconv.Itoa(point + 1)
+				if len(question.Labels) == scale {
+					option = question.Labels[point]
+				}
+				result.Counts = append(result.Counts, surveyOptionCount(option, count, result.Answered))
+			}
+			if result.Answered > 0 {
+				mean := math.Round(float64(sum)/float64(result.Answered)*100) / 100
+				result.Mean = &mean
+			}
+		case domain.SurveyQuestionMultiple:
+			counts := make(map[string]int, len(question.Options))
+			for _, response := range responses {
+				choices, err := surveyChoices(question, response.Answers[question.ID])
+				if err != nil {
+					continue
+				}
+				for _, choice := range choices {
+					counts[choice]++
+				}
+				result.Answered++
+			}
+			for _, option := range question.Options {
+				result.Counts = append(result.Counts, surveyOptionCount(option, counts[option], result.Answered))
+			}
+		case domain.SurveyQuestionOpen:
+			var texts []string
+			for _, response := range responses {
+				if text, ok := response.Answers[question.ID].(string); ok && text != "" {
+					texts = append(texts, text)
+				}
+			}
+			result.Answered = len(texts)
+			result.WordFrequency = surveyWordFrequency(texts)
+		}
+		results.Questions = append(results.Questions, result)
+	}
+	return results
+}
+
+func surveyOptionCount(option string, count, answered int) domain.SurveyOptionCount {
+	optionCount := domain.SurveyOptionCount{Option: option, Count: count}
+	if answered > 0 {
+		optionCount.Ratio = math.Round(float64(count)/float64(answered)*10000) / 10000
+	}
+	return optionCount
+}
+
+// surveyWordFrequency cuenta en cuántas respuestas aparece cada palabra, sin distinguir mayúsculas ni tildes
+func surveyWordFrequency(texts []string) []domain.SurveyWordCount {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		seen := make(map[string]bool)
+		for _, word := range normalizeAnswerWords(text) {
+			if utf8.RuneCountInString(word) < 3 || surveyStopWords[word] || seen[word] {
+				continue
+			}
+			seen[word] = true
+			counts[word]++
+		}
+	}
+
+	frequency := make([]domain.SurveyWordCount, 0, len(counts))
+	for word, count := range counts {
+		frequency = append(frequency, domain.SurveyWordCount{Word: word, Count: count})
+	}
+	sort.Slice(frequency, func(i, j int) bool {
+		if frequency[i].Count != frequency[j].Count {
+			return frequency[i].Count > frequency[j].Count
+		}
+		return frequency[i].Word < frequency[j].Word
+	})
+	if len(frequency) > surveyTopWords {
+		frequency = frequency[:surveyTopWords]
+	}
+	return frequency
+}
+
+// loadSurvey descarga y parsea la definición de una encuesta desde R2
+func (c *SurveyController) loadSurvey(url string) (domain.Survey, error) {
+	var survey domain.Survey
+	surveyBytes, err := c.GetSurveyContent("zeppelin", r2KeyFromURL(url))
+	if err != nil {
+		return survey, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener la encuesta desde R2: %s", err.Error()))
+	}
+	if err := json.Unmarshal(surveyBytes, &survey); err != nil {
+		return survey, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al parsear la encuesta: %s", err.Error()))
+	}
+	return survey, nil
+}
+
+// surveyContent devuelve la URL y el curso de una encuesta, verificando que el contenido sea una encuesta
+func (c *SurveyController) surveyContent(contentID string) (string, int, error) {
+	Url, err := c.CourseContentRepo.GetUrlByContentID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("contenido con ID %s no encontrado", contentID))
+	}
+	contentTypeID, err := c.CourseContentRepo.GetContentTypeID(contentID)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener tipo de contenido: %v", err))
+	}
+	if contentTypeID != 4 {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "el content_id no corresponde a una encuesta")
+	}
+	if Url == "" {
+		return "", 0, echo.NewHTTPError(http.StatusNotFound, "la encuesta aún no tiene preguntas")
+	}
+	courseID, err := courseIDFromQuizURL(Url)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusInternalServerError, "URL malformada")
+	}
+	return Url, courseID, nil
+}
+
+// SubmitSurvey guarda la respuesta de un estudiante a una encuesta y marca el contenido como completado.
+// En las encuestas anónimas la respuesta no queda vinculada al estudiante.
+func (c *SurveyController) SubmitSurvey() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+
+		var input domain.SubmitSurveyInput
+		if err := ValidateAndBind(e, &input); err != nil {
+			return err
+		}
+
+		Url, courseID, err := c.surveyContent(input.ContentID)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+		if _, err := c.AssignmentRepo.GetAssignmentsByStudentAndCourse(userID, courseID); err != nil {
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusForbidden, "Este estudiante no está asignado a este curso"), nil)
+		}
+		if err := checkContentUnlocked(c.CourseContentRepo, input.ContentID, userID); err != nil {
+			return err
+		}
+		survey, err := c.loadSurvey(Url)
+		if err != nil {
+			return ReturnWriteResponse(e, err, nil)
+		}
+
+		answers, fieldErrors := validateSurveyAnswers(survey, input.Answers)
+		if fieldErrors != nil {
+			return definitionError("Error on survey answers", fieldErrors)
+		}
+
+		response := domain.SurveyResponse{
+			ContentID:   input.ContentID,
+			Answers:     answers,
+			SubmittedAt: time.Now().UTC(),
+		}
+		if survey.Anonymous {
+			// Sin usuario ni hora exacta la respuesta no se puede cruzar con survey_participation
+			response.SubmittedAt = response.SubmittedAt.Truncate(24 * time.Hour)
+		} else {
+			response.UserID = &userID
+		}
+
+		if err := c.SurveyRepo.SubmitSurveyResponse(response, userID); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusConflict, "La encuesta ya fue respondida"), nil)
+			}
+			return ReturnWriteResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al guardar la respuesta de la encuesta: %v", err)), nil)
+		}
+
+		return ReturnWriteResponse(e, nil, map[string]interface{}{
+			"message":    "Encuesta respondida",
+			"content_id": input.ContentID,
+			"anonymous":  survey.Anonymous,
+		})
+	}
+}
+
+// GetSurveyResults devuelve al profesor los resultados agregados de una encuesta de su curso
+func (c *SurveyController) GetSurveyResults() echo.HandlerFunc {
+	return func(e echo.Context) error {
+		userID := e.Get("user_id").(string)
+		contentID := e.Param("contentId")
+		courseID, err := strconv.Atoi(e.Param("courseId"))
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusBadRequest, "ID de curso inválido"), nil)
+		}
+
+		if _, err := c.CourseRepo.GetCourseByTeacherAndCourseID(userID, courseID); err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusForbidden, "Este curso no le pertenece al profesor"), nil)
+		}
+		Url, surveyCourseID, err := c.surveyContent(contentID)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+		if surveyCourseID != courseID {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("la encuesta %s no pertenece al curso %d", contentID, courseID)), nil)
+		}
+		survey, err := c.loadSurvey(Url)
+		if err != nil {
+			return ReturnReadResponse(e, err, nil)
+		}
+
+		responses, err := c.SurveyRepo.GetSurveyResponses(contentID)
+		if err != nil {
+			return ReturnReadResponse(e, echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("error al obtener las respuestas de la encuesta: %v", err)), nil)
+		}
+
+		results := SurveyResultsFor(survey, responses)
+		results.ContentID = contentID
+		results.CourseID = courseID
+		return ReturnReadResponse(e, nil, results)
+	}
+}
@@ -14,6 +14,8 @@ import (
 
 const (
 	writeWait = 10 * time.Second
+	// sendBuffer es cuántos mensajes puede tener pendientes una conexión antes de considerarla lenta
+	sendBuffer = 64
 )
 
 var upgrader = websocket.Upgrader{
@@ -26,6 +28,66 @@ var upgrader = websocket.Upgrader{
 type UserConn struct {
 	Conn     *websocket.Conn
 	Platform string
+	out      *connWriter
+}
+
+// connWriter es el único que escribe en el socket; el resto encola mensajes con send.
+// Así los ACK de pomodoro, los status_update y los broadcasts nunca escriben a la vez
+// y nadie se bloquea esperando a un cliente lento.
+type connWriter struct {
+	conn      *websocket.Conn
+	queue     chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnWriter(logger echo.Logger, conn *websocket.Conn) *connWriter {
+	w := &connWriter{
+		conn:  conn,
+		queue: make(chan []byte, sendBuffer),
+		done:  make(chan struct{}),
+	}
+	go w.writePump(logger)
+	return w
+}
+
+// writePump escribe los mensajes encolados en orden hasta que se cierra la conexión
+func (w *connWriter) writePump(logger echo.Logger) {
+	for {
+		select {
+		case data := <-w.queue:
+			_ = w.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := w.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				logger.Warnf("Write to %s failed: %v", w.conn.RemoteAddr(), err)
+				w.close()
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// send encola un mensaje sin bloquear. Si el cliente no consume sus mensajes se cierra la
+// conexión y readPump hace la limpieza.
+func (w *connWriter) send(data []byte) {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	select {
+	case w.queue <- data:
+	default:
+		w.close()
+	}
+}
+
+func (w *connWriter) close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		_ = w.conn.Close()
+	})
 }
 
 // ConnectionManager gestiona sockets por usuario:curso y repositorios
@@ -34,6 +96,10 @@ type ConnectionManager struct {
 	mutex               sync.Mutex
 	SessionRepo         domain.SessionRepo
 	ParentalConsentRepo domain.ParentalConsentRepo
+	LiveQuiz            *QuizController             // repos del quiz en vivo; nil lo desactiva
+	liveQuizzes         map[string]*LiveQuizSession // key = courseId
+	// LiveQuizTeacherGrace es cuánto se espera a que el profesor se reconecte antes de terminar su quiz en vivo
+	LiveQuizTeacherGrace time.Duration
 }
 
 // NewConnectionManager inyecta SessionRepo y ParentalConsentRepo
@@ -42,9 +108,11 @@ func NewConnectionManager(
 	consentRepo domain.ParentalConsentRepo,
 ) *ConnectionManager {
 	return &ConnectionManager{
-		userConnections:     make(map[string][]UserConn),
-		SessionRepo:         sessionRepo,
-		ParentalConsentRepo: consentRepo,
+		userConnections:      make(map[string][]UserConn),
+		liveQuizzes:          make(map[string]*LiveQuizSession),
+		LiveQuizTeacherGrace: defaultLiveQuizTeacherGrace,
+		SessionRepo:          sessionRepo,
+		ParentalConsentRepo:  consentRepo,
 	}
 }
 
@@ -102,12 +170,7 @@ func (cm *ConnectionManager) sendStatusUpdate(logger echo.Logger, userID, course
 	}
 
 	for _, uc := range conns {
-		_ = uc.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-		if err := uc.Conn.WriteMessage(websocket.TextMessage, jsonData); err != nil {
-			logger.Warnf("User %s (Course %s, Platform %s): Failed to write status update: %v",
-				userID, courseId, uc.Platform, err)
-		}
-		_ = uc.Conn.SetWriteDeadline(time.Time{})
+		uc.out.send(jsonData)
 	}
 }
 
@@ -200,28 +263,33 @@ func (cm *ConnectionManager) WebSocketHandler() echo.HandlerFunc {
 		}
 		logger.Infof("User %s (Course %s): Connected (%s)", userID, courseId, platform)
 
+		out := newConnWriter(logger, conn)
 		cm.mutex.Lock()
-		cm.userConnections[key] = append(cm.userConnections[key], UserConn{Conn: conn, Platform: platform})
+		cm.userConnections[key] = append(cm.userConnections[key], UserConn{Conn: conn, Platform: platform, out: out})
 		cm.mutex.Unlock()
 
 		// Enviar primer status_update (incluye parental_consent_status)
 		cm.sendStatusUpdate(logger, userID, courseId)
+		// Quien se conecta durante un quiz en vivo recibe la pregunta abierta
+		cm.sendLiveQuizState(courseId, out)
 
 		// Leer/broadcast en background
-		go cm.readPump(logger, userID, courseId, platform, conn)
+		go cm.readPump(logger, userID, courseId, platform, conn, out)
 		return nil
 	}
 }
 
 // readPump lee mensajes del socket, maneja pomodoro y broadcast
-func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, platform string, conn *websocket.Conn) {
+func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, platform string, conn *websocket.Conn, out *connWriter) {
 	key := userID + ":" + courseId
 	defer func() {
 		logger.Infof("User %s (Course %s, %s): Cleaning up", userID, courseId, platform)
 		if cm.removeConnection(logger, userID, courseId, conn) {
 			cm.sendStatusUpdate(logger, userID, courseId)
+		} else {
+			cm.liveQuizTeacherLeft(logger, userID, courseId)
 		}
-		_ = conn.Close()
+		out.close()
 	}()
 
 	for {
@@ -253,6 +321,12 @@ func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, plat
 			continue
 		}
 
+		// Los mensajes del quiz en vivo se envían a todo el curso, no solo a las conexiones del usuario
+		if isLiveQuizMessage(msg.Type) {
+			cm.handleLiveQuizMessage(logger, userID, courseId, out, message)
+			continue
+		}
+
 		broadcast := message
 
 		switch msg.Type {
@@ -262,12 +336,12 @@ func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, plat
 			if err != nil {
 				errMsg := map[string]string{"type": "error", "error": "Failed to start session"}
 				data, _ := json.Marshal(errMsg)
-				_ = conn.WriteMessage(websocket.TextMessage, data)
+				out.send(data)
 			} else {
 				// Responder ACK al emisor
 				ack := map[string]any{"type": "session_started", "session_id": sessionID}
 				ackData, _ := json.Marshal(ack)
-				_ = conn.WriteMessage(websocket.TextMessage, ackData)
+				out.send(ackData)
 				// Preparar broadcast con session_id
 				bMsg := map[string]any{
 					"type":       msg.Type,
@@ -283,15 +357,15 @@ func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, plat
 			if msg.SessionId == 0 {
 				errMsg := map[string]string{"type": "error", "error": "No session_id provided"}
 				d, _ := json.Marshal(errMsg)
-				_ = conn.WriteMessage(websocket.TextMessage, d)
+				out.send(d)
 			} else if err := cm.SessionRepo.EndSession(msg.SessionId); err != nil {
 				errMsg := map[string]string{"type": "error", "error": "Failed to end session"}
 				d, _ := json.Marshal(errMsg)
-				_ = conn.WriteMessage(websocket.TextMessage, d)
+				out.send(d)
 			} else {
 				ack := map[string]any{"type": "session_ended", "session_id": msg.SessionId}
 				ackData, _ := json.Marshal(ack)
-				_ = conn.WriteMessage(websocket.TextMessage, ackData)
+				out.send(ackData)
 			}
 			// En cualquier caso, usamos el mensaje original o el modificado para broadcast
 
@@ -309,9 +383,7 @@ func (cm *ConnectionManager) readPump(logger echo.Logger, userID, courseId, plat
 			if msg.Type != "pomodoro_start" && uc.Conn == conn {
 				continue
 			}
-			_ = uc.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			_ = uc.Conn.WriteMessage(websocket.TextMessage, broadcast)
-			_ = uc.Conn.SetWriteDeadline(time.Time{})
+			uc.out.send(broadcast)
 		}
 	}
 }
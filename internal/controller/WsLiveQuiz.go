@@ -0,0 +1,319 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultLiveQuizTeacherGrace es la espera por defecto antes de terminar un quiz en vivo sin profesor conectado
+const defaultLiveQuizTeacherGrace = 2 * time.Minute
+
+// liveQuizMessage es un mensaje del modo de quiz en vivo
+type liveQuizMessage struct {
+	Type       string      `json:"type"`
+	ContentID  string      `json:"content_id"`
+	QuestionID string      `json:"question_id"`
+	Answer     interface{} `json:"answer"`
+}
+
+// isLiveQuizMessage indica si el mensaje pertenece al modo de quiz en vivo
+func isLiveQuizMessage(msgType string) bool {
+	return strings.HasPrefix(msgType, "live_quiz_")
+}
+
+// courseConnections devuelve las conexiones de todos los usuarios conectados a un curso
+func (cm *ConnectionManager) courseConnections(courseId string) []UserConn {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	var conns []UserConn
+	for key, userConns := range cm.userConnections {
+		if strings.HasSuffix(key, ":"+courseId) {
+			conns = append(conns, userConns...)
+		}
+	}
+	return conns
+}
+
+// broadcastCourse envía un mensaje a todas las conexiones del curso
+func (cm *ConnectionManager) broadcastCourse(logger echo.Logger, courseId string, payload map[string]any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf("Course %s: Failed to marshal %v: %v", courseId, payload["type"], err)
+		return
+	}
+	for _, uc := range cm.courseConnections(courseId) {
+		uc.out.send(data)
+	}
+}
+
+func (cm *ConnectionManager) sendLiveQuizError(out *connWriter, message string) {
+	data, _ := json.Marshal(map[string]string{"type": "error", "error": message})
+	out.send(data)
+}
+
+// liveQuizErrorMessage extrae el mensaje de los errores HTTP que devuelven los helpers del quiz
+func liveQuizErrorMessage(err error) string {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Sprint(httpErr.Message)
+	}
+	return err.Error()
+}
+
+func (cm *ConnectionManager) liveQuizSession(courseId string) *LiveQuizSession {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	return cm.liveQuizzes[courseId]
+}
+
+// handleLiveQuizMessage atiende los mensajes del quiz en vivo. Un profesor del curso lo dirige
+// (live_quiz_start, live_quiz_next, live_quiz_close, live_quiz_end) y los estudiantes responden con live_quiz_answer.
+// Los mensajes a todo el curso se encolan con el lock de la sesión tomado para que lleguen en orden;
+// encolar no bloquea, así que el lock no espera a ningún cliente lento.
+func (cm *ConnectionManager) handleLiveQuizMessage(logger echo.Logger, userID, courseId string, out *connWriter, message []byte) {
+	var msg liveQuizMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		cm.sendLiveQuizError(out, "Mensaje de quiz en vivo inválido")
+		return
+	}
+	if cm.LiveQuiz == nil {
+		cm.sendLiveQuizError(out, "El quiz en vivo no está disponible")
+		return
+	}
+	if msg.Type == "live_quiz_start" {
+		cm.startLiveQuiz(logger, userID, courseId, out, msg.ContentID)
+		return
+	}
+
+	session := cm.liveQuizSession(courseId)
+	if session == nil {
+		cm.sendLiveQuizError(out, "No hay un quiz en vivo en este curso")
+		return
+	}
+	if msg.Type != "live_quiz_answer" && !cm.canDirectLiveQuiz(userID, session) {
+		cm.sendLiveQuizError(out, "Solo un profesor del curso puede dirigir el quiz")
+		return
+	}
+
+	switch msg.Type {
+	case "live_quiz_next":
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		if _, _, open := session.CurrentQuestion(); open {
+			cm.closeLiveQuestion(logger, courseId, out, session)
+		}
+		question, err := session.NextQuestion(time.Now())
+		if err != nil {
+			cm.sendLiveQuizError(out, err.Error())
+			return
+		}
+		cm.broadcastCourse(logger, courseId, liveQuestionPayload(session, question))
+	case "live_quiz_close":
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		cm.closeLiveQuestion(logger, courseId, out, session)
+	case "live_quiz_answer":
+		cm.answerLiveQuiz(logger, userID, courseId, out, session, msg)
+	case "live_quiz_end":
+		cm.endLiveQuiz(logger, courseId, session)
+	default:
+		cm.sendLiveQuizError(out, fmt.Sprintf("Tipo de mensaje desconocido %q", msg.Type))
+	}
+}
+
+// canDirectLiveQuiz indica si el usuario es el profesor que inició el quiz o es dueño del curso
+func (cm *ConnectionManager) canDirectLiveQuiz(userID string, session *LiveQuizSession) bool {
+	if userID == session.TeacherID {
+		return true
+	}
+	_, err := cm.LiveQuiz.CourseRepo.GetCourseByTeacherAndCourseID(userID, session.CourseID)
+	return err == nil
+}
+
+// liveQuizTeacherLeft se llama al cerrarse la última conexión de un usuario en el curso. Si es el profesor
+// que dirige el quiz en vivo y no vuelve antes de LiveQuizTeacherGrace, el quiz se termina y se guardan
+// los intentos para que la sesión no quede abierta indefinidamente.
+func (cm *ConnectionManager) liveQuizTeacherLeft(logger echo.Logger, userID, courseId string) {
+	session := cm.liveQuizSession(courseId)
+	if session == nil || session.TeacherID != userID {
+		return
+	}
+	time.AfterFunc(cm.LiveQuizTeacherGrace, func() {
+		if cm.GetConnectionCount(userID, courseId) > 0 {
+			return
+		}
+		logger.Infof("Course %s: Teacher %s disconnected, ending live quiz %s", courseId, userID, session.ContentID)
+		cm.endLiveQuiz(logger, courseId, session)
+	})
+}
+
+func (cm *ConnectionManager) startLiveQuiz(logger echo.Logger, userID, courseId string, out *connWriter, contentID string) {
+	courseID, err := strconv.Atoi(courseId)
+	if err != nil {
+		cm.sendLiveQuizError(out, "ID de curso inválido")
+		return
+	}
+	// teacherQuizCourse verifica que el curso sea del profesor y que el contenido sea un quiz
+	Url, quizCourseID, err := cm.LiveQuiz.teacherQuizCourse(userID, contentID)
+	if err != nil {
+		cm.sendLiveQuizError(out, liveQuizErrorMessage(err))
+		return
+	}
+	if quizCourseID != courseID {
+		cm.sendLiveQuizError(out, fmt.Sprintf("el quiz %s no pertenece al curso %d", contentID, courseID))
+		return
+	}
+	Url, quizVersionID, err := cm.LiveQuiz.currentQuizVersion(contentID, Url)
+	if err != nil {
+		cm.sendLiveQuizError(out, liveQuizErrorMessage(err))
+		return
+	}
+	quiz, err := cm.LiveQuiz.loadTeacherQuiz(Url)
+	if err != nil {
+		cm.sendLiveQuizError(out, liveQuizErrorMessage(err))
+		return
+	}
+	if err := liveQuizSupported(quiz); err != nil {
+		cm.sendLiveQuizError(out, err.Error())
+		return
+	}
+
+	session := NewLiveQuizSession(courseID, contentID, userID, quiz, time.Now())
+	session.QuizURL = Url
+	session.QuizVersionID = quizVersionID
+
+	cm.mutex.Lock()
+	if _, running := cm.liveQuizzes[courseId]; running {
+		cm.mutex.Unlock()
+		cm.sendLiveQuizError(out, "Ya hay un quiz en vivo en este curso")
+		return
+	}
+	cm.liveQuizzes[courseId] = session
+	cm.mutex.Unlock()
+
+	logger.Infof("Course %s: Live quiz %s started by %s", courseId, contentID, userID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	cm.broadcastCourse(logger, courseId, map[string]any{
+		"type":            "live_quiz_started",
+		"content_id":      contentID,
+		"title":           quiz.Title,
+		"total_questions": len(quiz.Questions),
+	})
+}
+
+// closeLiveQuestion cierra la pregunta actual y publica su histograma, el ranking y la respuesta correcta
+func (cm *ConnectionManager) closeLiveQuestion(logger echo.Logger, courseId string, out *connWriter, session *LiveQuizSession) {
+	question, err := session.CloseQuestion()
+	if err != nil {
+		cm.sendLiveQuizError(out, err.Error())
+		return
+	}
+	cm.broadcastCourse(logger, courseId, map[string]any{
+		"type":        "live_quiz_question_closed",
+		"content_id":  session.ContentID,
+		"question_id": question.ID,
+		"answer_key":  question,
+		"answered":    session.Answered(),
+		"histogram":   session.Histogram(),
+		"leaderboard": session.Leaderboard(),
+	})
+}
+
+func (cm *ConnectionManager) answerLiveQuiz(logger echo.Logger, userID, courseId string, out *connWriter, session *LiveQuizSession, msg liveQuizMessage) {
+	if userID == session.TeacherID {
+		cm.sendLiveQuizError(out, "El profesor no puede responder el quiz en vivo")
+		return
+	}
+	// Solo responden los estudiantes asignados al curso
+	if _, err := cm.LiveQuiz.AssignmentRepo.GetAssignmentsByStudentAndCourse(userID, session.CourseID); err != nil {
+		cm.sendLiveQuizError(out, "Este estudiante no está asignado a este curso")
+		return
+	}
+	name := ""
+	if cm.LiveQuiz.UserRepo != nil {
+		if user, err := cm.LiveQuiz.UserRepo.GetUser(userID); err == nil && user != nil {
+			name = strings.TrimSpace(user.Name + " " + user.Lastname)
+		}
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, err := session.SubmitAnswer(userID, name, msg.QuestionID, msg.Answer, time.Now()); err != nil {
+		cm.sendLiveQuizError(out, err.Error())
+		return
+	}
+	ack, _ := json.Marshal(map[string]any{"type": "live_quiz_answer_received", "question_id": msg.QuestionID})
+	out.send(ack)
+	cm.broadcastCourse(logger, courseId, map[string]any{
+		"type":        "live_quiz_update",
+		"content_id":  session.ContentID,
+		"question_id": msg.QuestionID,
+		"answered":    session.Answered(),
+		"histogram":   session.Histogram(),
+		"leaderboard": session.Leaderboard(),
+	})
+}
+
+// endLiveQuiz termina el quiz, guarda los intentos en quiz_answer y publica el ranking final.
+// Si la sesión ya terminó no hace nada, así los intentos se guardan una sola vez.
+func (cm *ConnectionManager) endLiveQuiz(logger echo.Logger, courseId string, session *LiveQuizSession) {
+	cm.mutex.Lock()
+	if cm.liveQuizzes[courseId] != session {
+		cm.mutex.Unlock()
+		return
+	}
+	delete(cm.liveQuizzes, courseId)
+	cm.mutex.Unlock()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	payload := map[string]any{
+		"type":        "live_quiz_ended",
+		"content_id":  session.ContentID,
+		"leaderboard": session.Leaderboard(),
+	}
+	saved, err := cm.LiveQuiz.PersistLiveQuiz(session, time.Now())
+	payload["saved_attempts"] = saved
+	if err != nil {
+		logger.Errorf("Course %s: Failed to persist live quiz %s: %v", courseId, session.ContentID, err)
+		payload["error"] = "No se pudieron guardar todos los intentos"
+	}
+	cm.broadcastCourse(logger, courseId, payload)
+}
+
+func liveQuestionPayload(session *LiveQuizSession, question domain.TeacherQuizQuestion) map[string]any {
+	_, index, _ := session.CurrentQuestion()
+	return map[string]any{
+		"type":       "live_quiz_question",
+		"content_id": session.ContentID,
+		"index":      index,
+		"total":      len(session.Quiz.Questions),
+		"question":   liveStudentQuestion(question),
+	}
+}
+
+// sendLiveQuizState envía a una conexión nueva la pregunta abierta del quiz en vivo del curso, si la hay
+func (cm *ConnectionManager) sendLiveQuizState(courseId string, out *connWriter) {
+	session := cm.liveQuizSession(courseId)
+	if session == nil {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	question, _, open := session.CurrentQuestion()
+	if !open {
+		return
+	}
+	data, err := json.Marshal(liveQuestionPayload(session, question))
+	if err == nil {
+		out.send(data)
+	}
+}
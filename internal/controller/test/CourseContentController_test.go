@@ -1,6 +1,7 @@
 package controller_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
@@ -28,6 +29,16 @@ type MockCourseContentRepo struct {
 	UpdateModuleTitleT            func(courseContentID int, moduleTitle string) error
 	UpdateUserContentStatusT      func(userID, contentID string, statusID int) error
 	GetContentTypeIDT             func(contentID string) (int, error)
+	VerifyModuleOwnershipT        func(courseContentID int, userID string) error
+	ReorderModulesT               func(courseID int, courseContentIDs []int) error
+	ReorderSectionsT              func(courseContentID int, contentIDs []string) error
+	DeleteSectionT                func(contentID, userID string, force bool) (domain.DeletedContent, error)
+	DeleteModuleT                 func(courseContentID int, userID string, force bool) (domain.DeletedContent, error)
+	CloneCourseT                  func(request domain.CloneCourseRequest) (domain.CourseClone, error)
+	SetPrerequisitesT             func(input domain.SetPrerequisitesInput, userID string) error
+	GetPrerequisitesT             func(contentID, userID string) ([]domain.ContentPrerequisite, error)
+	GetContentLocksT              func(contentID, userID string) ([]domain.ContentLock, error)
+	GetCourseIDByContentIDT       func(contentID string) (int, error)
 }
 
 func (m MockCourseContentRepo) AddModule(courseID int, module string, userID string) (int, error) {
@@ -94,9 +105,69 @@ func (m MockCourseContentRepo) GetContentTypeID(contentID string) (int, error) {
 }
 
 func (m MockCourseContentRepo) VerifyModuleOwnership(courseContentID int, userID string) error {
+	if m.VerifyModuleOwnershipT != nil {
+		return m.VerifyModuleOwnershipT(courseContentID, userID)
+	}
 	return errors.New("VerifyModuleOwnership not implemented")
 }
 
+func (m MockCourseContentRepo) ReorderModules(courseID int, courseContentIDs []int) error {
+	if m.ReorderModulesT != nil {
+		return m.ReorderModulesT(courseID, courseContentIDs)
+	}
+	return errors.New("ReorderModules not implemented")
+}
+
+func (m MockCourseContentRepo) ReorderSections(courseContentID int, contentIDs []string) error {
+	if m.ReorderSectionsT != nil {
+		return m.ReorderSectionsT(courseContentID, contentIDs)
+	}
+	return errors.New("ReorderSections not implemented")
+}
+
+func (m MockCourseContentRepo) DeleteSection(contentID, userID string, force bool) (domain.DeletedContent, error) {
+	if m.DeleteSectionT != nil {
+		return m.DeleteSectionT(contentID, userID, force)
+	}
+	return domain.DeletedContent{}, errors.New("DeleteSection not implemented")
+}
+
+func (m MockCourseContentRepo) DeleteModule(courseContentID int, userID string, force bool) (domain.DeletedContent, error) {
+	if m.DeleteModuleT != nil {
+		return m.DeleteModuleT(courseContentID, userID, force)
+	}
+	return domain.DeletedContent{}, errors.New("DeleteModule not implemented")
+}
+
+func (m MockCourseContentRepo) CloneCourse(request domain.CloneCourseRequest) (domain.CourseClone, error) {
+	if m.CloneCourseT != nil {
+		return m.CloneCourseT(request)
+	}
+	return domain.CourseClone{}, errors.New("CloneCourse not implemented")
+}
+
+func (m MockCourseContentRepo) SetPrerequisites(input domain.SetPrerequisitesInput, userID string) error {
+	if m.SetPrerequisitesT != nil {
+		return m.SetPrerequisitesT(input, userID)
+	}
+	return errors.New("SetPrerequisites not implemented")
+}
+
+func (m MockCourseContentRepo) GetPrerequisites(contentID, userID string) ([]domain.ContentPrerequisite, error) {
+	if m.GetPrerequisitesT != nil {
+		return m.GetPrerequisitesT(contentID, userID)
+	}
+	return nil, errors.New("GetPrerequisites not implemented")
+}
+
+// GetContentLocks no bloquea nada salvo que el test lo indique
+func (m MockCourseContentRepo) GetContentLocks(contentID, userID string) ([]domain.ContentLock, error) {
+	if m.GetContentLocksT != nil {
+		return m.GetContentLocksT(contentID, userID)
+	}
+	return nil, nil
+}
+
 func (m MockCourseContentRepo) CreateContent(input domain.AddSectionInput) (string, error) {
 	return "", errors.New("CreateContent not implemented")
 }
@@ -105,6 +176,13 @@ func (m MockCourseContentRepo) GetUrlByContentID(contentID string) (string, erro
 	return "", errors.New("GetUrlByContentID not implemented")
 }
 
+func (m MockCourseContentRepo) GetCourseIDByContentID(contentID string) (int, error) {
+	if m.GetCourseIDByContentIDT != nil {
+		return m.GetCourseIDByContentIDT(contentID)
+	}
+	return 0, errors.New("GetCourseIDByContentID not implemented")
+}
+
 func TestCourseContentController_AddModule_Success(t *testing.T) {
 	userID := "teacher-123"
 	inputJSON := `{"course_id":1,"module":"New Module"}`
@@ -667,9 +745,13 @@ func TestCourseContentController_UpdateContent_Success_Text(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
 	e.Validator = &CustomValidator{Validator: validator.New()}
 
 	mockRepo := MockCourseContentRepo{
+		GetCourseIDByContentIDT: func(contentID string) (int, error) {
+			return 1, nil
+		},
 		GetContentTypeIDT: func(contentID string) (int, error) {
 			assert.Equal(t, "content-123", contentID)
 			return 2, nil // Texto
@@ -688,6 +770,11 @@ func TestCourseContentController_UpdateContent_Success_Text(t *testing.T) {
 	}
 
 	controller := controller.CourseContentController{
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
 		Repo:           mockRepo,
 		UploadTextFunc: mockUpload,
 	}
@@ -706,7 +793,7 @@ func TestCourseContentController_UpdateContent_Success_Quiz(t *testing.T) {
 	inputJSON := `{
 		"course_id": 1,
 		"content_id": "content-123",
-		"json_data": {"key":"value"}
+		"json_data": {"title":"Quiz 1","questions":[{"id":"q1","type":"multiple","question":"2+2","points":1,"options":["3","4"],"correctAnswer":"4"}]}
 	}`
 
 	os.Setenv("R2_ACCOUNT_ID", "test-account") // Simula variable de entorno
@@ -716,9 +803,13 @@ func TestCourseContentController_UpdateContent_Success_Quiz(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
 	e.Validator = &CustomValidator{Validator: validator.New()}
 
 	mockRepo := MockCourseContentRepo{
+		GetCourseIDByContentIDT: func(contentID string) (int, error) {
+			return 1, nil
+		},
 		GetContentTypeIDT: func(contentID string) (int, error) {
 			assert.Equal(t, "content-123", contentID)
 			return 3, nil
@@ -729,16 +820,41 @@ func TestCourseContentController_UpdateContent_Success_Quiz(t *testing.T) {
 		},
 	}
 
+	var uploads []string
 	mockUploadQuiz := func(courseID, contentID string, json []byte) error {
 		assert.Equal(t, "1", courseID)
 		assert.Equal(t, "content-123", contentID)
-		assert.JSONEq(t, `{"key":"value"}`, string(json))
+		assert.JSONEq(t, `{"title":"Quiz 1","questions":[{"id":"q1","type":"multiple","question":"2+2","points":1,"options":["3","4"],"correctAnswer":"4"}]}`, string(json))
+		uploads = append(uploads, "latest")
+		return nil
+	}
+	mockUploadQuizVersion := func(courseID, contentID string, version int, json []byte) error {
+		assert.Equal(t, 4, version)
+		uploads = append(uploads, "version")
 		return nil
 	}
 
+	versionCreated := false
 	controller := controller.CourseContentController{
-		Repo:           mockRepo,
-		UploadQuizFunc: mockUploadQuiz,
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		Repo:                  mockRepo,
+		UploadQuizFunc:        mockUploadQuiz,
+		UploadQuizVersionFunc: mockUploadQuizVersion,
+		QuizVersionRepo: mockQuizVersionRepo{
+			NextVersion: 4,
+			CreateQuizVersionFn: func(version domain.QuizVersion) (int, error) {
+				versionCreated = true
+				assert.Equal(t, []string{"version"}, uploads, "las copias vigentes se suben después de registrar la versión")
+				assert.Equal(t, "content-123", version.ContentID)
+				assert.Equal(t, 4, version.Version)
+				assert.Equal(t, "https://test-account.r2.cloudflarestorage.com/focused/1/quiz/teacher/content-123/v4.json", version.QuizURL)
+				return 10, nil
+			},
+		},
 	}
 
 	handler := controller.UpdateContent()
@@ -749,6 +865,8 @@ func TestCourseContentController_UpdateContent_Success_Quiz(t *testing.T) {
 
 	expected := `{"Body":{"message":"Contenido actualizado"}}`
 	assert.JSONEq(t, expected, rec.Body.String())
+	assert.True(t, versionCreated)
+	assert.Equal(t, []string{"version", "latest"}, uploads)
 }
 
 func TestCourseContentController_UpdateContent_Success_Video(t *testing.T) {
@@ -763,9 +881,13 @@ func TestCourseContentController_UpdateContent_Success_Video(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
 	e.Validator = &CustomValidator{Validator: validator.New()}
 
 	mockRepo := MockCourseContentRepo{
+		GetCourseIDByContentIDT: func(contentID string) (int, error) {
+			return 1, nil
+		},
 		GetContentTypeIDT: func(contentID string) (int, error) {
 			return 1, nil // Video
 		},
@@ -776,6 +898,11 @@ func TestCourseContentController_UpdateContent_Success_Video(t *testing.T) {
 	}
 
 	controller := controller.CourseContentController{
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
 		Repo: mockRepo,
 	}
 
@@ -903,6 +1030,52 @@ func TestCourseContentController_GetCourseContentForStudent_Success(t *testing.T
 	assert.Contains(t, rec.Body.String(), "https://signed-url/focused/1/quiz/student/content-2.json")
 }
 
+func TestCourseContentController_GetCourseContentForStudent_LockedSection(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/course-content/student?course_id=1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+	c.Set("user_role", "org:student")
+	os.Setenv("BOURBON", "0")
+
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			GetContentByCourseForStudentT: func(cid int, uid string) ([]domain.CourseContentWithStudentDetails, error) {
+				// El repositorio ya marca el quiz como bloqueado y le quita la URL
+				return []domain.CourseContentWithStudentDetails{{
+					CourseContentID: 1,
+					CourseID:        1,
+					Details: []domain.ContentWithStatus{
+						{ContentID: "intro", ContentTypeID: 2},
+						{ContentID: "final-quiz", ContentTypeID: 3, Locked: true,
+							LockReasons: []domain.ContentLock{{Reason: domain.LockReasonNotCompleted, RequiredContentID: "intro"}}},
+					},
+				}}, nil
+			},
+		},
+		RepoAssigment: MockAssignmentRepoLocal{
+			GetAssignmentsByStudentAndCourseFn: func(uid string, cid int) (domain.AssignmentWithCourse, error) {
+				return domain.AssignmentWithCourse{}, nil
+			},
+		},
+		GeneratePresignedURL: func(bucket, key string) (string, error) {
+			assert.NotContains(t, key, "final-quiz", "un contenido bloqueado no recibe URL firmada")
+			return "https://signed-url/" + key, nil
+		},
+	}
+
+	require.NoError(t, ctrl.GetCourseContentForStudent()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var modules []domain.CourseContentWithStudentDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &modules))
+	details := modules[0].Details
+	assert.Equal(t, "https://signed-url/focused/1/text/teacher/intro.json", details[0].Url)
+	assert.True(t, details[1].Locked)
+	assert.Empty(t, details[1].Url)
+}
+
 func TestCourseContentController_UpdateUserContentStatus_Success(t *testing.T) {
 	userID := "student-123"
 	inputJSON := `{"content_id":"content-abc"}`
@@ -1027,3 +1200,345 @@ func TestCourseContentController_UpdateUserContentStatus_AllCases(t *testing.T)
 		})
 	}
 }
+
+func TestCourseContentController_UpdateContent_InvalidQuiz(t *testing.T) {
+	inputJSON := `{
+		"course_id": 1,
+		"content_id": "content-123",
+		"json_data": {"questions":[
+			{"id":"q1","type":"multiple","question":"2+2","points":1,"options":["3","4"],"correctAnswer":4},
+			{"id":"q1","type":"essay","question":"Explique","points":2}
+		]}
+	}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/course-content", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
+	e.Validator = &CustomValidator{Validator: validator.New()}
+
+	mockRepo := MockCourseContentRepo{
+		GetCourseIDByContentIDT: func(contentID string) (int, error) {
+			return 1, nil
+		},
+		GetContentTypeIDT: func(contentID string) (int, error) {
+			return 3, nil
+		},
+	}
+
+	controller := controller.CourseContentController{
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		Repo: mockRepo,
+		UploadQuizFunc: func(courseID, contentID string, json []byte) error {
+			assert.Fail(t, "no se debe subir un quiz inválido a R2")
+			return nil
+		},
+	}
+
+	err := controller.UpdateContent()(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+
+	body, err := json.Marshal(httpErr.Message)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"questions[0].correctAnswer"`)
+	assert.Contains(t, string(body), `"questions[1].id"`)
+	assert.Contains(t, string(body), `"questions[1].type"`)
+}
+
+func newReorderContext(path, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = &CustomValidator{Validator: validator.New()}
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
+	return c, rec
+}
+
+func TestCourseContentController_ReorderModules(t *testing.T) {
+	courseRepo := MockCourseRepo{
+		GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+			if courseID != 1 {
+				return domain.CourseDB{}, errors.New("record not found")
+			}
+			return domain.CourseDB{}, nil
+		},
+	}
+	ctrl := controller.CourseContentController{
+		RepoCourse: courseRepo,
+		Repo: MockCourseContentRepo{
+			ReorderModulesT: func(courseID int, courseContentIDs []int) error {
+				if len(courseContentIDs) < 3 {
+					return fmt.Errorf("%w: the order must include every module of the course exactly once", domain.ErrValidationFailed)
+				}
+				assert.Equal(t, []int{3, 1, 2}, courseContentIDs)
+				return nil
+			},
+		},
+	}
+
+	c, rec := newReorderContext("/course-content/module-order", `{"course_id":1,"course_content_ids":[3,1,2]}`)
+	require.NoError(t, ctrl.ReorderModules()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Body":{"message":"Módulos reordenados"}}`, rec.Body.String())
+
+	// Orden incompleto
+	c, _ = newReorderContext("/course-content/module-order", `{"course_id":1,"course_content_ids":[3,1]}`)
+	err := ctrl.ReorderModules()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+
+	// Índices repetidos
+	c, _ = newReorderContext("/course-content/module-order", `{"course_id":1,"course_content_ids":[3,3,2]}`)
+	assert.Error(t, ctrl.ReorderModules()(c))
+
+	// Curso de otro profesor
+	c, _ = newReorderContext("/course-content/module-order", `{"course_id":2,"course_content_ids":[3,1,2]}`)
+	err = ctrl.ReorderModules()(c)
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
+
+func TestCourseContentController_ReorderSections(t *testing.T) {
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			VerifyModuleOwnershipT: func(courseContentID int, userID string) error {
+				if courseContentID != 1 {
+					return errors.New("module does not belong to the teacher's course")
+				}
+				return nil
+			},
+			ReorderSectionsT: func(courseContentID int, contentIDs []string) error {
+				if contentIDs[0] == "other" {
+					return fmt.Errorf("%w: section other does not belong to the course", domain.ErrValidationFailed)
+				}
+				assert.Equal(t, []string{"b", "x", "a"}, contentIDs)
+				return nil
+			},
+		},
+	}
+
+	c, rec := newReorderContext("/course-content/section-order", `{"course_content_id":1,"content_ids":["b","x","a"]}`)
+	require.NoError(t, ctrl.ReorderSections()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Body":{"message":"Secciones reordenadas"}}`, rec.Body.String())
+
+	c, _ = newReorderContext("/course-content/section-order", `{"course_content_id":1,"content_ids":["other"]}`)
+	err := ctrl.ReorderSections()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Contains(t, httpErr.Message, "section other does not belong to the course")
+
+	c, _ = newReorderContext("/course-content/section-order", `{"course_content_id":2,"content_ids":["a"]}`)
+	err = ctrl.ReorderSections()(c)
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
+
+func newDeleteContext(path, paramName, paramValue string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, path, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames(paramName)
+	c.SetParamValues(paramValue)
+	c.Set("user_id", "teacher-123")
+	return c, rec
+}
+
+func TestCourseContentController_DeleteSection(t *testing.T) {
+	var deletedPrefixes []string
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			DeleteSectionT: func(contentID, userID string, force bool) (domain.DeletedContent, error) {
+				assert.Equal(t, "teacher-123", userID)
+				deleted := domain.DeletedContent{CourseID: 7, ContentIDs: []string{contentID}, Attempts: 2}
+				if !force {
+					return deleted, fmt.Errorf("%w: 2 attempts", domain.ErrContentHasAttempts)
+				}
+				deleted.AnswerURLs = []string{"https://test-account.r2.cloudflarestorage.com/focused/test-account/quiz/answer/student-1/quiz-1/1700000000000.json"}
+				return deleted, nil
+			},
+		},
+		DeleteStorageFunc: func(prefix string) error {
+			deletedPrefixes = append(deletedPrefixes, prefix)
+			if strings.Contains(prefix, "/text/") {
+				return errors.New("r2 unavailable")
+			}
+			return nil
+		},
+	}
+
+	// Con intentos se pide confirmación y no se toca R2
+	c, _ := newDeleteContext("/course-content/section/quiz-1", "contentId", "quiz-1")
+	err := ctrl.DeleteSection()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+	assert.Equal(t, int64(2), httpErr.Message.(map[string]interface{})["attempts"])
+	assert.Empty(t, deletedPrefixes)
+
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newDeleteContext("/course-content/section/quiz-1?force=true", "contentId", "quiz-1")
+	require.NoError(t, ctrl.DeleteSection()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, deletedPrefixes, "focused/7/quiz/teacher/quiz-1.json")
+	assert.Contains(t, deletedPrefixes, "focused/7/quiz/teacher/quiz-1/")
+	assert.Contains(t, deletedPrefixes, "focused/7/quiz/student/quiz-1.json")
+	assert.Contains(t, deletedPrefixes, "focused/7/text/teacher/quiz-1.json")
+	assert.Contains(t, deletedPrefixes, "focused/test-account/quiz/answer/student-1/quiz-1/1700000000000.json")
+	assert.NotContains(t, deletedPrefixes, "focused/7/video/teacher/quiz-1.json")
+	// Un fallo en R2 se informa sin revertir el borrado
+	assert.JSONEq(t, `{"Body":{"message":"Sección eliminada","content_ids":["quiz-1"],"deleted_attempts":2,
+		"storage_errors":["focused/7/text/teacher/quiz-1.json"]}}`, rec.Body.String())
+}
+
+func TestCourseContentController_DeleteModule(t *testing.T) {
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			DeleteModuleT: func(courseContentID int, userID string, force bool) (domain.DeletedContent, error) {
+				if courseContentID != 4 {
+					return domain.DeletedContent{}, fmt.Errorf("%w: module %d not found in the teacher's courses", domain.ErrResourceNotFound, courseContentID)
+				}
+				return domain.DeletedContent{CourseID: 7, ContentIDs: []string{"c1", "c2"}}, nil
+			},
+		},
+		DeleteStorageFunc: func(prefix string) error { return nil },
+	}
+
+	c, rec := newDeleteContext("/course-content/module/4", "courseContentId", "4")
+	require.NoError(t, ctrl.DeleteModule()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Body":{"message":"Módulo eliminado","content_ids":["c1","c2"],"deleted_attempts":0}}`, rec.Body.String())
+
+	c, rec = newDeleteContext("/course-content/module/5", "courseContentId", "5")
+	require.NoError(t, ctrl.DeleteModule()(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	c, _ = newDeleteContext("/course-content/module/abc", "courseContentId", "abc")
+	err := ctrl.DeleteModule()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestCourseContentController_UpdateUserContentStatus_Locked(t *testing.T) {
+	minScore := 80.0
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			GetContentLocksT: func(contentID, userID string) ([]domain.ContentLock, error) {
+				assert.Equal(t, "final", contentID)
+				assert.Equal(t, "teacher-123", userID)
+				return []domain.ContentLock{{Reason: domain.LockReasonMinScore, RequiredContentID: "quiz", RequiredTitle: "Quiz", MinScore: &minScore}}, nil
+			},
+			UpdateUserContentStatusT: func(uid, contentID string, statusID int) error {
+				t.Fatal("un contenido bloqueado no debe avanzar")
+				return nil
+			},
+		},
+	}
+
+	c, _ := newReorderContext("/course-content/completed", `{"content_id":"final"}`)
+	err := ctrl.UpdateUserContentStatus(3)(c)
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	body, _ := json.Marshal(httpErr.Message)
+	assert.Contains(t, string(body), `"reason":"min_score"`)
+	assert.Contains(t, string(body), `"required_content_id":"quiz"`)
+}
+
+func TestCourseContentController_SetPrerequisites(t *testing.T) {
+	ctrl := controller.CourseContentController{
+		Repo: MockCourseContentRepo{
+			SetPrerequisitesT: func(input domain.SetPrerequisitesInput, userID string) error {
+				if input.ContentID == "otro" {
+					return fmt.Errorf("%w: content otro not found in the teacher's courses", domain.ErrResourceNotFound)
+				}
+				if len(input.Prerequisites) > 0 && input.Prerequisites[0].RequiredContentID == "despues" {
+					return fmt.Errorf("%w: content despues must come before final", domain.ErrValidationFailed)
+				}
+				return nil
+			},
+		},
+	}
+
+	c, rec := newReorderContext("/course-content/prerequisites", `{"content_id":"final","prerequisites":[{"required_content_id":"quiz","min_score":70}]}`)
+	require.NoError(t, ctrl.SetPrerequisites()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Body":{"message":"Prerrequisitos actualizados"}}`, rec.Body.String())
+
+	var httpErr *echo.HTTPError
+	c, _ = newReorderContext("/course-content/prerequisites", `{"content_id":"final","prerequisites":[{"required_content_id":"despues"}]}`)
+	require.ErrorAs(t, ctrl.SetPrerequisites()(c), &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+
+	c, _ = newReorderContext("/course-content/prerequisites", `{"content_id":"otro","prerequisites":[]}`)
+	require.ErrorAs(t, ctrl.SetPrerequisites()(c), &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+
+	// Un prerrequisito repetido o un puntaje fuera de rango no pasa la validación
+	c, _ = newReorderContext("/course-content/prerequisites", `{"content_id":"final","prerequisites":[{"required_content_id":"quiz"},{"required_content_id":"quiz"}]}`)
+	require.ErrorAs(t, ctrl.SetPrerequisites()(c), &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	c, _ = newReorderContext("/course-content/prerequisites", `{"content_id":"final","prerequisites":[{"required_content_id":"quiz","min_score":120}]}`)
+	require.ErrorAs(t, ctrl.SetPrerequisites()(c), &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestCourseContentController_UpdateContent_ContentOfAnotherCourse(t *testing.T) {
+	inputJSON := `{
+		"course_id": 1,
+		"content_id": "content-999",
+		"json_data": {"title":"Quiz 1","questions":[{"id":"q1","type":"multiple","question":"2+2","points":1,"options":["3","4"],"correctAnswer":"4"}]}
+	}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/course-content", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-123")
+	e.Validator = &CustomValidator{Validator: validator.New()}
+
+	controller := controller.CourseContentController{
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		Repo: MockCourseContentRepo{
+			GetCourseIDByContentIDT: func(contentID string) (int, error) {
+				assert.Equal(t, "content-999", contentID)
+				return 2, nil
+			},
+			UpdateContentT: func(input domain.UpdateContentInput) error {
+				assert.Fail(t, "no se debe modificar un contenido ajeno")
+				return nil
+			},
+		},
+		QuizVersionRepo: mockQuizVersionRepo{
+			CreateQuizVersionFn: func(version domain.QuizVersion) (int, error) {
+				assert.Fail(t, "no se debe crear una versión para un contenido ajeno")
+				return 0, nil
+			},
+		},
+	}
+
+	err := controller.UpdateContent()(c)
+	require.NoError(t, err)
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "El contenido no pertenece a este curso")
+}
@@ -2,6 +2,7 @@ package controller_test
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -384,3 +385,84 @@ func TestGetCoursesByTeacher_RepoError(t *testing.T) {
 		}
 	}
 }
+
+func TestCloneStorageKey(t *testing.T) {
+	clone := domain.CourseClone{SourceCourseID: 12, CourseID: 40, ContentIDs: map[string]string{"quiz-1": "quiz-9", "text-1": "text-9"}}
+
+	assert.Equal(t, "focused/40/quiz/teacher/quiz-9/v2.json", controller.CloneStorageKey(clone, "focused/12/quiz/teacher/quiz-1/v2.json"))
+	assert.Equal(t, "https://acc.r2.cloudflarestorage.com/focused/40/text/teacher/text-9.json",
+		controller.CloneStorageKey(clone, "https://acc.r2.cloudflarestorage.com/focused/12/text/teacher/text-1.json"))
+	// Solo se reemplazan segmentos completos
+	assert.Equal(t, "focused/120/quiz/teacher/quiz-10.json", controller.CloneStorageKey(clone, "focused/120/quiz/teacher/quiz-10.json"))
+	assert.Equal(t, "https://youtu.be/abc", controller.CloneStorageKey(clone, "https://youtu.be/abc"))
+}
+
+func TestCourseController_CloneCourse(t *testing.T) {
+	copies := map[string]string{}
+	ctrl := controller.CourseController{
+		ContentRepo: MockCourseContentRepo{
+			CloneCourseT: func(request domain.CloneCourseRequest) (domain.CourseClone, error) {
+				if request.SourceCourseID != 12 {
+					return domain.CourseClone{}, fmt.Errorf("%w: course %d not found in the teacher's courses", domain.ErrResourceNotFound, request.SourceCourseID)
+				}
+				assert.Equal(t, "teacher-123", request.TeacherID)
+				assert.Equal(t, "Go 2025", request.Title)
+				assert.True(t, request.ExcludeAttempts)
+				assert.NotEmpty(t, request.QRCode)
+				return domain.CourseClone{
+					SourceCourseID: 12,
+					CourseID:       40,
+					QRCode:         request.QRCode,
+					ModuleIDs:      map[int]int{1: 5},
+					ContentIDs:     map[string]string{"quiz-1": "quiz-9"},
+					Enrollments:    3,
+				}, nil
+			},
+		},
+		ListStorageFunc: func(prefix string) ([]string, error) {
+			assert.Equal(t, "focused/12/", prefix)
+			return []string{"focused/12/quiz/teacher/quiz-1.json", "focused/12/quiz/variant/quiz-1/s1.json", "focused/12/video/teacher/broken.mp4"}, nil
+		},
+		CopyStorageFunc: func(sourceKey, targetKey string) error {
+			if strings.HasSuffix(sourceKey, ".mp4") {
+				return errors.New("copy failed")
+			}
+			copies[sourceKey] = targetKey
+			return nil
+		},
+	}
+
+	newCloneContext := func(courseID, body string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		e.Validator = &CustomValidator{Validator: validator.New()}
+		req := httptest.NewRequest(http.MethodPost, "/course/"+courseID+"/clone", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("courseId")
+		c.SetParamValues(courseID)
+		c.Set("user_id", "teacher-123")
+		return c, rec
+	}
+
+	c, rec := newCloneContext("12", `{"title":"Go 2025","exclude_attempts":true}`)
+	if assert.NoError(t, ctrl.CloneCourse()(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"course_id":40`)
+		assert.Contains(t, rec.Body.String(), `"enrollments":3`)
+		assert.Contains(t, rec.Body.String(), `"storage_errors":["focused/12/video/teacher/broken.mp4"]`)
+	}
+	// Sin intentos no se copian las variantes de los estudiantes
+	assert.Equal(t, map[string]string{"focused/12/quiz/teacher/quiz-1.json": "focused/40/quiz/teacher/quiz-9.json"}, copies)
+
+	c, rec = newCloneContext("99", `{}`)
+	if assert.NoError(t, ctrl.CloneCourse()(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	c, _ = newCloneContext("abc", `{}`)
+	var httpErr *echo.HTTPError
+	if assert.ErrorAs(t, ctrl.CloneCourse()(c), &httpErr) {
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package controller_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type mockGradebookRepo struct {
+	GetGradebookFn      func(courseID int) (domain.Gradebook, error)
+	SaveGradebookFn     func(gradebook domain.Gradebook) error
+	GetCourseProgressFn func(courseID int) ([]domain.StudentCourseProgress, error)
+}
+
+func (m mockGradebookRepo) GetGradebook(courseID int) (domain.Gradebook, error) {
+	if m.GetGradebookFn != nil {
+		return m.GetGradebookFn(courseID)
+	}
+	return domain.Gradebook{}, gorm.ErrRecordNotFound
+}
+
+func (m mockGradebookRepo) SaveGradebook(gradebook domain.Gradebook) error {
+	if m.SaveGradebookFn != nil {
+		return m.SaveGradebookFn(gradebook)
+	}
+	return nil
+}
+
+func (m mockGradebookRepo) GetCourseProgress(courseID int) ([]domain.StudentCourseProgress, error) {
+	if m.GetCourseProgressFn != nil {
+		return m.GetCourseProgressFn(courseID)
+	}
+	return nil, nil
+}
+
+func gradebookAttempt(userID, contentID string, grade float64, total int, end time.Time) domain.QuizAttemptView {
+	return domain.QuizAttemptView{UserID: userID, ContentID: contentID, Grade: &grade, TotalPoints: &total, StartTime: end.Add(-time.Minute), EndTime: end}
+}
+
+func TestBuildGradebook_AttemptPoliciesAndWeights(t *testing.T) {
+	day := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	columns := []domain.GradebookColumn{{ContentID: "quiz-1", Title: "Quiz 1"}, {ContentID: "quiz-2", Title: "Quiz 2"}, {ContentID: "exam", Title: "Examen"}}
+	students := []domain.AssignmentWithStudent{{UserID: "s1", Name: "Ana", Lastname: "Zapata"}, {UserID: "s2", Name: "Luis", Lastname: "Arias"}}
+	attempts := []domain.QuizAttemptView{
+		gradebookAttempt("s1", "quiz-1", 4, 10, day),
+		gradebookAttempt("s1", "quiz-1", 8, 10, day.Add(time.Hour)),
+		gradebookAttempt("s1", "quiz-1", 6, 10, day.Add(2*time.Hour)),
+		gradebookAttempt("s1", "quiz-2", 10, 10, day),
+		gradebookAttempt("s1", "exam", 45, 50, day),
+		gradebookAttempt("s2", "quiz-1", 5, 10, day),
+	}
+	progress := []domain.StudentCourseProgress{{UserID: "s1", CompletionPercentage: 50}, {UserID: "s2", CompletionPercentage: 100}}
+
+	gradebook := domain.Gradebook{
+		AttemptPolicy: domain.AttemptPolicyBest,
+		PassThreshold: 70,
+		Categories: []domain.GradebookCategory{
+			{ID: "tareas", Name: "Tareas", Weight: 30, ContentIDs: []string{"quiz-1", "quiz-2"}},
+			{ID: "examen", Name: "Examen", Weight: 60, ContentIDs: []string{"exam"}},
+			{ID: "avance", Name: "Avance", Weight: 10, Source: domain.CategorySourceProgress},
+		},
+		LetterScale: []domain.LetterGrade{{Letter: "F", MinPercent: 0}, {Letter: "A", MinPercent: 85}, {Letter: "B", MinPercent: 70}},
+	}
+
+	report := controller.BuildGradebook(gradebook, columns, students, attempts, progress)
+	require.Len(t, report.Rows, 2)
+	assert.Equal(t, "s2", report.Rows[0].UserID, "ordenado por apellido")
+	assert.Equal(t, "examen", report.Columns[2].CategoryID)
+
+	ana := report.Rows[1]
+	assert.InDelta(t, 80, *ana.Quizzes["quiz-1"].Percent, 1e-9)
+	assert.Equal(t, 3, ana.Quizzes["quiz-1"].Attempts)
+	assert.InDelta(t, 90, *ana.Categories["tareas"], 1e-9)
+	// 0.3*90 + 0.6*90 + 0.1*50
+	assert.InDelta(t, 86, *ana.Final, 1e-9)
+	assert.Equal(t, "A", ana.Letter)
+	assert.True(t, ana.Passed)
+
+	// Luis no tiene examen: el peso se reparte entre tareas y avance
+	luis := report.Rows[0]
+	assert.Nil(t, luis.Categories["examen"])
+	assert.Nil(t, luis.Quizzes["exam"].Percent)
+	assert.InDelta(t, (0.3*50+0.1*100)/0.4, *luis.Final, 1e-9)
+	assert.False(t, luis.Passed)
+
+	gradebook.AttemptPolicy = domain.AttemptPolicyLatest
+	report = controller.BuildGradebook(gradebook, columns, students, attempts, progress)
+	assert.InDelta(t, 60, *report.Rows[1].Quizzes["quiz-1"].Percent, 1e-9)
+
+	gradebook.AttemptPolicy = domain.AttemptPolicyAverage
+	report = controller.BuildGradebook(gradebook, columns, students, attempts, progress)
+	assert.InDelta(t, 60, *report.Rows[1].Quizzes["quiz-1"].Percent, 1e-9)
+}
+
+func TestBuildGradebook_DefaultCategoryAndCSV(t *testing.T) {
+	columns := []domain.GradebookColumn{{ContentID: "quiz-1", Title: "Quiz, parte 1"}}
+	students := []domain.AssignmentWithStudent{
+		{UserID: "s1", Name: "Ana", Lastname: "Zapata", Email: "ana@example.com"},
+		{UserID: "s2", Name: "=HYPERLINK(\"http://x\")", Lastname: "Zz", Email: "@evil"},
+	}
+	attempts := []domain.QuizAttemptView{gradebookAttempt("s1", "quiz-1", 3, 4, time.Now())}
+
+	report := controller.BuildGradebook(domain.Gradebook{AttemptPolicy: domain.AttemptPolicyBest, PassThreshold: 60,
+		LetterScale: []domain.LetterGrade{{Letter: "Aprobado", MinPercent: 60}, {Letter: "Reprobado", MinPercent: 0}}},
+		columns, students, attempts, nil)
+	require.Len(t, report.Settings.Categories, 1)
+	assert.InDelta(t, 75, *report.Rows[0].Final, 1e-9)
+
+	csvBytes, err := controller.GradebookCSV(report)
+	require.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(string(csvBytes))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"user_id", "lastname", "name", "email", "Quiz, parte 1", "Quizzes", "progress", "final", "letter", "passed"}, records[0])
+	assert.Equal(t, []string{"s1", "Zapata", "Ana", "ana@example.com", "75.00", "75.00", "0.00", "75.00", "Aprobado", "true"}, records[1])
+	assert.Equal(t, "'=HYPERLINK(\"http://x\")", records[2][2])
+	assert.Equal(t, "'@evil", records[2][3])
+}
+
+func gradebookController(saved *domain.Gradebook) controller.GradebookController {
+	return controller.GradebookController{
+		GradebookRepo: mockGradebookRepo{SaveGradebookFn: func(gradebook domain.Gradebook) error {
+			*saved = gradebook
+			return nil
+		}},
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				if courseID != 10 {
+					return domain.CourseDB{}, errors.New("record not found")
+				}
+				return domain.CourseDB{CourseID: 10, TeacherID: teacherID}, nil
+			},
+		},
+		AssignmentRepo: &MockAssignmentRepo{GetStudentsByC: func(courseID int) ([]domain.AssignmentWithStudent, error) {
+			return []domain.AssignmentWithStudent{{UserID: "s1", Name: "Ana", Lastname: "Zapata"}}, nil
+		}},
+		CourseContentRepo: MockCourseContentRepo{GetContentByCourseT: func(courseID int) ([]domain.CourseContentWithDetails, error) {
+			return []domain.CourseContentWithDetails{{
+				CourseContentDB: domain.CourseContentDB{Module: "Módulo 1"},
+				Details: []domain.Content{
+					{ContentID: "video-1", ContentTypeID: 1, Title: "Video"},
+					{ContentID: "quiz-1", ContentTypeID: 3, Title: "Quiz 1", SectionIndex: 1},
+				},
+			}}, nil
+		}},
+		QuizRepo: mockQuizRepo{GetQuizAttemptsByCourseFn: func(courseID int) ([]domain.QuizAttemptView, error) {
+			return []domain.QuizAttemptView{gradebookAttempt("s1", "quiz-1", 9, 10, time.Now())}, nil
+		}},
+	}
+}
+
+func TestGradebookController_UpdateSettings(t *testing.T) {
+	var saved domain.Gradebook
+	ctrl := gradebookController(&saved)
+
+	body, _ := json.Marshal(domain.GradebookInput{
+		AttemptPolicy: domain.AttemptPolicyLatest,
+		PassThreshold: 65,
+		Categories:    []domain.GradebookCategory{{ID: "quizzes", Name: "Quizzes", Weight: 1, ContentIDs: []string{"quiz-1"}}},
+	})
+	c, rec := newTeacherContext(http.MethodPut, "/courses/10/gradebook/settings", body)
+	c.SetParamNames("courseId")
+	c.SetParamValues("10")
+	require.NoError(t, ctrl.UpdateGradebookSettings()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 10, saved.CourseID)
+	assert.Equal(t, domain.AttemptPolicyLatest, saved.AttemptPolicy)
+
+	body, _ = json.Marshal(domain.GradebookInput{
+		AttemptPolicy: domain.AttemptPolicyBest,
+		Categories: []domain.GradebookCategory{
+			{ID: "a", Name: "A", Weight: 1, ContentIDs: []string{"quiz-1", "video-1"}},
+			{ID: "b", Name: "B", Weight: 1, ContentIDs: []string{"quiz-1"}},
+		},
+		LetterScale: []domain.LetterGrade{{Letter: "A", MinPercent: 90}, {Letter: "B", MinPercent: 60}},
+	})
+	c, _ = newTeacherContext(http.MethodPut, "/courses/10/gradebook/settings", body)
+	c.SetParamNames("courseId")
+	c.SetParamValues("10")
+	err := ctrl.UpdateGradebookSettings()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	errBody, _ := json.Marshal(httpErr.Message)
+	assert.Contains(t, string(errBody), "categories[0].content_ids[1]")
+	assert.Contains(t, string(errBody), "categories[1].content_ids[0]")
+	assert.Contains(t, string(errBody), "min_percent 0")
+}
+
+func TestGradebookController_GetAndExport(t *testing.T) {
+	var saved domain.Gradebook
+	ctrl := gradebookController(&saved)
+
+	c, rec := newTeacherContext(http.MethodGet, "/courses/10/gradebook", nil)
+	c.SetParamNames("courseId")
+	c.SetParamValues("10")
+	require.NoError(t, ctrl.GetGradebook()(c))
+	var report domain.GradebookReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Columns, 1)
+	assert.Equal(t, "A", report.Rows[0].Letter)
+
+	c, rec = newTeacherContext(http.MethodGet, "/courses/10/gradebook/export", nil)
+	c.SetParamNames("courseId")
+	c.SetParamValues("10")
+	require.NoError(t, ctrl.ExportGradebook()(c))
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "gradebook-10.csv")
+	assert.Contains(t, rec.Body.String(), "s1,Zapata,Ana,,90.00")
+
+	c, _ = newTeacherContext(http.MethodGet, "/courses/11/gradebook", nil)
+	c.SetParamNames("courseId")
+	c.SetParamValues("11")
+	err := ctrl.GetGradebook()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
@@ -0,0 +1,286 @@
+package controller_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func liveQuiz() domain.TeacherQuiz {
+	return domain.TeacherQuiz{
+		Title: "Repaso en clase",
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "multiple", Question: "¿2+2?", Points: 2, Options: []string{"3", "4", "5"}, CorrectAnswer: "4"},
+			{ID: "q2", Type: "boolean", Question: "¿El agua hierve a 100°C?", Points: 1, CorrectAnswer: true},
+		},
+	}
+}
+
+func TestLiveQuizSession_AnswersAndLeaderboard(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	session := controller.NewLiveQuizSession(123, "quiz-1", "teacher-1", liveQuiz(), start)
+
+	_, err := session.SubmitAnswer("ana", "Ana", "q1", "4", start)
+	assert.EqualError(t, err, "la pregunta no acepta respuestas")
+
+	question, err := session.NextQuestion(start)
+	require.NoError(t, err)
+	assert.Equal(t, "q1", question.ID)
+
+	grade, err := session.SubmitAnswer("ana", "Ana", "q1", "4", start.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.True(t, grade.IsCorrect)
+	_, err = session.SubmitAnswer("ana", "Ana", "q1", "3", start.Add(6*time.Second))
+	assert.EqualError(t, err, "ya respondió esta pregunta")
+	_, err = session.SubmitAnswer("beto", "Beto", "q2", true, start.Add(time.Second))
+	assert.Error(t, err, "solo se responde la pregunta actual")
+
+	_, err = session.SubmitAnswer("beto", "Beto", "q1", "4", start.Add(2*time.Second))
+	require.NoError(t, err)
+	_, err = session.SubmitAnswer("carla", "Carla", "q1", "5", start.Add(time.Second))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, session.Answered())
+	assert.Equal(t, []domain.LiveQuizBucket{{Label: "3", Count: 0}, {Label: "4", Count: 2}, {Label: "5", Count: 1}}, session.Histogram())
+
+	// A igual puntaje gana quien respondió más rápido
+	leaderboard := session.Leaderboard()
+	require.Len(t, leaderboard, 3)
+	assert.Equal(t, domain.LiveQuizLeaderboardEntry{Rank: 1, UserID: "beto", Name: "Beto", Score: 2, Correct: 1, Answered: 1}, leaderboard[0])
+	assert.Equal(t, "ana", leaderboard[1].UserID)
+	assert.Equal(t, "carla", leaderboard[2].UserID)
+
+	_, err = session.CloseQuestion()
+	require.NoError(t, err)
+	_, err = session.SubmitAnswer("dani", "Dani", "q1", "4", start.Add(10*time.Second))
+	assert.EqualError(t, err, "la pregunta no acepta respuestas")
+
+	_, err = session.NextQuestion(start.Add(20 * time.Second))
+	require.NoError(t, err)
+	_, err = session.SubmitAnswer("ana", "Ana", "q2", "maybe", start.Add(21*time.Second))
+	assert.Error(t, err, "la respuesta se valida según el tipo de pregunta")
+	_, err = session.NextQuestion(start.Add(30 * time.Second))
+	assert.EqualError(t, err, "no quedan preguntas")
+}
+
+func TestQuizController_PersistLiveQuiz(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	session := controller.NewLiveQuizSession(123, "quiz-1", "teacher-1", liveQuiz(), start)
+	session.QuizURL = "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/quiz-1.json"
+
+	_, err := session.NextQuestion(start)
+	require.NoError(t, err)
+	_, err = session.SubmitAnswer("ana", "Ana", "q1", "4", start.Add(time.Second))
+	require.NoError(t, err)
+	_, err = session.SubmitAnswer("beto", "Beto", "q1", "3", start.Add(time.Second))
+	require.NoError(t, err)
+
+	uploads := map[string]string{}
+	var attempts []domain.QuizAnswer
+	var items [][]domain.QuizAnswerItem
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			SaveQuizAttemptFn: func(input domain.QuizAnswer) error {
+				attempts = append(attempts, input)
+				return nil
+			},
+			SaveQuizAnswerItemsFn: func(saved []domain.QuizAnswerItem) error {
+				items = append(items, saved)
+				return nil
+			},
+		},
+		UploadStudentAnswers: func(key string, data []byte) error {
+			uploads[key] = string(data)
+			return nil
+		},
+	}
+
+	saved, err := ctrl.PersistLiveQuiz(session, end)
+	require.NoError(t, err)
+	assert.Equal(t, 2, saved)
+
+	// Solo se califica la pregunta que se llegó a enviar
+	variantKey := "focused/123/quiz/variant/quiz-1/live-1714557600.json"
+	require.Contains(t, uploads, variantKey)
+	assert.Contains(t, uploads[variantKey], `"q1"`)
+	assert.NotContains(t, uploads[variantKey], `"q2"`)
+	assert.Contains(t, uploads, "focused/123/quiz/live/quiz-1/1714557600/ana.json")
+
+	require.Len(t, attempts, 2)
+	ana, beto := attempts[0], attempts[1]
+	assert.Equal(t, "ana", ana.UserID)
+	assert.Equal(t, "https://test-account.r2.cloudflarestorage.com/"+variantKey, ana.VariantURL)
+	require.NotNil(t, ana.Grade)
+	assert.Equal(t, 2.0, *ana.Grade)
+	assert.Equal(t, 2, *ana.TotalPoints)
+	assert.NotNil(t, ana.ReviewedAt)
+	assert.Equal(t, 0.0, *beto.Grade)
+	require.Len(t, items, 2)
+	assert.Len(t, items[0], 1)
+
+	// La sesión terminada ya no acepta respuestas
+	_, err = session.SubmitAnswer("carla", "Carla", "q1", "4", end)
+	assert.EqualError(t, err, "el quiz en vivo ya terminó")
+}
+
+// setupLiveQuizServer levanta un servidor WebSocket donde el usuario se toma de la ruta
+func setupLiveQuizServer(t *testing.T, quiz domain.TeacherQuiz, saved *[]domain.QuizAnswer) (*httptest.Server, string, *controller.ConnectionManager) {
+	t.Helper()
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	cm := controller.NewConnectionManager(&MockSessionRepo{}, &MockParentalConsentRepo{})
+	cm.LiveQuiz = &controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			SaveQuizAttemptFn: func(input domain.QuizAnswer) error {
+				*saved = append(*saved, input)
+				return nil
+			},
+		},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		AssignmentRepo:    mockAssignmentRepo{},
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				if teacherID != "teacher-1" && teacherID != "teacher-2" {
+					return domain.CourseDB{}, errors.New("record not found")
+				}
+				return domain.CourseDB{}, nil
+			},
+		},
+		UploadStudentAnswers:  func(key string, data []byte) error { return nil },
+		GetTeacherQuizContent: mockGetFromR2(t, quiz),
+	}
+
+	e := echo.New()
+	e.GET("/ws/:user", cm.WebSocketHandler(), func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", c.Param("user"))
+			return next(c)
+		}
+	})
+	server := httptest.NewServer(e)
+	return server, "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/", cm
+}
+
+// readLiveQuizMessage lee mensajes hasta encontrar uno del tipo indicado
+func readLiveQuizMessage(t *testing.T, conn *websocket.Conn, msgType string) map[string]interface{} {
+	t.Helper()
+	for {
+		msg := readJSONMessage(t, conn)
+		if msg["type"] == msgType {
+			return msg
+		}
+		require.NotEqual(t, "error", msg["type"], "unexpected error message: %v", msg)
+	}
+}
+
+func TestWebSocketHandler_LiveQuizFlow(t *testing.T) {
+	var saved []domain.QuizAnswer
+	server, wsURL, _ := setupLiveQuizServer(t, liveQuiz(), &saved)
+	defer server.Close()
+
+	teacher := connectWebSocketNoCleanup(t, wsURL+"teacher-1", "web", "123")
+	defer teacher.Close()
+	readLiveQuizMessage(t, teacher, "status_update")
+	student := connectWebSocketNoCleanup(t, wsURL+"student-123", "web", "123")
+	defer student.Close()
+	readLiveQuizMessage(t, student, "status_update")
+
+	// Un estudiante no puede dirigir el quiz
+	require.NoError(t, student.WriteJSON(map[string]string{"type": "live_quiz_start", "content_id": "quiz-1"}))
+	errMsg := readJSONMessage(t, student)
+	assert.Equal(t, "error", errMsg["type"])
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_start", "content_id": "quiz-1"}))
+	started := readLiveQuizMessage(t, student, "live_quiz_started")
+	assert.Equal(t, float64(2), started["total_questions"])
+	readLiveQuizMessage(t, teacher, "live_quiz_started")
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_next"}))
+	question := readLiveQuizMessage(t, student, "live_quiz_question")
+	q := question["question"].(map[string]interface{})
+	assert.Equal(t, "q1", q["id"])
+	assert.NotContains(t, q, "correctAnswer", "los estudiantes no reciben la respuesta")
+	readLiveQuizMessage(t, teacher, "live_quiz_question")
+
+	require.NoError(t, student.WriteJSON(map[string]string{"type": "live_quiz_answer", "question_id": "q1", "answer": "4"}))
+	readLiveQuizMessage(t, student, "live_quiz_answer_received")
+	update := readLiveQuizMessage(t, teacher, "live_quiz_update")
+	assert.Equal(t, float64(1), update["answered"])
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_close"}))
+	closed := readLiveQuizMessage(t, student, "live_quiz_question_closed")
+	leaderboard := closed["leaderboard"].([]interface{})
+	require.Len(t, leaderboard, 1)
+	assert.Equal(t, "student-123", leaderboard[0].(map[string]interface{})["user_id"])
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_end"}))
+	ended := readLiveQuizMessage(t, teacher, "live_quiz_ended")
+	assert.Equal(t, float64(1), ended["saved_attempts"])
+	require.Len(t, saved, 1)
+	assert.Equal(t, "student-123", saved[0].UserID)
+	assert.Equal(t, 2.0, *saved[0].Grade)
+}
+
+func TestWebSocketHandler_LiveQuizOtherOwnerEnds(t *testing.T) {
+	var saved []domain.QuizAnswer
+	server, wsURL, _ := setupLiveQuizServer(t, liveQuiz(), &saved)
+	defer server.Close()
+
+	teacher := connectWebSocketNoCleanup(t, wsURL+"teacher-1", "web", "123")
+	defer teacher.Close()
+	readLiveQuizMessage(t, teacher, "status_update")
+	owner := connectWebSocketNoCleanup(t, wsURL+"teacher-2", "web", "123")
+	defer owner.Close()
+	readLiveQuizMessage(t, owner, "status_update")
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_start", "content_id": "quiz-1"}))
+	readLiveQuizMessage(t, owner, "live_quiz_started")
+
+	// Otro dueño del curso también puede dirigir y terminar el quiz
+	require.NoError(t, owner.WriteJSON(map[string]string{"type": "live_quiz_next"}))
+	readLiveQuizMessage(t, teacher, "live_quiz_question")
+	require.NoError(t, owner.WriteJSON(map[string]string{"type": "live_quiz_end"}))
+	readLiveQuizMessage(t, teacher, "live_quiz_ended")
+}
+
+func TestWebSocketHandler_LiveQuizEndsWhenTeacherLeaves(t *testing.T) {
+	var saved []domain.QuizAnswer
+	server, wsURL, cm := setupLiveQuizServer(t, liveQuiz(), &saved)
+	defer server.Close()
+	cm.LiveQuizTeacherGrace = 50 * time.Millisecond
+
+	teacher := connectWebSocketNoCleanup(t, wsURL+"teacher-1", "web", "123")
+	readLiveQuizMessage(t, teacher, "status_update")
+	student := connectWebSocketNoCleanup(t, wsURL+"student-123", "web", "123")
+	defer student.Close()
+	readLiveQuizMessage(t, student, "status_update")
+
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_start", "content_id": "quiz-1"}))
+	readLiveQuizMessage(t, student, "live_quiz_started")
+	require.NoError(t, teacher.WriteJSON(map[string]string{"type": "live_quiz_next"}))
+	readLiveQuizMessage(t, student, "live_quiz_question")
+	require.NoError(t, student.WriteJSON(map[string]string{"type": "live_quiz_answer", "question_id": "q1", "answer": "4"}))
+	readLiveQuizMessage(t, student, "live_quiz_answer_received")
+
+	// Sin profesor conectado el quiz termina solo y se guardan los intentos
+	require.NoError(t, teacher.Close())
+	ended := readLiveQuizMessage(t, student, "live_quiz_ended")
+	assert.Equal(t, float64(1), ended["saved_attempts"])
+
+	require.NoError(t, student.WriteJSON(map[string]string{"type": "live_quiz_answer", "question_id": "q1", "answer": "4"}))
+	errMsg := readLiveQuizMessage(t, student, "error")
+	assert.Equal(t, "No hay un quiz en vivo en este curso", errMsg["error"])
+}
@@ -0,0 +1,191 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analysisItem(attemptID int, questionID string, answer interface{}, points int, score float64) domain.QuizAnswerItem {
+	encoded, _ := json.Marshal(answer)
+	isCorrect := score == float64(points)
+	return domain.QuizAnswerItem{
+		QuizAnswerID: attemptID,
+		QuestionID:   questionID,
+		Answer:       string(encoded),
+		Points:       points,
+		Score:        &score,
+		IsCorrect:    &isCorrect,
+	}
+}
+
+func TestAnalyzeQuizItems(t *testing.T) {
+	quiz := domain.TeacherQuiz{Title: "Álgebra", Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "multiple", Points: 2, Options: []string{"A", "B", "C"}, CorrectAnswer: "A"},
+		{ID: "q2", Type: "boolean", Points: 2, CorrectAnswer: true},
+	}}
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	total := 4
+	attempts := make([]domain.QuizAttemptView, 0, 4)
+	items := make(map[int][]domain.QuizAnswerItem)
+	// Notas 4, 2, 2, 0: q1 la aciertan los dos mejores; q2 solo el mejor y uno del medio
+	answers := []struct {
+		q1 string
+		q2 bool
+	}{{"A", true}, {"A", false}, {"B", true}, {"C", false}}
+	for i, a := range answers {
+		id := i + 1
+		q1Score, q2Score := 0.0, 0.0
+		if a.q1 == "A" {
+			q1Score = 2
+		}
+		if a.q2 {
+			q2Score = 2
+		}
+		grade := q1Score + q2Score
+		attempts = append(attempts, domain.QuizAttemptView{
+			QuizAnswerID: id,
+			UserID:       fmt.Sprintf("s%d", id),
+			Grade:        &grade,
+			TotalPoints:  &total,
+			StartTime:    start,
+			EndTime:      start.Add(time.Duration(id) * time.Minute),
+		})
+		items[id] = []domain.QuizAnswerItem{
+			analysisItem(id, "q1", a.q1, 2, q1Score),
+			analysisItem(id, "q2", a.q2, 2, q2Score),
+		}
+	}
+
+	analysis := controller.AnalyzeQuizItems(quiz, attempts, items)
+
+	assert.Equal(t, 4, analysis.Attempts)
+	assert.Equal(t, 1, analysis.GroupSize)
+	assert.Equal(t, 2.0, analysis.AverageScore)
+	assert.Equal(t, 150.0, analysis.AverageTimeSeconds)
+	require.Len(t, analysis.Questions, 2)
+
+	q1 := analysis.Questions[0]
+	assert.Equal(t, 2, q1.Correct)
+	assert.InDelta(t, 0.5, *q1.Difficulty, 1e-9)
+	assert.InDelta(t, 1.0, *q1.Discrimination, 1e-9)
+	require.Len(t, q1.Distractors, 3)
+	assert.Equal(t, domain.DistractorStat{Option: "A", IsCorrect: true, Count: 2, Ratio: 0.5}, q1.Distractors[0])
+	assert.Equal(t, 1, q1.Distractors[2].Count)
+
+	q2 := analysis.Questions[1]
+	assert.InDelta(t, 0.5, *q2.Difficulty, 1e-9)
+	assert.InDelta(t, 1.0, *q2.Discrimination, 1e-9)
+	assert.Empty(t, q2.Distractors)
+}
+
+func TestAnalyzeQuizItems_PendingAndUnseenQuestions(t *testing.T) {
+	quiz := domain.TeacherQuiz{Pools: []domain.QuestionPool{{ID: "bank", Draw: 1, Questions: []domain.TeacherQuizQuestion{
+		{ID: "p1", Type: "text", Points: 5},
+		{ID: "p2", Type: "text", Points: 5},
+	}}}}
+	now := time.Now()
+	attempts := []domain.QuizAttemptView{{QuizAnswerID: 1, StartTime: now, EndTime: now}}
+	items := map[int][]domain.QuizAnswerItem{1: {{QuizAnswerID: 1, QuestionID: "p1", Points: 5, Answer: `"texto"`, NeedsReview: true}}}
+
+	analysis := controller.AnalyzeQuizItems(quiz, attempts, items)
+
+	require.Len(t, analysis.Questions, 2)
+	assert.Equal(t, 1, analysis.Questions[0].Pending)
+	assert.Nil(t, analysis.Questions[0].Difficulty)
+	assert.Nil(t, analysis.Questions[0].Discrimination)
+	assert.Equal(t, 0, analysis.Questions[1].Pending+analysis.Questions[1].Responses)
+}
+
+func TestQuizController_GetQuizItemAnalysis(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	base := "https://test-account.r2.cloudflarestorage.com/"
+
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "multiple", Points: 1, Options: []string{"A", "B"}, CorrectAnswer: "A"},
+	}}
+	start := time.Now().Add(-time.Hour)
+	grade := func(v float64) *float64 { return &v }
+	total := 1
+	courseAttempts := []domain.QuizAttemptView{
+		{QuizAnswerID: 1, UserID: "s1", ContentID: "content-quiz-1", Grade: grade(1), TotalPoints: &total, StartTime: start, EndTime: start.Add(time.Minute)},
+		// segundo intento de s1: no cuenta para el análisis
+		{QuizAnswerID: 2, UserID: "s1", ContentID: "content-quiz-1", Grade: grade(0), TotalPoints: &total, StartTime: start.Add(time.Minute * 10), EndTime: start.Add(time.Minute * 11)},
+		// intento anterior a quiz_answer_item: se califica desde R2
+		{QuizAnswerID: 3, UserID: "s2", ContentID: "content-quiz-1", QuizAnswerURL: base + "answers/3.json", Grade: grade(0), TotalPoints: &total, StartTime: start, EndTime: start.Add(time.Minute)},
+		{QuizAnswerID: 4, UserID: "s3", ContentID: "otro-quiz", StartTime: start, EndTime: start.Add(time.Minute)},
+	}
+	// la versión que respondió s2 tenía "B" como respuesta correcta
+	oldQuiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "multiple", Points: 1, Options: []string{"A", "B"}, CorrectAnswer: "B"},
+	}}
+	r2 := map[string]interface{}{
+		"focused/123/quiz/teacher/content-quiz-1.json":    quiz,
+		"focused/123/quiz/teacher/content-quiz-1/v1.json": oldQuiz,
+		"answers/3.json": map[string]interface{}{"q1": "B"},
+	}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByCourseFn: func(courseID int) ([]domain.QuizAttemptView, error) {
+				assert.Equal(t, 123, courseID)
+				return courseAttempts, nil
+			},
+			FindQuizAnswerItemsByAttemptsFn: func(ids []int) ([]domain.QuizAnswerItem, error) {
+				assert.Equal(t, []int{1, 3}, ids)
+				return []domain.QuizAnswerItem{analysisItem(1, "q1", "A", 1, 1)}, nil
+			},
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				assert.Equal(t, 3, id)
+				return domain.QuizAnswer{QuizAnswerID: 3, QuizURL: base + "focused/123/quiz/teacher/content-quiz-1/v1.json"}, nil
+			},
+		},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			value, ok := r2[key]
+			if !ok {
+				return nil, fmt.Errorf("unexpected key %s", key)
+			}
+			return json.Marshal(value)
+		},
+	}
+
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/courses/123/quizzes/content-quiz-1/analysis", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "content-quiz-1")
+	require.NoError(t, ctrl.GetQuizItemAnalysis()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var analysis domain.QuizItemAnalysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &analysis))
+	assert.Equal(t, 2, analysis.Attempts)
+	require.Len(t, analysis.Questions, 1)
+	assert.Equal(t, 2, analysis.Questions[0].Correct)
+	assert.InDelta(t, 1, *analysis.Questions[0].Difficulty, 1e-9)
+	assert.Equal(t, 1, analysis.Questions[0].Distractors[1].Count)
+
+	c, _ = newTeacherContext(http.MethodGet, "/quiz/teacher/courses/999/quizzes/content-quiz-1/analysis", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("999", "content-quiz-1")
+	err := ctrl.GetQuizItemAnalysis()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
@@ -18,16 +18,33 @@ import (
 	"zeppelin/internal/domain"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 // --- Mocks locales ---
 
 type mockQuizRepo struct {
-	SaveQuizAttemptFn            func(input domain.QuizAnswer) error
-	GetQuizAttemptsByStudentFunc func(userID string) ([]domain.QuizAttemptView, error)
-	GetQuizAttemptsByCourseFn    func(courseID int) ([]domain.QuizAttemptView, error)
-	FindQuizAttemptByIDMock      func(quizAnswerID int) (domain.QuizAnswer, error)
-	UpdateQuizAttemptMock        func(attempt domain.QuizAnswer) error
+	SaveQuizAttemptFn               func(input domain.QuizAnswer) error
+	StartQuizAttemptFn              func(attempt domain.QuizAnswer) (int, error)
+	CountQuizAttemptsFn             func(userID, contentID string) (int64, error)
+	FindOpenQuizAttemptFn           func(userID, contentID string) (domain.QuizAnswer, error)
+	GetQuizAttemptsByStudentFunc    func(userID string) ([]domain.QuizAttemptView, error)
+	GetQuizAttemptsByCourseFn       func(courseID int) ([]domain.QuizAttemptView, error)
+	FindQuizAttemptByIDMock         func(quizAnswerID int) (domain.QuizAnswer, error)
+	UpdateQuizAttemptMock           func(attempt domain.QuizAnswer) error
+	FindSubmittedByContentFn        func(contentID string) ([]domain.QuizAnswer, error)
+	SaveQuizAnswerItemsFn           func(items []domain.QuizAnswerItem) error
+	FindQuizAnswerItemsFn           func(quizAnswerID int) ([]domain.QuizAnswerItem, error)
+	FindQuizAnswerItemsByAttemptsFn func(quizAnswerIDs []int) ([]domain.QuizAnswerItem, error)
+	SaveQuizDraftFn                 func(quizAnswerID int, answers map[string]interface{}, savedAt time.Time) error
+	FindPendingReviewItemsFn        func(filter domain.ReviewQueueFilter) ([]domain.ReviewQueueRow, error)
+}
+
+func (m mockQuizRepo) FindSubmittedQuizAttemptsByContent(contentID string) ([]domain.QuizAnswer, error) {
+	if m.FindSubmittedByContentFn != nil {
+		return m.FindSubmittedByContentFn(contentID)
+	}
+	return nil, nil
 }
 
 func (m mockQuizRepo) FindQuizAttemptByID(quizAnswerID int) (domain.QuizAnswer, error) {
@@ -38,6 +55,20 @@ func (m mockQuizRepo) UpdateQuizAttempt(attempt domain.QuizAnswer) error {
 	return m.UpdateQuizAttemptMock(attempt)
 }
 
+func (m mockQuizRepo) FindPendingReviewItems(filter domain.ReviewQueueFilter) ([]domain.ReviewQueueRow, error) {
+	if m.FindPendingReviewItemsFn != nil {
+		return m.FindPendingReviewItemsFn(filter)
+	}
+	return nil, nil
+}
+
+func (m mockQuizRepo) SaveQuizDraft(quizAnswerID int, answers map[string]interface{}, savedAt time.Time) error {
+	if m.SaveQuizDraftFn != nil {
+		return m.SaveQuizDraftFn(quizAnswerID, answers, savedAt)
+	}
+	return nil
+}
+
 func (m mockQuizRepo) FindQuizAttemptsByCourse(courseID int) ([]domain.QuizAnswer, error) {
 	//TODO implement me
 	panic("implement me")
@@ -59,13 +90,146 @@ func (m mockQuizRepo) GetQuizAttemptsByStudent(userID string) ([]domain.QuizAtte
 	return m.GetQuizAttemptsByStudentFunc(userID)
 }
 
-func (m mockQuizRepo) SaveQuizAttempt(input domain.QuizAnswer) error {
+func (m mockQuizRepo) SaveQuizAttempt(input domain.QuizAnswer, maxAttempts int) (int, error) {
 	if m.SaveQuizAttemptFn != nil {
-		return m.SaveQuizAttemptFn(input)
+		return 1, m.SaveQuizAttemptFn(input)
+	}
+	return 1, nil
+}
+
+func (m mockQuizRepo) SaveQuizAnswerItems(items []domain.QuizAnswerItem) error {
+	if m.SaveQuizAnswerItemsFn != nil {
+		return m.SaveQuizAnswerItemsFn(items)
+	}
+	return nil
+}
+
+func (m mockQuizRepo) FindQuizAnswerItemsByAttempts(quizAnswerIDs []int) ([]domain.QuizAnswerItem, error) {
+	if m.FindQuizAnswerItemsByAttemptsFn != nil {
+		return m.FindQuizAnswerItemsByAttemptsFn(quizAnswerIDs)
+	}
+	return nil, nil
+}
+
+func (m mockQuizRepo) FindQuizAnswerItems(quizAnswerID int) ([]domain.QuizAnswerItem, error) {
+	if m.FindQuizAnswerItemsFn != nil {
+		return m.FindQuizAnswerItemsFn(quizAnswerID)
+	}
+	return nil, nil
+}
+
+func (m mockQuizRepo) StartQuizAttempt(attempt domain.QuizAnswer, maxAttempts int) (int, error) {
+	if m.StartQuizAttemptFn != nil {
+		return m.StartQuizAttemptFn(attempt)
+	}
+	return 1, nil
+}
+
+func (m mockQuizRepo) CountQuizAttempts(userID, contentID string) (int64, error) {
+	if m.CountQuizAttemptsFn != nil {
+		return m.CountQuizAttemptsFn(userID, contentID)
+	}
+	return 0, nil
+}
+
+func (m mockQuizRepo) FindOpenQuizAttempt(userID, contentID string) (domain.QuizAnswer, error) {
+	if m.FindOpenQuizAttemptFn != nil {
+		return m.FindOpenQuizAttemptFn(userID, contentID)
+	}
+	return domain.QuizAnswer{}, gorm.ErrRecordNotFound
+}
+
+type mockDueDateRepo struct {
+	GetDueDateFn           func(contentID string) (domain.ContentDueDate, error)
+	SaveDueDateFn          func(dueDate domain.ContentDueDate) error
+	GetDueDateExtensionFn  func(contentID, userID string) (domain.DueDateExtension, error)
+	SaveDueDateExtensionFn func(extension domain.DueDateExtension) error
+}
+
+func (m mockDueDateRepo) GetDueDate(contentID string) (domain.ContentDueDate, error) {
+	if m.GetDueDateFn != nil {
+		return m.GetDueDateFn(contentID)
+	}
+	return domain.ContentDueDate{}, gorm.ErrRecordNotFound
+}
+
+func (m mockDueDateRepo) SaveDueDate(dueDate domain.ContentDueDate) error {
+	if m.SaveDueDateFn != nil {
+		return m.SaveDueDateFn(dueDate)
+	}
+	return nil
+}
+
+func (m mockDueDateRepo) GetDueDateExtension(contentID, userID string) (domain.DueDateExtension, error) {
+	if m.GetDueDateExtensionFn != nil {
+		return m.GetDueDateExtensionFn(contentID, userID)
+	}
+	return domain.DueDateExtension{}, gorm.ErrRecordNotFound
+}
+
+func (m mockDueDateRepo) SaveDueDateExtension(extension domain.DueDateExtension) error {
+	if m.SaveDueDateExtensionFn != nil {
+		return m.SaveDueDateExtensionFn(extension)
+	}
+	return nil
+}
+
+type mockAnswerReleaseRepo struct {
+	GetAnswerReleaseFn    func(contentID string) (domain.QuizAnswerRelease, error)
+	SaveAnswerReleaseFn   func(release domain.QuizAnswerRelease) error
+	DeleteAnswerReleaseFn func(contentID string) error
+}
+
+func (m mockAnswerReleaseRepo) GetAnswerRelease(contentID string) (domain.QuizAnswerRelease, error) {
+	if m.GetAnswerReleaseFn != nil {
+		return m.GetAnswerReleaseFn(contentID)
+	}
+	return domain.QuizAnswerRelease{}, gorm.ErrRecordNotFound
+}
+
+func (m mockAnswerReleaseRepo) SaveAnswerRelease(release domain.QuizAnswerRelease) error {
+	if m.SaveAnswerReleaseFn != nil {
+		return m.SaveAnswerReleaseFn(release)
+	}
+	return nil
+}
+
+func (m mockAnswerReleaseRepo) DeleteAnswerRelease(contentID string) error {
+	if m.DeleteAnswerReleaseFn != nil {
+		return m.DeleteAnswerReleaseFn(contentID)
 	}
 	return nil
 }
 
+type mockQuizVersionRepo struct {
+	NextVersion            int // versión que se asigna al publicar (1 por defecto)
+	CreateQuizVersionFn    func(version domain.QuizVersion) (int, error)
+	GetLatestQuizVersionFn func(contentID string) (domain.QuizVersion, error)
+}
+
+func (m mockQuizVersionRepo) CreateQuizVersion(contentID string, publish func(version int) (string, error)) (domain.QuizVersion, error) {
+	version := domain.QuizVersion{ContentID: contentID, Version: 1, QuizVersionID: 1}
+	if m.NextVersion > 0 {
+		version.Version = m.NextVersion
+	}
+	quizURL, err := publish(version.Version)
+	if err != nil {
+		return domain.QuizVersion{}, err
+	}
+	version.QuizURL = quizURL
+	if m.CreateQuizVersionFn != nil {
+		version.QuizVersionID, err = m.CreateQuizVersionFn(version)
+	}
+	return version, err
+}
+
+func (m mockQuizVersionRepo) GetLatestQuizVersion(contentID string) (domain.QuizVersion, error) {
+	if m.GetLatestQuizVersionFn != nil {
+		return m.GetLatestQuizVersionFn(contentID)
+	}
+	return domain.QuizVersion{}, gorm.ErrRecordNotFound
+}
+
 type mockAssignmentRepo struct {
 	GetAssignmentsByStudentAndCourseFn func(userID string, courseID int) (domain.AssignmentWithCourse, error)
 }
@@ -91,6 +255,7 @@ func (m mockAssignmentRepo) GetCourseIDByQRCode(string) (int, error) { return 0,
 type mockCourseContentRepo struct {
 	GetUrlByContentIDFn func(contentID string) (string, error)
 	GetContentTypeIDFn  func(contentID string) (int, error)
+	GetContentLocksFn   func(contentID, userID string) ([]domain.ContentLock, error)
 }
 
 func (m mockCourseContentRepo) GetUrlByContentID(contentID string) (string, error) {
@@ -124,12 +289,41 @@ func (m mockCourseContentRepo) UpdateContent(domain.UpdateContentInput) error
 func (m mockCourseContentRepo) UpdateContentStatus(string, bool) error            { return nil }
 func (m mockCourseContentRepo) UpdateModuleTitle(int, string) error               { return nil }
 func (m mockCourseContentRepo) UpdateUserContentStatus(string, string, int) error { return nil }
+func (m mockCourseContentRepo) ReorderModules(int, []int) error                   { return nil }
+func (m mockCourseContentRepo) ReorderSections(int, []string) error               { return nil }
+func (m mockCourseContentRepo) DeleteSection(string, string, bool) (domain.DeletedContent, error) {
+	return domain.DeletedContent{}, nil
+}
+func (m mockCourseContentRepo) DeleteModule(int, string, bool) (domain.DeletedContent, error) {
+	return domain.DeletedContent{}, nil
+}
+
+func (m mockCourseContentRepo) CloneCourse(domain.CloneCourseRequest) (domain.CourseClone, error) {
+	return domain.CourseClone{}, nil
+}
+
+func (m mockCourseContentRepo) SetPrerequisites(domain.SetPrerequisitesInput, string) error {
+	return nil
+}
+
+func (m mockCourseContentRepo) GetPrerequisites(string, string) ([]domain.ContentPrerequisite, error) {
+	return nil, nil
+}
+
+func (m mockCourseContentRepo) GetContentLocks(contentID, userID string) ([]domain.ContentLock, error) {
+	if m.GetContentLocksFn != nil {
+		return m.GetContentLocksFn(contentID, userID)
+	}
+	return nil, nil
+}
+
+func (m mockCourseContentRepo) GetCourseIDByContentID(string) (int, error) { return 0, nil }
 
 // --- Funciones de mock para R2 ---
 
 func mockUploadToR2(t *testing.T) func(string, []byte) error {
 	return func(key string, data []byte) error {
-		assert.Contains(t, key, "quiz/answer/student-123/content-quiz-1.json")
+		assert.Regexp(t, `quiz/answer/student-123/content-quiz-1/\d+\.json$`, key)
 		assert.JSONEq(t, `{"q1":"A"}`, string(data))
 		return nil
 	}
@@ -188,6 +382,8 @@ func TestQuizController_SubmitQuiz_Success(t *testing.T) {
 				return domain.AssignmentWithCourse{}, nil
 			},
 		},
+		QuizVersionRepo: mockQuizVersionRepo{},
+		DueDateRepo:     mockDueDateRepo{},
 		CourseContentRepo: mockCourseContentRepo{
 			GetUrlByContentIDFn: func(id string) (string, error) {
 				return fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/123/quiz/teacher/%s.json", accountID, id), nil
@@ -226,6 +422,8 @@ func TestQuizController_SubmitQuiz_AllTypesSuccess(t *testing.T) {
 			"q2": []interface{}{"A", "C"},
 			"q3": true,
 			"q4": "respuesta abierta",
+			// Las claves que no son preguntas del quiz no se guardan
+			"extra_review": []interface{}{map[string]interface{}{"q4": map[string]interface{}{"points_awarded": 99}}},
 		},
 	}
 	body, _ := json.Marshal(input)
@@ -263,6 +461,8 @@ func TestQuizController_SubmitQuiz_AllTypesSuccess(t *testing.T) {
 				return domain.AssignmentWithCourse{}, nil
 			},
 		},
+		QuizVersionRepo: mockQuizVersionRepo{},
+		DueDateRepo:     mockDueDateRepo{},
 		CourseContentRepo: mockCourseContentRepo{
 			GetUrlByContentIDFn: func(id string) (string, error) {
 				return fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/123/quiz/teacher/%s.json", accountID, id), nil
@@ -272,6 +472,7 @@ func TestQuizController_SubmitQuiz_AllTypesSuccess(t *testing.T) {
 			},
 		},
 		UploadStudentAnswers: func(key string, data []byte) error {
+			assert.NotContains(t, string(data), "extra_review")
 			return nil
 		},
 		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
@@ -324,10 +525,66 @@ func TestQuizController_gradeQuiz_BooleanCases(t *testing.T) {
 	}
 	quiz := domain.TeacherQuiz{Questions: questions}
 
-	score, total := ctrl.GradeQuiz(quiz, studentAnswers)
+	result := ctrl.GradeQuiz(quiz, studentAnswers)
+
+	assert.Equal(t, 13.0, result.Score)
+	assert.Equal(t, 23, result.TotalPoints)
+	require.Len(t, result.Questions, 4)
+	assert.True(t, result.Questions[0].IsCorrect)
+	assert.False(t, result.Questions[1].IsCorrect)
+}
+
+func TestQuizController_GradeQuiz_CheckboxScoringPolicies(t *testing.T) {
+	ctrl := controller.QuizController{}
+	correct := []string{"A", "B", "C", "D"}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "all", Type: "checkbox", Points: 8, CorrectAnswers: correct},
+		{ID: "prop", Type: "checkbox", Points: 8, CorrectAnswers: correct, Scoring: domain.ScoringProportional},
+		{ID: "prop_extra", Type: "checkbox", Points: 8, CorrectAnswers: correct, Scoring: domain.ScoringProportional},
+		{ID: "rmw", Type: "checkbox", Points: 8, CorrectAnswers: correct, Scoring: domain.ScoringRightMinusWrong},
+		{ID: "rmw_floor", Type: "checkbox", Points: 8, CorrectAnswers: correct, Scoring: domain.ScoringRightMinusWrong},
+	}}
+	answers := map[string]interface{}{
+		"all":        []interface{}{"A", "B", "C"},
+		"prop":       []interface{}{"A", "B", "C"},
+		"prop_extra": []interface{}{"A", "B", "C", "D", "E", "F", "G", "H"},
+		"rmw":        []interface{}{"A", "B", "C", "E"},
+		"rmw_floor":  []interface{}{"A", "E", "F"},
+	}
+
+	result := ctrl.GradeQuiz(quiz, answers)
 
-	assert.Equal(t, 13.0, score)
-	assert.Equal(t, 23, total)
+	earned := map[string]float64{}
+	for _, q := range result.Questions {
+		earned[q.QuestionID] = q.Earned
+	}
+	assert.Equal(t, 0.0, earned["all"])
+	assert.Equal(t, 6.0, earned["prop"])
+	assert.Equal(t, 4.0, earned["prop_extra"])
+	assert.Equal(t, 4.0, earned["rmw"])
+	assert.Equal(t, 0.0, earned["rmw_floor"])
+	assert.Equal(t, 14.0, result.Score)
+	assert.Equal(t, 40, result.TotalPoints)
+	assert.False(t, result.NeedsReview)
+}
+
+func TestQuizController_GradeQuiz_TextNeedsReview(t *testing.T) {
+	ctrl := controller.QuizController{}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "text", Points: 5},
+		{ID: "q2", Type: "multiple", Points: 5, CorrectAnswer: 3}, // clave mal formada: no debe causar pánico
+	}}
+
+	result := ctrl.GradeQuiz(quiz, map[string]interface{}{"q1": "respuesta", "q2": "3"})
+
+	assert.True(t, result.NeedsReview)
+	assert.True(t, result.Questions[0].NeedsReview)
+	assert.Equal(t, 0.0, result.Score)
+
+	// El estudiante no puede calificar su propia respuesta de texto
+	result = ctrl.GradeQuiz(quiz, map[string]interface{}{"q1": map[string]interface{}{"value": "respuesta", "points_awarded": 1000.0}})
+	assert.Equal(t, 0.0, result.Questions[0].Earned)
+	assert.Equal(t, 0.0, result.Score)
 }
 
 func TestQuizController_SubmitQuiz_Error_GetUrlByContentID(t *testing.T) {
@@ -357,6 +614,8 @@ func TestQuizController_SubmitQuiz_Error_GetUrlByContentID(t *testing.T) {
 				return domain.AssignmentWithCourse{}, nil
 			},
 		},
+		QuizVersionRepo: mockQuizVersionRepo{},
+		DueDateRepo:     mockDueDateRepo{},
 		CourseContentRepo: mockCourseContentRepo{
 			GetUrlByContentIDFn: func(id string) (string, error) {
 				return "", errors.New("contenido no encontrado") // <- forzamos error aquí
@@ -405,6 +664,8 @@ func TestQuizController_SubmitQuiz_Error_StudentNotAssigned(t *testing.T) {
 				return domain.AssignmentWithCourse{}, errors.New("no asignado") // fuerza el error
 			},
 		},
+		QuizVersionRepo: mockQuizVersionRepo{},
+		DueDateRepo:     mockDueDateRepo{},
 		CourseContentRepo: mockCourseContentRepo{
 			GetUrlByContentIDFn: func(id string) (string, error) {
 				return "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/content-id.json", nil
@@ -452,6 +713,8 @@ func TestQuizController_SubmitQuiz_Error_GetContentTypeID(t *testing.T) {
 				return domain.AssignmentWithCourse{}, nil
 			},
 		},
+		QuizVersionRepo: mockQuizVersionRepo{},
+		DueDateRepo:     mockDueDateRepo{},
 		CourseContentRepo: mockCourseContentRepo{
 			GetUrlByContentIDFn: func(id string) (string, error) {
 				return "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/content-id.json", nil
@@ -523,6 +786,10 @@ func TestQuizController_GetQuizzesByStudent(t *testing.T) {
 				return attempts, nil
 			},
 		},
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{
+			Settings: domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterSubmission},
+		}),
+		QuizVersionRepo: mockQuizVersionRepo{},
 		GeneratePresignedURL: func(bucket, key string) (string, error) {
 			return "https://signed.url/" + key, nil
 		},
@@ -640,11 +907,13 @@ func TestQuizController_ReviewTextAnswer_Success(t *testing.T) {
 	}
 
 	studentAnswers := map[string]interface{}{
+		"q1": "B",
 		"q4": "respuesta abierta",
 	}
 
 	teacherQuiz := domain.TeacherQuiz{
 		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "multiple", Points: 10, Options: []string{"A", "B"}, CorrectAnswer: "B"},
 			{ID: "q4", Type: "text", Points: 5},
 		},
 	}
@@ -658,7 +927,7 @@ func TestQuizController_ReviewTextAnswer_Success(t *testing.T) {
 			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
 				assert.Equal(t, 1, attempt.QuizAnswerID)
 				assert.Equal(t, 15.0, *attempt.Grade)
-				assert.Equal(t, 5, *attempt.TotalPoints)
+				assert.Equal(t, 15, *attempt.TotalPoints)
 				assert.NotNil(t, attempt.ReviewedAt)
 				return nil
 			},
@@ -685,6 +954,857 @@ func TestQuizController_ReviewTextAnswer_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Contains(t, rec.Body.String(), `"score":15`)
-	assert.Contains(t, rec.Body.String(), `"total_points":5`)
+	assert.Contains(t, rec.Body.String(), `"total_points":15`)
 	assert.Contains(t, rec.Body.String(), `"message":"Respuesta de texto revisada exitosamente"`)
 }
+
+func quizContentRepoMock(accountID string) mockCourseContentRepo {
+	return mockCourseContentRepo{
+		GetUrlByContentIDFn: func(id string) (string, error) {
+			return fmt.Sprintf("https://%s.r2.cloudflarestorage.com/focused/123/quiz/teacher/%s.json", accountID, id), nil
+		},
+		GetContentTypeIDFn: func(id string) (int, error) {
+			return 3, nil
+		},
+	}
+}
+
+func newQuizContext(t *testing.T, path string, payload interface{}) (echo.Context, *httptest.ResponseRecorder) {
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	e := echo.New()
+	e.Validator = &CustomValidator{Validator: validator.New()}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+	return c, rec
+}
+
+func TestQuizController_StartQuizAttempt_Success(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	mockQuiz := domain.TeacherQuiz{
+		Settings:  domain.QuizSettings{MaxAttempts: 3, TimeLimitMinutes: 20},
+		Questions: []domain.TeacherQuizQuestion{{ID: "q1", Type: "multiple", Points: 5, CorrectAnswer: "A"}},
+	}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			CountQuizAttemptsFn: func(userID, contentID string) (int64, error) {
+				return 1, nil
+			},
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				assert.Equal(t, "student-123", attempt.UserID)
+				assert.Equal(t, "content-quiz-1", attempt.ContentID)
+				assert.Nil(t, attempt.EndTime)
+				assert.WithinDuration(t, time.Now(), attempt.StartTime, time.Second)
+				return 42, nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz_answer_id":42`)
+	assert.Contains(t, rec.Body.String(), `"attempts_remaining":1`)
+	assert.Contains(t, rec.Body.String(), `"expires_at"`)
+}
+
+func TestQuizController_StartQuizAttempt_ResumesOpenAttempt(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{TimeLimitMinutes: 20}}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindOpenQuizAttemptFn: func(userID, contentID string) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{QuizAnswerID: 7, UserID: userID, ContentID: contentID, StartTime: time.Now().Add(-5 * time.Minute)}, nil
+			},
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				assert.Fail(t, "no se debe crear un nuevo intento")
+				return 0, nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz_answer_id":7`)
+}
+
+func TestQuizController_StartQuizAttempt_MaxAttemptsReached(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{MaxAttempts: 2}}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			CountQuizAttemptsFn: func(userID, contentID string) (int64, error) {
+				return 2, nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "máximo de 2 intentos")
+}
+
+func TestQuizController_StartQuizAttempt_ConcurrentStartHitsLimit(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{MaxAttempts: 2}}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			CountQuizAttemptsFn: func(userID, contentID string) (int64, error) {
+				return 1, nil
+			},
+			// otro inicio simultáneo ocupó el último intento entre el conteo y la inserción
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				return 0, fmt.Errorf("error starting quiz attempt: %w", domain.ErrMaxAttemptsReached)
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "máximo de 2 intentos")
+}
+
+func TestQuizController_StartQuizAttempt_QuizClosed(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	closedAt := time.Now().Add(-time.Hour)
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{ClosesAt: &closedAt}}
+
+	ctrl := controller.QuizController{
+		QuizRepo:              mockQuizRepo{},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "el quiz cerró")
+}
+
+func TestQuizController_SubmitQuiz_StartedAttemptUsesServerTime(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	startedAt := time.Now().Add(-10 * time.Minute)
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID:    "content-quiz-1",
+		QuizAnswerID: 9,
+		StartTime:    time.Now().Add(-time.Minute), // valores falsificados por el cliente
+		EndTime:      time.Now().Add(-time.Minute),
+		Answers:      map[string]interface{}{"q1": "A"},
+	})
+
+	mockQuiz := domain.TeacherQuiz{
+		Settings:  domain.QuizSettings{TimeLimitMinutes: 15},
+		Questions: []domain.TeacherQuizQuestion{{ID: "q1", Type: "multiple", Points: 5, CorrectAnswer: "A"}},
+	}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{QuizAnswerID: 9, UserID: "student-123", ContentID: "content-quiz-1", StartTime: startedAt,
+					QuizURL: "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/content-quiz-1.json"}, nil
+			},
+			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
+				assert.Equal(t, 9, attempt.QuizAnswerID)
+				assert.Equal(t, startedAt, attempt.StartTime)
+				require.NotNil(t, attempt.EndTime)
+				assert.WithinDuration(t, time.Now(), *attempt.EndTime, time.Second)
+				assert.Equal(t, 5.0, *attempt.Grade)
+				return nil
+			},
+			SaveQuizAttemptFn: func(input domain.QuizAnswer) error {
+				assert.Fail(t, "un intento iniciado se debe actualizar, no crear")
+				return nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		UploadStudentAnswers:  func(string, []byte) error { return nil },
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz_answer_id":9`)
+	assert.Contains(t, rec.Body.String(), `"score":5`)
+}
+
+func TestQuizController_SubmitQuiz_TimeLimitExceeded(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID:    "content-quiz-1",
+		QuizAnswerID: 9,
+		Answers:      map[string]interface{}{"q1": "A"},
+	})
+
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{TimeLimitMinutes: 15}}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{QuizAnswerID: 9, UserID: "student-123", ContentID: "content-quiz-1", StartTime: time.Now().Add(-time.Hour)}, nil
+			},
+		},
+		AssignmentRepo:    mockAssignmentRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		UploadStudentAnswers: func(string, []byte) error {
+			assert.Fail(t, "no se deben subir respuestas fuera de tiempo")
+			return nil
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "tiempo límite del intento venció")
+}
+
+func TestQuizController_SubmitQuiz_TimedQuizRequiresStart(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		Answers:   map[string]interface{}{"q1": "A"},
+	})
+
+	mockQuiz := domain.TeacherQuiz{Settings: domain.QuizSettings{TimeLimitMinutes: 15}}
+
+	ctrl := controller.QuizController{
+		QuizRepo:              mockQuizRepo{},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "/quiz/start")
+}
+
+func TestQuizController_SubmitQuiz_AlreadySubmitted(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID:    "content-quiz-1",
+		QuizAnswerID: 9,
+		Answers:      map[string]interface{}{"q1": "A"},
+	})
+
+	endedAt := time.Now().Add(-time.Minute)
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{QuizAnswerID: 9, UserID: "student-123", ContentID: "content-quiz-1", EndTime: &endedAt}, nil
+			},
+		},
+		AssignmentRepo:    mockAssignmentRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), "ya fue enviado")
+}
+
+func TestBuildQuizVariant_PoolsAndShuffle(t *testing.T) {
+	quiz := domain.TeacherQuiz{
+		Settings: domain.QuizSettings{ShuffleQuestions: true, ShuffleOptions: true},
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "fixed", Type: "multiple", Points: 1, Options: []string{"A", "B", "C", "D"}, CorrectAnswer: "C"},
+		},
+		Pools: []domain.QuestionPool{{ID: "bank", Draw: 2, Questions: []domain.TeacherQuizQuestion{
+			{ID: "p1", Type: "boolean", Points: 1, CorrectAnswer: true},
+			{ID: "p2", Type: "boolean", Points: 1, CorrectAnswer: false},
+			{ID: "p3", Type: "boolean", Points: 1, CorrectAnswer: true},
+			{ID: "p4", Type: "boolean", Points: 1, CorrectAnswer: false},
+		}}},
+	}
+
+	variant := controller.BuildQuizVariant(quiz, 12345)
+	again := controller.BuildQuizVariant(quiz, 12345)
+
+	assert.Equal(t, variant, again, "la misma semilla debe generar la misma variante")
+	assert.Len(t, variant.Questions, 3)
+	assert.Nil(t, variant.Pools)
+	for _, q := range variant.Questions {
+		if q.ID == "fixed" {
+			assert.ElementsMatch(t, []string{"A", "B", "C", "D"}, q.Options)
+			assert.Equal(t, "C", q.CorrectAnswer)
+		}
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D"}, quiz.Questions[0].Options, "no se debe modificar el quiz original")
+}
+
+func TestQuizController_StartQuizAttempt_StoresVariant(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	mockQuiz := domain.TeacherQuiz{
+		Settings: domain.QuizSettings{ShuffleOptions: true},
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "multiple", Points: 5, Options: []string{"A", "B"}, CorrectAnswer: "A"},
+		},
+	}
+
+	uploaded := map[string]string{}
+	var variantKey string
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				require.NotNil(t, attempt.Seed)
+				assert.Equal(t, "https://test-account.r2.cloudflarestorage.com/"+variantKey, attempt.VariantURL)
+				assert.Contains(t, attempt.QuizURL, "quiz/teacher/content-quiz-1.json")
+				return 42, nil
+			},
+		},
+		AssignmentRepo:    mockAssignmentRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		UploadStudentAnswers: func(key string, data []byte) error {
+			assert.Contains(t, key, "focused/123/quiz/variant/content-quiz-1/student-123-")
+			if variantKey == "" {
+				variantKey = key
+			}
+			uploaded[key] = string(data)
+			return nil
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	err := ctrl.StartQuizAttempt()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz":`)
+	assert.NotContains(t, rec.Body.String(), "correctAnswer", "la variante enviada al estudiante no debe incluir la clave")
+
+	// Se guarda la variante con la clave y una copia sin ella para el listado del estudiante
+	require.Len(t, uploaded, 2)
+	assert.Contains(t, uploaded[variantKey], "correctAnswer")
+	studentKey := strings.TrimSuffix(variantKey, ".json") + "-student.json"
+	require.Contains(t, uploaded, studentKey)
+	assert.NotContains(t, uploaded[studentKey], "correctAnswer")
+}
+
+func TestQuizController_SubmitQuiz_GradesAgainstVariant(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID:    "content-quiz-1",
+		QuizAnswerID: 9,
+		Answers:      map[string]interface{}{"p2": false},
+	})
+
+	variant := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{{ID: "p2", Type: "boolean", Points: 3, CorrectAnswer: false}}}
+	variantURL := "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/variant/content-quiz-1/student-123-77.json"
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{QuizAnswerID: 9, UserID: "student-123", ContentID: "content-quiz-1", StartTime: time.Now(),
+					QuizURL:    "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/content-quiz-1.json",
+					VariantURL: variantURL}, nil
+			},
+			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
+				assert.Equal(t, 3.0, *attempt.Grade)
+				assert.Equal(t, 3, *attempt.TotalPoints)
+				return nil
+			},
+		},
+		AssignmentRepo:       mockAssignmentRepo{},
+		CourseContentRepo:    quizContentRepoMock("test-account"),
+		QuizVersionRepo:      mockQuizVersionRepo{},
+		DueDateRepo:          mockDueDateRepo{},
+		UploadStudentAnswers: func(string, []byte) error { return nil },
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			assert.Equal(t, "focused/123/quiz/variant/content-quiz-1/student-123-77.json", key)
+			return json.Marshal(variant)
+		},
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"score":3`)
+}
+
+func TestQuizController_SubmitQuiz_PinsCurrentQuizVersion(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		Answers:   map[string]interface{}{"q1": "A"},
+	})
+
+	versionURL := "https://test-account.r2.cloudflarestorage.com/focused/123/quiz/teacher/content-quiz-1/v2.json"
+	mockQuiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{{ID: "q1", Type: "multiple", Points: 5, CorrectAnswer: "A"}}}
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			SaveQuizAttemptFn: func(attempt domain.QuizAnswer) error {
+				assert.Equal(t, versionURL, attempt.QuizURL)
+				require.NotNil(t, attempt.QuizVersionID)
+				assert.Equal(t, 11, *attempt.QuizVersionID)
+				return nil
+			},
+		},
+		DueDateRepo: mockDueDateRepo{},
+		QuizVersionRepo: mockQuizVersionRepo{
+			GetLatestQuizVersionFn: func(contentID string) (domain.QuizVersion, error) {
+				return domain.QuizVersion{QuizVersionID: 11, ContentID: contentID, Version: 2, QuizURL: versionURL}, nil
+			},
+		},
+		AssignmentRepo:       mockAssignmentRepo{},
+		CourseContentRepo:    quizContentRepoMock("test-account"),
+		UploadStudentAnswers: func(string, []byte) error { return nil },
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			assert.Equal(t, "focused/123/quiz/teacher/content-quiz-1/v2.json", key)
+			return json.Marshal(mockQuiz)
+		},
+	}
+
+	err := ctrl.SubmitQuiz()(c)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestQuizController_GetQuizzesByCourse_ShowsQuizVersion(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/quiz/teacher/courses/1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("courseId")
+	c.SetParamValues("1")
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	versionID, version := 11, 2
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByCourseFn: func(courseID int) ([]domain.QuizAttemptView, error) {
+				return []domain.QuizAttemptView{
+					{QuizAnswerID: 1, ContentID: "content-quiz-1", CourseID: 1, EndTime: time.Now(),
+						QuizVersionID: &versionID, QuizVersion: &version},
+					// intento en curso: no se lista
+					{QuizAnswerID: 2, ContentID: "content-quiz-1", CourseID: 1, StartTime: time.Now()},
+				}, nil
+			},
+		},
+		GeneratePresignedURL: func(bucket, key string) (string, error) { return "https://signed/" + key, nil },
+	}
+
+	err := ctrl.GetQuizzesByCourse()(c)
+	require.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), `"quiz_version_id":11`)
+	assert.Contains(t, rec.Body.String(), `"quiz_version":2`)
+	assert.Contains(t, rec.Body.String(), `"quiz_answer_id":1`)
+	assert.NotContains(t, rec.Body.String(), `"quiz_answer_id":2`)
+}
+
+func TestQuizController_SubmitQuiz_SavesAnswerItems(t *testing.T) {
+	accountID := "test-account"
+	os.Setenv("R2_ACCOUNT_ID", accountID)
+
+	mockQuiz := domain.TeacherQuiz{
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "multiple", Points: 4, Options: []string{"A", "B"}, CorrectAnswer: "A"},
+			{ID: "q2", Type: "text", Points: 6},
+		},
+	}
+
+	var saved []domain.QuizAnswerItem
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			SaveQuizAnswerItemsFn: func(items []domain.QuizAnswerItem) error {
+				saved = items
+				return nil
+			},
+		},
+		AssignmentRepo: mockAssignmentRepo{
+			GetAssignmentsByStudentAndCourseFn: func(uid string, cid int) (domain.AssignmentWithCourse, error) {
+				return domain.AssignmentWithCourse{}, nil
+			},
+		},
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		CourseContentRepo:     quizContentRepoMock(accountID),
+		UploadStudentAnswers:  func(key string, data []byte) error { return nil },
+		GetTeacherQuizContent: mockGetFromR2(t, mockQuiz),
+	}
+
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		StartTime: time.Now().Add(-time.Minute),
+		EndTime:   time.Now(),
+		Answers:   map[string]interface{}{"q1": "A", "q2": "una respuesta"},
+	})
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, saved, 2)
+	assert.Equal(t, 1, saved[0].QuizAnswerID)
+	assert.Equal(t, "q1", saved[0].QuestionID)
+	assert.Equal(t, `"A"`, saved[0].Answer)
+	require.NotNil(t, saved[0].Score)
+	assert.Equal(t, 4.0, *saved[0].Score)
+	assert.True(t, *saved[0].IsCorrect)
+	assert.False(t, saved[0].NeedsReview)
+
+	assert.Equal(t, "q2", saved[1].QuestionID)
+	assert.True(t, saved[1].NeedsReview)
+	assert.Nil(t, saved[1].Score)
+	assert.Nil(t, saved[1].ReviewedAt)
+}
+
+func TestQuizController_ReviewTextAnswer_RecomputesFromItems(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	quizAttempt := domain.QuizAnswer{
+		QuizAnswerID:  1,
+		UserID:        "student-123",
+		Grade:         floatPointer(7),
+		QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/folder/answer.json",
+		QuizURL:       "https://test-account.r2.cloudflarestorage.com/folder/quiz.json",
+	}
+	teacherQuiz := domain.TeacherQuiz{
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "boolean", Points: 4, CorrectAnswer: true},
+			{ID: "q2", Type: "text", Points: 6},
+		},
+	}
+	// q2 ya fue revisada con 3 puntos; una segunda revisión reemplaza la nota, no la suma
+	items := []domain.QuizAnswerItem{
+		{QuizAnswerItemID: 10, QuizAnswerID: 1, QuestionID: "q1", Points: 4, Score: floatPointer(4)},
+		{QuizAnswerItemID: 11, QuizAnswerID: 1, QuestionID: "q2", Points: 6, Score: floatPointer(3)},
+	}
+
+	var saved []domain.QuizAnswerItem
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return quizAttempt, nil
+			},
+			FindQuizAnswerItemsFn: func(quizAnswerID int) ([]domain.QuizAnswerItem, error) {
+				assert.Equal(t, 1, quizAnswerID)
+				return items, nil
+			},
+			SaveQuizAnswerItemsFn: func(items []domain.QuizAnswerItem) error {
+				saved = items
+				return nil
+			},
+			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
+				assert.Equal(t, 9.0, *attempt.Grade)
+				assert.NotNil(t, attempt.ReviewedAt)
+				return nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			if strings.Contains(key, "answer.json") {
+				return json.Marshal(map[string]interface{}{"q1": true, "q2": "respuesta"})
+			}
+			return json.Marshal(teacherQuiz)
+		},
+		UploadStudentAnswers: func(key string, data []byte) error { return nil },
+	}
+
+	c, rec := newQuizContext(t, "/quiz/review-text-answer", domain.TextAnswerReviewInput{
+		QuizAnswerID:  1,
+		QuestionID:    "q2",
+		PointsAwarded: 5,
+		IsCorrect:     true,
+		Feedback:      "Bien argumentado",
+	})
+	c.Set("user_id", "teacher-1")
+	require.NoError(t, ctrl.ReviewTextAnswer()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"score":9`)
+
+	require.Len(t, saved, 2)
+	assert.Equal(t, 11, saved[1].QuizAnswerItemID)
+	assert.Equal(t, 5.0, *saved[1].Score)
+	assert.Equal(t, "teacher-1", *saved[1].ReviewerID)
+	assert.Equal(t, "Bien argumentado", saved[1].Feedback)
+	assert.NotNil(t, saved[1].ReviewedAt)
+}
+
+func TestQuizController_ReviewTextAnswer_LegacyAttemptBackfillsItems(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	// Intento anterior a quiz_answer_item, con dos preguntas de texto
+	quizAttempt := domain.QuizAnswer{
+		QuizAnswerID:  1,
+		UserID:        "student-123",
+		Grade:         floatPointer(4),
+		QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/folder/answer.json",
+		QuizURL:       "https://test-account.r2.cloudflarestorage.com/folder/quiz.json",
+	}
+	teacherQuiz := domain.TeacherQuiz{
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "boolean", Points: 4, CorrectAnswer: true},
+			{ID: "q2", Type: "text", Points: 6},
+			{ID: "q3", Type: "text", Points: 6},
+		},
+	}
+
+	var saved []domain.QuizAnswerItem
+	var updated domain.QuizAnswer
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return quizAttempt, nil
+			},
+			SaveQuizAnswerItemsFn: func(items []domain.QuizAnswerItem) error {
+				saved = items
+				return nil
+			},
+			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
+				updated = attempt
+				return nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			if strings.Contains(key, "answer.json") {
+				return json.Marshal(map[string]interface{}{"q1": true, "q2": "respuesta", "q3": "otra"})
+			}
+			return json.Marshal(teacherQuiz)
+		},
+		UploadStudentAnswers: func(key string, data []byte) error { return nil },
+	}
+
+	c, rec := newQuizContext(t, "/quiz/review-text-answer", domain.TextAnswerReviewInput{
+		QuizAnswerID:  1,
+		QuestionID:    "q2",
+		PointsAwarded: 5,
+	})
+	c.Set("user_id", "teacher-1")
+	require.NoError(t, ctrl.ReviewTextAnswer()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Se guardan todas las preguntas, así que una segunda revisión parte de los puntos automáticos
+	require.Len(t, saved, 3)
+	assert.Equal(t, 4.0, *saved[0].Score)
+	assert.Equal(t, 5.0, *saved[1].Score)
+	assert.True(t, saved[2].NeedsReview)
+	assert.Nil(t, saved[2].Score)
+	assert.Equal(t, 9.0, *updated.Grade)
+	assert.Nil(t, updated.ReviewedAt, "q3 sigue pendiente de revisión")
+}
+
+func TestQuizController_ReviewTextAnswer_WithRubric(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	quizAttempt := domain.QuizAnswer{
+		QuizAnswerID:  1,
+		UserID:        "student-123",
+		Grade:         floatPointer(0),
+		QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/folder/answer.json",
+		QuizURL:       "https://test-account.r2.cloudflarestorage.com/folder/quiz.json",
+	}
+	teacherQuiz := domain.TeacherQuiz{
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "text", Points: 6, Rubric: []domain.RubricCriterion{
+				{ID: "arg", Title: "Argumentación", Levels: []domain.RubricLevel{
+					{ID: "mid", Title: "Aceptable", Points: 2},
+					{ID: "high", Title: "Excelente", Points: 4},
+				}},
+				{ID: "ort", Title: "Ortografía", Levels: []domain.RubricLevel{
+					{ID: "bad", Title: "Con errores", Points: 0},
+					{ID: "good", Title: "Correcta", Points: 2},
+				}},
+			}},
+		},
+	}
+	items := []domain.QuizAnswerItem{
+		{QuizAnswerItemID: 3, QuizAnswerID: 1, QuestionID: "q1", Points: 6, NeedsReview: true},
+	}
+
+	newController := func(saved *[]domain.QuizAnswerItem) controller.QuizController {
+		return controller.QuizController{
+			QuizRepo: mockQuizRepo{
+				FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) { return quizAttempt, nil },
+				FindQuizAnswerItemsFn: func(quizAnswerID int) ([]domain.QuizAnswerItem, error) {
+					return append([]domain.QuizAnswerItem(nil), items...), nil
+				},
+				SaveQuizAnswerItemsFn: func(items []domain.QuizAnswerItem) error {
+					*saved = items
+					return nil
+				},
+				UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error { return nil },
+			},
+			GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+				if strings.Contains(key, "answer.json") {
+					return json.Marshal(map[string]interface{}{"q1": "ensayo"})
+				}
+				return json.Marshal(teacherQuiz)
+			},
+			UploadStudentAnswers: func(key string, data []byte) error { return nil },
+		}
+	}
+
+	t.Run("Total calculado por el servidor", func(t *testing.T) {
+		var saved []domain.QuizAnswerItem
+		ctrl := newController(&saved)
+		c, rec := newQuizContext(t, "/quiz/review-text-answer", domain.TextAnswerReviewInput{
+			QuizAnswerID:     1,
+			QuestionID:       "q1",
+			PointsAwarded:    6, // se ignora: manda la rúbrica
+			RubricSelections: map[string]string{"arg": "high", "ort": "bad"},
+		})
+		require.NoError(t, ctrl.ReviewTextAnswer()(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"score":4`)
+
+		require.Len(t, saved, 1)
+		assert.Equal(t, 4.0, *saved[0].Score)
+		require.Len(t, saved[0].Rubric, 2)
+		assert.Equal(t, "Excelente", saved[0].Rubric[0].Level)
+		assert.Equal(t, 0.0, saved[0].Rubric[1].Points)
+	})
+
+	t.Run("Selección incompleta", func(t *testing.T) {
+		var saved []domain.QuizAnswerItem
+		ctrl := newController(&saved)
+		c, rec := newQuizContext(t, "/quiz/review-text-answer", domain.TextAnswerReviewInput{
+			QuizAnswerID:     1,
+			QuestionID:       "q1",
+			RubricSelections: map[string]string{"arg": "high"},
+		})
+		require.NoError(t, ctrl.ReviewTextAnswer()(c))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "ort")
+		assert.Nil(t, saved)
+	})
+}
+
+func TestQuizController_GetQuizzesByStudent_IncludesRubricBreakdown(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/quiz/student", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByStudentFunc: func(userID string) ([]domain.QuizAttemptView, error) {
+				return []domain.QuizAttemptView{{
+					QuizAnswerID:  7,
+					UserID:        userID,
+					CourseID:      101,
+					ContentID:     "quiz-123",
+					EndTime:       time.Now(),
+					QuizURL:       "https://test-account.r2.cloudflarestorage.com/path/to/quiz.json",
+					QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/path/to/answer.json",
+				}}, nil
+			},
+			FindQuizAnswerItemsByAttemptsFn: func(ids []int) ([]domain.QuizAnswerItem, error) {
+				assert.Equal(t, []int{7}, ids)
+				return []domain.QuizAnswerItem{{
+					QuizAnswerID: 7,
+					QuestionID:   "q1",
+					Points:       6,
+					Score:        floatPointer(4),
+					Feedback:     "Cuide la ortografía",
+					Rubric: []domain.RubricScore{
+						{CriterionID: "arg", Criterion: "Argumentación", LevelID: "high", Level: "Excelente", Points: 4, MaxPoints: 4},
+						{CriterionID: "ort", Criterion: "Ortografía", LevelID: "bad", Level: "Con errores", Points: 0, MaxPoints: 2},
+					},
+				}}, nil
+			},
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{Settings: domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterSubmission}}),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		GeneratePresignedURL:  func(bucket, key string) (string, error) { return "https://signed.url/" + key, nil },
+	}
+
+	require.NoError(t, ctrl.GetQuizzesByStudent()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"feedback":"Cuide la ortografía"`)
+	assert.Contains(t, rec.Body.String(), `"level":"Excelente"`)
+	assert.Contains(t, rec.Body.String(), `"max_points":2`)
+}
+
+// lockedQuizContentRepo es quizContentRepoMock con el quiz bloqueado por un prerrequisito
+func lockedQuizContentRepo() mockCourseContentRepo {
+	repo := quizContentRepoMock("test-account")
+	repo.GetContentLocksFn = func(contentID, userID string) ([]domain.ContentLock, error) {
+		return []domain.ContentLock{{Reason: domain.LockReasonNotCompleted, RequiredContentID: "intro", RequiredTitle: "Introducción"}}, nil
+	}
+	return repo
+}
+
+func TestQuizController_LockedQuiz(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				t.Fatal("un quiz bloqueado no debe iniciarse")
+				return 0, nil
+			},
+			SaveQuizAttemptFn: func(input domain.QuizAnswer) error {
+				t.Fatal("un quiz bloqueado no debe enviarse")
+				return nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		CourseContentRepo:     lockedQuizContentRepo(),
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{{ID: "q1", Type: "multiple", Points: 1, CorrectAnswer: "A"}}}),
+	}
+
+	c, _ := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+	err := ctrl.StartQuizAttempt()(c)
+	assert.ErrorIs(t, err, domain.ErrContentLocked)
+
+	c, _ = newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{ContentID: "content-quiz-1", Answers: map[string]interface{}{"q1": "A"}})
+	err = ctrl.SubmitQuiz()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.ErrorIs(t, err, domain.ErrContentLocked)
+}
@@ -0,0 +1,202 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateLateSubmission(t *testing.T) {
+	due := time.Date(2025, 4, 10, 23, 59, 0, 0, time.UTC)
+
+	t.Run("A tiempo y dentro del periodo de gracia", func(t *testing.T) {
+		policy := domain.ContentDueDate{LatePolicy: domain.LatePolicyReject, GraceMinutes: 15}
+		result, err := controller.EvaluateLateSubmission(policy, due, due.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.False(t, result.Late)
+
+		result, err = controller.EvaluateLateSubmission(policy, due, due.Add(10*time.Minute))
+		require.NoError(t, err)
+		assert.False(t, result.Late)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		policy := domain.ContentDueDate{LatePolicy: domain.LatePolicyReject, GraceMinutes: 15}
+		_, err := controller.EvaluateLateSubmission(policy, due, due.Add(16*time.Minute))
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	})
+
+	t.Run("Flat", func(t *testing.T) {
+		policy := domain.ContentDueDate{LatePolicy: domain.LatePolicyFlat, PenaltyPercent: 20}
+		result, err := controller.EvaluateLateSubmission(policy, due, due.Add(72*time.Hour))
+		require.NoError(t, err)
+		assert.True(t, result.Late)
+		assert.Equal(t, 72*60, result.LateMinutes)
+		assert.Equal(t, 20.0, result.PenaltyPercent)
+	})
+
+	t.Run("Per day con tope", func(t *testing.T) {
+		policy := domain.ContentDueDate{LatePolicy: domain.LatePolicyPerDay, PenaltyPercent: 10, MaxPenaltyPercent: 25}
+		result, err := controller.EvaluateLateSubmission(policy, due, due.Add(25*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 20.0, result.PenaltyPercent, "un día y una hora cuentan como dos días")
+
+		result, err = controller.EvaluateLateSubmission(policy, due, due.Add(5*24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 25.0, result.PenaltyPercent)
+	})
+
+	t.Run("None solo marca la entrega", func(t *testing.T) {
+		policy := domain.ContentDueDate{LatePolicy: domain.LatePolicyNone}
+		result, err := controller.EvaluateLateSubmission(policy, due, due.Add(time.Minute))
+		require.NoError(t, err)
+		assert.True(t, result.Late)
+		assert.Zero(t, result.PenaltyPercent)
+	})
+}
+
+func submitQuizController(t *testing.T, dueDates mockDueDateRepo, saved *domain.QuizAnswer) controller.QuizController {
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "boolean", Points: 10, CorrectAnswer: true},
+	}}
+	return controller.QuizController{
+		QuizRepo: mockQuizRepo{SaveQuizAttemptFn: func(attempt domain.QuizAnswer) error {
+			*saved = attempt
+			return nil
+		}},
+		AssignmentRepo:    mockAssignmentRepo{},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       dueDates,
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		UploadStudentAnswers: func(key string, data []byte) error {
+			return nil
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			return json.Marshal(quiz)
+		},
+	}
+}
+
+func TestQuizController_SubmitQuiz_LatePenalty(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	due := time.Now().Add(-36 * time.Hour)
+
+	var saved domain.QuizAnswer
+	ctrl := submitQuizController(t, mockDueDateRepo{
+		GetDueDateFn: func(contentID string) (domain.ContentDueDate, error) {
+			return domain.ContentDueDate{ContentID: contentID, DueAt: due, LatePolicy: domain.LatePolicyPerDay, PenaltyPercent: 10}, nil
+		},
+	}, &saved)
+
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		Answers:   map[string]interface{}{"q1": true},
+	})
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, 10.0, *saved.RawGrade)
+	assert.InDelta(t, 8.0, *saved.Grade, 1e-9, "dos días de retraso, 10 % por día")
+	assert.Equal(t, 20.0, saved.LatePenalty)
+	assert.True(t, saved.DueAt.Equal(due))
+	assert.Contains(t, rec.Body.String(), `"raw_score":10`)
+	assert.Contains(t, rec.Body.String(), `"late":true`)
+}
+
+func TestQuizController_SubmitQuiz_ExtensionAndReject(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	rejectPolicy := func(contentID string) (domain.ContentDueDate, error) {
+		return domain.ContentDueDate{ContentID: contentID, DueAt: time.Now().Add(-time.Hour), LatePolicy: domain.LatePolicyReject}, nil
+	}
+
+	var saved domain.QuizAnswer
+	ctrl := submitQuizController(t, mockDueDateRepo{GetDueDateFn: rejectPolicy}, &saved)
+	c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		Answers:   map[string]interface{}{"q1": true},
+	})
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "la fecha de entrega venció")
+	assert.Nil(t, saved.Grade)
+
+	extended := time.Now().Add(24 * time.Hour)
+	ctrl = submitQuizController(t, mockDueDateRepo{
+		GetDueDateFn: rejectPolicy,
+		GetDueDateExtensionFn: func(contentID, userID string) (domain.DueDateExtension, error) {
+			assert.Equal(t, "student-123", userID)
+			return domain.DueDateExtension{ContentID: contentID, UserID: userID, DueAt: extended}, nil
+		},
+	}, &saved)
+	c, rec = newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+		ContentID: "content-quiz-1",
+		Answers:   map[string]interface{}{"q1": true},
+	})
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 10.0, *saved.Grade)
+	assert.Zero(t, saved.LateMinutes)
+	assert.True(t, saved.DueAt.Equal(extended))
+}
+
+func TestNewQuizAttemptDetail_LatePenalty(t *testing.T) {
+	due := time.Date(2025, 4, 10, 23, 59, 0, 0, time.UTC)
+	raw, grade := 8.0, 6.0
+	detail := domain.NewQuizAttemptDetail(domain.QuizAttemptView{
+		QuizAnswerID: 7,
+		ContentID:    "content-quiz-1",
+		Grade:        &grade,
+		RawGrade:     &raw,
+		DueAt:        &due,
+		LateMinutes:  90,
+		LatePenalty:  25,
+	})
+
+	assert.Equal(t, 7, detail.QuizAnswerID)
+	assert.True(t, detail.Late)
+	assert.Equal(t, 90, detail.LateMinutes)
+	assert.Equal(t, 25.0, detail.LatePenalty)
+	assert.Equal(t, 8.0, *detail.RawGrade)
+	assert.Equal(t, 6.0, *detail.Grade)
+	assert.Equal(t, due, *detail.DueAt)
+
+	onTime := domain.NewQuizAttemptDetail(domain.QuizAttemptView{Grade: &grade, RawGrade: &grade})
+	assert.False(t, onTime.Late)
+}
+
+func TestQuizController_GrantDueDateExtension(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	var saved domain.DueDateExtension
+	ctrl := controller.QuizController{
+		DueDateRepo: mockDueDateRepo{SaveDueDateExtensionFn: func(extension domain.DueDateExtension) error {
+			saved = extension
+			return nil
+		}},
+		AssignmentRepo:    mockAssignmentRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{CourseID: courseID}, nil
+			},
+		},
+	}
+
+	dueAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	body, _ := json.Marshal(domain.DueDateExtensionInput{ContentID: "content-quiz-1", UserID: "student-123", DueAt: dueAt})
+	c, rec := newTeacherContext(http.MethodPut, "/quiz/teacher/due-date/extension", body)
+	require.NoError(t, ctrl.GrantDueDateExtension()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "student-123", saved.UserID)
+	assert.True(t, saved.DueAt.Equal(dueAt))
+}
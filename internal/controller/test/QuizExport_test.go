@@ -0,0 +1,176 @@
+package controller_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestQuiz() domain.TeacherQuiz {
+	return domain.TeacherQuiz{
+		Title: "Repaso <final>",
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "multiple", Question: "¿2 + 2?", Points: 2, Options: []string{"3", "4"}, CorrectAnswer: "4", Explanation: "Aritmética"},
+			{ID: "q2", Type: "checkbox", Question: "Primos", Points: 1, Options: []string{"2", "3", "4"}, CorrectAnswers: []string{"2", "3"}},
+			{ID: "q3", Type: "ordering", Question: "Ordene", Points: 1, Options: []string{"b", "a"}, CorrectAnswers: []string{"a", "b"}},
+			{ID: "q4", Type: "matching", Question: "Relacione", Points: 1, Items: []string{"Perú", "Chile"}, Options: []string{"Lima", "Santiago"},
+				CorrectPairs: map[string]string{"Perú": "Lima", "Chile": "Santiago"}},
+			{ID: "q5", Type: "short", Question: "Capital de Francia", Points: 1, AcceptedAnswers: []string{"París", "Paris"}},
+			{ID: "q6", Type: "numeric", Question: "Pi", Points: 1, CorrectAnswer: 3.14, Tolerance: 0.01},
+			{ID: "q7", Type: "text", Question: "Explique & argumente", Points: 5},
+		},
+		Pools: []domain.QuestionPool{{ID: "banco", Draw: 1, Questions: []domain.TeacherQuizQuestion{
+			{ID: "p1", Type: "boolean", Question: "El sol es una estrella", Points: 1, CorrectAnswer: true},
+		}}},
+	}
+}
+
+func TestQuizQTIPackage_RoundTrip(t *testing.T) {
+	packageBytes, err := controller.QuizQTIPackage("content-quiz-1", exportTestQuiz())
+	require.NoError(t, err)
+
+	reader, err := zip.NewReader(bytes.NewReader(packageBytes), int64(len(packageBytes)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "imsmanifest.xml")
+	assert.Contains(t, names, "test.xml")
+	assert.Contains(t, names, "items/p1.xml")
+
+	// El paquete exportado se puede volver a importar sin perder preguntas
+	quiz, issues, err := controller.ConvertImportedQuiz(domain.QuizImportQTI, []byte(base64.StdEncoding.EncodeToString(packageBytes)))
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+	require.Len(t, quiz.Questions, 8)
+
+	assert.Equal(t, "multiple", quiz.Questions[0].Type)
+	assert.Equal(t, "4", quiz.Questions[0].CorrectAnswer)
+	assert.Equal(t, 2, quiz.Questions[0].Points)
+	assert.Equal(t, []string{"2", "3"}, quiz.Questions[1].CorrectAnswers)
+	assert.Equal(t, []string{"a", "b"}, quiz.Questions[2].CorrectAnswers)
+	assert.Equal(t, map[string]string{"Perú": "Lima", "Chile": "Santiago"}, quiz.Questions[3].CorrectPairs)
+	assert.Equal(t, []string{"París", "Paris"}, quiz.Questions[4].AcceptedAnswers)
+	assert.Equal(t, 3.14, quiz.Questions[5].CorrectAnswer)
+	assert.Equal(t, "text", quiz.Questions[6].Type)
+	assert.Equal(t, "Explique & argumente", quiz.Questions[6].Question)
+	assert.Equal(t, "Verdadero", quiz.Questions[7].CorrectAnswer)
+}
+
+func exportQuizController(t *testing.T, quiz domain.TeacherQuiz, attempts []domain.QuizAttemptView, items []domain.QuizAnswerItem, r2 map[string]interface{}) controller.QuizController {
+	r2["focused/123/quiz/teacher/content-quiz-1.json"] = quiz
+	return controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByCourseFn: func(courseID int) ([]domain.QuizAttemptView, error) {
+				assert.Equal(t, 123, courseID)
+				return attempts, nil
+			},
+			FindQuizAnswerItemsByAttemptsFn: func(ids []int) ([]domain.QuizAnswerItem, error) {
+				return items, nil
+			},
+		},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			value, ok := r2[key]
+			if !ok {
+				return nil, fmt.Errorf("unexpected key %s", key)
+			}
+			return json.Marshal(value)
+		},
+	}
+}
+
+func TestQuizController_ExportQuizQTI(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	ctrl := exportQuizController(t, exportTestQuiz(), nil, nil, map[string]interface{}{})
+
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/courses/123/quizzes/content-quiz-1/export/qti", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "content-quiz-1")
+	require.NoError(t, ctrl.ExportQuizQTI()(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "quiz-content-quiz-1-qti.zip")
+	_, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	assert.NoError(t, err)
+
+	// Un quiz de otro curso no se exporta
+	c, _ = newTeacherContext(http.MethodGet, "/quiz/teacher/courses/999/quizzes/content-quiz-1/export/qti", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("999", "content-quiz-1")
+	err = ctrl.ExportQuizQTI()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestQuizController_ExportQuizAttempts(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	base := "https://test-account.r2.cloudflarestorage.com/"
+
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "checkbox", Question: "Primos", Points: 2, Options: []string{"2", "3", "4"}, CorrectAnswers: []string{"2", "3"}},
+		{ID: "q2", Type: "text", Question: "Explique", Points: 5},
+	}}
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	grade, version := 70.0, 2
+	attempts := []domain.QuizAttemptView{
+		{QuizAnswerID: 1, UserID: "s1", StudentName: "Ana", StudentLastname: "Zapata", ContentID: "content-quiz-1",
+			StartTime: start, EndTime: start.Add(10 * time.Minute), Grade: &grade, QuizVersion: &version, NeedsReview: true},
+		// intento anterior a quiz_answer_item: la respuesta se lee de R2
+		{QuizAnswerID: 2, UserID: "s2", StudentName: "Luis", StudentLastname: "Alba", ContentID: "content-quiz-1",
+			QuizAnswerURL: base + "answers/2.json", StartTime: start, EndTime: start.Add(5 * time.Minute)},
+		// sin enviar y de otro quiz: no se exportan
+		{QuizAnswerID: 3, UserID: "s3", ContentID: "content-quiz-1", StartTime: start},
+		{QuizAnswerID: 4, UserID: "s4", ContentID: "otro-quiz", StartTime: start, EndTime: start.Add(time.Minute)},
+	}
+	score := 2.0
+	items := []domain.QuizAnswerItem{
+		{QuizAnswerID: 1, QuestionID: "q1", Answer: `["2","3"]`, Score: &score},
+		{QuizAnswerID: 1, QuestionID: "q2", Answer: `"=HYPERLINK(\"http://x\")"`},
+	}
+	ctrl := exportQuizController(t, quiz, attempts, items, map[string]interface{}{
+		"answers/2.json": map[string]interface{}{"q1": []string{"4"}, "q2": "Respuesta antigua"},
+	})
+
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/courses/123/quizzes/content-quiz-1/export/attempts", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "content-quiz-1")
+	require.NoError(t, ctrl.ExportQuizAttempts()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "quiz-content-quiz-1-attempts.csv")
+
+	records, err := csv.NewReader(bytes.NewReader(rec.Body.Bytes())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"quiz_answer_id", "user_id", "lastname", "name", "email", "quiz_version", "start_time", "end_time",
+		"late_minutes", "late_penalty", "raw_grade", "grade", "total_points", "needs_review",
+		"q1_response", "q1_score", "q2_response", "q2_score"}, records[0])
+
+	// ordenado por apellido
+	assert.Equal(t, []string{"2", "s2", "Alba", "Luis", "", "", "2025-03-10T09:00:00Z", "2025-03-10T09:05:00Z",
+		"0", "0.00", "", "", "", "false", "4", "", "Respuesta antigua", ""}, records[1])
+	assert.Equal(t, []string{"1", "s1", "Zapata", "Ana", "", "2", "2025-03-10T09:00:00Z", "2025-03-10T09:10:00Z",
+		"0", "0.00", "", "70.00", "", "true", "2; 3", "2.00", "'=HYPERLINK(\"http://x\")", ""}, records[2])
+}
@@ -0,0 +1,150 @@
+package controller_test
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormula_Evaluate(t *testing.T) {
+	cases := []struct {
+		source string
+		values map[string]float64
+		want   float64
+	}{
+		{"1 + 2 * 3", nil, 7},
+		{"(1 + 2) * 3", nil, 9},
+		{"2^3^2", nil, 512},
+		{"-2^2", nil, -4},
+		{"{a} / b - 1", map[string]float64{"a": 9, "b": 3}, 2},
+		{"sqrt({a}^2 + {b}^2)", map[string]float64{"a": 3, "b": 4}, 5},
+		{"1.5e2 + log(100)", nil, 152},
+		{"round(2 * pi)", nil, 6},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			formula, err := controller.ParseFormula(tc.source)
+			require.NoError(t, err)
+			got, err := formula.Evaluate(tc.values)
+			require.NoError(t, err)
+			assert.InDelta(t, tc.want, got, 1e-9)
+		})
+	}
+
+	formula, err := controller.ParseFormula("m * g * {h}")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"g", "h", "m"}, formula.Variables())
+}
+
+func TestParseFormula_Errors(t *testing.T) {
+	for _, source := range []string{"", "1 +", "(1 + 2", "os.exit(1)", "system(1)", "2 $ 3", "{a", strings.Repeat("(", 60) + "1" + strings.Repeat(")", 60)} {
+		_, err := controller.ParseFormula(source)
+		assert.Error(t, err, source)
+	}
+
+	formula, err := controller.ParseFormula("a / b")
+	require.NoError(t, err)
+	_, err = formula.Evaluate(map[string]float64{"a": 1, "b": 0})
+	assert.Error(t, err, "división por cero")
+	_, err = formula.Evaluate(map[string]float64{"a": 1})
+	assert.Error(t, err, "variable sin valor")
+
+	formula, err = controller.ParseFormula("sqrt(x)")
+	require.NoError(t, err)
+	_, err = formula.Evaluate(map[string]float64{"x": -1})
+	assert.Error(t, err, "resultado no finito")
+}
+
+func calculatedQuestion() domain.TeacherQuizQuestion {
+	return domain.TeacherQuizQuestion{
+		ID: "c1", Type: "calculated", Points: 4, Question: "Un cuerpo de {m} kg acelera a {a} m/s². ¿Qué fuerza actúa?",
+		Formula: "m * a", Tolerance: 0.01, ToleranceType: "relative",
+		Variables: []domain.QuestionVariable{
+			{Name: "m", Min: 1, Max: 10, Decimals: 1},
+			{Name: "a", Min: 2, Max: 5, Decimals: 0},
+		},
+	}
+}
+
+func TestCalculatedQuestion_ValidateKey(t *testing.T) {
+	questionType, ok := controller.LookupQuestionType("calculated")
+	require.True(t, ok)
+	assert.NoError(t, questionType.ValidateKey(calculatedQuestion()))
+
+	undeclared := calculatedQuestion()
+	undeclared.Formula = "m * a * t"
+	badRange := calculatedQuestion()
+	badRange.Variables[0].Min = 20
+	badName := calculatedQuestion()
+	badName.Variables[1].Name = "a b"
+	badFormula := calculatedQuestion()
+	badFormula.Formula = "m *"
+	noValue := calculatedQuestion()
+	noValue.Formula = "m / (a - a)"
+	for name, question := range map[string]domain.TeacherQuizQuestion{
+		"variable no declarada": undeclared, "rango invertido": badRange, "nombre inválido": badName,
+		"fórmula inválida": badFormula, "no evaluable": noValue,
+	} {
+		assert.Error(t, questionType.ValidateKey(question), name)
+	}
+}
+
+func TestBuildQuizVariant_CalculatedValues(t *testing.T) {
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{calculatedQuestion()}}
+
+	variant := controller.BuildQuizVariant(quiz, 7)
+	assert.Equal(t, variant, controller.BuildQuizVariant(quiz, 7), "la misma semilla debe generar los mismos valores")
+
+	question := variant.Questions[0]
+	require.Len(t, question.Values, 2)
+	assert.GreaterOrEqual(t, question.Values["m"], 1.0)
+	assert.LessOrEqual(t, question.Values["m"], 10.0)
+	assert.Equal(t, question.Values["a"], float64(int(question.Values["a"])), "a no tiene decimales")
+	assert.NotContains(t, question.Question, "{m}")
+	assert.Contains(t, quiz.Questions[0].Question, "{m}", "no se debe modificar el quiz original")
+
+	// Calificación con los valores del estudiante y tolerancia relativa
+	expected := question.Values["m"] * question.Values["a"]
+	ctrl := controller.QuizController{}
+	assert.Equal(t, 4.0, ctrl.GradeQuiz(variant, map[string]interface{}{"c1": expected * 1.005}).Questions[0].Earned)
+	assert.Equal(t, 0.0, ctrl.GradeQuiz(variant, map[string]interface{}{"c1": expected * 1.05}).Questions[0].Earned)
+
+	// Sin valores sorteados la pregunta queda para revisión manual
+	result := ctrl.GradeQuiz(quiz, map[string]interface{}{"c1": 10.0})
+	assert.True(t, result.NeedsReview)
+}
+
+func TestQuizController_StartQuizAttempt_StoresVariableValues(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			StartQuizAttemptFn: func(attempt domain.QuizAnswer) (int, error) {
+				require.NotNil(t, attempt.Seed)
+				require.Contains(t, attempt.VariableValues, "c1")
+				assert.Len(t, attempt.VariableValues["c1"], 2)
+				return 42, nil
+			},
+		},
+		AssignmentRepo:       mockAssignmentRepo{},
+		CourseContentRepo:    quizContentRepoMock("test-account"),
+		QuizVersionRepo:      mockQuizVersionRepo{},
+		DueDateRepo:          mockDueDateRepo{},
+		UploadStudentAnswers: func(key string, data []byte) error { return nil },
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{
+			Questions: []domain.TeacherQuizQuestion{calculatedQuestion()},
+		}),
+	}
+
+	require.NoError(t, ctrl.StartQuizAttempt()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "m * a", "la fórmula no se envía al estudiante")
+	assert.NotContains(t, rec.Body.String(), "{m}")
+}
@@ -0,0 +1,477 @@
+package controller_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const moodleQuizXML = `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="category"><category><text>$course$/Álgebra</text></category></question>
+  <question type="multichoice">
+    <name><text>Suma</text></name>
+    <questiontext format="html"><text><![CDATA[<p>¿Cuánto es 2&nbsp;+&nbsp;2?</p>]]></text></questiontext>
+    <generalfeedback format="html"><text><![CDATA[<p>Se suman las unidades.</p>]]></text></generalfeedback>
+    <defaultgrade>2.0000000</defaultgrade>
+    <single>true</single>
+    <answer fraction="100" format="html"><text><![CDATA[<p>4</p>]]></text><feedback format="html"><text>Correcto</text></feedback></answer>
+    <answer fraction="0" format="html"><text><![CDATA[<p>5</p>]]></text></answer>
+  </question>
+  <question type="multichoice">
+    <name><text>Primos</text></name>
+    <questiontext format="html"><text>Elija los primos</text></questiontext>
+    <single>false</single>
+    <answer fraction="50"><text>2</text></answer>
+    <answer fraction="50"><text>3</text></answer>
+    <answer fraction="-100"><text>4</text></answer>
+  </question>
+  <question type="truefalse">
+    <name><text>Tierra</text></name>
+    <questiontext><text>La Tierra es redonda</text></questiontext>
+    <answer fraction="100"><text>true</text></answer>
+    <answer fraction="0"><text>false</text></answer>
+  </question>
+  <question type="shortanswer">
+    <name><text>Capital</text></name>
+    <questiontext><text>Capital de Francia</text></questiontext>
+    <answer fraction="100"><text>París</text></answer>
+    <answer fraction="100"><text>Par*s</text></answer>
+  </question>
+  <question type="numerical">
+    <name><text>Pi</text></name>
+    <questiontext><text>Valor de pi</text></questiontext>
+    <answer fraction="100"><text>3.14</text><tolerance>0.01</tolerance></answer>
+  </question>
+  <question type="matching">
+    <name><text>Capitales</text></name>
+    <questiontext><text>Relacione</text></questiontext>
+    <subquestion><text>Perú</text><answer><text>Lima</text></answer></subquestion>
+    <subquestion><text>Chile</text><answer><text>Santiago</text></answer></subquestion>
+    <subquestion><text></text><answer><text>Quito</text></answer></subquestion>
+  </question>
+  <question type="essay">
+    <name><text>Ensayo</text></name>
+    <questiontext><text>Explique la fotosíntesis</text></questiontext>
+  </question>
+  <question type="calculated">
+    <name><text>Fórmula</text></name>
+    <questiontext><text>Calcule {a} + {b}</text></questiontext>
+  </question>
+</quiz>`
+
+func TestConvertImportedQuiz_MoodleXML(t *testing.T) {
+	quiz, issues, err := controller.ConvertImportedQuiz(domain.QuizImportMoodleXML, []byte(moodleQuizXML))
+	require.NoError(t, err)
+	require.Len(t, quiz.Questions, 7)
+
+	multiple := quiz.Questions[0]
+	assert.Equal(t, "q1", multiple.ID)
+	assert.Equal(t, "multiple", multiple.Type)
+	assert.Equal(t, "¿Cuánto es 2 + 2?", multiple.Question)
+	assert.Equal(t, 2, multiple.Points)
+	assert.Equal(t, []string{"4", "5"}, multiple.Options)
+	assert.Equal(t, "4", multiple.CorrectAnswer)
+	assert.Equal(t, "Se suman las unidades.", multiple.Explanation)
+	assert.Equal(t, map[string]string{"4": "Correcto"}, multiple.OptionExplanations)
+
+	checkbox := quiz.Questions[1]
+	assert.Equal(t, "checkbox", checkbox.Type)
+	assert.Equal(t, []string{"2", "3"}, checkbox.CorrectAnswers)
+	assert.Equal(t, domain.ScoringProportional, checkbox.Scoring)
+
+	assert.Equal(t, "boolean", quiz.Questions[2].Type)
+	assert.Equal(t, "true", quiz.Questions[2].CorrectAnswer)
+
+	short := quiz.Questions[3]
+	assert.Equal(t, "short", short.Type)
+	assert.Equal(t, []string{"París"}, short.AcceptedAnswers)
+	assert.Equal(t, "(?i)^(Par.*s)$", short.Pattern)
+
+	numeric := quiz.Questions[4]
+	assert.Equal(t, "numeric", numeric.Type)
+	assert.Equal(t, 3.14, numeric.CorrectAnswer)
+	assert.Equal(t, 0.01, numeric.Tolerance)
+
+	matching := quiz.Questions[5]
+	assert.Equal(t, "matching", matching.Type)
+	assert.Equal(t, []string{"Perú", "Chile"}, matching.Items)
+	assert.Equal(t, []string{"Lima", "Santiago", "Quito"}, matching.Options)
+	assert.Equal(t, map[string]string{"Perú": "Lima", "Chile": "Santiago"}, matching.CorrectPairs)
+
+	assert.Equal(t, "text", quiz.Questions[6].Type)
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, 8, issues[0].Position)
+	assert.Equal(t, "Fórmula", issues[0].Name)
+	assert.Equal(t, "calculated", issues[0].Type)
+
+	// El resultado debe pasar la misma validación que un quiz editado a mano
+	jsonBytes, err := json.Marshal(quiz)
+	require.NoError(t, err)
+	assert.Nil(t, controller.ValidateTeacherQuiz(jsonBytes))
+}
+
+func TestConvertImportedQuiz_GIFT(t *testing.T) {
+	gift := `// Preguntas de prueba
+$CATEGORY: $course$/Geografía
+
+::Capital::¿Cuál es la capital de Perú? {=Lima#Muy bien ~Cusco ~Arequipa####Lima es la capital desde 1535.}
+
+::Sol::El sol es una estrella {T}
+
+Dos más dos es {=cuatro =4}
+
+::Pi::Valor de pi {#3.14:0.01}
+
+::Rango::Un número entre 1 y 5 {#1..5}
+
+::Pares:: Relacione {
+  =Perú -> Lima
+  =Chile -> Santiago
+}
+
+::Primos:: Elija los primos {~%50%2 ~%50%3 ~%-100%4}
+
+Colón llegó a América en {=1492 ~1500} y volvió.
+
+::Ensayo::Explique la fotosíntesis {}
+
+Esto es solo una descripción con llaves escapadas \{ \}
+
+::Doble::Elija {=a =b ~c}
+`
+	quiz, issues, err := controller.ConvertImportedQuiz(domain.QuizImportGIFT, []byte(gift))
+	require.NoError(t, err)
+	require.Len(t, quiz.Questions, 9)
+
+	multiple := quiz.Questions[0]
+	assert.Equal(t, "multiple", multiple.Type)
+	assert.Equal(t, "¿Cuál es la capital de Perú?", multiple.Question)
+	assert.Equal(t, []string{"Lima", "Cusco", "Arequipa"}, multiple.Options)
+	assert.Equal(t, "Lima", multiple.CorrectAnswer)
+	assert.Equal(t, map[string]string{"Lima": "Muy bien"}, multiple.OptionExplanations)
+	assert.Equal(t, "Lima es la capital desde 1535.", multiple.Explanation)
+
+	assert.Equal(t, "boolean", quiz.Questions[1].Type)
+	assert.Equal(t, true, quiz.Questions[1].CorrectAnswer)
+
+	assert.Equal(t, "short", quiz.Questions[2].Type)
+	assert.Equal(t, []string{"cuatro", "4"}, quiz.Questions[2].AcceptedAnswers)
+
+	assert.Equal(t, 3.14, quiz.Questions[3].CorrectAnswer)
+	assert.Equal(t, 0.01, quiz.Questions[3].Tolerance)
+	assert.Equal(t, 3.0, quiz.Questions[4].CorrectAnswer)
+	assert.Equal(t, 2.0, quiz.Questions[4].Tolerance)
+
+	assert.Equal(t, "matching", quiz.Questions[5].Type)
+	assert.Equal(t, map[string]string{"Perú": "Lima", "Chile": "Santiago"}, quiz.Questions[5].CorrectPairs)
+
+	assert.Equal(t, "checkbox", quiz.Questions[6].Type)
+	assert.Equal(t, []string{"2", "3"}, quiz.Questions[6].CorrectAnswers)
+
+	// Palabra faltante: el bloque de respuestas queda como un hueco en el enunciado
+	assert.Equal(t, "Colón llegó a América en _____ y volvió.", quiz.Questions[7].Question)
+	assert.Equal(t, "1492", quiz.Questions[7].CorrectAnswer)
+
+	assert.Equal(t, "text", quiz.Questions[8].Type)
+
+	require.Len(t, issues, 2)
+	assert.Equal(t, "description", issues[0].Type)
+	assert.Equal(t, "Doble", issues[1].Name)
+}
+
+const qtiChoiceItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="item1" title="Colores" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>B</value></correctResponse>
+  </responseDeclaration>
+  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float" normalMaximum="3"/>
+  <itemBody>
+    <p>Mire el cielo.</p>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+      <prompt>¿De qué color es?</prompt>
+      <simpleChoice identifier="A">Verde</simpleChoice>
+      <simpleChoice identifier="B">Azul</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+const qtiItems = `<items>
+` + qtiChoiceItem + `
+<assessmentItem identifier="item2" title="Orden">
+  <responseDeclaration identifier="RESPONSE" cardinality="ordered" baseType="identifier">
+    <correctResponse><value>C1</value><value>C2</value><value>C3</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <orderInteraction responseIdentifier="RESPONSE">
+      <prompt>Ordene de menor a mayor</prompt>
+      <simpleChoice identifier="C3">3</simpleChoice>
+      <simpleChoice identifier="C1">1</simpleChoice>
+      <simpleChoice identifier="C2">2</simpleChoice>
+    </orderInteraction>
+  </itemBody>
+</assessmentItem>
+<assessmentItem identifier="item3" title="Capitales">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="directedPair">
+    <correctResponse><value>P L</value><value>C S</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <matchInteraction responseIdentifier="RESPONSE">
+      <prompt>Relacione</prompt>
+      <simpleMatchSet>
+        <simpleAssociableChoice identifier="P" matchMax="1">Perú</simpleAssociableChoice>
+        <simpleAssociableChoice identifier="C" matchMax="1">Chile</simpleAssociableChoice>
+      </simpleMatchSet>
+      <simpleMatchSet>
+        <simpleAssociableChoice identifier="L" matchMax="1">Lima</simpleAssociableChoice>
+        <simpleAssociableChoice identifier="S" matchMax="1">Santiago</simpleAssociableChoice>
+      </simpleMatchSet>
+    </matchInteraction>
+  </itemBody>
+</assessmentItem>
+<assessmentItem identifier="item4" title="Capital">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">
+    <correctResponse><value>Lima</value></correctResponse>
+    <mapping defaultValue="0"><mapEntry mapKey="lima" mappedValue="1"/></mapping>
+  </responseDeclaration>
+  <itemBody><p>La capital de Perú es <textEntryInteraction responseIdentifier="RESPONSE"/>.</p></itemBody>
+</assessmentItem>
+<assessmentItem identifier="item5" title="Hotspot">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="point"/>
+  <itemBody><hotspotInteraction responseIdentifier="RESPONSE"><prompt>Marque</prompt></hotspotInteraction></itemBody>
+</assessmentItem>
+</items>`
+
+func TestConvertImportedQuiz_QTI(t *testing.T) {
+	quiz, issues, err := controller.ConvertImportedQuiz(domain.QuizImportQTI, []byte(qtiItems))
+	require.NoError(t, err)
+	require.Len(t, quiz.Questions, 4)
+
+	choice := quiz.Questions[0]
+	assert.Equal(t, "multiple", choice.Type)
+	assert.Equal(t, "Mire el cielo. ¿De qué color es?", choice.Question)
+	assert.Equal(t, []string{"Verde", "Azul"}, choice.Options)
+	assert.Equal(t, "Azul", choice.CorrectAnswer)
+	assert.Equal(t, 3, choice.Points)
+
+	ordering := quiz.Questions[1]
+	assert.Equal(t, "ordering", ordering.Type)
+	assert.Equal(t, []string{"3", "1", "2"}, ordering.Options)
+	assert.Equal(t, []string{"1", "2", "3"}, ordering.CorrectAnswers)
+
+	matching := quiz.Questions[2]
+	assert.Equal(t, "matching", matching.Type)
+	assert.Equal(t, map[string]string{"Perú": "Lima", "Chile": "Santiago"}, matching.CorrectPairs)
+
+	short := quiz.Questions[3]
+	assert.Equal(t, "short", short.Type)
+	assert.Equal(t, "La capital de Perú es .", short.Question)
+	assert.Equal(t, []string{"Lima", "lima"}, short.AcceptedAnswers)
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, 5, issues[0].Position)
+	assert.Equal(t, "hotspotInteraction", issues[0].Type)
+}
+
+func TestConvertImportedQuiz_QTIPackage(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := map[string]string{
+		"imsmanifest.xml": `<manifest><resources>
+			<resource identifier="r2" type="imsqti_item_xmlv2p1" href="items/b.xml"/>
+			<resource identifier="r1" type="imsqti_item_xmlv2p1" href="items/a.xml"/>
+		</resources></manifest>`,
+		"items/a.xml": qtiChoiceItem,
+		"items/b.xml": `<assessmentItem identifier="essay" title="Ensayo">
+			<itemBody><extendedTextInteraction responseIdentifier="RESPONSE"><prompt>Explique</prompt></extendedTextInteraction></itemBody>
+		</assessmentItem>`,
+	}
+	for name, content := range files {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	quiz, issues, err := controller.ConvertImportedQuiz(domain.QuizImportQTI, []byte(data))
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+	require.Len(t, quiz.Questions, 2)
+	assert.Equal(t, "text", quiz.Questions[0].Type)
+	assert.Equal(t, "Explique", quiz.Questions[0].Question)
+	assert.Equal(t, "multiple", quiz.Questions[1].Type)
+}
+
+func TestConvertImportedQuiz_InvalidFile(t *testing.T) {
+	_, _, err := controller.ConvertImportedQuiz(domain.QuizImportMoodleXML, []byte("<quiz><question>"))
+	assert.Error(t, err)
+
+	_, _, err = controller.ConvertImportedQuiz(domain.QuizImportGIFT, []byte("Pregunta {=a ~b"))
+	assert.Error(t, err)
+}
+
+func importCourseContentController(repo MockCourseContentRepo, upload func(courseID, contentID string, version int, json []byte) error) controller.CourseContentController {
+	if repo.GetCourseIDByContentIDT == nil {
+		repo.GetCourseIDByContentIDT = func(contentID string) (int, error) { return 1, nil }
+	}
+	return controller.CourseContentController{
+		Repo: repo,
+		RepoCourse: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				if teacherID != "teacher-1" || courseID != 1 {
+					return domain.CourseDB{}, errors.New("not found")
+				}
+				return domain.CourseDB{}, nil
+			},
+		},
+		UploadQuizFunc:        func(courseID, contentID string, json []byte) error { return nil },
+		UploadQuizVersionFunc: upload,
+		QuizVersionRepo: mockQuizVersionRepo{
+			NextVersion: 2,
+		},
+	}
+}
+
+func TestCourseContentController_ImportQuiz_Publish(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	body, _ := json.Marshal(domain.QuizImportInput{
+		CourseID:  1,
+		ContentID: "content-123",
+		Format:    domain.QuizImportGIFT,
+		Title:     "Importado",
+		Data:      "::Sol::El sol es una estrella {T}\n\n::Hotspot:: Solo texto",
+	})
+	c, rec := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+
+	var uploaded domain.TeacherQuiz
+	updated := false
+	ctrl := importCourseContentController(MockCourseContentRepo{
+		GetContentTypeIDT: func(contentID string) (int, error) { return 3, nil },
+		UpdateContentT: func(input domain.UpdateContentInput) error {
+			updated = true
+			assert.Equal(t, "Importado", input.Title)
+			assert.Equal(t, "https://test-account.r2.cloudflarestorage.com/focused/1/quiz/teacher/content-123.json", input.Url)
+			return nil
+		},
+	}, func(courseID, contentID string, version int, jsonBytes []byte) error {
+		assert.Equal(t, "1", courseID)
+		assert.Equal(t, 2, version)
+		return json.Unmarshal(jsonBytes, &uploaded)
+	})
+
+	require.NoError(t, ctrl.ImportQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, updated)
+	require.Len(t, uploaded.Questions, 1)
+	assert.Equal(t, "Importado", uploaded.Title)
+
+	var response struct {
+		Body domain.QuizImportResult
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Body.Imported)
+	assert.Equal(t, 2, response.Body.Version)
+	require.Len(t, response.Body.Unsupported, 1)
+	assert.Equal(t, "Hotspot", response.Body.Unsupported[0].Name)
+}
+
+func TestCourseContentController_ImportQuiz_DryRun(t *testing.T) {
+	body, _ := json.Marshal(domain.QuizImportInput{
+		CourseID:  1,
+		ContentID: "content-123",
+		Format:    domain.QuizImportQTI,
+		Data:      qtiChoiceItem,
+		DryRun:    true,
+	})
+	c, rec := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+
+	ctrl := importCourseContentController(MockCourseContentRepo{
+		GetContentTypeIDT: func(contentID string) (int, error) { return 3, nil },
+	}, func(courseID, contentID string, version int, jsonBytes []byte) error {
+		assert.Fail(t, "una simulación no debe subir el quiz a R2")
+		return nil
+	})
+
+	require.NoError(t, ctrl.ImportQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"dry_run":true`)
+	assert.Contains(t, rec.Body.String(), `"correctAnswer":"Azul"`)
+}
+
+func TestCourseContentController_ImportQuiz_Rejected(t *testing.T) {
+	upload := func(courseID, contentID string, version int, jsonBytes []byte) error {
+		assert.Fail(t, "no se debe subir nada a R2")
+		return nil
+	}
+
+	t.Run("curso de otro profesor", func(t *testing.T) {
+		body, _ := json.Marshal(domain.QuizImportInput{CourseID: 2, ContentID: "content-123", Format: domain.QuizImportGIFT, Data: "P {T}"})
+		c, rec := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+		ctrl := importCourseContentController(MockCourseContentRepo{}, upload)
+
+		require.NoError(t, ctrl.ImportQuiz()(c))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Este curso no le pertenece al profesor")
+	})
+
+	t.Run("contenido de otro curso", func(t *testing.T) {
+		body, _ := json.Marshal(domain.QuizImportInput{CourseID: 1, ContentID: "content-999", Format: domain.QuizImportGIFT, Data: "P {T}"})
+		c, rec := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+		ctrl := importCourseContentController(MockCourseContentRepo{
+			GetCourseIDByContentIDT: func(contentID string) (int, error) {
+				assert.Equal(t, "content-999", contentID)
+				return 2, nil
+			},
+			GetContentTypeIDT: func(contentID string) (int, error) { return 3, nil },
+			UpdateContentT: func(input domain.UpdateContentInput) error {
+				assert.Fail(t, "no se debe modificar un contenido ajeno")
+				return nil
+			},
+		}, upload)
+
+		require.NoError(t, ctrl.ImportQuiz()(c))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "El contenido no pertenece a este curso")
+	})
+
+	t.Run("el contenido no es un quiz", func(t *testing.T) {
+		body, _ := json.Marshal(domain.QuizImportInput{CourseID: 1, ContentID: "content-123", Format: domain.QuizImportGIFT, Data: "P {T}"})
+		c, rec := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+		ctrl := importCourseContentController(MockCourseContentRepo{
+			GetContentTypeIDT: func(contentID string) (int, error) { return 2, nil },
+		}, upload)
+
+		require.NoError(t, ctrl.ImportQuiz()(c))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "El contenido no es un quiz")
+	})
+
+	t.Run("sin preguntas compatibles", func(t *testing.T) {
+		body, _ := json.Marshal(domain.QuizImportInput{CourseID: 1, ContentID: "content-123", Format: domain.QuizImportGIFT, Data: "Solo una descripción"})
+		c, _ := newTeacherContext(http.MethodPost, "/course-content/quiz/import", body)
+		ctrl := importCourseContentController(MockCourseContentRepo{
+			GetContentTypeIDT: func(contentID string) (int, error) { return 3, nil },
+		}, upload)
+
+		err := ctrl.ImportQuiz()(c)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		message, _ := json.Marshal(httpErr.Message)
+		assert.Contains(t, string(message), "las descripciones no son preguntas")
+	})
+}
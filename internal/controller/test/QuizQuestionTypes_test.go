@@ -0,0 +1,235 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"testing"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func earnedByQuestion(result domain.QuizGradeResult) map[string]float64 {
+	earned := map[string]float64{}
+	for _, q := range result.Questions {
+		earned[q.QuestionID] = q.Earned
+	}
+	return earned
+}
+
+func TestQuizController_GradeQuiz_NumericTolerance(t *testing.T) {
+	ctrl := controller.QuizController{}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "exact", Type: "numeric", Points: 2, CorrectAnswer: 9.81},
+		{ID: "abs", Type: "numeric", Points: 2, CorrectAnswer: 9.81, Tolerance: 0.05},
+		{ID: "abs_out", Type: "numeric", Points: 2, CorrectAnswer: 9.81, Tolerance: 0.05},
+		{ID: "rel", Type: "numeric", Points: 2, CorrectAnswer: 200.0, Tolerance: 0.1, ToleranceType: "relative"},
+		{ID: "comma", Type: "numeric", Points: 2, CorrectAnswer: "3.5"},
+		{ID: "invalid", Type: "numeric", Points: 2, CorrectAnswer: 1.0},
+	}}
+	answers := map[string]interface{}{
+		"exact":   9.81,
+		"abs":     "9.85",
+		"abs_out": 9.9,
+		"rel":     215.0,
+		"comma":   "3,5",
+		"invalid": "uno",
+	}
+
+	result := ctrl.GradeQuiz(quiz, answers)
+	earned := earnedByQuestion(result)
+
+	assert.Equal(t, 2.0, earned["exact"])
+	assert.Equal(t, 2.0, earned["abs"])
+	assert.Equal(t, 0.0, earned["abs_out"])
+	assert.Equal(t, 2.0, earned["rel"])
+	assert.Equal(t, 2.0, earned["comma"])
+	assert.Equal(t, 0.0, earned["invalid"])
+}
+
+func TestQuizController_GradeQuiz_ShortAnswer(t *testing.T) {
+	ctrl := controller.QuizController{}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "accents", Type: "short", Points: 1, AcceptedAnswers: []string{"Revolución Francesa"}},
+		{ID: "correct", Type: "short", Points: 1, CorrectAnswer: "Bogotá"},
+		{ID: "pattern", Type: "short", Points: 1, Pattern: `^h2o$`},
+		{ID: "wrong", Type: "short", Points: 1, AcceptedAnswers: []string{"Quito"}},
+	}}
+	answers := map[string]interface{}{
+		"accents": "  revolucion   FRANCESA ",
+		"correct": "bogota",
+		"pattern": "H2O",
+		"wrong":   "Lima",
+	}
+
+	earned := earnedByQuestion(ctrl.GradeQuiz(quiz, answers))
+
+	assert.Equal(t, 1.0, earned["accents"])
+	assert.Equal(t, 1.0, earned["correct"])
+	assert.Equal(t, 1.0, earned["pattern"])
+	assert.Equal(t, 0.0, earned["wrong"])
+}
+
+func TestQuizController_GradeQuiz_OrderingAndMatching(t *testing.T) {
+	ctrl := controller.QuizController{}
+	order := []string{"Mercurio", "Venus", "Tierra", "Marte"}
+	pairs := map[string]string{"Ecuador": "Quito", "Perú": "Lima", "Chile": "Santiago", "Colombia": "Bogotá"}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "order_ok", Type: "ordering", Points: 4, Options: order, CorrectAnswers: order},
+		{ID: "order_partial", Type: "ordering", Points: 4, Options: order, CorrectAnswers: order, Scoring: domain.ScoringProportional},
+		{ID: "order_all", Type: "ordering", Points: 4, Options: order, CorrectAnswers: order},
+		{ID: "match_partial", Type: "matching", Points: 4, CorrectPairs: pairs, Scoring: domain.ScoringProportional},
+		{ID: "match_bad_shape", Type: "matching", Points: 4, CorrectPairs: pairs},
+	}}
+	answers := map[string]interface{}{
+		"order_ok":        []interface{}{"Mercurio", "Venus", "Tierra", "Marte"},
+		"order_partial":   []interface{}{"Mercurio", "Venus", "Marte", "Tierra"},
+		"order_all":       []interface{}{"Mercurio", "Venus", "Marte", "Tierra"},
+		"match_partial":   map[string]interface{}{"Ecuador": "Quito", "Perú": "Lima", "Chile": "Bogotá"},
+		"match_bad_shape": []interface{}{"Quito"},
+	}
+
+	earned := earnedByQuestion(ctrl.GradeQuiz(quiz, answers))
+
+	assert.Equal(t, 4.0, earned["order_ok"])
+	assert.Equal(t, 2.0, earned["order_partial"])
+	assert.Equal(t, 0.0, earned["order_all"])
+	assert.Equal(t, 2.0, earned["match_partial"])
+	assert.Equal(t, 0.0, earned["match_bad_shape"])
+}
+
+type fixedQuestionType struct{}
+
+func (fixedQuestionType) ValidateKey(domain.TeacherQuizQuestion) error { return nil }
+func (fixedQuestionType) ValidateAnswer(domain.TeacherQuizQuestion, interface{}) error {
+	return nil
+}
+func (fixedQuestionType) Grade(domain.TeacherQuizQuestion, interface{}) (float64, bool) {
+	return 0.5, false
+}
+
+func TestQuizController_GradeQuiz_RegistryExtension(t *testing.T) {
+	ctrl := controller.QuizController{}
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "custom", Type: "test_fixed", Points: 4},
+		{ID: "unknown", Type: "no_registrado", Points: 4},
+	}}
+	answers := map[string]interface{}{"custom": "x", "unknown": "y"}
+
+	result := ctrl.GradeQuiz(quiz, answers)
+	assert.True(t, result.NeedsReview, "un tipo desconocido queda pendiente de revisión")
+
+	controller.RegisterQuestionType("test_fixed", fixedQuestionType{})
+	result = ctrl.GradeQuiz(quiz, answers)
+	assert.Equal(t, 2.0, earnedByQuestion(result)["custom"])
+	_, ok := controller.LookupQuestionType("test_fixed")
+	assert.True(t, ok)
+}
+
+func TestQuestionTypes_ValidateKey(t *testing.T) {
+	cases := []struct {
+		name     string
+		question domain.TeacherQuizQuestion
+		wantErr  bool
+	}{
+		{"numeric ok", domain.TeacherQuizQuestion{Type: "numeric", CorrectAnswer: 3.0, Tolerance: 0.1}, false},
+		{"numeric sin número", domain.TeacherQuizQuestion{Type: "numeric", CorrectAnswer: "tres"}, true},
+		{"short regex inválida", domain.TeacherQuizQuestion{Type: "short", Pattern: "("}, true},
+		{"ordering incompleto", domain.TeacherQuizQuestion{Type: "ordering", Options: []string{"a", "b"}, CorrectAnswers: []string{"a"}}, true},
+		{"matching opción inexistente", domain.TeacherQuizQuestion{Type: "matching", Options: []string{"x"}, CorrectPairs: map[string]string{"a": "y"}}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			questionType, ok := controller.LookupQuestionType(tc.question.Type)
+			assert.True(t, ok)
+			err := questionType.ValidateKey(tc.question)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "arbol nino", controller.NormalizeText("  Árbol   Niño "))
+}
+
+func TestValidateTeacherQuiz(t *testing.T) {
+	valid := `{"title":"Quiz","settings":{"maxAttempts":2},"questions":[
+		{"id":"q1","type":"boolean","question":"¿El agua hierve a 100 °C?","points":1,"correctAnswer":true},
+		{"id":"q2","type":"numeric","question":"g","points":2,"correctAnswer":9.81,"tolerance":0.1}
+	],"pools":[{"id":"bank","draw":1,"questions":[
+		{"id":"p1","type":"text","question":"Explique","points":3}
+	]}]}`
+	assert.Nil(t, controller.ValidateTeacherQuiz([]byte(valid)))
+
+	invalid := `{"settings":{"maxAttempts":-1},"questions":[
+		{"id":"","type":"checkbox","question":"Elija","points":-1,"options":["A","B"],"correctAnswers":["C"]},
+		{"id":"q2","type":"short","question":"Capital","points":1}
+	],"pools":[{"id":"bank","draw":3,"questions":[
+		{"id":"q2","type":"boolean","question":"x","points":1,"correctAnswer":"quizás"}
+	]}]}`
+	errs := controller.ValidateTeacherQuiz([]byte(invalid))
+	assert.Contains(t, errs, "settings.maxAttempts")
+	assert.Contains(t, errs, "questions[0].id")
+	assert.Contains(t, errs, "questions[0].points")
+	assert.Contains(t, errs, "questions[0].correctAnswers")
+	assert.Contains(t, errs, "questions[1].acceptedAnswers")
+	assert.Contains(t, errs, "pools[0].draw")
+	assert.Contains(t, errs, "pools[0].questions[0].id")
+	assert.Contains(t, errs, "pools[0].questions[0].correctAnswer")
+
+	typeErrs := controller.ValidateTeacherQuiz([]byte(`{"questions":[{"id":"q1","points":"diez"}]}`))
+	assert.Contains(t, typeErrs, "questions[0].points")
+}
+
+func essayRubric() []domain.RubricCriterion {
+	return []domain.RubricCriterion{
+		{ID: "arg", Title: "Argumentación", Levels: []domain.RubricLevel{
+			{ID: "low", Title: "Insuficiente", Points: 0},
+			{ID: "mid", Title: "Aceptable", Points: 2},
+			{ID: "high", Title: "Excelente", Points: 4},
+		}},
+		{ID: "ort", Title: "Ortografía", Levels: []domain.RubricLevel{
+			{ID: "bad", Title: "Con errores", Points: 0},
+			{ID: "good", Title: "Correcta", Points: 2},
+		}},
+	}
+}
+
+func TestGradeRubric(t *testing.T) {
+	scores, total, err := controller.GradeRubric(essayRubric(), map[string]string{"arg": "mid", "ort": "good"})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, total)
+	require.Len(t, scores, 2)
+	assert.Equal(t, "Aceptable", scores[0].Level)
+	assert.Equal(t, 4.0, scores[0].MaxPoints)
+
+	_, _, err = controller.GradeRubric(essayRubric(), map[string]string{"arg": "mid"})
+	assert.Error(t, err, "todos los criterios deben tener nivel")
+
+	_, _, err = controller.GradeRubric(essayRubric(), map[string]string{"arg": "mid", "ort": "perfecta"})
+	assert.Error(t, err)
+
+	_, _, err = controller.GradeRubric(essayRubric(), map[string]string{"arg": "mid", "ort": "good", "extra": "x"})
+	assert.Error(t, err)
+}
+
+func TestValidateTeacherQuiz_Rubric(t *testing.T) {
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "text", Question: "Ensayo", Points: 6, Rubric: essayRubric()},
+	}}
+	valid, _ := json.Marshal(quiz)
+	assert.Nil(t, controller.ValidateTeacherQuiz(valid))
+
+	quiz.Questions[0].Points = 10
+	quiz.Questions[0].Rubric[1].Levels[1].ID = "bad"
+	invalid, _ := json.Marshal(quiz)
+	errs := controller.ValidateTeacherQuiz(invalid)
+	assert.Contains(t, errs, "questions[0].rubric")
+	assert.Contains(t, errs, "questions[0].rubric[1].levels[1].id")
+
+	notText, _ := json.Marshal(domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "boolean", Question: "x", Points: 6, CorrectAnswer: true, Rubric: essayRubric()},
+	}})
+	assert.Contains(t, controller.ValidateTeacherQuiz(notText), "questions[0].rubric")
+}
@@ -0,0 +1,153 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTeacherContext(method, path string, body []byte) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = &CustomValidator{Validator: validator.New()}
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "teacher-1")
+	return c, rec
+}
+
+func TestQuizController_Regrade_ReportAndCommit(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	base := "https://test-account.r2.cloudflarestorage.com/"
+
+	correctedQuiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "multiple", Points: 5, Options: []string{"A", "B"}, CorrectAnswer: "B"},
+		{ID: "q2", Type: "text", Points: 5},
+	}}
+	r2 := map[string]interface{}{
+		"focused/123/quiz/teacher/content-quiz-1.json": correctedQuiz,
+		"answers/1.json": map[string]interface{}{"q1": "B", "q2": "respuesta"},
+		// Una revisión escrita por el estudiante en su archivo de respuestas no cuenta
+		"answers/2.json": map[string]interface{}{"q1": "A", "q2": "otra",
+			"extra_review": []interface{}{map[string]interface{}{"q2": map[string]interface{}{"value": "otra", "points_awarded": 99}}}},
+		"answers/3.json": map[string]interface{}{"q1": "B"},
+	}
+
+	grade := func(v float64) *float64 { return &v }
+	total := 10
+	quizURL := base + "focused/123/quiz/teacher/content-quiz-1.json"
+	attempts := []domain.QuizAnswer{
+		{QuizAnswerID: 1, UserID: "s1", QuizURL: quizURL, QuizAnswerURL: base + "answers/1.json", Grade: grade(3), TotalPoints: &total},
+		{QuizAnswerID: 2, UserID: "s2", QuizURL: quizURL, QuizAnswerURL: base + "answers/2.json", Grade: grade(5), TotalPoints: &total},
+		{QuizAnswerID: 3, UserID: "s3", QuizURL: quizURL, QuizAnswerURL: base + "answers/3.json", Grade: grade(5), TotalPoints: &total},
+	}
+
+	reviewer := "teacher-1"
+	manualScore, manualCorrect := 3.0, false
+	var updated []domain.QuizAnswer
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindSubmittedByContentFn: func(contentID string) ([]domain.QuizAnswer, error) {
+				assert.Equal(t, "content-quiz-1", contentID)
+				return attempts, nil
+			},
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				return attempts[id-1], nil
+			},
+			FindQuizAnswerItemsFn: func(quizAnswerID int) ([]domain.QuizAnswerItem, error) {
+				if quizAnswerID != 1 {
+					return nil, nil
+				}
+				return []domain.QuizAnswerItem{
+					{QuizAnswerID: 1, QuestionID: "q2", QuestionType: "text", Points: 5, Score: &manualScore, IsCorrect: &manualCorrect, ReviewerID: &reviewer},
+				}, nil
+			},
+			UpdateQuizAttemptMock: func(attempt domain.QuizAnswer) error {
+				updated = append(updated, attempt)
+				return nil
+			},
+		},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				assert.Equal(t, "teacher-1", teacherID)
+				assert.Equal(t, 123, courseID)
+				return domain.CourseDB{}, nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			return json.Marshal(r2[key])
+		},
+		RegradeJobs: controller.NewRegradeJobManager(),
+	}
+
+	c, rec := newTeacherContext(http.MethodPost, "/quiz/teacher/regrade", []byte(`{"content_id":"content-quiz-1"}`))
+	require.NoError(t, ctrl.StartRegrade()(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var started struct {
+		Body struct {
+			JobID string `json:"job_id"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &started))
+	require.NotEmpty(t, started.Body.JobID)
+
+	var job domain.RegradeJob
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = ctrl.RegradeJobs.Get(started.Body.JobID)
+		return ok && job.Status == domain.RegradeStatusReady
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 1, job.Unchanged)
+	require.Len(t, job.Changes, 2)
+	newGrades := map[int]float64{}
+	for _, change := range job.Changes {
+		newGrades[change.QuizAnswerID] = change.NewGrade
+	}
+	assert.Equal(t, 8.0, newGrades[1], "se conservan los puntos manuales de la pregunta de texto")
+	assert.Equal(t, 0.0, newGrades[2])
+	assert.Empty(t, updated, "no se guarda nada antes de confirmar")
+
+	c, rec = newTeacherContext(http.MethodGet, "/quiz/teacher/regrade/"+started.Body.JobID, nil)
+	c.SetParamNames("jobId")
+	c.SetParamValues(started.Body.JobID)
+	require.NoError(t, ctrl.GetRegradeJob()(c))
+	assert.Contains(t, rec.Body.String(), `"status":"ready"`)
+
+	c, rec = newTeacherContext(http.MethodPost, "/quiz/teacher/regrade/"+started.Body.JobID+"/commit", nil)
+	c.SetParamNames("jobId")
+	c.SetParamValues(started.Body.JobID)
+	require.NoError(t, ctrl.CommitRegrade()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"committed":2`)
+	require.Len(t, updated, 2)
+	for _, attempt := range updated {
+		assert.Equal(t, newGrades[attempt.QuizAnswerID], *attempt.Grade)
+	}
+
+	// Un segundo commit no vuelve a aplicar los cambios
+	c, rec = newTeacherContext(http.MethodPost, "/quiz/teacher/regrade/"+started.Body.JobID+"/commit", nil)
+	c.SetParamNames("jobId")
+	c.SetParamValues(started.Body.JobID)
+	require.NoError(t, ctrl.CommitRegrade()(c))
+	assert.True(t, strings.Contains(rec.Body.String(), "no se puede confirmar"))
+	assert.Len(t, updated, 2)
+}
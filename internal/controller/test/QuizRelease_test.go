@@ -0,0 +1,326 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnswersReleased(t *testing.T) {
+	now := time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		settings  domain.QuizSettings
+		released  bool
+		submitted bool
+		want      bool
+	}{
+		{"Sin política equivale a never", domain.QuizSettings{}, true, true, false},
+		{"Never", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersNever}, true, true, false},
+		{"Después del envío", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterSubmission}, false, true, true},
+		{"Antes del envío", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterSubmission}, false, false, false},
+		{"Quiz cerrado", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterClose, ClosesAt: &past}, false, true, true},
+		{"Quiz abierto", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterClose, ClosesAt: &future}, false, true, false},
+		{"Publicadas por el profesor", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterRelease}, true, false, true},
+		{"Sin publicar", domain.QuizSettings{ReleaseAnswers: domain.ReleaseAnswersAfterRelease}, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, controller.AnswersReleased(tt.settings, tt.released, tt.submitted, now))
+		})
+	}
+}
+
+func TestValidateTeacherQuiz_ReleaseAnswers(t *testing.T) {
+	errs := controller.ValidateTeacherQuiz([]byte(`{
+		"settings": {"releaseAnswers": "after_close"},
+		"questions": [{"id": "q1", "type": "multiple", "question": "¿2+2?", "points": 1,
+			"options": ["3", "4"], "correctAnswer": "4",
+			"optionExplanations": {"4": "Correcto", "5": "No es una opción"}}]
+	}`))
+	assert.Equal(t, "Required when releaseAnswers is after_close", errs["settings.closesAt"])
+	assert.Equal(t, `Unknown option "5"`, errs["questions[0].optionExplanations"])
+
+	errs = controller.ValidateTeacherQuiz([]byte(`{"settings": {"releaseAnswers": "soon"}, "questions": [{"id": "q1", "type": "boolean", "question": "¿Sí?", "points": 1, "correctAnswer": true}]}`))
+	assert.Equal(t, "Invalid value", errs["settings.releaseAnswers"])
+}
+
+func releaseQuiz(policy string) domain.TeacherQuiz {
+	return domain.TeacherQuiz{
+		Settings: domain.QuizSettings{ReleaseAnswers: policy},
+		Questions: []domain.TeacherQuizQuestion{{
+			ID: "q1", Type: "multiple", Question: "¿2+2?", Points: 10,
+			Options: []string{"3", "4"}, CorrectAnswer: "4",
+			Explanation:        "Suma básica",
+			OptionExplanations: map[string]string{"3": "Falta una unidad"},
+		}},
+	}
+}
+
+func TestQuizController_SubmitQuiz_ReleasePolicy(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	submit := func(t *testing.T, quiz domain.TeacherQuiz, releases mockAnswerReleaseRepo) map[string]interface{} {
+		ctrl := controller.QuizController{
+			QuizRepo:          mockQuizRepo{SaveQuizAttemptFn: func(domain.QuizAnswer) error { return nil }},
+			AssignmentRepo:    mockAssignmentRepo{},
+			QuizVersionRepo:   mockQuizVersionRepo{},
+			DueDateRepo:       mockDueDateRepo{},
+			AnswerReleaseRepo: releases,
+			CourseContentRepo: quizContentRepoMock("test-account"),
+			UploadStudentAnswers: func(key string, data []byte) error {
+				return nil
+			},
+			GetTeacherQuizContent: mockGetFromR2(t, quiz),
+		}
+		c, rec := newQuizContext(t, "/quiz/submit", domain.StudentQuizAnswersInput{
+			ContentID: "content-quiz-1",
+			Answers:   map[string]interface{}{"q1": "3"},
+		})
+		require.NoError(t, ctrl.SubmitQuiz()(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			Body map[string]interface{}
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.Body
+	}
+
+	t.Run("Sin publicar no devuelve la clave", func(t *testing.T) {
+		body := submit(t, releaseQuiz(domain.ReleaseAnswersAfterRelease), mockAnswerReleaseRepo{})
+		assert.Equal(t, false, body["answers_released"])
+		question := body["quizTeacherResponse"].(map[string]interface{})["questions"].([]interface{})[0].(map[string]interface{})
+		assert.NotContains(t, question, "correctAnswer")
+		assert.NotContains(t, question, "explanation")
+		assert.NotContains(t, question, "optionExplanations")
+		assert.Equal(t, "¿2+2?", question["question"])
+	})
+
+	t.Run("Baraja las preguntas ordering", func(t *testing.T) {
+		order := []string{"uno", "dos", "tres"}
+		quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "ordering", Question: "Ordene", Points: 1, Options: order, CorrectAnswers: order},
+		}}
+		for i := 0; i < 5; i++ {
+			body := submit(t, quiz, mockAnswerReleaseRepo{})
+			question := body["quizTeacherResponse"].(map[string]interface{})["questions"].([]interface{})[0].(map[string]interface{})
+			assert.NotContains(t, question, "correctAnswers")
+			assert.ElementsMatch(t, []interface{}{"uno", "dos", "tres"}, question["options"])
+			assert.NotEqual(t, []interface{}{"uno", "dos", "tres"}, question["options"])
+		}
+	})
+
+	t.Run("Después del envío devuelve clave y explicaciones", func(t *testing.T) {
+		body := submit(t, releaseQuiz(domain.ReleaseAnswersAfterSubmission), mockAnswerReleaseRepo{})
+		assert.Equal(t, true, body["answers_released"])
+		question := body["quizTeacherResponse"].(map[string]interface{})["questions"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "4", question["correctAnswer"])
+		assert.Equal(t, "Falta una unidad", question["optionExplanations"].(map[string]interface{})["3"])
+	})
+
+	t.Run("Publicadas por el profesor", func(t *testing.T) {
+		body := submit(t, releaseQuiz(domain.ReleaseAnswersAfterRelease), mockAnswerReleaseRepo{
+			GetAnswerReleaseFn: func(contentID string) (domain.QuizAnswerRelease, error) {
+				return domain.QuizAnswerRelease{ContentID: contentID, ReleasedAt: time.Now()}, nil
+			},
+		})
+		assert.Equal(t, true, body["answers_released"])
+	})
+}
+
+func TestQuizController_GetQuizzesByStudent_SignsStudentCopyUntilReleased(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/quiz/student", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByStudentFunc: func(userID string) ([]domain.QuizAttemptView, error) {
+				return []domain.QuizAttemptView{{
+					QuizAnswerID:  7,
+					UserID:        userID,
+					CourseID:      101,
+					ContentID:     "quiz-123",
+					EndTime:       time.Now(),
+					QuizURL:       "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/quiz-123.json",
+					QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/path/to/answer.json",
+				}}, nil
+			},
+		},
+		AnswerReleaseRepo: mockAnswerReleaseRepo{},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			assert.Equal(t, "focused/101/quiz/teacher/quiz-123.json", key)
+			return json.Marshal(releaseQuiz(domain.ReleaseAnswersAfterRelease))
+		},
+		QuizVersionRepo:      mockQuizVersionRepo{},
+		GeneratePresignedURL: func(bucket, key string) (string, error) { return "https://signed.url/" + key, nil },
+	}
+
+	require.NoError(t, ctrl.GetQuizzesByStudent()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz_url":"https://signed.url/focused/101/quiz/student/quiz-123.json"`)
+	assert.NotContains(t, rec.Body.String(), "quiz/teacher")
+	assert.NotContains(t, rec.Body.String(), `"answers_released"`)
+}
+
+func TestQuizController_GetQuizzesByStudent_WithholdsGradesAndSignsVariant(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/quiz/student", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByStudentFunc: func(userID string) ([]domain.QuizAttemptView, error) {
+				return []domain.QuizAttemptView{{
+					QuizAnswerID:  7,
+					UserID:        userID,
+					CourseID:      101,
+					ContentID:     "quiz-123",
+					EndTime:       time.Now(),
+					QuizURL:       "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/quiz-123.json",
+					QuizAnswerURL: "https://test-account.r2.cloudflarestorage.com/path/to/answer.json",
+					VariantURL:    "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/variant/quiz-123/student-123-9.json",
+				}}, nil
+			},
+			FindQuizAnswerItemsByAttemptsFn: func(ids []int) ([]domain.QuizAnswerItem, error) {
+				correct := true
+				score := 2.0
+				return []domain.QuizAnswerItem{{QuizAnswerID: 7, QuestionID: "q1", Points: 2, Score: &score, IsCorrect: &correct, Feedback: "Muy bien"}}, nil
+			},
+		},
+		AnswerReleaseRepo: mockAnswerReleaseRepo{},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			return json.Marshal(releaseQuiz(domain.ReleaseAnswersNever))
+		},
+		QuizVersionRepo:      mockQuizVersionRepo{},
+		GeneratePresignedURL: func(bucket, key string) (string, error) { return "https://signed.url/" + key, nil },
+	}
+
+	require.NoError(t, ctrl.GetQuizzesByStudent()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `"quiz_url":"https://signed.url/focused/101/quiz/variant/quiz-123/student-123-9-student.json"`)
+	assert.Contains(t, body, `"question_id":"q1"`)
+	assert.Contains(t, body, `"is_correct":null`)
+	assert.Contains(t, body, `"score":null`)
+	assert.NotContains(t, body, "Muy bien")
+}
+
+func TestQuizController_GetQuizzesByStudent_CachesQuizVersion(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	fetches := 0
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByStudentFunc: func(userID string) ([]domain.QuizAttemptView, error) {
+				return []domain.QuizAttemptView{{
+					QuizAnswerID: 7, UserID: userID, CourseID: 101, ContentID: "quiz-123", EndTime: time.Now(),
+					QuizURL: "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/quiz-123.json",
+				}}, nil
+			},
+		},
+		QuizVersionRepo: mockQuizVersionRepo{GetLatestQuizVersionFn: func(contentID string) (domain.QuizVersion, error) {
+			return domain.QuizVersion{QuizVersionID: 3, ContentID: contentID, Version: 2,
+				QuizURL: "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/quiz-123/v2.json"}, nil
+		}},
+		AnswerReleaseRepo: mockAnswerReleaseRepo{},
+		QuizCache:         controller.NewQuizVersionCache(),
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			fetches++
+			assert.Equal(t, "focused/101/quiz/teacher/quiz-123/v2.json", key)
+			return json.Marshal(releaseQuiz(domain.ReleaseAnswersNever))
+		},
+		GeneratePresignedURL: func(bucket, key string) (string, error) { return "https://signed.url/" + key, nil },
+	}
+
+	for i := 0; i < 3; i++ {
+		c, rec := newTeacherContext(http.MethodGet, "/quiz/student", nil)
+		c.Set("user_id", "student-123")
+		require.NoError(t, ctrl.GetQuizzesByStudent()(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, 1, fetches)
+}
+
+func TestQuizController_ReleaseQuizAnswers(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	newController := func(policy string, releases mockAnswerReleaseRepo) controller.QuizController {
+		return controller.QuizController{
+			AnswerReleaseRepo: releases,
+			QuizVersionRepo:   mockQuizVersionRepo{},
+			CourseContentRepo: quizContentRepoMock("test-account"),
+			CourseRepo: MockCourseRepo{
+				GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+					return domain.CourseDB{CourseID: courseID}, nil
+				},
+			},
+			GetTeacherQuizContent: mockGetFromR2(t, releaseQuiz(policy)),
+		}
+	}
+
+	t.Run("Publica las respuestas", func(t *testing.T) {
+		var saved domain.QuizAnswerRelease
+		ctrl := newController(domain.ReleaseAnswersAfterRelease, mockAnswerReleaseRepo{
+			SaveAnswerReleaseFn: func(release domain.QuizAnswerRelease) error {
+				saved = release
+				return nil
+			},
+		})
+		body, _ := json.Marshal(domain.QuizAnswerReleaseInput{ContentID: "content-quiz-1", Released: true})
+		c, rec := newTeacherContext(http.MethodPut, "/quiz/teacher/release", body)
+		require.NoError(t, ctrl.ReleaseQuizAnswers()(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "content-quiz-1", saved.ContentID)
+		assert.Equal(t, "teacher-1", saved.ReleasedBy)
+	})
+
+	t.Run("Retira la publicación", func(t *testing.T) {
+		deleted := ""
+		ctrl := newController(domain.ReleaseAnswersAfterRelease, mockAnswerReleaseRepo{
+			DeleteAnswerReleaseFn: func(contentID string) error {
+				deleted = contentID
+				return nil
+			},
+		})
+		body, _ := json.Marshal(domain.QuizAnswerReleaseInput{ContentID: "content-quiz-1"})
+		c, rec := newTeacherContext(http.MethodPut, "/quiz/teacher/release", body)
+		require.NoError(t, ctrl.ReleaseQuizAnswers()(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "content-quiz-1", deleted)
+	})
+
+	t.Run("El quiz no usa publicación manual", func(t *testing.T) {
+		ctrl := newController(domain.ReleaseAnswersAfterSubmission, mockAnswerReleaseRepo{
+			SaveAnswerReleaseFn: func(domain.QuizAnswerRelease) error {
+				t.Fatal("no debe guardar la publicación")
+				return nil
+			},
+		})
+		body, _ := json.Marshal(domain.QuizAnswerReleaseInput{ContentID: "content-quiz-1", Released: true})
+		c, rec := newTeacherContext(http.MethodPut, "/quiz/teacher/release", body)
+		require.NoError(t, ctrl.ReleaseQuizAnswers()(c))
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "after_release")
+	})
+}
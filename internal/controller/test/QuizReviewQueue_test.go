@@ -0,0 +1,122 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reviewQueueController(t *testing.T, rows []domain.ReviewQueueRow, filters *[]domain.ReviewQueueFilter) controller.QuizController {
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "text", Question: "Explique la fotosíntesis", Points: 5},
+	}}
+	return controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindPendingReviewItemsFn: func(filter domain.ReviewQueueFilter) ([]domain.ReviewQueueRow, error) {
+				*filters = append(*filters, filter)
+				// Simula el orden y el cursor de la consulta
+				result := []domain.ReviewQueueRow{}
+				for _, row := range rows {
+					if filter.AfterEndTime != nil && !row.EndTime.After(*filter.AfterEndTime) &&
+						!(row.EndTime.Equal(*filter.AfterEndTime) && row.QuizAnswerItemID > filter.AfterItemID) {
+						continue
+					}
+					if len(result) < filter.Limit {
+						result = append(result, row)
+					}
+				}
+				return result, nil
+			},
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, quiz),
+	}
+}
+
+func getReviewQueue(t *testing.T, ctrl controller.QuizController, query string) domain.ReviewQueuePage {
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/review-queue?"+query, nil)
+	require.NoError(t, ctrl.GetReviewQueue()(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var page domain.ReviewQueuePage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	return page
+}
+
+func TestQuizController_GetReviewQueue(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	base := "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/"
+	submitted := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	rows := []domain.ReviewQueueRow{
+		{QuizAnswerItemID: 11, QuizAnswerID: 1, QuestionID: "q1", Answer: `"La luz produce glucosa"`, Points: 5, ContentID: "quiz-1", UserID: "s1", StudentName: "Ana", StudentLastname: "Ruiz", EndTime: submitted, CourseID: 101, QuizURL: base + "quiz-1.json"},
+		{QuizAnswerItemID: 12, QuizAnswerID: 2, QuestionID: "q1", Answer: `"Las plantas comen sol"`, Points: 5, ContentID: "quiz-1", UserID: "s2", StudentName: "Luis", EndTime: submitted, CourseID: 101, QuizURL: base + "quiz-1.json"},
+		{QuizAnswerItemID: 9, QuizAnswerID: 3, QuestionID: "q1", Answer: `"No sé"`, Points: 5, ContentID: "quiz-1", UserID: "s3", EndTime: submitted.Add(time.Hour), CourseID: 101, QuizURL: base + "quiz-1.json"},
+	}
+
+	var filters []domain.ReviewQueueFilter
+	ctrl := reviewQueueController(t, rows, &filters)
+
+	page := getReviewQueue(t, ctrl, "course_id=101&content_id=quiz-1&limit=2")
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "teacher-1", filters[0].TeacherID)
+	assert.Equal(t, 101, filters[0].CourseID)
+	assert.Equal(t, "quiz-1", filters[0].ContentID)
+	assert.Equal(t, 3, filters[0].Limit, "se pide un ítem de más para detectar la página siguiente")
+	assert.Equal(t, "Explique la fotosíntesis", page.Items[0].Question)
+	assert.Equal(t, "La luz produce glucosa", page.Items[0].Answer)
+	assert.Equal(t, "Ana Ruiz", page.Items[0].StudentLabel)
+	assert.Equal(t, "s1", page.Items[0].UserID)
+	require.NotEmpty(t, page.NextCursor)
+
+	// El cursor continúa después del último ítem devuelto
+	next := getReviewQueue(t, ctrl, "limit=2&cursor="+page.NextCursor)
+	require.Len(t, next.Items, 1)
+	assert.Equal(t, 9, next.Items[0].QuizAnswerItemID)
+	assert.Empty(t, next.NextCursor)
+	require.NotNil(t, filters[1].AfterEndTime)
+	assert.True(t, filters[1].AfterEndTime.Equal(submitted))
+	assert.Equal(t, 12, filters[1].AfterItemID)
+}
+
+func TestQuizController_GetReviewQueue_Anonymous(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	row := domain.ReviewQueueRow{
+		QuizAnswerItemID: 11, QuizAnswerID: 1, QuestionID: "q1", Answer: `"texto"`, ContentID: "quiz-1",
+		UserID: "s1", StudentName: "Ana", StudentEmail: "ana@example.com", EndTime: time.Now(),
+		QuizURL: "https://test-account.r2.cloudflarestorage.com/focused/101/quiz/teacher/quiz-1.json",
+	}
+	var filters []domain.ReviewQueueFilter
+	ctrl := reviewQueueController(t, []domain.ReviewQueueRow{row}, &filters)
+
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/review-queue?anonymous=true", nil)
+	require.NoError(t, ctrl.GetReviewQueue()(c))
+	assert.NotContains(t, rec.Body.String(), "Ana")
+	assert.NotContains(t, rec.Body.String(), "ana@example.com")
+	assert.NotContains(t, rec.Body.String(), `"user_id"`)
+
+	first := getReviewQueue(t, ctrl, "anonymous=true")
+	second := getReviewQueue(t, ctrl, "anonymous=1")
+	assert.True(t, first.Anonymous)
+	assert.Regexp(t, `^Estudiante [0-9a-f]{8}$`, first.Items[0].StudentLabel)
+	assert.Equal(t, first.Items[0].StudentLabel, second.Items[0].StudentLabel)
+}
+
+func TestQuizController_GetReviewQueue_InvalidParams(t *testing.T) {
+	var filters []domain.ReviewQueueFilter
+	ctrl := reviewQueueController(t, nil, &filters)
+
+	for _, query := range []string{"course_id=abc", "limit=0", "limit=500", "anonymous=quizás", "cursor=bm9wZQ"} {
+		c, _ := newTeacherContext(http.MethodGet, "/quiz/teacher/review-queue?"+query, nil)
+		err := ctrl.GetReviewQueue()(c)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+	}
+	assert.Empty(t, filters)
+}
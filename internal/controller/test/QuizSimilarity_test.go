@@ -0,0 +1,132 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSimilarAnswers(t *testing.T) {
+	answers := []domain.SimilarityAnswer{
+		{QuizAnswerID: 1, UserID: "s1", Text: "La fotosíntesis transforma la energía de la luz en energía química dentro de los cloroplastos."},
+		// misma respuesta con otras mayúsculas, sin tildes y con otra puntuación
+		{QuizAnswerID: 2, UserID: "s2", Text: "la fotosintesis transforma la energia de la luz en energia quimica, dentro de los cloroplastos"},
+		{QuizAnswerID: 3, UserID: "s3", Text: "Las plantas producen glucosa usando agua, dióxido de carbono y la luz del sol como fuente."},
+		{QuizAnswerID: 4, UserID: "s4", Text: "No sé"},
+		{QuizAnswerID: 5, UserID: "s5", Text: ""},
+	}
+
+	result := controller.FindSimilarAnswers(answers, 0.5)
+
+	assert.Equal(t, 3, result.Compared)
+	assert.Equal(t, 2, result.Skipped)
+	require.Len(t, result.Pairs, 1)
+	assert.Equal(t, 1, result.Pairs[0].First.QuizAnswerID)
+	assert.Equal(t, 2, result.Pairs[0].Second.QuizAnswerID)
+	assert.Equal(t, 1.0, result.Pairs[0].Score)
+}
+
+func TestFindSimilarAnswers_PartialCopy(t *testing.T) {
+	original := "el ciclo del agua comienza con la evaporacion de los oceanos y continua con la condensacion en las nubes"
+	answers := []domain.SimilarityAnswer{
+		{QuizAnswerID: 1, Text: original},
+		{QuizAnswerID: 2, Text: original + " y finalmente la precipitacion devuelve el agua a la tierra"},
+	}
+
+	flagged := controller.FindSimilarAnswers(answers, 0.5)
+	require.Len(t, flagged.Pairs, 1)
+	assert.Greater(t, flagged.Pairs[0].Score, 0.5)
+	assert.Less(t, flagged.Pairs[0].Score, 1.0)
+
+	assert.Empty(t, controller.FindSimilarAnswers(answers, 0.9).Pairs)
+}
+
+func TestQuizController_GetQuizSimilarity(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	base := "https://test-account.r2.cloudflarestorage.com/"
+
+	copied := "La revolución industrial cambió la forma de producir bienes en las fábricas."
+	quiz := domain.TeacherQuiz{
+		Title: "Historia",
+		Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "boolean", Question: "¿Verdadero?", Points: 1, CorrectAnswer: true},
+			{ID: "q2", Type: "text", Question: "Explique la revolución industrial", Points: 5},
+		},
+	}
+	start := time.Now().Add(-time.Hour)
+	courseAttempts := []domain.QuizAttemptView{
+		// primer intento de s1: se reemplaza por el último
+		{QuizAnswerID: 1, UserID: "s1", ContentID: "content-quiz-1", StartTime: start, EndTime: start.Add(time.Minute)},
+		{QuizAnswerID: 2, UserID: "s1", StudentName: "Ana", ContentID: "content-quiz-1", StartTime: start.Add(10 * time.Minute), EndTime: start.Add(11 * time.Minute)},
+		// intento anterior a quiz_answer_item: la respuesta se lee de R2
+		{QuizAnswerID: 3, UserID: "s2", StudentName: "Luis", ContentID: "content-quiz-1", QuizAnswerURL: base + "answers/3.json", StartTime: start, EndTime: start.Add(time.Minute)},
+		{QuizAnswerID: 4, UserID: "s3", ContentID: "otro-quiz", StartTime: start, EndTime: start.Add(time.Minute)},
+	}
+	r2 := map[string]interface{}{
+		"focused/123/quiz/teacher/content-quiz-1.json": quiz,
+		"answers/3.json": map[string]interface{}{"q1": true, "q2": copied},
+	}
+	encoded, _ := json.Marshal(copied)
+
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			GetQuizAttemptsByCourseFn: func(courseID int) ([]domain.QuizAttemptView, error) {
+				return courseAttempts, nil
+			},
+			FindQuizAnswerItemsByAttemptsFn: func(ids []int) ([]domain.QuizAnswerItem, error) {
+				assert.Equal(t, []int{2, 3}, ids)
+				return []domain.QuizAnswerItem{
+					{QuizAnswerID: 2, QuestionID: "q1", QuestionType: "boolean", Answer: "true"},
+					{QuizAnswerID: 2, QuestionID: "q2", QuestionType: "text", Answer: string(encoded)},
+				}, nil
+			},
+		},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		GetTeacherQuizContent: func(bucket, key string) ([]byte, error) {
+			value, ok := r2[key]
+			if !ok {
+				return nil, fmt.Errorf("unexpected key %s", key)
+			}
+			return json.Marshal(value)
+		},
+	}
+
+	c, rec := newTeacherContext(http.MethodGet, "/quiz/teacher/courses/123/quizzes/content-quiz-1/similarity", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "content-quiz-1")
+	require.NoError(t, ctrl.GetQuizSimilarity()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report domain.QuizSimilarityReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Attempts)
+	assert.Equal(t, 0.5, report.Threshold)
+	require.Len(t, report.Questions, 1)
+	assert.Equal(t, "q2", report.Questions[0].QuestionID)
+	require.Len(t, report.Questions[0].Pairs, 1)
+	assert.Equal(t, "Ana", report.Questions[0].Pairs[0].First.StudentName)
+	assert.Equal(t, "Luis", report.Questions[0].Pairs[0].Second.StudentName)
+
+	c, _ = newTeacherContext(http.MethodGet, "/quiz/teacher/courses/123/quizzes/content-quiz-1/similarity?threshold=2", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "content-quiz-1")
+	err := ctrl.GetQuizSimilarity()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
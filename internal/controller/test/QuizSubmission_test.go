@@ -0,0 +1,300 @@
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memorySubmissionKeyRepo guarda las claves en memoria con la misma semántica que la tabla
+type memorySubmissionKeyRepo struct {
+	mu   sync.Mutex
+	keys map[string]domain.QuizSubmissionKey
+}
+
+func newMemorySubmissionKeyRepo() *memorySubmissionKeyRepo {
+	return &memorySubmissionKeyRepo{keys: make(map[string]domain.QuizSubmissionKey)}
+}
+
+func (m *memorySubmissionKeyRepo) ReserveSubmissionKey(key domain.QuizSubmissionKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := key.UserID + "/" + key.IdempotencyKey
+	if _, ok := m.keys[id]; ok {
+		return fmt.Errorf("error reserving submission key: %w", gorm.ErrDuplicatedKey)
+	}
+	m.keys[id] = key
+	return nil
+}
+
+func (m *memorySubmissionKeyRepo) GetSubmissionKey(userID, idempotencyKey string) (domain.QuizSubmissionKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.keys[userID+"/"+idempotencyKey]
+	if !ok {
+		return domain.QuizSubmissionKey{}, gorm.ErrRecordNotFound
+	}
+	return key, nil
+}
+
+func (m *memorySubmissionKeyRepo) CompleteSubmissionKey(key domain.QuizSubmissionKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := key.UserID + "/" + key.IdempotencyKey
+	stored := m.keys[id]
+	stored.QuizAnswerID = key.QuizAnswerID
+	stored.Response = key.Response
+	m.keys[id] = stored
+	return nil
+}
+
+func (m *memorySubmissionKeyRepo) DeleteSubmissionKey(userID, idempotencyKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, userID+"/"+idempotencyKey)
+	return nil
+}
+
+func newSubmitRequest(t *testing.T, idempotencyKey string, input domain.StudentQuizAnswersInput) (echo.Context, *httptest.ResponseRecorder) {
+	body, err := json.Marshal(input)
+	require.NoError(t, err)
+	e := echo.New()
+	e.Validator = &CustomValidator{Validator: validator.New()}
+	req := httptest.NewRequest(http.MethodPost, "/quiz/submit", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user_id", "student-123")
+	return c, rec
+}
+
+func TestQuizController_SubmitQuiz_IdempotencyKey(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	saves, uploads := 0, 0
+	keys := newMemorySubmissionKeyRepo()
+	quiz := domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+		{ID: "q1", Type: "boolean", Points: 10, CorrectAnswer: true},
+	}}
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{SaveQuizAttemptFn: func(domain.QuizAnswer) error {
+			saves++
+			return nil
+		}},
+		AssignmentRepo:    mockAssignmentRepo{},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		SubmissionKeyRepo: keys,
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		UploadStudentAnswers: func(key string, data []byte) error {
+			uploads++
+			return nil
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, quiz),
+	}
+	input := domain.StudentQuizAnswersInput{ContentID: "content-quiz-1", Answers: map[string]interface{}{"q1": true}}
+
+	c, first := newSubmitRequest(t, "retry-1", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// El reintento devuelve la misma respuesta aunque ahora las respuestas sean otras
+	retryInput := input
+	retryInput.Answers = map[string]interface{}{"q1": false}
+	c, retry := newSubmitRequest(t, "retry-1", retryInput)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 1, saves)
+	assert.Equal(t, 1, uploads)
+
+	// La misma clave no sirve para otro quiz
+	otherInput := input
+	otherInput.ContentID = "otro-quiz"
+	c, other := newSubmitRequest(t, "retry-1", otherInput)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.NotEqual(t, http.StatusOK, other.Code)
+	assert.Contains(t, other.Body.String(), "ya se usó para otro quiz")
+
+	// Una clave nueva es un envío nuevo
+	c, second := newSubmitRequest(t, "retry-2", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, 2, saves)
+}
+
+func TestQuizController_SubmitQuiz_IdempotencyKeyReleasedOnFailure(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	keys := newMemorySubmissionKeyRepo()
+	failUpload := true
+	ctrl := controller.QuizController{
+		QuizRepo:          mockQuizRepo{SaveQuizAttemptFn: func(domain.QuizAnswer) error { return nil }},
+		AssignmentRepo:    mockAssignmentRepo{},
+		QuizVersionRepo:   mockQuizVersionRepo{},
+		DueDateRepo:       mockDueDateRepo{},
+		SubmissionKeyRepo: keys,
+		CourseContentRepo: quizContentRepoMock("test-account"),
+		UploadStudentAnswers: func(key string, data []byte) error {
+			if failUpload {
+				return fmt.Errorf("R2 no disponible")
+			}
+			return nil
+		},
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "boolean", Points: 10, CorrectAnswer: true},
+		}}),
+	}
+	input := domain.StudentQuizAnswersInput{ContentID: "content-quiz-1", Answers: map[string]interface{}{"q1": true}}
+
+	c, rec := newSubmitRequest(t, "retry-1", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	_, err := keys.GetSubmissionKey("student-123", "retry-1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	failUpload = false
+	c, rec = newSubmitRequest(t, "retry-1", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+}
+
+func TestQuizController_SubmitQuiz_IdempotencyKeyKeptWhenItemsFail(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	saves := 0
+	keys := newMemorySubmissionKeyRepo()
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			SaveQuizAttemptFn: func(domain.QuizAnswer) error {
+				saves++
+				return nil
+			},
+			SaveQuizAnswerItemsFn: func([]domain.QuizAnswerItem) error {
+				return fmt.Errorf("conexión perdida")
+			},
+		},
+		AssignmentRepo:       mockAssignmentRepo{},
+		QuizVersionRepo:      mockQuizVersionRepo{},
+		DueDateRepo:          mockDueDateRepo{},
+		SubmissionKeyRepo:    keys,
+		CourseContentRepo:    quizContentRepoMock("test-account"),
+		UploadStudentAnswers: func(key string, data []byte) error { return nil },
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{
+			{ID: "q1", Type: "boolean", Points: 10, CorrectAnswer: true},
+		}}),
+	}
+	input := domain.StudentQuizAnswersInput{ContentID: "content-quiz-1", Answers: map[string]interface{}{"q1": true}}
+
+	// El intento ya se guardó: se devuelve y la clave queda completada en lugar de liberarse
+	c, first := newSubmitRequest(t, "retry-1", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	require.Equal(t, http.StatusOK, first.Code)
+	key, err := keys.GetSubmissionKey("student-123", "retry-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.Response)
+
+	c, retry := newSubmitRequest(t, "retry-1", input)
+	require.NoError(t, ctrl.SubmitQuiz()(c))
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 1, saves)
+}
+
+func TestQuizController_AutosaveQuiz(t *testing.T) {
+	var savedID int
+	var savedAnswers map[string]interface{}
+	attempts := map[int]domain.QuizAnswer{
+		5: {QuizAnswerID: 5, UserID: "student-123", ContentID: "content-quiz-1", StartTime: time.Now()},
+		6: {QuizAnswerID: 6, UserID: "otro", ContentID: "content-quiz-1", StartTime: time.Now()},
+	}
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindQuizAttemptByIDMock: func(id int) (domain.QuizAnswer, error) {
+				attempt, ok := attempts[id]
+				if !ok {
+					return domain.QuizAnswer{}, gorm.ErrRecordNotFound
+				}
+				return attempt, nil
+			},
+			SaveQuizDraftFn: func(id int, answers map[string]interface{}, savedAt time.Time) error {
+				savedID, savedAnswers = id, answers
+				return nil
+			},
+		},
+		CourseContentRepo: mockCourseContentRepo{},
+	}
+
+	c, rec := newQuizContext(t, "/quiz/autosave", domain.AutosaveQuizInput{QuizAnswerID: 5, Answers: map[string]interface{}{"q1": "borrador"}})
+	require.NoError(t, ctrl.AutosaveQuiz()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 5, savedID)
+	assert.Equal(t, "borrador", savedAnswers["q1"])
+
+	c, rec = newQuizContext(t, "/quiz/autosave", domain.AutosaveQuizInput{QuizAnswerID: 6, Answers: map[string]interface{}{"q1": "x"}})
+	require.NoError(t, ctrl.AutosaveQuiz()(c))
+	assert.Contains(t, rec.Body.String(), "no encontrado")
+
+	ended := time.Now()
+	attempts[5] = domain.QuizAnswer{QuizAnswerID: 5, UserID: "student-123", EndTime: &ended}
+	c, rec = newQuizContext(t, "/quiz/autosave", domain.AutosaveQuizInput{QuizAnswerID: 5, Answers: map[string]interface{}{"q1": "x"}})
+	require.NoError(t, ctrl.AutosaveQuiz()(c))
+	assert.Contains(t, rec.Body.String(), "ya fue enviado")
+
+	// Un quiz bloqueado por prerrequisitos no guarda borradores
+	attempts[5] = domain.QuizAnswer{QuizAnswerID: 5, UserID: "student-123", ContentID: "content-quiz-1", StartTime: time.Now()}
+	savedID = 0
+	ctrl.CourseContentRepo = lockedQuizContentRepo()
+	c, _ = newQuizContext(t, "/quiz/autosave", domain.AutosaveQuizInput{QuizAnswerID: 5, Answers: map[string]interface{}{"q1": "x"}})
+	assert.ErrorIs(t, ctrl.AutosaveQuiz()(c), domain.ErrContentLocked)
+	assert.Zero(t, savedID)
+}
+
+func TestQuizController_StartQuizAttempt_ResumesAutosavedAnswers(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+
+	savedAt := time.Now().Add(-time.Minute)
+	ctrl := controller.QuizController{
+		QuizRepo: mockQuizRepo{
+			FindOpenQuizAttemptFn: func(userID, contentID string) (domain.QuizAnswer, error) {
+				return domain.QuizAnswer{
+					QuizAnswerID: 9,
+					StartTime:    time.Now().Add(-5 * time.Minute),
+					DraftAnswers: map[string]interface{}{"q1": true},
+					AutosavedAt:  &savedAt,
+				}, nil
+			},
+		},
+		AssignmentRepo:        mockAssignmentRepo{},
+		QuizVersionRepo:       mockQuizVersionRepo{},
+		DueDateRepo:           mockDueDateRepo{},
+		CourseContentRepo:     quizContentRepoMock("test-account"),
+		GetTeacherQuizContent: mockGetFromR2(t, domain.TeacherQuiz{Questions: []domain.TeacherQuizQuestion{{ID: "q1", Type: "boolean", Points: 1, CorrectAnswer: true}}}),
+	}
+
+	c, rec := newQuizContext(t, "/quiz/start", domain.StartQuizAttemptInput{ContentID: "content-quiz-1"})
+	require.NoError(t, ctrl.StartQuizAttempt()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"quiz_answer_id":9`)
+	assert.Contains(t, rec.Body.String(), `"answers":{"q1":true}`)
+}
@@ -0,0 +1,229 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/domain"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type mockSurveyRepo struct {
+	SubmitSurveyResponseFn func(response domain.SurveyResponse, userID string) error
+	GetSurveyResponsesFn   func(contentID string) ([]domain.SurveyResponse, error)
+}
+
+func (m mockSurveyRepo) SubmitSurveyResponse(response domain.SurveyResponse, userID string) error {
+	if m.SubmitSurveyResponseFn != nil {
+		return m.SubmitSurveyResponseFn(response, userID)
+	}
+	return errors.New("SubmitSurveyResponse not implemented")
+}
+
+func (m mockSurveyRepo) GetSurveyResponses(contentID string) ([]domain.SurveyResponse, error) {
+	if m.GetSurveyResponsesFn != nil {
+		return m.GetSurveyResponsesFn(contentID)
+	}
+	return nil, errors.New("GetSurveyResponses not implemented")
+}
+
+func feedbackSurvey(anonymous bool) domain.Survey {
+	return domain.Survey{
+		Title:     "Opinión del curso",
+		Anonymous: anonymous,
+		Questions: []domain.SurveyQuestion{
+			{ID: "s1", Type: "likert", Question: "¿El curso fue útil?", Required: true},
+			{ID: "s2", Type: "multiple", Question: "¿Qué recursos usó?", Options: []string{"Videos", "Lecturas", "Quizzes"}, AllowMultiple: true},
+			{ID: "s3", Type: "open", Question: "Comentarios"},
+		},
+	}
+}
+
+func surveyController(survey domain.Survey, repo mockSurveyRepo) controller.SurveyController {
+	return controller.SurveyController{
+		SurveyRepo: repo,
+		CourseContentRepo: mockCourseContentRepo{
+			GetUrlByContentIDFn: func(id string) (string, error) {
+				return fmt.Sprintf("https://test-account.r2.cloudflarestorage.com/focused/123/survey/teacher/%s.json", id), nil
+			},
+			GetContentTypeIDFn: func(id string) (int, error) { return 4, nil },
+		},
+		CourseRepo: MockCourseRepo{
+			GetCourseByTeacherAndCourseIDT: func(teacherID string, courseID int) (domain.CourseDB, error) {
+				return domain.CourseDB{}, nil
+			},
+		},
+		AssignmentRepo: mockAssignmentRepo{},
+		GetSurveyContent: func(bucket, key string) ([]byte, error) {
+			if key != "focused/123/survey/teacher/survey-1.json" {
+				return nil, fmt.Errorf("unexpected key %s", key)
+			}
+			return json.Marshal(survey)
+		},
+	}
+}
+
+func TestValidateSurvey(t *testing.T) {
+	valid, _ := json.Marshal(feedbackSurvey(true))
+	assert.Nil(t, controller.ValidateSurvey(valid))
+
+	errs := controller.ValidateSurvey([]byte(`{"questions":[
+		{"id":"a","type":"likert","question":"Escala","scale":12},
+		{"id":"a","type":"multiple","question":"Elija","options":["x","x"]},
+		{"id":"c","type":"ranking","question":""}
+	]}`))
+	assert.Contains(t, errs, "questions[0].scale")
+	assert.Contains(t, errs, "questions[1].id")
+	assert.Contains(t, errs, "questions[1].options")
+	assert.Contains(t, errs, "questions[2].type")
+	assert.Contains(t, errs, "questions[2].question")
+}
+
+func TestSurveyController_SubmitSurvey_Anonymous(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	var saved domain.SurveyResponse
+	ctrl := surveyController(feedbackSurvey(true), mockSurveyRepo{
+		SubmitSurveyResponseFn: func(response domain.SurveyResponse, userID string) error {
+			assert.Equal(t, "student-123", userID)
+			saved = response
+			return nil
+		},
+	})
+
+	c, rec := newQuizContext(t, "/survey/submit", domain.SubmitSurveyInput{
+		ContentID: "survey-1",
+		Answers:   map[string]interface{}{"s1": 4, "s2": []string{"Videos", "Quizzes"}, "s3": "  Muy buenos videos "},
+	})
+	require.NoError(t, ctrl.SubmitSurvey()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Nil(t, saved.UserID, "la respuesta anónima no guarda el estudiante")
+	assert.Zero(t, saved.SubmittedAt.Hour())
+	assert.Equal(t, 4, saved.Answers["s1"])
+	assert.Equal(t, []string{"Videos", "Quizzes"}, saved.Answers["s2"])
+	assert.Equal(t, "Muy buenos videos", saved.Answers["s3"])
+}
+
+func TestSurveyController_SubmitSurvey_Errors(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	ctrl := surveyController(feedbackSurvey(false), mockSurveyRepo{
+		SubmitSurveyResponseFn: func(response domain.SurveyResponse, userID string) error {
+			require.NotNil(t, response.UserID)
+			return fmt.Errorf("error saving survey participation: %w", gorm.ErrDuplicatedKey)
+		},
+	})
+
+	// Respuestas inválidas: falta la obligatoria, opción inexistente y pregunta desconocida
+	c, _ := newQuizContext(t, "/survey/submit", domain.SubmitSurveyInput{
+		ContentID: "survey-1",
+		Answers:   map[string]interface{}{"s2": []string{"Podcasts"}, "s9": "x"},
+	})
+	err := ctrl.SubmitSurvey()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	body, _ := json.Marshal(httpErr.Message)
+	assert.Contains(t, string(body), "answers.s1")
+	assert.Contains(t, string(body), "answers.s2")
+	assert.Contains(t, string(body), "answers.s9")
+
+	// Segunda respuesta del mismo estudiante
+	c, rec := newQuizContext(t, "/survey/submit", domain.SubmitSurveyInput{
+		ContentID: "survey-1",
+		Answers:   map[string]interface{}{"s1": 5},
+	})
+	require.NoError(t, ctrl.SubmitSurvey()(c))
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "La encuesta ya fue respondida")
+}
+
+func TestSurveyController_GetSurveyResults(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	responses := []domain.SurveyResponse{
+		{Answers: map[string]interface{}{"s1": 5.0, "s2": []interface{}{"Videos"}, "s3": "Los videos fueron excelentes"}},
+		{Answers: map[string]interface{}{"s1": 4.0, "s2": []interface{}{"Videos", "Lecturas"}, "s3": "Más videos, por favor"}},
+		{Answers: map[string]interface{}{"s1": 2.0}},
+	}
+	ctrl := surveyController(feedbackSurvey(true), mockSurveyRepo{
+		GetSurveyResponsesFn: func(contentID string) ([]domain.SurveyResponse, error) {
+			assert.Equal(t, "survey-1", contentID)
+			return responses, nil
+		},
+	})
+
+	c, rec := newTeacherContext(http.MethodGet, "/survey/teacher/courses/123/surveys/survey-1/results", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("123", "survey-1")
+	require.NoError(t, ctrl.GetSurveyResults()(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var results domain.SurveyResults
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+	assert.Equal(t, 3, results.Responses)
+	assert.True(t, results.Anonymous)
+	require.Len(t, results.Questions, 3)
+
+	likert := results.Questions[0]
+	assert.Equal(t, 3, likert.Answered)
+	require.NotNil(t, likert.Mean)
+	assert.Equal(t, 3.67, *likert.Mean)
+	require.Len(t, likert.Counts, 5)
+	assert.Equal(t, domain.SurveyOptionCount{Option: "5", Count: 1, Ratio: 0.3333}, likert.Counts[4])
+
+	multiple := results.Questions[1]
+	assert.Equal(t, 2, multiple.Answered)
+	assert.Equal(t, []domain.SurveyOptionCount{
+		{Option: "Videos", Count: 2, Ratio: 1},
+		{Option: "Lecturas", Count: 1, Ratio: 0.5},
+		{Option: "Quizzes", Count: 0, Ratio: 0},
+	}, multiple.Counts)
+
+	open := results.Questions[2]
+	assert.Equal(t, 2, open.Answered)
+	require.NotEmpty(t, open.WordFrequency)
+	assert.Equal(t, domain.SurveyWordCount{Word: "videos", Count: 2}, open.WordFrequency[0])
+	assert.NotContains(t, rec.Body.String(), "Los videos fueron excelentes", "no se exponen respuestas individuales")
+
+	// Una encuesta de otro curso no se muestra
+	c, _ = newTeacherContext(http.MethodGet, "/survey/teacher/courses/999/surveys/survey-1/results", nil)
+	c.SetParamNames("courseId", "contentId")
+	c.SetParamValues("999", "survey-1")
+	err := ctrl.GetSurveyResults()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestSurveyController_SubmitSurvey_Locked(t *testing.T) {
+	os.Setenv("R2_ACCOUNT_ID", "test-account")
+	ctrl := surveyController(feedbackSurvey(false), mockSurveyRepo{
+		SubmitSurveyResponseFn: func(response domain.SurveyResponse, userID string) error {
+			t.Fatal("una encuesta bloqueada no debe responderse")
+			return nil
+		},
+	})
+	repo := ctrl.CourseContentRepo.(mockCourseContentRepo)
+	repo.GetContentLocksFn = func(contentID, userID string) ([]domain.ContentLock, error) {
+		assert.Equal(t, "survey-1", contentID)
+		return []domain.ContentLock{{Reason: domain.LockReasonNotCompleted, RequiredContentID: "intro"}}, nil
+	}
+	ctrl.CourseContentRepo = repo
+
+	c, _ := newQuizContext(t, "/survey/submit", domain.SubmitSurveyInput{
+		ContentID: "survey-1",
+		Answers:   map[string]interface{}{"s1": 4},
+	})
+	err := ctrl.SubmitSurvey()(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	assert.ErrorIs(t, err, domain.ErrContentLocked)
+}
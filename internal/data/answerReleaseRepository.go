@@ -0,0 +1,40 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+type answerReleaseRepo struct {
+	db *gorm.DB
+}
+
+func NewAnswerReleaseRepo(db *gorm.DB) domain.AnswerReleaseRepo {
+	return &answerReleaseRepo{db: db}
+}
+
+// GetAnswerRelease obtiene la publicación de respuestas de un quiz
+func (r *answerReleaseRepo) GetAnswerRelease(contentID string) (domain.QuizAnswerRelease, error) {
+	var release domain.QuizAnswerRelease
+	if err := r.db.Where("content_id = ?", contentID).First(&release).Error; err != nil {
+		return domain.QuizAnswerRelease{}, err
+	}
+	return release, nil
+}
+
+// SaveAnswerRelease crea o reemplaza la publicación de respuestas de un quiz
+func (r *answerReleaseRepo) SaveAnswerRelease(release domain.QuizAnswerRelease) error {
+	if err := r.db.Save(&release).Error; err != nil {
+		return fmt.Errorf("error saving answer release: %w", err)
+	}
+	return nil
+}
+
+// DeleteAnswerRelease retira la publicación de respuestas de un quiz
+func (r *answerReleaseRepo) DeleteAnswerRelease(contentID string) error {
+	if err := r.db.Where("content_id = ?", contentID).Delete(&domain.QuizAnswerRelease{}).Error; err != nil {
+		return fmt.Errorf("error deleting answer release: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+// contentPosition ubica un contenido dentro de su curso
+type contentPosition struct {
+	ContentID     string
+	ContentTypeID int
+	Title         string
+	SectionIndex  int
+	CourseID      int
+	ModuleIndex   int
+}
+
+// before indica si el contenido está antes que other en el orden del curso
+func (p contentPosition) before(other contentPosition) bool {
+	if p.ModuleIndex != other.ModuleIndex {
+		return p.ModuleIndex < other.ModuleIndex
+	}
+	return p.SectionIndex < other.SectionIndex
+}
+
+// requiredContent es el estado para un estudiante de un contenido que otro requiere
+type requiredContent struct {
+	ContentID string
+	Title     string
+	StatusID  int
+}
+
+func contentPositionQuery(tx *gorm.DB) *gorm.DB {
+	return tx.Table("content").
+		Select("content.content_id, content.content_type_id, content.title, content.section_index, course_content.course_id, course_content.module_index").
+		Joins("JOIN course_content ON content.course_content_id = course_content.course_content_id")
+}
+
+// ownedContentPosition devuelve la posición de un contenido de los cursos del profesor
+func ownedContentPosition(tx *gorm.DB, contentID, userID string) (contentPosition, error) {
+	var position contentPosition
+	err := contentPositionQuery(tx).
+		Joins("JOIN course ON course_content.course_id = course.course_id").
+		Where("content.content_id = ? AND course.teacher_id = ?", contentID, userID).
+		Take(&position).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return position, fmt.Errorf("%w: content %s not found in the teacher's courses", domain.ErrResourceNotFound, contentID)
+		}
+		return position, fmt.Errorf("error fetching content: %w", err)
+	}
+	return position, nil
+}
+
+// SetPrerequisites reemplaza los prerrequisitos de un contenido del profesor. Los contenidos requeridos
+// deben estar antes en el mismo curso (así no hay ciclos) y el puntaje mínimo solo aplica a quizzes.
+func (r *courseContentRepo) SetPrerequisites(input domain.SetPrerequisitesInput, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		target, err := ownedContentPosition(tx, input.ContentID, userID)
+		if err != nil {
+			return err
+		}
+
+		rules := make([]domain.ContentPrerequisite, 0, len(input.Prerequisites))
+		for _, prerequisite := range input.Prerequisites {
+			var required contentPosition
+			err := contentPositionQuery(tx).
+				Where("content.content_id = ? AND course_content.course_id = ?", prerequisite.RequiredContentID, target.CourseID).
+				Take(&required).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: content %s is not in course %d", domain.ErrValidationFailed, prerequisite.RequiredContentID, target.CourseID)
+				}
+				return fmt.Errorf("error fetching required content: %w", err)
+			}
+			if !required.before(target) {
+				return fmt.Errorf("%w: content %s must come before %s", domain.ErrValidationFailed, required.ContentID, target.ContentID)
+			}
+			if prerequisite.MinScore != nil && required.ContentTypeID != 3 {
+				return fmt.Errorf("%w: min_score only applies to quizzes, %s is not a quiz", domain.ErrValidationFailed, required.ContentID)
+			}
+			rules = append(rules, domain.ContentPrerequisite{
+				ContentID:         target.ContentID,
+				RequiredContentID: required.ContentID,
+				MinScore:          prerequisite.MinScore,
+			})
+		}
+
+		if err := tx.Where("content_id = ?", target.ContentID).Delete(&domain.ContentPrerequisite{}).Error; err != nil {
+			return fmt.Errorf("error deleting prerequisites: %w", err)
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		if err := tx.Create(&rules).Error; err != nil {
+			return fmt.Errorf("error saving prerequisites: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetPrerequisites devuelve los prerrequisitos de un contenido del profesor
+func (r *courseContentRepo) GetPrerequisites(contentID, userID string) ([]domain.ContentPrerequisite, error) {
+	if _, err := ownedContentPosition(r.db, contentID, userID); err != nil {
+		return nil, err
+	}
+	prerequisites := []domain.ContentPrerequisite{}
+	if err := r.db.Where("content_id = ?", contentID).Order("required_content_id").Find(&prerequisites).Error; err != nil {
+		return nil, fmt.Errorf("error fetching prerequisites: %w", err)
+	}
+	return prerequisites, nil
+}
+
+// GetContentLocks devuelve los prerrequisitos de un contenido que el estudiante todavía no cumple
+func (r *courseContentRepo) GetContentLocks(contentID, userID string) ([]domain.ContentLock, error) {
+	var rules []domain.ContentPrerequisite
+	if err := r.db.Where("content_id = ?", contentID).Order("required_content_id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("error fetching prerequisites: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	requiredIDs := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		requiredIDs = append(requiredIDs, rule.RequiredContentID)
+	}
+	var rows []requiredContent
+	err := r.db.Table("content").
+		Select("content.content_id, content.title, user_content.status_id").
+		Joins("JOIN user_content ON user_content.content_id = content.content_id AND user_content.user_id = ?", userID).
+		Where("content.content_id IN ? AND content.is_active = ?", requiredIDs, true).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching required contents: %w", err)
+	}
+	required := make(map[string]requiredContent, len(rows))
+	for _, row := range rows {
+		required[row.ContentID] = row
+	}
+
+	scores, err := bestQuizScores(r.db, userID, rules)
+	if err != nil {
+		return nil, err
+	}
+	return contentLocks(rules, required, scores)[contentID], nil
+}
+
+// applyContentLocks marca los contenidos bloqueados del estudiante y oculta su URL
+func (r *courseContentRepo) applyContentLocks(userID string, modules []domain.CourseContentWithStudentDetails) error {
+	required := make(map[string]requiredContent)
+	var contentIDs []string
+	for _, module := range modules {
+		for _, detail := range module.Details {
+			required[detail.ContentID] = requiredContent{ContentID: detail.ContentID, Title: detail.Title, StatusID: *detail.StatusID}
+			contentIDs = append(contentIDs, detail.ContentID)
+		}
+	}
+	if len(contentIDs) == 0 {
+		return nil
+	}
+
+	var rules []domain.ContentPrerequisite
+	if err := r.db.Where("content_id IN ?", contentIDs).Order("content_id, required_content_id").Find(&rules).Error; err != nil {
+		return fmt.Errorf("error fetching prerequisites: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	scores, err := bestQuizScores(r.db, userID, rules)
+	if err != nil {
+		return err
+	}
+
+	locks := contentLocks(rules, required, scores)
+	for i := range modules {
+		for j := range modules[i].Details {
+			detail := &modules[i].Details[j]
+			if reasons, locked := locks[detail.ContentID]; locked {
+				detail.Locked = true
+				detail.LockReasons = reasons
+				detail.Url = ""
+			}
+		}
+	}
+	return nil
+}
+
+// bestQuizScores devuelve el mejor porcentaje del estudiante en los quizzes que piden un puntaje mínimo
+func bestQuizScores(db *gorm.DB, userID string, rules []domain.ContentPrerequisite) (map[string]float64, error) {
+	var quizIDs []string
+	for _, rule := range rules {
+		if rule.MinScore != nil {
+			quizIDs = append(quizIDs, rule.RequiredContentID)
+		}
+	}
+	scores := make(map[string]float64)
+	if len(quizIDs) == 0 {
+		return scores, nil
+	}
+
+	var rows []struct {
+		ContentID string
+		Score     float64
+	}
+	err := db.Model(&domain.QuizAnswer{}).
+		Select("content_id, MAX(grade * 100.0 / total_points) AS score").
+		Where("user_id = ? AND content_id IN ? AND end_time IS NOT NULL AND grade IS NOT NULL AND total_points > 0", userID, quizIDs).
+		Group("content_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quiz scores: %w", err)
+	}
+	for _, row := range rows {
+		scores[row.ContentID] = row.Score
+	}
+	return scores, nil
+}
+
+// contentLocks evalúa los prerrequisitos y devuelve, por contenido, los que no se cumplen. Un contenido
+// requerido que el estudiante no ve (inactivo o sin asignar) no bloquea, porque no lo podría completar.
+func contentLocks(rules []domain.ContentPrerequisite, required map[string]requiredContent, scores map[string]float64) map[string][]domain.ContentLock {
+	locks := make(map[string][]domain.ContentLock)
+	for _, rule := range rules {
+		content, visible := required[rule.RequiredContentID]
+		if !visible {
+			continue
+		}
+		lock := domain.ContentLock{RequiredContentID: rule.RequiredContentID, RequiredTitle: content.Title}
+		if rule.MinScore != nil {
+			score, attempted := scores[rule.RequiredContentID]
+			if attempted && score >= *rule.MinScore {
+				continue
+			}
+			lock.Reason = domain.LockReasonMinScore
+			lock.MinScore = rule.MinScore
+			if attempted {
+				lock.BestScore = &score
+			}
+		} else {
+			if content.StatusID == 3 {
+				continue
+			}
+			lock.Reason = domain.LockReasonNotCompleted
+		}
+		locks[rule.ContentID] = append(locks[rule.ContentID], lock)
+	}
+	return locks
+}
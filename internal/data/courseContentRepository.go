@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
 	"zeppelin/internal/controller"
 	"zeppelin/internal/domain"
 )
@@ -23,38 +25,59 @@ func NewCourseContentRepo(db *gorm.DB, generateUID func() string) domain.CourseC
 	}
 }
 
+// AddModule agrega un módulo al final del curso, o devuelve el existente con el mismo nombre.
+// El curso se bloquea durante la transacción para que dos altas simultáneas no repitan module_index.
 func (r *courseContentRepo) AddModule(courseID int, module string, userID string) (int, error) {
-	var course domain.CourseDB
-	err := r.db.Table("course").
-		Where("course_id = ? AND teacher_id = ?", courseID, userID).
-		First(&course).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return 0, errors.New("course does not belong to the teacher")
+	var courseContentID int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var course domain.CourseDB
+		err := tx.Table("course").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("course_id = ? AND teacher_id = ?", courseID, userID).
+			First(&course).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.New("course does not belong to the teacher")
+			}
+			return err
 		}
-		return 0, err
-	}
 
-	var courseContent domain.CourseContentDB
-	err = r.db.Table("course_content").
-		Where("course_id = ? AND module = ?", courseID, module).
-		First(&courseContent).Error
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return 0, err
-	}
+		var courseContent domain.CourseContentDB
+		err = tx.Table("course_content").
+			Where("course_id = ? AND module = ?", courseID, module).
+			First(&courseContent).Error
+		if err == nil {
+			courseContentID = courseContent.CourseContentID
+			return nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
 
-	if err == gorm.ErrRecordNotFound {
+		// El módulo nuevo va al final del curso
+		var lastIndex int
+		err = tx.Table("course_content").
+			Select("COALESCE(MAX(module_index), 0)").
+			Where("course_id = ?", courseID).
+			Scan(&lastIndex).Error
+		if err != nil {
+			return err
+		}
 		newModule := domain.CourseContentDB{
-			CourseID: courseID,
-			Module:   module,
+			CourseID:    courseID,
+			Module:      module,
+			ModuleIndex: lastIndex + 1,
 		}
-		if err := r.db.Create(&newModule).Error; err != nil {
-			return 0, err
+		if err := tx.Create(&newModule).Error; err != nil {
+			return err
 		}
-		return newModule.CourseContentID, nil
+		courseContentID = newModule.CourseContentID
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
-
-	return courseContent.CourseContentID, nil
+	return courseContentID, nil
 }
 
 // VerifyModuleOwnership
@@ -73,26 +96,44 @@ func (r *courseContentRepo) VerifyModuleOwnership(courseContentID int, userID st
 	return nil
 }
 
-// CreateContent
+// CreateContent agrega una sección al final del módulo. El módulo se bloquea durante la transacción
+// para que dos altas simultáneas no repitan section_index.
 func (r *courseContentRepo) CreateContent(input domain.AddSectionInput) (string, error) {
-	var count int64
-	if err := r.db.Table("course_content").Where("course_content_id = ?", input.CourseContentID).Count(&count).Error; err != nil {
-		return "", err
-	}
-	if count == 0 {
-		return "", gorm.ErrRecordNotFound
-	}
-
 	contentID := r.generateUID()
-	content := domain.Content{
-		ContentID:       contentID,
-		CourseContentID: input.CourseContentID,
-		ContentTypeID:   input.ContentTypeID,
-		Title:           input.Title,
-		Description:     input.Description,
-	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var modules []int
+		err := tx.Table("course_content").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("course_content_id = ?", input.CourseContentID).
+			Pluck("course_content_id", &modules).Error
+		if err != nil {
+			return err
+		}
+		if len(modules) == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	if err := r.db.Table("content").Create(&content).Error; err != nil {
+		// La sección nueva va al final del módulo
+		var lastIndex int
+		err = tx.Table("content").
+			Select("COALESCE(MAX(section_index), 0)").
+			Where("course_content_id = ?", input.CourseContentID).
+			Scan(&lastIndex).Error
+		if err != nil {
+			return err
+		}
+
+		content := domain.Content{
+			ContentID:       contentID,
+			CourseContentID: input.CourseContentID,
+			ContentTypeID:   input.ContentTypeID,
+			Title:           input.Title,
+			Description:     input.Description,
+			SectionIndex:    lastIndex + 1,
+		}
+		return tx.Table("content").Create(&content).Error
+	})
+	if err != nil {
 		return "", err
 	}
 	return contentID, nil
@@ -105,7 +146,7 @@ func (r *courseContentRepo) AddSection(input domain.AddSectionInput, userID stri
 		return "", err
 	}
 
-	if input.ContentTypeID < 1 || input.ContentTypeID > 3 {
+	if input.ContentTypeID < 1 || input.ContentTypeID > 4 {
 		return "", errors.New("invalid content_type_id")
 	}
 
@@ -209,6 +250,9 @@ func (r *courseContentRepo) GetContentByCourseForStudent(courseID int, userID st
 		}
 	}
 
+	if err := r.applyContentLocks(userID, finalResult); err != nil {
+		return nil, err
+	}
 	return finalResult, nil
 }
 
@@ -243,6 +287,514 @@ func (r *courseContentRepo) UpdateModuleTitle(courseContentID int, moduleTitle s
 		Update("module", moduleTitle).Error
 }
 
+// ReorderModules asigna module_index (desde 1) según el orden recibido, que debe incluir
+// todos los módulos del curso una sola vez
+func (r *courseContentRepo) ReorderModules(courseID int, courseContentIDs []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current []int
+		err := tx.Table("course_content").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("course_id = ?", courseID).
+			Pluck("course_content_id", &current).Error
+		if err != nil {
+			return fmt.Errorf("error fetching course modules: %w", err)
+		}
+		if !sameModules(current, courseContentIDs) {
+			return fmt.Errorf("%w: the order must include every module of the course exactly once", domain.ErrValidationFailed)
+		}
+
+		for i, courseContentID := range courseContentIDs {
+			err := tx.Table("course_content").
+				Where("course_content_id = ?", courseContentID).
+				Update("module_index", i+1).Error
+			if err != nil {
+				return fmt.Errorf("error updating module index: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ReorderSections asigna section_index (desde 1) a las secciones del módulo según el orden recibido.
+// Las secciones de otros módulos del curso que aparezcan en la lista se mueven a este módulo y
+// los módulos de origen se renumeran para no dejar huecos.
+func (r *courseContentRepo) ReorderSections(courseContentID int, contentIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var module domain.CourseContentDB
+		err := tx.Table("course_content").
+			Where("course_content_id = ?", courseContentID).
+			First(&module).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: module %d", domain.ErrResourceNotFound, courseContentID)
+			}
+			return fmt.Errorf("error fetching module: %w", err)
+		}
+
+		var sections []domain.Content
+		err = tx.Table("content").
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "content"}}).
+			Select("content.content_id, content.course_content_id").
+			Joins("JOIN course_content ON content.course_content_id = course_content.course_content_id").
+			Where("course_content.course_id = ? AND (content.course_content_id = ? OR content.content_id IN ?)", module.CourseID, courseContentID, contentIDs).
+			Find(&sections).Error
+		if err != nil {
+			return fmt.Errorf("error fetching sections: %w", err)
+		}
+
+		moduleOf := make(map[string]int, len(sections))
+		for _, section := range sections {
+			moduleOf[section.ContentID] = section.CourseContentID
+		}
+		ordered := make(map[string]bool, len(contentIDs))
+		for _, contentID := range contentIDs {
+			if _, ok := moduleOf[contentID]; !ok {
+				return fmt.Errorf("%w: section %s does not belong to the course", domain.ErrValidationFailed, contentID)
+			}
+			ordered[contentID] = true
+		}
+		var sources []int
+		for _, section := range sections {
+			if section.CourseContentID == courseContentID {
+				if !ordered[section.ContentID] {
+					return fmt.Errorf("%w: the order must include every section of the module", domain.ErrValidationFailed)
+				}
+			} else if !containsModule(sources, section.CourseContentID) {
+				sources = append(sources, section.CourseContentID)
+			}
+		}
+
+		for i, contentID := range contentIDs {
+			err := tx.Table("content").
+				Where("content_id = ?", contentID).
+				Updates(map[string]interface{}{"course_content_id": courseContentID, "section_index": i + 1}).Error
+			if err != nil {
+				return fmt.Errorf("error updating section index: %w", err)
+			}
+		}
+
+		sort.Ints(sources)
+		for _, source := range sources {
+			if err := renumberSections(tx, source); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSection borra una sección del profesor junto con el progreso, las fechas de entrega, las versiones
+// y, si force es true, los intentos de quiz. Sin force, una sección con intentos no se borra y se
+// devuelve ErrContentHasAttempts con la cantidad de intentos.
+func (r *courseContentRepo) DeleteSection(contentID, userID string, force bool) (domain.DeletedContent, error) {
+	deleted := domain.DeletedContent{ContentIDs: []string{contentID}}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var owned struct {
+			CourseContentID int
+			CourseID        int
+		}
+		err := tx.Table("content").
+			Select("content.course_content_id, course_content.course_id").
+			Joins("JOIN course_content ON content.course_content_id = course_content.course_content_id").
+			Joins("JOIN course ON course_content.course_id = course.course_id").
+			Where("content.content_id = ? AND course.teacher_id = ?", contentID, userID).
+			Take(&owned).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: content %s not found in the teacher's courses", domain.ErrResourceNotFound, contentID)
+			}
+			return fmt.Errorf("error fetching content: %w", err)
+		}
+		deleted.CourseID = owned.CourseID
+
+		if deleted.Attempts, deleted.AnswerURLs, err = deleteContents(tx, deleted.ContentIDs, force); err != nil {
+			return err
+		}
+		return renumberSections(tx, owned.CourseContentID)
+	})
+	return deleted, err
+}
+
+// DeleteModule borra un módulo del profesor con todas sus secciones; force tiene el mismo sentido que en DeleteSection
+func (r *courseContentRepo) DeleteModule(courseContentID int, userID string, force bool) (domain.DeletedContent, error) {
+	var deleted domain.DeletedContent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var module domain.CourseContentDB
+		err := tx.Table("course_content").
+			Joins("JOIN course ON course_content.course_id = course.course_id").
+			Where("course_content.course_content_id = ? AND course.teacher_id = ?", courseContentID, userID).
+			First(&module).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: module %d not found in the teacher's courses", domain.ErrResourceNotFound, courseContentID)
+			}
+			return fmt.Errorf("error fetching module: %w", err)
+		}
+		deleted.CourseID = module.CourseID
+
+		err = tx.Table("content").
+			Where("course_content_id = ?", courseContentID).
+			Order("section_index, content_id").
+			Pluck("content_id", &deleted.ContentIDs).Error
+		if err != nil {
+			return fmt.Errorf("error fetching module sections: %w", err)
+		}
+		if deleted.Attempts, deleted.AnswerURLs, err = deleteContents(tx, deleted.ContentIDs, force); err != nil {
+			return err
+		}
+
+		if err := tx.Where("course_content_id = ?", courseContentID).Delete(&domain.SessionCourseContent{}).Error; err != nil {
+			return fmt.Errorf("error deleting module sessions: %w", err)
+		}
+		if err := tx.Where("course_content_id = ?", courseContentID).Delete(&domain.CourseContentDB{}).Error; err != nil {
+			return fmt.Errorf("error deleting module: %w", err)
+		}
+		return renumberModules(tx, module.CourseID)
+	})
+	return deleted, err
+}
+
+// deleteContents borra los contenidos y las filas que dependen de ellos. Devuelve la cantidad de intentos
+// de quiz y las URLs de sus respuestas en R2; si hay intentos y force es false no borra nada.
+func deleteContents(tx *gorm.DB, contentIDs []string, force bool) (int64, []string, error) {
+	if len(contentIDs) == 0 {
+		return 0, nil, nil
+	}
+
+	var attempts int64
+	var answerURLs []string
+	if err := tx.Model(&domain.QuizAnswer{}).Where("content_id IN ?", contentIDs).Count(&attempts).Error; err != nil {
+		return 0, nil, fmt.Errorf("error counting quiz attempts: %w", err)
+	}
+	if attempts > 0 {
+		if !force {
+			return attempts, nil, fmt.Errorf("%w: %d attempts", domain.ErrContentHasAttempts, attempts)
+		}
+		err := tx.Model(&domain.QuizAnswer{}).
+			Where("content_id IN ? AND quiz_answer_url <> ''", contentIDs).
+			Pluck("quiz_answer_url", &answerURLs).Error
+		if err != nil {
+			return attempts, nil, fmt.Errorf("error fetching quiz answer files: %w", err)
+		}
+		attemptIDs := tx.Model(&domain.QuizAnswer{}).Select("quiz_answer_id").Where("content_id IN ?", contentIDs)
+		if err := tx.Where("quiz_answer_id IN (?)", attemptIDs).Delete(&domain.QuizAnswerItem{}).Error; err != nil {
+			return attempts, nil, fmt.Errorf("error deleting quiz answer items: %w", err)
+		}
+	}
+
+	err := tx.Where("content_id IN ? OR required_content_id IN ?", contentIDs, contentIDs).Delete(&domain.ContentPrerequisite{}).Error
+	if err != nil {
+		return attempts, nil, fmt.Errorf("error deleting prerequisites: %w", err)
+	}
+	// Primero las tablas que referencian al contenido y al final el contenido
+	dependents := []interface{}{
+		&domain.QuizSubmissionKey{},
+		&domain.QuizAnswer{},
+		&domain.QuizAnswerRelease{},
+		&domain.QuizVersion{},
+		&domain.DueDateExtension{},
+		&domain.ContentDueDate{},
+		&domain.SurveyResponse{},
+		&domain.SurveyParticipation{},
+		&domain.UserContent{},
+		&domain.Content{},
+	}
+	for _, model := range dependents {
+		if err := tx.Where("content_id IN ?", contentIDs).Delete(model).Error; err != nil {
+			return attempts, nil, fmt.Errorf("error deleting content: %w", err)
+		}
+	}
+	return attempts, answerURLs, nil
+}
+
+// renumberModules vuelve a numerar desde 1 los módulos que quedan en un curso
+func renumberModules(tx *gorm.DB, courseID int) error {
+	var remaining []int
+	err := tx.Table("course_content").
+		Where("course_id = ?", courseID).
+		Order("module_index, course_content_id").
+		Pluck("course_content_id", &remaining).Error
+	if err != nil {
+		return fmt.Errorf("error fetching course modules: %w", err)
+	}
+	for i, courseContentID := range remaining {
+		err := tx.Table("course_content").
+			Where("course_content_id = ?", courseContentID).
+			Update("module_index", i+1).Error
+		if err != nil {
+			return fmt.Errorf("error updating module index: %w", err)
+		}
+	}
+	return nil
+}
+
+// renumberSections vuelve a numerar desde 1 las secciones que quedan en un módulo
+func renumberSections(tx *gorm.DB, courseContentID int) error {
+	var remaining []string
+	err := tx.Table("content").
+		Where("course_content_id = ?", courseContentID).
+		Order("section_index, content_id").
+		Pluck("content_id", &remaining).Error
+	if err != nil {
+		return fmt.Errorf("error fetching module sections: %w", err)
+	}
+	for i, contentID := range remaining {
+		err := tx.Table("content").
+			Where("content_id = ?", contentID).
+			Update("section_index", i+1).Error
+		if err != nil {
+			return fmt.Errorf("error updating section index: %w", err)
+		}
+	}
+	return nil
+}
+
+func sameModules(current, ordered []int) bool {
+	if len(current) != len(ordered) {
+		return false
+	}
+	seen := make(map[int]bool, len(current))
+	for _, id := range current {
+		seen[id] = true
+	}
+	for _, id := range ordered {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
+
+func containsModule(ids []int, id int) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CloneCourse copia un curso del profesor con sus módulos, secciones, versiones de quiz y fechas de entrega.
+// Las secciones reciben UIDs nuevos y las URLs se reescriben con controller.CloneStorageKey; los objetos
+// de R2 los copia el controlador. Sin ExcludeEnrollments se copian las inscripciones y, sin
+// ExcludeAttempts, también el progreso y los intentos de quiz de los estudiantes.
+func (r *courseContentRepo) CloneCourse(request domain.CloneCourseRequest) (domain.CourseClone, error) {
+	clone := domain.CourseClone{
+		SourceCourseID: request.SourceCourseID,
+		QRCode:         request.QRCode,
+		ModuleIDs:      make(map[int]int),
+		ContentIDs:     make(map[string]string),
+		URLs:           make(map[string]string),
+	}
+	rewrite := func(url string) string {
+		if url == "" {
+			return url
+		}
+		newURL := controller.CloneStorageKey(clone, url)
+		if newURL != url {
+			clone.URLs[url] = newURL
+		}
+		return newURL
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var source domain.CourseDB
+		err := tx.Where("course_id = ? AND teacher_id = ?", request.SourceCourseID, request.TeacherID).First(&source).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: course %d not found in the teacher's courses", domain.ErrResourceNotFound, request.SourceCourseID)
+			}
+			return fmt.Errorf("error fetching course: %w", err)
+		}
+
+		course := domain.CourseDB{
+			TeacherID:   source.TeacherID,
+			StartDate:   source.StartDate,
+			Title:       source.Title,
+			Description: source.Description,
+			QRCode:      request.QRCode,
+		}
+		if request.Title != "" {
+			course.Title = request.Title
+		}
+		if request.StartDate != "" {
+			course.StartDate = request.StartDate
+		}
+		if err := tx.Create(&course).Error; err != nil {
+			return fmt.Errorf("error creating course: %w", err)
+		}
+		clone.CourseID = course.CourseID
+
+		var modules []domain.CourseContentDB
+		if err := tx.Where("course_id = ?", source.CourseID).Order("module_index, course_content_id").Find(&modules).Error; err != nil {
+			return fmt.Errorf("error fetching course modules: %w", err)
+		}
+		moduleIDs := make([]int, 0, len(modules))
+		for _, module := range modules {
+			copied := domain.CourseContentDB{CourseID: course.CourseID, Module: module.Module, ModuleIndex: module.ModuleIndex}
+			if err := tx.Omit(clause.Associations).Create(&copied).Error; err != nil {
+				return fmt.Errorf("error copying module: %w", err)
+			}
+			clone.ModuleIDs[module.CourseContentID] = copied.CourseContentID
+			moduleIDs = append(moduleIDs, module.CourseContentID)
+		}
+		if len(moduleIDs) == 0 {
+			return nil
+		}
+
+		var contents []domain.Content
+		if err := tx.Where("course_content_id IN ?", moduleIDs).Order("course_content_id, section_index, content_id").Find(&contents).Error; err != nil {
+			return fmt.Errorf("error fetching course sections: %w", err)
+		}
+		contentIDs := make([]string, 0, len(contents))
+		for _, content := range contents {
+			clone.ContentIDs[content.ContentID] = r.generateUID()
+			contentIDs = append(contentIDs, content.ContentID)
+		}
+		// Las URLs se reescriben cuando ya se conocen todos los UIDs nuevos
+		for _, content := range contents {
+			content.ContentID = clone.ContentIDs[content.ContentID]
+			content.CourseContentID = clone.ModuleIDs[content.CourseContentID]
+			content.Url = rewrite(content.Url)
+			content.UserContent = nil
+			if err := tx.Omit(clause.Associations).Create(&content).Error; err != nil {
+				return fmt.Errorf("error copying section: %w", err)
+			}
+		}
+		if len(contentIDs) == 0 {
+			return nil
+		}
+
+		var versions []domain.QuizVersion
+		if err := tx.Where("content_id IN ?", contentIDs).Order("quiz_version_id").Find(&versions).Error; err != nil {
+			return fmt.Errorf("error fetching quiz versions: %w", err)
+		}
+		versionIDs := make(map[int]int, len(versions))
+		for _, version := range versions {
+			copied := domain.QuizVersion{
+				ContentID: clone.ContentIDs[version.ContentID],
+				Version:   version.Version,
+				QuizURL:   rewrite(version.QuizURL),
+			}
+			if err := tx.Create(&copied).Error; err != nil {
+				return fmt.Errorf("error copying quiz version: %w", err)
+			}
+			versionIDs[version.QuizVersionID] = copied.QuizVersionID
+		}
+
+		var dueDates []domain.ContentDueDate
+		if err := tx.Where("content_id IN ?", contentIDs).Find(&dueDates).Error; err != nil {
+			return fmt.Errorf("error fetching due dates: %w", err)
+		}
+		for _, dueDate := range dueDates {
+			dueDate.ContentID = clone.ContentIDs[dueDate.ContentID]
+			if err := tx.Create(&dueDate).Error; err != nil {
+				return fmt.Errorf("error copying due date: %w", err)
+			}
+		}
+
+		var prerequisites []domain.ContentPrerequisite
+		if err := tx.Where("content_id IN ?", contentIDs).Order("content_id, required_content_id").Find(&prerequisites).Error; err != nil {
+			return fmt.Errorf("error fetching prerequisites: %w", err)
+		}
+		for _, prerequisite := range prerequisites {
+			prerequisite.ContentID = clone.ContentIDs[prerequisite.ContentID]
+			prerequisite.RequiredContentID = clone.ContentIDs[prerequisite.RequiredContentID]
+			if err := tx.Create(&prerequisite).Error; err != nil {
+				return fmt.Errorf("error copying prerequisite: %w", err)
+			}
+		}
+
+		if request.ExcludeEnrollments {
+			return nil
+		}
+		enrollments, err := cloneEnrollments(tx, source.CourseID, course.CourseID)
+		if err != nil {
+			return err
+		}
+		clone.Enrollments = enrollments
+
+		if request.ExcludeAttempts {
+			return nil
+		}
+		clone.Attempts, err = cloneAttempts(tx, clone.ContentIDs, contentIDs, versionIDs, rewrite)
+		return err
+	})
+	return clone, err
+}
+
+// cloneEnrollments copia las inscripciones de un curso a su copia
+func cloneEnrollments(tx *gorm.DB, sourceCourseID, courseID int) (int, error) {
+	var assignments []domain.AssignmentDB
+	if err := tx.Where("course_id = ?", sourceCourseID).Order("assignment_id").Find(&assignments).Error; err != nil {
+		return 0, fmt.Errorf("error fetching enrollments: %w", err)
+	}
+	for _, assignment := range assignments {
+		assignment.AssignmentID = 0
+		assignment.CourseID = courseID
+		if err := tx.Create(&assignment).Error; err != nil {
+			return 0, fmt.Errorf("error copying enrollment: %w", err)
+		}
+	}
+	return len(assignments), nil
+}
+
+// cloneAttempts copia el progreso de los estudiantes y sus intentos de quiz con las respuestas de cada pregunta.
+// Devuelve la cantidad de intentos copiados.
+func cloneAttempts(tx *gorm.DB, newContentIDs map[string]string, contentIDs []string, versionIDs map[int]int, rewrite func(string) string) (int, error) {
+	var progress []domain.UserContent
+	if err := tx.Where("content_id IN ?", contentIDs).Find(&progress).Error; err != nil {
+		return 0, fmt.Errorf("error fetching student progress: %w", err)
+	}
+	for _, userContent := range progress {
+		userContent.ContentID = newContentIDs[userContent.ContentID]
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "content_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status_id"}),
+		}).Create(&userContent).Error
+		if err != nil {
+			return 0, fmt.Errorf("error copying student progress: %w", err)
+		}
+	}
+
+	var attempts []domain.QuizAnswer
+	if err := tx.Where("content_id IN ?", contentIDs).Order("quiz_answer_id").Find(&attempts).Error; err != nil {
+		return 0, fmt.Errorf("error fetching quiz attempts: %w", err)
+	}
+	for _, attempt := range attempts {
+		sourceID := attempt.QuizAnswerID
+		attempt.QuizAnswerID = 0
+		attempt.ContentID = newContentIDs[attempt.ContentID]
+		attempt.QuizURL = rewrite(attempt.QuizURL)
+		attempt.QuizAnswerURL = rewrite(attempt.QuizAnswerURL)
+		attempt.VariantURL = rewrite(attempt.VariantURL)
+		if attempt.QuizVersionID != nil {
+			if versionID, ok := versionIDs[*attempt.QuizVersionID]; ok {
+				attempt.QuizVersionID = &versionID
+			} else {
+				attempt.QuizVersionID = nil
+			}
+		}
+		if err := tx.Create(&attempt).Error; err != nil {
+			return 0, fmt.Errorf("error copying quiz attempt: %w", err)
+		}
+
+		var items []domain.QuizAnswerItem
+		if err := tx.Where("quiz_answer_id = ?", sourceID).Order("quiz_answer_item_id").Find(&items).Error; err != nil {
+			return 0, fmt.Errorf("error fetching quiz answer items: %w", err)
+		}
+		for _, item := range items {
+			item.QuizAnswerItemID = 0
+			item.QuizAnswerID = attempt.QuizAnswerID
+			if err := tx.Create(&item).Error; err != nil {
+				return 0, fmt.Errorf("error copying quiz answer item: %w", err)
+			}
+		}
+	}
+	return len(attempts), nil
+}
+
 // UpdateUserContentStatus
 func (r *courseContentRepo) UpdateUserContentStatus(userID, contentID string, statusID int) error {
 	return r.db.Table("user_content").
@@ -264,3 +816,19 @@ func (r *courseContentRepo) GetUrlByContentID(contentID string) (string, error)
 	}
 	return content.Url, nil
 }
+
+// GetCourseIDByContentID devuelve el curso al que pertenece un contenido a través de su módulo
+func (r *courseContentRepo) GetCourseIDByContentID(contentID string) (int, error) {
+	var courseIDs []int
+	err := r.db.Table("content").
+		Joins("JOIN course_content ON course_content.course_content_id = content.course_content_id").
+		Where("content.content_id = ?", contentID).
+		Pluck("course_content.course_id", &courseIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(courseIDs) == 0 {
+		return 0, errors.New("content not found")
+	}
+	return courseIDs[0], nil
+}
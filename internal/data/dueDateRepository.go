@@ -0,0 +1,49 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+type dueDateRepo struct {
+	db *gorm.DB
+}
+
+func NewDueDateRepo(db *gorm.DB) domain.DueDateRepo {
+	return &dueDateRepo{db: db}
+}
+
+// GetDueDate obtiene la fecha de entrega de un contenido
+func (r *dueDateRepo) GetDueDate(contentID string) (domain.ContentDueDate, error) {
+	var dueDate domain.ContentDueDate
+	if err := r.db.Where("content_id = ?", contentID).First(&dueDate).Error; err != nil {
+		return domain.ContentDueDate{}, err
+	}
+	return dueDate, nil
+}
+
+// SaveDueDate crea o reemplaza la fecha de entrega de un contenido
+func (r *dueDateRepo) SaveDueDate(dueDate domain.ContentDueDate) error {
+	if err := r.db.Save(&dueDate).Error; err != nil {
+		return fmt.Errorf("error saving due date: %w", err)
+	}
+	return nil
+}
+
+// GetDueDateExtension obtiene la prórroga de un estudiante para un contenido
+func (r *dueDateRepo) GetDueDateExtension(contentID, userID string) (domain.DueDateExtension, error) {
+	var extension domain.DueDateExtension
+	if err := r.db.Where("content_id = ? AND user_id = ?", contentID, userID).First(&extension).Error; err != nil {
+		return domain.DueDateExtension{}, err
+	}
+	return extension, nil
+}
+
+// SaveDueDateExtension crea o reemplaza la prórroga de un estudiante
+func (r *dueDateRepo) SaveDueDateExtension(extension domain.DueDateExtension) error {
+	if err := r.db.Save(&extension).Error; err != nil {
+		return fmt.Errorf("error saving due date extension: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+type gradebookRepo struct {
+	db *gorm.DB
+}
+
+func NewGradebookRepo(db *gorm.DB) domain.GradebookRepo {
+	return &gradebookRepo{db: db}
+}
+
+// GetGradebook obtiene la configuración del libro de calificaciones de un curso
+func (r *gradebookRepo) GetGradebook(courseID int) (domain.Gradebook, error) {
+	var gradebook domain.Gradebook
+	if err := r.db.Where("course_id = ?", courseID).First(&gradebook).Error; err != nil {
+		return domain.Gradebook{}, err
+	}
+	return gradebook, nil
+}
+
+// SaveGradebook crea o reemplaza la configuración del libro de calificaciones
+func (r *gradebookRepo) SaveGradebook(gradebook domain.Gradebook) error {
+	if err := r.db.Save(&gradebook).Error; err != nil {
+		return fmt.Errorf("error saving gradebook: %w", err)
+	}
+	return nil
+}
+
+// GetCourseProgress obtiene el avance de todos los estudiantes de un curso
+func (r *gradebookRepo) GetCourseProgress(courseID int) ([]domain.StudentCourseProgress, error) {
+	var progress []domain.StudentCourseProgress
+	if err := r.db.Where("course_id = ?", courseID).Find(&progress).Error; err != nil {
+		return nil, fmt.Errorf("error finding course progress: %w", err)
+	}
+	return progress, nil
+}
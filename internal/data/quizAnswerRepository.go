@@ -3,6 +3,8 @@ package data
 import (
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 	"zeppelin/internal/domain"
 )
 
@@ -16,11 +18,76 @@ func NewQuizRepository(db *gorm.DB) domain.QuizRepository {
 	}
 }
 
-func (r *quizRepository) SaveQuizAttempt(attempt domain.QuizAnswer) error {
-	if err := r.db.Create(&attempt).Error; err != nil {
-		return fmt.Errorf("error creating new quiz attempt: %w", err)
+// SaveQuizAttempt guarda un intento completo; con maxAttempts > 0 falla con domain.ErrMaxAttemptsReached
+// si el estudiante ya agotó sus intentos
+func (r *quizRepository) SaveQuizAttempt(attempt domain.QuizAnswer, maxAttempts int) (int, error) {
+	if err := r.createAttempt(&attempt, maxAttempts); err != nil {
+		return 0, fmt.Errorf("error creating new quiz attempt: %w", err)
 	}
-	return nil
+	return attempt.QuizAnswerID, nil
+}
+
+// StartQuizAttempt crea un intento en curso y devuelve su ID; con maxAttempts > 0 falla con
+// domain.ErrMaxAttemptsReached si el estudiante ya agotó sus intentos
+func (r *quizRepository) StartQuizAttempt(attempt domain.QuizAnswer, maxAttempts int) (int, error) {
+	if err := r.createAttempt(&attempt, maxAttempts); err != nil {
+		return 0, fmt.Errorf("error starting quiz attempt: %w", err)
+	}
+	return attempt.QuizAnswerID, nil
+}
+
+// createAttempt cuenta los intentos y crea el nuevo en una misma transacción. La fila del contenido
+// se bloquea para que dos inicios simultáneos no superen el máximo.
+func (r *quizRepository) createAttempt(attempt *domain.QuizAnswer, maxAttempts int) error {
+	if maxAttempts <= 0 {
+		return r.db.Create(attempt).Error
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var locked []string
+		err := tx.Table("content").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("content_id = ?", attempt.ContentID).
+			Pluck("content_id", &locked).Error
+		if err != nil {
+			return err
+		}
+
+		var used int64
+		err = tx.Model(&domain.QuizAnswer{}).
+			Where("user_id = ? AND content_id = ?", attempt.UserID, attempt.ContentID).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(maxAttempts) {
+			return domain.ErrMaxAttemptsReached
+		}
+		return tx.Create(attempt).Error
+	})
+}
+
+// CountQuizAttempts cuenta los intentos (en curso o enviados) de un estudiante para un quiz
+func (r *quizRepository) CountQuizAttempts(userID, contentID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.QuizAnswer{}).
+		Where("user_id = ? AND content_id = ?", userID, contentID).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("error counting quiz attempts: %w", err)
+	}
+	return count, nil
+}
+
+// FindOpenQuizAttempt obtiene el intento en curso más reciente de un estudiante para un quiz
+func (r *quizRepository) FindOpenQuizAttempt(userID, contentID string) (domain.QuizAnswer, error) {
+	var attempt domain.QuizAnswer
+	err := r.db.Where("user_id = ? AND content_id = ? AND end_time IS NULL", userID, contentID).
+		Order("start_time DESC").
+		First(&attempt).Error
+	if err != nil {
+		return domain.QuizAnswer{}, fmt.Errorf("error finding open quiz attempt: %w", err)
+	}
+	return attempt, nil
 }
 
 func (r *quizRepository) UpdateQuizAttempt(attempt domain.QuizAnswer) error {
@@ -30,6 +97,20 @@ func (r *quizRepository) UpdateQuizAttempt(attempt domain.QuizAnswer) error {
 	return nil
 }
 
+// SaveQuizDraft guarda las respuestas en curso de un intento que aún no se ha enviado
+func (r *quizRepository) SaveQuizDraft(quizAnswerID int, answers map[string]interface{}, savedAt time.Time) error {
+	result := r.db.Model(&domain.QuizAnswer{QuizAnswerID: quizAnswerID}).
+		Where("end_time IS NULL").
+		Updates(domain.QuizAnswer{DraftAnswers: answers, AutosavedAt: &savedAt})
+	if result.Error != nil {
+		return fmt.Errorf("error saving quiz draft: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("error saving quiz draft: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
 func (r *quizRepository) FindQuizAttemptByID(quizAnswerID int) (domain.QuizAnswer, error) {
 	var attempt domain.QuizAnswer
 	if err := r.db.Where("quiz_answer_id = ?", quizAnswerID).First(&attempt).Error; err != nil {
@@ -59,10 +140,75 @@ func (r *quizRepository) FindQuizAttemptsByUser(userID string) ([]domain.QuizAns
 	return attempts, nil
 }
 
+// FindSubmittedQuizAttemptsByContent obtiene los intentos enviados de un quiz
+func (r *quizRepository) FindSubmittedQuizAttemptsByContent(contentID string) ([]domain.QuizAnswer, error) {
+	var attempts []domain.QuizAnswer
+	err := r.db.Where("content_id = ? AND end_time IS NOT NULL", contentID).
+		Order("quiz_answer_id").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding submitted quiz attempts by content: %w", err)
+	}
+	return attempts, nil
+}
+
+// SaveQuizAnswerItems inserta o actualiza las calificaciones por pregunta (única por intento y pregunta)
+func (r *quizRepository) SaveQuizAnswerItems(items []domain.QuizAnswerItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "quiz_answer_id"}, {Name: "question_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"question_type", "answer", "points", "score", "is_correct", "needs_review", "reviewer_id", "reviewed_at", "feedback", "rubric",
+		}),
+	}).Create(&items).Error
+	if err != nil {
+		return fmt.Errorf("error saving quiz answer items: %w", err)
+	}
+	return nil
+}
+
+// FindQuizAnswerItems obtiene las calificaciones por pregunta de un intento
+func (r *quizRepository) FindQuizAnswerItems(quizAnswerID int) ([]domain.QuizAnswerItem, error) {
+	var items []domain.QuizAnswerItem
+	err := r.db.Where("quiz_answer_id = ?", quizAnswerID).
+		Order("quiz_answer_item_id").
+		Find(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding quiz answer items: %w", err)
+	}
+	return items, nil
+}
+
+// FindQuizAnswerItemsByAttempts obtiene las calificaciones por pregunta de varios intentos
+func (r *quizRepository) FindQuizAnswerItemsByAttempts(quizAnswerIDs []int) ([]domain.QuizAnswerItem, error) {
+	var items []domain.QuizAnswerItem
+	if len(quizAnswerIDs) == 0 {
+		return items, nil
+	}
+	err := r.db.Where("quiz_answer_id IN ?", quizAnswerIDs).
+		Order("quiz_answer_id, quiz_answer_item_id").
+		Find(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding quiz answer items: %w", err)
+	}
+	return items, nil
+}
+
+// withQuizVersion agrega a la vista de intentos la versión del quiz que respondió cada intento,
+// su variante y los datos de entrega tardía guardados en quiz_answer
+func (r *quizRepository) withQuizVersion() *gorm.DB {
+	return r.db.Table("quiz_attempts_view AS v").
+		Select("v.*, qv.quiz_version_id, qv.version AS quiz_version, qa.raw_grade, qa.due_at, qa.late_minutes, qa.late_penalty, qa.variant_url").
+		Joins("LEFT JOIN quiz_answer qa ON qa.quiz_answer_id = v.quiz_answer_id").
+		Joins("LEFT JOIN quiz_version qv ON qv.quiz_version_id = qa.quiz_version_id")
+}
+
 // GetQuizAttemptsByCourse obtiene los intentos de quiz para un curso desde la vista
 func (r *quizRepository) GetQuizAttemptsByCourse(courseID int) ([]domain.QuizAttemptView, error) {
 	var attempts []domain.QuizAttemptView
-	err := r.db.Where("course_id = ?", courseID).Find(&attempts).Error
+	err := r.withQuizVersion().Where("v.course_id = ?", courseID).Find(&attempts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -72,9 +218,40 @@ func (r *quizRepository) GetQuizAttemptsByCourse(courseID int) ([]domain.QuizAtt
 // GetQuizAttemptsByStudent obtiene los intentos de quiz para un estudiante desde la vista
 func (r *quizRepository) GetQuizAttemptsByStudent(userID string) ([]domain.QuizAttemptView, error) {
 	var attempts []domain.QuizAttemptView
-	err := r.db.Where("user_id = ?", userID).Find(&attempts).Error
+	err := r.withQuizVersion().Where("v.user_id = ?", userID).Find(&attempts).Error
 	if err != nil {
 		return nil, err
 	}
 	return attempts, nil
 }
+
+// FindPendingReviewItems obtiene las respuestas de texto pendientes de revisión de los cursos de un profesor,
+// de la más antigua a la más reciente según la hora de envío
+func (r *quizRepository) FindPendingReviewItems(filter domain.ReviewQueueFilter) ([]domain.ReviewQueueRow, error) {
+	query := r.db.Table("quiz_answer_item AS qai").
+		Select("qai.quiz_answer_item_id, qai.quiz_answer_id, qai.question_id, qai.answer, qai.points, "+
+			"v.content_id, v.user_id, qa.end_time, v.course_id, v.course_title, v.quiz_title, "+
+			"v.student_name, v.student_lastname, v.student_email, qa.quiz_url AS attempt_quiz_url, qa.variant_url").
+		Joins("JOIN quiz_answer qa ON qa.quiz_answer_id = qai.quiz_answer_id").
+		Joins("JOIN quiz_attempts_view v ON v.quiz_answer_id = qai.quiz_answer_id").
+		Where("v.teacher_id = ? AND qai.question_type = ? AND qai.needs_review = ? AND qa.end_time IS NOT NULL", filter.TeacherID, "text", true)
+	if filter.CourseID != 0 {
+		query = query.Where("v.course_id = ?", filter.CourseID)
+	}
+	if filter.ContentID != "" {
+		query = query.Where("v.content_id = ?", filter.ContentID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("v.user_id = ?", filter.UserID)
+	}
+	if filter.AfterEndTime != nil {
+		query = query.Where("(qa.end_time, qai.quiz_answer_item_id) > (?, ?)", *filter.AfterEndTime, filter.AfterItemID)
+	}
+
+	var rows []domain.ReviewQueueRow
+	err := query.Order("qa.end_time, qai.quiz_answer_item_id").Limit(filter.Limit).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error finding pending review items: %w", err)
+	}
+	return rows, nil
+}
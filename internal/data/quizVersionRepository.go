@@ -0,0 +1,66 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"zeppelin/internal/domain"
+)
+
+type quizVersionRepo struct {
+	db *gorm.DB
+}
+
+func NewQuizVersionRepo(db *gorm.DB) domain.QuizVersionRepo {
+	return &quizVersionRepo{db: db}
+}
+
+// CreateQuizVersion reserva el siguiente número de versión de un quiz (1 si aún no tiene versiones),
+// sube el quiz con publish y registra la versión. La fila del contenido queda bloqueada hasta el commit
+// para que dos ediciones simultáneas no obtengan el mismo número.
+func (r *quizVersionRepo) CreateQuizVersion(contentID string, publish func(version int) (string, error)) (domain.QuizVersion, error) {
+	version := domain.QuizVersion{ContentID: contentID}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked []string
+		err := tx.Table("content").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("content_id = ?", contentID).
+			Pluck("content_id", &locked).Error
+		if err != nil {
+			return err
+		}
+
+		var current int
+		err = tx.Model(&domain.QuizVersion{}).
+			Select("COALESCE(MAX(version), 0)").
+			Where("content_id = ?", contentID).
+			Scan(&current).Error
+		if err != nil {
+			return err
+		}
+		version.Version = current + 1
+
+		quizURL, err := publish(version.Version)
+		if err != nil {
+			return err
+		}
+		version.QuizURL = quizURL
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		return domain.QuizVersion{}, fmt.Errorf("error creating quiz version: %w", err)
+	}
+	return version, nil
+}
+
+// GetLatestQuizVersion obtiene la versión vigente de un quiz
+func (r *quizVersionRepo) GetLatestQuizVersion(contentID string) (domain.QuizVersion, error) {
+	var version domain.QuizVersion
+	err := r.db.Where("content_id = ?", contentID).
+		Order("version DESC").
+		First(&version).Error
+	if err != nil {
+		return domain.QuizVersion{}, fmt.Errorf("error finding latest quiz version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+type submissionKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewSubmissionKeyRepo(db *gorm.DB) domain.SubmissionKeyRepo {
+	return &submissionKeyRepo{db: db}
+}
+
+// ReserveSubmissionKey registra la clave antes de procesar el envío; la clave primaria evita
+// que dos envíos concurrentes con la misma clave se procesen a la vez
+func (r *submissionKeyRepo) ReserveSubmissionKey(key domain.QuizSubmissionKey) error {
+	if err := r.db.Create(&key).Error; err != nil {
+		return fmt.Errorf("error reserving submission key: %w", err)
+	}
+	return nil
+}
+
+// GetSubmissionKey obtiene un envío por su clave de idempotencia
+func (r *submissionKeyRepo) GetSubmissionKey(userID, idempotencyKey string) (domain.QuizSubmissionKey, error) {
+	var key domain.QuizSubmissionKey
+	if err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, idempotencyKey).First(&key).Error; err != nil {
+		return domain.QuizSubmissionKey{}, err
+	}
+	return key, nil
+}
+
+// CompleteSubmissionKey guarda el intento y la respuesta de un envío procesado
+func (r *submissionKeyRepo) CompleteSubmissionKey(key domain.QuizSubmissionKey) error {
+	err := r.db.Model(&domain.QuizSubmissionKey{}).
+		Where("user_id = ? AND idempotency_key = ?", key.UserID, key.IdempotencyKey).
+		Updates(map[string]interface{}{"quiz_answer_id": key.QuizAnswerID, "response": key.Response}).Error
+	if err != nil {
+		return fmt.Errorf("error completing submission key: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubmissionKey libera la clave de un envío que falló para que pueda reintentarse
+func (r *submissionKeyRepo) DeleteSubmissionKey(userID, idempotencyKey string) error {
+	err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, idempotencyKey).
+		Delete(&domain.QuizSubmissionKey{}).Error
+	if err != nil {
+		return fmt.Errorf("error deleting submission key: %w", err)
+	}
+	return nil
+}
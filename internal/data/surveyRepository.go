@@ -0,0 +1,45 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"zeppelin/internal/domain"
+)
+
+type surveyRepo struct {
+	db *gorm.DB
+}
+
+func NewSurveyRepo(db *gorm.DB) domain.SurveyRepo {
+	return &surveyRepo{db: db}
+}
+
+// SubmitSurveyResponse guarda la respuesta y la participación en una misma transacción; la clave primaria
+// de survey_participation impide responder dos veces aunque la respuesta sea anónima
+func (r *surveyRepo) SubmitSurveyResponse(response domain.SurveyResponse, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		participation := domain.SurveyParticipation{ContentID: response.ContentID, UserID: userID}
+		if err := tx.Create(&participation).Error; err != nil {
+			return fmt.Errorf("error saving survey participation: %w", err)
+		}
+		if err := tx.Create(&response).Error; err != nil {
+			return fmt.Errorf("error saving survey response: %w", err)
+		}
+		err := tx.Table("user_content").
+			Where("user_id = ? AND content_id = ?", userID, response.ContentID).
+			Update("status_id", 3).Error
+		if err != nil {
+			return fmt.Errorf("error updating user content status: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetSurveyResponses obtiene todas las respuestas de una encuesta
+func (r *surveyRepo) GetSurveyResponses(contentID string) ([]domain.SurveyResponse, error) {
+	var responses []domain.SurveyResponse
+	if err := r.db.Where("content_id = ?", contentID).Order("survey_response_id").Find(&responses).Error; err != nil {
+		return nil, fmt.Errorf("error getting survey responses: %w", err)
+	}
+	return responses, nil
+}
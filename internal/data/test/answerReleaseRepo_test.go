@@ -0,0 +1,24 @@
+package test_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"zeppelin/internal/data"
+)
+
+func TestAnswerReleaseRepo_DeleteAnswerRelease(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewAnswerReleaseRepo(gormDb)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(quoteSql(`DELETE FROM "quiz_answer_release" WHERE content_id = $1`)).
+		WithArgs("quiz-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.DeleteAnswerRelease("quiz-1")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
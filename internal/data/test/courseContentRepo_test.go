@@ -1,11 +1,14 @@
 package test_test
 
 import (
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 	"zeppelin/internal/data"
@@ -96,22 +99,24 @@ func TestCourseContentRepo_AddModule(t *testing.T) {
 	courseID := 1
 	module := "New Module"
 	userID := "teacher_123"
-	expectedCourseQuery := quoteSql(`SELECT * FROM "course" WHERE course_id = $1 AND teacher_id = $2 ORDER BY "course"."course_id" LIMIT $3`)
+	expectedCourseQuery := quoteSql(`SELECT * FROM "course" WHERE course_id = $1 AND teacher_id = $2 ORDER BY "course"."course_id" LIMIT $3 FOR UPDATE`)
 	expectedCourseContentQuery := quoteSql(`SELECT * FROM "course_content" WHERE course_id = $1 AND module = $2 ORDER BY "course_content"."course_content_id" LIMIT $3`)
+	expectedLastIndexQuery := quoteSql(`SELECT COALESCE(MAX(module_index), 0) FROM "course_content" WHERE course_id = $1`)
 	expectedInsertQuery := quoteSql(`INSERT INTO "course_content" ("course_id","module","module_index","created_at") VALUES ($1,$2,$3,$4) RETURNING "course_content_id"`)
 
 	t.Run("Success - Module does not exist", func(t *testing.T) {
 		gormDb, mock := setupMockDb(t)
 		repo := data.NewCourseContentRepo(gormDb, nil)
 
+		mock.ExpectBegin()
 		courseRows := sqlmock.NewRows([]string{"course_id", "teacher_id"}).AddRow(courseID, userID)
 		mock.ExpectQuery(expectedCourseQuery).WithArgs(courseID, userID, 1).WillReturnRows(courseRows)
 
 		mock.ExpectQuery(expectedCourseContentQuery).WithArgs(courseID, module, 1).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectQuery(expectedLastIndexQuery).WithArgs(courseID).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(2))
 
-		mock.ExpectBegin()
 		mock.ExpectQuery(expectedInsertQuery).
-			WithArgs(courseID, module, sqlmock.AnyArg(), sqlmock.AnyArg()). // Using AnyArg for time
+			WithArgs(courseID, module, 3, sqlmock.AnyArg()). // Using AnyArg for time
 			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(1))
 		mock.ExpectCommit()
 
@@ -122,11 +127,32 @@ func TestCourseContentRepo_AddModule(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Success - Module already exists", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		courseRows := sqlmock.NewRows([]string{"course_id", "teacher_id"}).AddRow(courseID, userID)
+		mock.ExpectQuery(expectedCourseQuery).WithArgs(courseID, userID, 1).WillReturnRows(courseRows)
+		mock.ExpectQuery(expectedCourseContentQuery).
+			WithArgs(courseID, module, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id", "course_id", "module"}).AddRow(4, courseID, module))
+		mock.ExpectCommit()
+
+		courseContentID, err := repo.AddModule(courseID, module, userID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, courseContentID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("Error - Course does not belong to the teacher", func(t *testing.T) {
 		gormDb, mock := setupMockDb(t)
 		repo := data.NewCourseContentRepo(gormDb, nil)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(expectedCourseQuery).WithArgs(courseID, userID, 1).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
 
 		courseContentID, err := repo.AddModule(courseID, module, userID)
 
@@ -145,18 +171,20 @@ func TestCourseContentRepo_CreateContent(t *testing.T) {
 		Description:     "Section description",
 	}
 	generatedContentID := "generated_uid_123"
-	expectedCountQuery := quoteSql(`SELECT count(*) FROM "course_content" WHERE course_content_id = $1`)
+	expectedModuleLockQuery := quoteSql(`SELECT "course_content_id" FROM "course_content" WHERE course_content_id = $1 FOR UPDATE`)
+	expectedLastIndexQuery := quoteSql(`SELECT COALESCE(MAX(section_index), 0) FROM "content" WHERE course_content_id = $1`)
 	expectedInsertQuery := quoteSql(`INSERT INTO "content" ("content_id","course_content_id","content_type_id","title","url","description","section_index","is_active") VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`)
 
 	t.Run("Success", func(t *testing.T) {
 		gormDb, mock := setupMockDb(t)
 		repo := data.NewCourseContentRepo(gormDb, func() string { return generatedContentID })
 
-		mock.ExpectQuery(expectedCountQuery).WithArgs(input.CourseContentID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-
 		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleLockQuery).WithArgs(input.CourseContentID).WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(input.CourseContentID))
+		mock.ExpectQuery(expectedLastIndexQuery).WithArgs(input.CourseContentID).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+
 		mock.ExpectExec(expectedInsertQuery).
-			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 0, false). // Assuming default values
+			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 1, false). // Assuming default values
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -171,7 +199,9 @@ func TestCourseContentRepo_CreateContent(t *testing.T) {
 		gormDb, mock := setupMockDb(t)
 		repo := data.NewCourseContentRepo(gormDb, func() string { return generatedContentID })
 
-		mock.ExpectQuery(expectedCountQuery).WithArgs(input.CourseContentID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleLockQuery).WithArgs(input.CourseContentID).WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}))
+		mock.ExpectRollback()
 
 		contentID, err := repo.CreateContent(input)
 
@@ -420,7 +450,8 @@ func TestCourseContentRepo_AddSection(t *testing.T) {
 	userID := "teacher_123"
 	generatedContentID := "generated_uid_123"
 	expectedOwnershipQuery := quoteSql(`SELECT "course_content"."course_content_id","course_content"."course_id","course_content"."module","course_content"."module_index","course_content"."created_at" FROM "course_content" JOIN course ON course_content.course_id = course.course_id WHERE course_content.course_content_id = $1 AND course.teacher_id = $2 ORDER BY "course_content"."course_content_id" LIMIT $3`)
-	expectedCountQuery := quoteSql(`SELECT count(*) FROM "course_content" WHERE course_content_id = $1`)
+	expectedModuleLockQuery := quoteSql(`SELECT "course_content_id" FROM "course_content" WHERE course_content_id = $1 FOR UPDATE`)
+	expectedLastIndexQuery := quoteSql(`SELECT COALESCE(MAX(section_index), 0) FROM "content" WHERE course_content_id = $1`)
 	expectedInsertQuery := quoteSql(`INSERT INTO "content" ("content_id","course_content_id","content_type_id","title","url","description","section_index","is_active") VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`)
 
 	t.Run("Success", func(t *testing.T) {
@@ -435,13 +466,16 @@ func TestCourseContentRepo_AddSection(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Mock CreateContent
-		mock.ExpectQuery(expectedCountQuery).
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleLockQuery).
 			WithArgs(input.CourseContentID).
-			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(input.CourseContentID))
+		mock.ExpectQuery(expectedLastIndexQuery).
+			WithArgs(input.CourseContentID).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
 
-		mock.ExpectBegin()
 		mock.ExpectExec(expectedInsertQuery).
-			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 0, false).
+			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 1, false).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -473,7 +507,7 @@ func TestCourseContentRepo_AddSection(t *testing.T) {
 		repo := data.NewCourseContentRepo(gormDb, func() string { return generatedContentID })
 
 		invalidInput := input
-		invalidInput.ContentTypeID = 4 // Invalid content type ID
+		invalidInput.ContentTypeID = 5 // Invalid content type ID
 
 		// Mock VerifyModuleOwnership
 		rows := sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
@@ -502,14 +536,17 @@ func TestCourseContentRepo_AddSection(t *testing.T) {
 			WillReturnRows(rows)
 
 		// Mock CreateContent DB error
-		mock.ExpectQuery(expectedCountQuery).
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleLockQuery).
 			WithArgs(input.CourseContentID).
-			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(input.CourseContentID))
+		mock.ExpectQuery(expectedLastIndexQuery).
+			WithArgs(input.CourseContentID).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
 
 		dbErr := errors.New("db insert error")
-		mock.ExpectBegin()
 		mock.ExpectExec(expectedInsertQuery).
-			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 0, false).
+			WithArgs(generatedContentID, input.CourseContentID, input.ContentTypeID, input.Title, "", input.Description, 1, false).
 			WillReturnError(dbErr)
 		mock.ExpectRollback()
 
@@ -528,6 +565,7 @@ func TestCourseContentRepo_GetContentByCourseForStudent(t *testing.T) {
 	expectedCourseContentQuery := quoteSql(`SELECT * FROM "course_content" WHERE course_id = $1 ORDER BY module_index`)
 	expectedContentQuery := quoteSql(`SELECT * FROM "content" WHERE is_active = $1 AND "content"."course_content_id" = $2 ORDER BY section_index`)
 	expectedUserContentQuery := quoteSql(`SELECT * FROM "user_content" WHERE "user_content"."content_id" = $1 AND user_id = $2`)
+	expectedPrerequisitesQuery := quoteSql(`SELECT * FROM "content_prerequisite" WHERE content_id IN ($1) ORDER BY content_id, required_content_id`)
 
 	t.Run("Success - With Content and Status", func(t *testing.T) {
 		gormDb, mock := setupMockDb(t)
@@ -554,6 +592,11 @@ func TestCourseContentRepo_GetContentByCourseForStudent(t *testing.T) {
 			WithArgs("content_1", userID). // Note: content_id first, then user_id
 			WillReturnRows(userContentRows)
 
+		// Sin prerrequisitos nada queda bloqueado
+		mock.ExpectQuery(expectedPrerequisitesQuery).
+			WithArgs("content_1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "required_content_id", "min_score"}))
+
 		result, err := repo.GetContentByCourseForStudent(courseID, userID)
 
 		assert.NoError(t, err)
@@ -670,3 +713,595 @@ func TestCourseContentRepo_GetUrlByContentID(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestCourseContentRepo_ReorderModules(t *testing.T) {
+	courseID := 7
+	expectedModulesQuery := quoteSql(`SELECT "course_content_id" FROM "course_content" WHERE course_id = $1 FOR UPDATE`)
+	expectedUpdate := quoteSql(`UPDATE "course_content" SET "module_index"=$1 WHERE course_content_id = $2`)
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModulesQuery).WithArgs(courseID).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(1).AddRow(2).AddRow(3))
+		mock.ExpectExec(expectedUpdate).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedUpdate).WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedUpdate).WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.ReorderModules(courseID, []int{3, 1, 2})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Incomplete order", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModulesQuery).WithArgs(courseID).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(1).AddRow(2).AddRow(3))
+		mock.ExpectRollback()
+
+		err := repo.ReorderModules(courseID, []int{3, 1, 9})
+
+		assert.ErrorIs(t, err, domain.ErrValidationFailed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - DB Error rolls back", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		dbErr := errors.New("db update error")
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModulesQuery).WithArgs(courseID).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(1).AddRow(2))
+		mock.ExpectExec(expectedUpdate).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedUpdate).WithArgs(2, 1).WillReturnError(dbErr)
+		mock.ExpectRollback()
+
+		err := repo.ReorderModules(courseID, []int{2, 1})
+
+		assert.ErrorIs(t, err, dbErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_ReorderSections(t *testing.T) {
+	expectedModuleQuery := quoteSql(`SELECT * FROM "course_content" WHERE course_content_id = $1 ORDER BY "course_content"."course_content_id" LIMIT $2`)
+	expectedSectionsQuery := quoteSql(`SELECT content.content_id, content.course_content_id FROM "content" JOIN course_content ON content.course_content_id = course_content.course_content_id WHERE course_content.course_id = $1 AND (content.course_content_id = $2 OR content.content_id IN ($3,$4,$5)) FOR UPDATE OF "content"`)
+	expectedMove := quoteSql(`UPDATE "content" SET "course_content_id"=$1,"section_index"=$2 WHERE content_id = $3`)
+	expectedRemainingQuery := quoteSql(`SELECT "content_id" FROM "content" WHERE course_content_id = $1 ORDER BY section_index, content_id`)
+	expectedRenumber := quoteSql(`UPDATE "content" SET "section_index"=$1 WHERE content_id = $2`)
+	moduleRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
+			AddRow(1, 7, "Module 1", 1, time.Now())
+	}
+
+	t.Run("Success - Move section from another module", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(1, 1).WillReturnRows(moduleRows())
+		mock.ExpectQuery(expectedSectionsQuery).WithArgs(7, 1, "b", "x", "a").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "course_content_id"}).
+				AddRow("a", 1).AddRow("b", 1).AddRow("x", 2))
+		mock.ExpectExec(expectedMove).WithArgs(1, 1, "b").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedMove).WithArgs(1, 2, "x").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedMove).WithArgs(1, 3, "a").WillReturnResult(sqlmock.NewResult(0, 1))
+		// El módulo de origen se renumera sin huecos
+		mock.ExpectQuery(expectedRemainingQuery).WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("w").AddRow("y"))
+		mock.ExpectExec(expectedRenumber).WithArgs(1, "w").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedRenumber).WithArgs(2, "y").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.ReorderSections(1, []string{"b", "x", "a"})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Section missing from the order", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(1, 1).WillReturnRows(moduleRows())
+		mock.ExpectQuery(expectedSectionsQuery).WithArgs(7, 1, "b", "x", "y").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "course_content_id"}).
+				AddRow("a", 1).AddRow("b", 1).AddRow("x", 2).AddRow("y", 2))
+		mock.ExpectRollback()
+
+		err := repo.ReorderSections(1, []string{"b", "x", "y"})
+
+		assert.ErrorIs(t, err, domain.ErrValidationFailed)
+		assert.Contains(t, err.Error(), "every section of the module")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Section from another course", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(1, 1).WillReturnRows(moduleRows())
+		mock.ExpectQuery(expectedSectionsQuery).WithArgs(7, 1, "a", "b", "other").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "course_content_id"}).
+				AddRow("a", 1).AddRow("b", 1))
+		mock.ExpectRollback()
+
+		err := repo.ReorderSections(1, []string{"a", "b", "other"})
+
+		assert.ErrorIs(t, err, domain.ErrValidationFailed)
+		assert.Contains(t, err.Error(), "section other does not belong to the course")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Module not found", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(1, 1).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		err := repo.ReorderSections(1, []string{"a"})
+
+		assert.ErrorIs(t, err, domain.ErrResourceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// expectContentDeletes espera el borrado de las filas que dependen de los contenidos y de los contenidos
+func expectContentDeletes(mock sqlmock.Sqlmock, contentIDs ...driver.Value) {
+	placeholders := make([]string, len(contentIDs))
+	for i := range contentIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	in := strings.Join(placeholders, ",")
+	required := make([]string, len(contentIDs))
+	for i := range contentIDs {
+		required[i] = fmt.Sprintf("$%d", len(contentIDs)+i+1)
+	}
+	mock.ExpectExec(quoteSql(fmt.Sprintf(`DELETE FROM "content_prerequisite" WHERE content_id IN (%s) OR required_content_id IN (%s)`, in, strings.Join(required, ",")))).
+		WithArgs(append(append([]driver.Value{}, contentIDs...), contentIDs...)...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, table := range []string{"quiz_submission_key", "quiz_answer", "quiz_answer_release", "quiz_version", "content_due_date_extension", "content_due_date", "survey_response", "survey_participation", "user_content", "content"} {
+		mock.ExpectExec(quoteSql(fmt.Sprintf(`DELETE FROM "%s" WHERE content_id IN (%s)`, table, in))).
+			WithArgs(contentIDs...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestCourseContentRepo_DeleteSection(t *testing.T) {
+	userID := "teacher_123"
+	expectedOwnershipQuery := quoteSql(`SELECT content.course_content_id, course_content.course_id FROM "content" JOIN course_content ON content.course_content_id = course_content.course_content_id JOIN course ON course_content.course_id = course.course_id WHERE content.content_id = $1 AND course.teacher_id = $2 LIMIT $3`)
+	expectedAttemptsQuery := quoteSql(`SELECT count(*) FROM "quiz_answer" WHERE content_id IN ($1)`)
+	expectedAnswerURLsQuery := quoteSql(`SELECT "quiz_answer_url" FROM "quiz_answer" WHERE content_id IN ($1) AND quiz_answer_url <> ''`)
+	expectedItemsDelete := quoteSql(`DELETE FROM "quiz_answer_item" WHERE quiz_answer_id IN (SELECT "quiz_answer_id" FROM "quiz_answer" WHERE content_id IN ($1))`)
+	expectedRemainingQuery := quoteSql(`SELECT "content_id" FROM "content" WHERE course_content_id = $1 ORDER BY section_index, content_id`)
+	expectedRenumber := quoteSql(`UPDATE "content" SET "section_index"=$1 WHERE content_id = $2`)
+	ownerRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"course_content_id", "course_id"}).AddRow(4, 7)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedOwnershipQuery).WithArgs("c1", userID, 1).WillReturnRows(ownerRows())
+		mock.ExpectQuery(expectedAttemptsQuery).WithArgs("c1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		expectContentDeletes(mock, "c1")
+		mock.ExpectQuery(expectedRemainingQuery).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("c2"))
+		mock.ExpectExec(expectedRenumber).WithArgs(1, "c2").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		deleted, err := repo.DeleteSection("c1", userID, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.DeletedContent{CourseID: 7, ContentIDs: []string{"c1"}}, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Attempts without force", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedOwnershipQuery).WithArgs("c1", userID, 1).WillReturnRows(ownerRows())
+		mock.ExpectQuery(expectedAttemptsQuery).WithArgs("c1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectRollback()
+
+		deleted, err := repo.DeleteSection("c1", userID, false)
+
+		assert.ErrorIs(t, err, domain.ErrContentHasAttempts)
+		assert.Equal(t, int64(3), deleted.Attempts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success - Force deletes attempts", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedOwnershipQuery).WithArgs("c1", userID, 1).WillReturnRows(ownerRows())
+		mock.ExpectQuery(expectedAttemptsQuery).WithArgs("c1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(expectedAnswerURLsQuery).WithArgs("c1").
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_answer_url"}).AddRow("https://acc.r2.cloudflarestorage.com/quiz/answer/s1/c1/1.json"))
+		mock.ExpectExec(expectedItemsDelete).WithArgs("c1").WillReturnResult(sqlmock.NewResult(0, 6))
+		expectContentDeletes(mock, "c1")
+		mock.ExpectQuery(expectedRemainingQuery).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"content_id"}))
+		mock.ExpectCommit()
+
+		deleted, err := repo.DeleteSection("c1", userID, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), deleted.Attempts)
+		assert.Equal(t, []string{"https://acc.r2.cloudflarestorage.com/quiz/answer/s1/c1/1.json"}, deleted.AnswerURLs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Not the teacher's content", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedOwnershipQuery).WithArgs("c1", userID, 1).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		_, err := repo.DeleteSection("c1", userID, false)
+
+		assert.ErrorIs(t, err, domain.ErrResourceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_DeleteModule(t *testing.T) {
+	userID := "teacher_123"
+	expectedModuleQuery := quoteSql(`SELECT "course_content"."course_content_id","course_content"."course_id","course_content"."module","course_content"."module_index","course_content"."created_at" FROM "course_content" JOIN course ON course_content.course_id = course.course_id WHERE course_content.course_content_id = $1 AND course.teacher_id = $2 ORDER BY "course_content"."course_content_id" LIMIT $3`)
+	expectedSectionsQuery := quoteSql(`SELECT "content_id" FROM "content" WHERE course_content_id = $1 ORDER BY section_index, content_id`)
+	expectedAttemptsQuery := quoteSql(`SELECT count(*) FROM "quiz_answer" WHERE content_id IN ($1,$2)`)
+	expectedSessionsDelete := quoteSql(`DELETE FROM "session_course_content" WHERE course_content_id = $1`)
+	expectedModuleDelete := quoteSql(`DELETE FROM "course_content" WHERE course_content_id = $1`)
+	expectedModulesQuery := quoteSql(`SELECT "course_content_id" FROM "course_content" WHERE course_id = $1 ORDER BY module_index, course_content_id`)
+	expectedRenumber := quoteSql(`UPDATE "course_content" SET "module_index"=$1 WHERE course_content_id = $2`)
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(4, userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
+				AddRow(4, 7, "Module 2", 2, time.Now()))
+		mock.ExpectQuery(expectedSectionsQuery).WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("c1").AddRow("c2"))
+		mock.ExpectQuery(expectedAttemptsQuery).WithArgs("c1", "c2").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		expectContentDeletes(mock, "c1", "c2")
+		mock.ExpectExec(expectedSessionsDelete).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(expectedModuleDelete).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(expectedModulesQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(1).AddRow(9))
+		mock.ExpectExec(expectedRenumber).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedRenumber).WithArgs(2, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		deleted, err := repo.DeleteModule(4, userID, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.DeletedContent{CourseID: 7, ContentIDs: []string{"c1", "c2"}}, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - DB error rolls back", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		dbErr := errors.New("db delete error")
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedModuleQuery).WithArgs(4, userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
+				AddRow(4, 7, "Module 2", 2, time.Now()))
+		mock.ExpectQuery(expectedSectionsQuery).WithArgs(4).WillReturnRows(sqlmock.NewRows([]string{"content_id"}))
+		mock.ExpectExec(expectedSessionsDelete).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(expectedModuleDelete).WithArgs(4).WillReturnError(dbErr)
+		mock.ExpectRollback()
+
+		_, err := repo.DeleteModule(4, userID, false)
+
+		assert.ErrorIs(t, err, dbErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_CloneCourse(t *testing.T) {
+	userID := "teacher_123"
+	expectedCourseQuery := quoteSql(`SELECT * FROM "course" WHERE course_id = $1 AND teacher_id = $2 ORDER BY "course"."course_id" LIMIT $3`)
+	expectedCourseInsert := quoteSql(`INSERT INTO "course" ("teacher_id","start_date","title","description","qr_code") VALUES ($1,$2,$3,$4,$5) RETURNING "course_id"`)
+	expectedModulesQuery := quoteSql(`SELECT * FROM "course_content" WHERE course_id = $1 ORDER BY module_index, course_content_id`)
+	expectedModuleInsert := quoteSql(`INSERT INTO "course_content" ("course_id","module","module_index","created_at") VALUES ($1,$2,$3,$4) RETURNING "course_content_id"`)
+	expectedContentsQuery := quoteSql(`SELECT * FROM "content" WHERE course_content_id IN ($1,$2) ORDER BY course_content_id, section_index, content_id`)
+	expectedContentInsert := quoteSql(`INSERT INTO "content" ("content_id","course_content_id","content_type_id","title","url","description","section_index","is_active") VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`)
+	expectedVersionsQuery := quoteSql(`SELECT * FROM "quiz_version" WHERE content_id IN ($1,$2) ORDER BY quiz_version_id`)
+	expectedVersionInsert := quoteSql(`INSERT INTO "quiz_version" ("content_id","version","quiz_url","created_at") VALUES ($1,$2,$3,$4) RETURNING "quiz_version_id"`)
+	expectedDueDatesQuery := quoteSql(`SELECT * FROM "content_due_date" WHERE content_id IN ($1,$2)`)
+	expectedPrerequisitesQuery := quoteSql(`SELECT * FROM "content_prerequisite" WHERE content_id IN ($1,$2) ORDER BY content_id, required_content_id`)
+	expectedPrerequisiteInsert := quoteSql(`INSERT INTO "content_prerequisite" ("content_id","required_content_id","min_score") VALUES ($1,$2,$3)`)
+
+	t.Run("Success without enrollments", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		uids := []string{"new-video", "new-quiz"}
+		repo := data.NewCourseContentRepo(gormDb, func() string {
+			uid := uids[0]
+			uids = uids[1:]
+			return uid
+		})
+		oldQuizURL := "https://acc.r2.cloudflarestorage.com/focused/7/quiz/teacher/quiz-1.json"
+		newQuizURL := "https://acc.r2.cloudflarestorage.com/focused/20/quiz/teacher/new-quiz.json"
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedCourseQuery).WithArgs(7, userID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"course_id", "teacher_id", "start_date", "title", "description", "qr_code"}).
+				AddRow(7, userID, "2024-03-01", "Go", "Curso de Go", "OLDQR"))
+		mock.ExpectQuery(expectedCourseInsert).WithArgs(userID, "2025-03-01", "Go 2025", "Curso de Go", "NEWQR").
+			WillReturnRows(sqlmock.NewRows([]string{"course_id"}).AddRow(20))
+		mock.ExpectQuery(expectedModulesQuery).WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
+				AddRow(1, 7, "Intro", 1, time.Now()).AddRow(2, 7, "Evaluación", 2, time.Now()))
+		mock.ExpectQuery(expectedModuleInsert).WithArgs(20, "Intro", 1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(31))
+		mock.ExpectQuery(expectedModuleInsert).WithArgs(20, "Evaluación", 2, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"course_content_id"}).AddRow(32))
+		mock.ExpectQuery(expectedContentsQuery).WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "course_content_id", "content_type_id", "title", "url", "description", "section_index", "is_active"}).
+				AddRow("video-1", 1, 1, "Bienvenida", "https://youtu.be/abc", "", 1, true).
+				AddRow("quiz-1", 2, 3, "Quiz", oldQuizURL, "", 1, false))
+		mock.ExpectExec(expectedContentInsert).WithArgs("new-video", 31, 1, "Bienvenida", "https://youtu.be/abc", "", 1, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(expectedContentInsert).WithArgs("new-quiz", 32, 3, "Quiz", newQuizURL, "", 1, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(expectedVersionsQuery).WithArgs("video-1", "quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_version_id", "content_id", "version", "quiz_url", "created_at"}).
+				AddRow(5, "quiz-1", 1, "https://acc.r2.cloudflarestorage.com/focused/7/quiz/teacher/quiz-1/v1.json", time.Now()))
+		mock.ExpectQuery(expectedVersionInsert).
+			WithArgs("new-quiz", 1, "https://acc.r2.cloudflarestorage.com/focused/20/quiz/teacher/new-quiz/v1.json", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_version_id"}).AddRow(50))
+		mock.ExpectQuery(expectedDueDatesQuery).WithArgs("video-1", "quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "due_at"}))
+		mock.ExpectQuery(expectedPrerequisitesQuery).WithArgs("video-1", "quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "required_content_id", "min_score"}).AddRow("quiz-1", "video-1", nil))
+		mock.ExpectExec(expectedPrerequisiteInsert).WithArgs("new-quiz", "new-video", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		clone, err := repo.CloneCourse(domain.CloneCourseRequest{
+			CloneCourseInput: domain.CloneCourseInput{Title: "Go 2025", StartDate: "2025-03-01", ExcludeEnrollments: true},
+			SourceCourseID:   7,
+			TeacherID:        userID,
+			QRCode:           "NEWQR",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20, clone.CourseID)
+		assert.Equal(t, map[int]int{1: 31, 2: 32}, clone.ModuleIDs)
+		assert.Equal(t, map[string]string{"video-1": "new-video", "quiz-1": "new-quiz"}, clone.ContentIDs)
+		assert.Equal(t, newQuizURL, clone.URLs[oldQuizURL])
+		assert.Len(t, clone.URLs, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Course of another teacher", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedCourseQuery).WithArgs(7, userID, 1).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		_, err := repo.CloneCourse(domain.CloneCourseRequest{SourceCourseID: 7, TeacherID: userID, QRCode: "NEWQR"})
+
+		assert.ErrorIs(t, err, domain.ErrResourceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_GetContentByCourseForStudent_Locks(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewCourseContentRepo(gormDb, nil)
+	userID := "student_123"
+
+	mock.ExpectQuery(quoteSql(`SELECT * FROM "course_content" WHERE course_id = $1 ORDER BY module_index`)).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"course_content_id", "course_id", "module", "module_index", "created_at"}).
+			AddRow(1, 1, "Module 1", 1, time.Now()))
+	mock.ExpectQuery(quoteSql(`SELECT * FROM "content" WHERE is_active = $1 AND "content"."course_content_id" = $2 ORDER BY section_index`)).WithArgs(true, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"content_id", "course_content_id", "content_type_id", "title", "url", "description", "section_index", "is_active"}).
+			AddRow("lectura", 1, 2, "Lectura", "url1", "", 1, true).
+			AddRow("quiz", 1, 3, "Quiz", "url2", "", 2, true).
+			AddRow("final", 1, 2, "Cierre", "url3", "", 3, true))
+	mock.ExpectQuery(quoteSql(`SELECT * FROM "user_content" WHERE "user_content"."content_id" IN ($1,$2,$3) AND user_id = $4`)).
+		WithArgs("lectura", "quiz", "final", userID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "content_id", "status_id"}).
+			AddRow(userID, "lectura", 2).AddRow(userID, "quiz", 3).AddRow(userID, "final", 1))
+	mock.ExpectQuery(quoteSql(`SELECT * FROM "content_prerequisite" WHERE content_id IN ($1,$2,$3) ORDER BY content_id, required_content_id`)).
+		WithArgs("lectura", "quiz", "final").
+		WillReturnRows(sqlmock.NewRows([]string{"content_id", "required_content_id", "min_score"}).
+			AddRow("final", "lectura", nil).
+			AddRow("final", "quiz", 80.0).
+			AddRow("quiz", "oculto", nil))
+	mock.ExpectQuery(quoteSql(`SELECT content_id, MAX(grade * 100.0 / total_points) AS score FROM "quiz_answer" WHERE user_id = $1 AND content_id IN ($2) AND end_time IS NOT NULL AND grade IS NOT NULL AND total_points > 0 GROUP BY "content_id"`)).
+		WithArgs(userID, "quiz").
+		WillReturnRows(sqlmock.NewRows([]string{"content_id", "score"}).AddRow("quiz", 60.0))
+
+	result, err := repo.GetContentByCourseForStudent(1, userID)
+
+	assert.NoError(t, err)
+	if assert.Len(t, result, 1) && assert.Len(t, result[0].Details, 3) {
+		assert.False(t, result[0].Details[0].Locked)
+		// Un contenido requerido que el estudiante no ve no bloquea
+		assert.False(t, result[0].Details[1].Locked)
+		final := result[0].Details[2]
+		assert.True(t, final.Locked)
+		assert.Empty(t, final.Url)
+		minScore, bestScore := 80.0, 60.0
+		assert.Equal(t, []domain.ContentLock{
+			{Reason: domain.LockReasonNotCompleted, RequiredContentID: "lectura", RequiredTitle: "Lectura"},
+			{Reason: domain.LockReasonMinScore, RequiredContentID: "quiz", RequiredTitle: "Quiz", MinScore: &minScore, BestScore: &bestScore},
+		}, final.LockReasons)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCourseContentRepo_GetContentLocks(t *testing.T) {
+	userID := "student_123"
+	expectedRulesQuery := quoteSql(`SELECT * FROM "content_prerequisite" WHERE content_id = $1 ORDER BY required_content_id`)
+	expectedRequiredQuery := quoteSql(`SELECT content.content_id, content.title, user_content.status_id FROM "content" JOIN user_content ON user_content.content_id = content.content_id AND user_content.user_id = $1 WHERE content.content_id IN ($2) AND content.is_active = $3`)
+
+	t.Run("Unlocked after completing", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectQuery(expectedRulesQuery).WithArgs("final").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "required_content_id", "min_score"}).AddRow("final", "lectura", nil))
+		mock.ExpectQuery(expectedRequiredQuery).WithArgs(userID, "lectura", true).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "title", "status_id"}).AddRow("lectura", "Lectura", 3))
+
+		locks, err := repo.GetContentLocks("final", userID)
+
+		assert.NoError(t, err)
+		assert.Empty(t, locks)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Locked without attempts", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectQuery(expectedRulesQuery).WithArgs("final").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "required_content_id", "min_score"}).AddRow("final", "quiz", 70.0))
+		mock.ExpectQuery(expectedRequiredQuery).WithArgs(userID, "quiz", true).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "title", "status_id"}).AddRow("quiz", "Quiz", 3))
+		mock.ExpectQuery(quoteSql(`FROM "quiz_answer" WHERE user_id = $1 AND content_id IN ($2)`)).WithArgs(userID, "quiz").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "score"}))
+
+		locks, err := repo.GetContentLocks("final", userID)
+
+		assert.NoError(t, err)
+		if assert.Len(t, locks, 1) {
+			assert.Equal(t, domain.LockReasonMinScore, locks[0].Reason)
+			assert.Nil(t, locks[0].BestScore)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_SetPrerequisites(t *testing.T) {
+	userID := "teacher_123"
+	expectedTargetQuery := quoteSql(`SELECT content.content_id, content.content_type_id, content.title, content.section_index, course_content.course_id, course_content.module_index FROM "content" JOIN course_content ON content.course_content_id = course_content.course_content_id JOIN course ON course_content.course_id = course.course_id WHERE content.content_id = $1 AND course.teacher_id = $2 LIMIT $3`)
+	expectedRequiredQuery := quoteSql(`SELECT content.content_id, content.content_type_id, content.title, content.section_index, course_content.course_id, course_content.module_index FROM "content" JOIN course_content ON content.course_content_id = course_content.course_content_id WHERE content.content_id = $1 AND course_content.course_id = $2 LIMIT $3`)
+	positionRows := func(contentID string, contentTypeID, sectionIndex, moduleIndex int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"content_id", "content_type_id", "title", "section_index", "course_id", "module_index"}).
+			AddRow(contentID, contentTypeID, contentID, sectionIndex, 7, moduleIndex)
+	}
+	minScore := 70.0
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedTargetQuery).WithArgs("final", userID, 1).WillReturnRows(positionRows("final", 2, 1, 2))
+		mock.ExpectQuery(expectedRequiredQuery).WithArgs("quiz", 7, 1).WillReturnRows(positionRows("quiz", 3, 4, 1))
+		mock.ExpectExec(quoteSql(`DELETE FROM "content_prerequisite" WHERE content_id = $1`)).WithArgs("final").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(quoteSql(`INSERT INTO "content_prerequisite" ("content_id","required_content_id","min_score") VALUES ($1,$2,$3)`)).
+			WithArgs("final", "quiz", minScore).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.SetPrerequisites(domain.SetPrerequisitesInput{
+			ContentID:     "final",
+			Prerequisites: []domain.PrerequisiteInput{{RequiredContentID: "quiz", MinScore: &minScore}},
+		}, userID)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Required content comes later", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedTargetQuery).WithArgs("final", userID, 1).WillReturnRows(positionRows("final", 2, 1, 2))
+		mock.ExpectQuery(expectedRequiredQuery).WithArgs("extra", 7, 1).WillReturnRows(positionRows("extra", 2, 2, 2))
+		mock.ExpectRollback()
+
+		err := repo.SetPrerequisites(domain.SetPrerequisitesInput{
+			ContentID:     "final",
+			Prerequisites: []domain.PrerequisiteInput{{RequiredContentID: "extra"}},
+		}, userID)
+
+		assert.ErrorIs(t, err, domain.ErrValidationFailed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Min score on a text", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(expectedTargetQuery).WithArgs("final", userID, 1).WillReturnRows(positionRows("final", 2, 1, 2))
+		mock.ExpectQuery(expectedRequiredQuery).WithArgs("lectura", 7, 1).WillReturnRows(positionRows("lectura", 2, 1, 1))
+		mock.ExpectRollback()
+
+		err := repo.SetPrerequisites(domain.SetPrerequisitesInput{
+			ContentID:     "final",
+			Prerequisites: []domain.PrerequisiteInput{{RequiredContentID: "lectura", MinScore: &minScore}},
+		}, userID)
+
+		assert.ErrorIs(t, err, domain.ErrValidationFailed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCourseContentRepo_GetCourseIDByContentID(t *testing.T) {
+	contentID := "content_123"
+	expectedQuery := quoteSql(`SELECT "course_content"."course_id" FROM "content" JOIN course_content ON course_content.course_content_id = content.course_content_id WHERE content.content_id = $1`)
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectQuery(expectedQuery).
+			WithArgs(contentID).
+			WillReturnRows(sqlmock.NewRows([]string{"course_id"}).AddRow(7))
+
+		courseID, err := repo.GetCourseIDByContentID(contentID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, courseID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error - Content not found", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewCourseContentRepo(gormDb, nil)
+
+		mock.ExpectQuery(expectedQuery).
+			WithArgs(contentID).
+			WillReturnRows(sqlmock.NewRows([]string{"course_id"}))
+
+		courseID, err := repo.GetCourseIDByContentID(contentID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "content not found", err.Error())
+		assert.Equal(t, 0, courseID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,45 @@
+package test_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+	"zeppelin/internal/data"
+)
+
+func TestDueDateRepo_GetDueDateExtension(t *testing.T) {
+	expectedSql := `SELECT * FROM "content_due_date_extension" WHERE content_id = $1 AND user_id = $2 ORDER BY "content_due_date_extension"."content_id" LIMIT $3`
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewDueDateRepo(gormDb)
+
+		dueAt := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs("quiz-1", "student-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"content_id", "user_id", "due_at"}).
+				AddRow("quiz-1", "student-1", dueAt))
+
+		extension, err := repo.GetDueDateExtension("quiz-1", "student-1")
+
+		assert.NoError(t, err)
+		assert.True(t, extension.DueAt.Equal(dueAt))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewDueDateRepo(gormDb)
+
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs("quiz-1", "student-1", 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := repo.GetDueDateExtension("quiz-1", "student-1")
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
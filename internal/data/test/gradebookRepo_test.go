@@ -0,0 +1,45 @@
+package test_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"zeppelin/internal/data"
+)
+
+func TestGradebookRepo_GetGradebook(t *testing.T) {
+	expectedSql := `SELECT * FROM "gradebook" WHERE course_id = $1 ORDER BY "gradebook"."course_id" LIMIT $2`
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewGradebookRepo(gormDb)
+
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs(10, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"course_id", "attempt_policy", "pass_threshold", "categories", "letter_scale"}).
+				AddRow(10, "latest", 65.0, []byte(`[{"id":"q","name":"Quizzes","weight":1,"content_ids":["quiz-1"]}]`), []byte(`[{"letter":"A","min_percent":90}]`)))
+
+		gradebook, err := repo.GetGradebook(10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "latest", gradebook.AttemptPolicy)
+		assert.Equal(t, []string{"quiz-1"}, gradebook.Categories[0].ContentIDs)
+		assert.Equal(t, "A", gradebook.LetterScale[0].Letter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewGradebookRepo(gormDb)
+
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs(10, 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := repo.GetGradebook(10)
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,183 @@
+package test_test
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+	"zeppelin/internal/data"
+	"zeppelin/internal/domain"
+)
+
+func TestQuizRepository_FindQuizAnswerItems(t *testing.T) {
+	expectedSql := `SELECT * FROM "quiz_answer_item" WHERE quiz_answer_id = $1 ORDER BY quiz_answer_item_id`
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_answer_item_id", "quiz_answer_id", "question_id", "points", "score", "needs_review"}).
+				AddRow(1, 5, "q1", 4, 4.0, false).
+				AddRow(2, 5, "q2", 6, nil, true))
+
+		items, err := repo.FindQuizAnswerItems(5)
+
+		assert.NoError(t, err)
+		assert.Len(t, items, 2)
+		assert.Equal(t, 4.0, *items[0].Score)
+		assert.Nil(t, items[1].Score)
+		assert.True(t, items[1].NeedsReview)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DB Error", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectQuery(quoteSql(expectedSql)).
+			WithArgs(5).
+			WillReturnError(errors.New("db error"))
+
+		_, err := repo.FindQuizAnswerItems(5)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestQuizRepository_FindQuizAnswerItemsByAttempts(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewQuizRepository(gormDb)
+
+	expectedSql := `SELECT * FROM "quiz_answer_item" WHERE quiz_answer_id IN ($1,$2) ORDER BY quiz_answer_id, quiz_answer_item_id`
+	mock.ExpectQuery(quoteSql(expectedSql)).
+		WithArgs(5, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"quiz_answer_item_id", "quiz_answer_id", "question_id", "rubric"}).
+			AddRow(1, 5, "q1", nil).
+			AddRow(2, 6, "q1", []byte(`[{"criterion_id":"arg","level_id":"high","points":4,"max_points":4}]`)))
+
+	items, err := repo.FindQuizAnswerItemsByAttempts([]int{5, 6})
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Nil(t, items[0].Rubric)
+	assert.Equal(t, "high", items[1].Rubric[0].LevelID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuizRepository_StartQuizAttempt_MaxAttempts(t *testing.T) {
+	lockSql := `SELECT "content_id" FROM "content" WHERE content_id = $1 FOR UPDATE`
+	countSql := `SELECT count(*) FROM "quiz_answer" WHERE user_id = $1 AND content_id = $2`
+
+	t.Run("Limit Reached", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(quoteSql(lockSql)).WithArgs("quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("quiz-1"))
+		mock.ExpectQuery(quoteSql(countSql)).WithArgs("s1", "quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		_, err := repo.StartQuizAttempt(domain.QuizAnswer{UserID: "s1", ContentID: "quiz-1", StartTime: time.Now()}, 2)
+
+		assert.ErrorIs(t, err, domain.ErrMaxAttemptsReached)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Attempt Left", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(quoteSql(lockSql)).WithArgs("quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("quiz-1"))
+		mock.ExpectQuery(quoteSql(countSql)).WithArgs("s1", "quiz-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`INSERT INTO "quiz_answer"`).
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_answer_id"}).AddRow(9))
+		mock.ExpectCommit()
+
+		id, err := repo.StartQuizAttempt(domain.QuizAnswer{UserID: "s1", ContentID: "quiz-1", StartTime: time.Now()}, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 9, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestQuizRepository_SaveQuizDraft(t *testing.T) {
+	expectedSql := `UPDATE "quiz_answer" SET "draft_answers"=$1,"autosaved_at"=$2 WHERE end_time IS NULL AND "quiz_answer_id" = $3`
+	savedAt := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(quoteSql(expectedSql)).
+			WithArgs(`{"q1":"borrador"}`, savedAt, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.SaveQuizDraft(5, map[string]interface{}{"q1": "borrador"}, savedAt)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Submitted", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizRepository(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(quoteSql(expectedSql)).
+			WithArgs(`{"q1":"borrador"}`, savedAt, 5).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.SaveQuizDraft(5, map[string]interface{}{"q1": "borrador"}, savedAt)
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestQuizRepository_FindPendingReviewItems(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewQuizRepository(gormDb)
+
+	after := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	expectedSql := `SELECT qai.quiz_answer_item_id, qai.quiz_answer_id, qai.question_id, qai.answer, qai.points, ` +
+		`v.content_id, v.user_id, qa.end_time, v.course_id, v.course_title, v.quiz_title, ` +
+		`v.student_name, v.student_lastname, v.student_email, qa.quiz_url AS attempt_quiz_url, qa.variant_url ` +
+		`FROM quiz_answer_item AS qai JOIN quiz_answer qa ON qa.quiz_answer_id = qai.quiz_answer_id ` +
+		`JOIN quiz_attempts_view v ON v.quiz_answer_id = qai.quiz_answer_id ` +
+		`WHERE (v.teacher_id = $1 AND qai.question_type = $2 AND qai.needs_review = $3 AND qa.end_time IS NOT NULL) ` +
+		`AND v.course_id = $4 AND (qa.end_time, qai.quiz_answer_item_id) > ($5, $6) ` +
+		`ORDER BY qa.end_time, qai.quiz_answer_item_id LIMIT $7`
+
+	mock.ExpectQuery(quoteSql(expectedSql)).
+		WithArgs("teacher-1", "text", true, 101, after, 12, 21).
+		WillReturnRows(sqlmock.NewRows([]string{"quiz_answer_item_id", "quiz_answer_id", "question_id", "answer", "end_time", "attempt_quiz_url"}).
+			AddRow(13, 4, "q1", `"respuesta"`, after.Add(time.Minute), "https://x/quiz.json"))
+
+	rows, err := repo.FindPendingReviewItems(domain.ReviewQueueFilter{
+		TeacherID:    "teacher-1",
+		CourseID:     101,
+		AfterEndTime: &after,
+		AfterItemID:  12,
+		Limit:        21,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, 13, rows[0].QuizAnswerItemID)
+	assert.Equal(t, "https://x/quiz.json", rows[0].QuizURL)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,77 @@
+package test_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"zeppelin/internal/data"
+)
+
+func TestQuizVersionRepo_CreateQuizVersion(t *testing.T) {
+	lockSql := `SELECT "content_id" FROM "content" WHERE content_id = $1 FOR UPDATE`
+	maxSql := `SELECT COALESCE(MAX(version), 0) FROM "quiz_version" WHERE content_id = $1`
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizVersionRepo(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(quoteSql(lockSql)).WithArgs("content-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("content-1"))
+		mock.ExpectQuery(quoteSql(maxSql)).WithArgs("content-1").
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(3))
+		mock.ExpectQuery(`INSERT INTO "quiz_version"`).
+			WithArgs("content-1", 4, "https://acc/v4.json", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"quiz_version_id"}).AddRow(8))
+		mock.ExpectCommit()
+
+		version, err := repo.CreateQuizVersion("content-1", func(version int) (string, error) {
+			assert.Equal(t, 4, version)
+			return fmt.Sprintf("https://acc/v%d.json", version), nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 8, version.QuizVersionID)
+		assert.Equal(t, 4, version.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Publish Error", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewQuizVersionRepo(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(quoteSql(lockSql)).WithArgs("content-1").
+			WillReturnRows(sqlmock.NewRows([]string{"content_id"}).AddRow("content-1"))
+		mock.ExpectQuery(quoteSql(maxSql)).WithArgs("content-1").
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+		mock.ExpectRollback()
+
+		_, err := repo.CreateQuizVersion("content-1", func(version int) (string, error) {
+			return "", errors.New("r2 error")
+		})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestQuizVersionRepo_GetLatestQuizVersion(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewQuizVersionRepo(gormDb)
+
+	expectedSql := `SELECT * FROM "quiz_version" WHERE content_id = $1 ORDER BY version DESC,"quiz_version"."quiz_version_id" LIMIT $2`
+	mock.ExpectQuery(quoteSql(expectedSql)).
+		WithArgs("content-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"quiz_version_id", "content_id", "version", "quiz_url"}).
+			AddRow(7, "content-1", 2, "https://acc.r2.cloudflarestorage.com/focused/1/quiz/teacher/content-1/v2.json"))
+
+	version, err := repo.GetLatestQuizVersion("content-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, version.QuizVersionID)
+	assert.Equal(t, 2, version.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
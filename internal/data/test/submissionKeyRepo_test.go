@@ -0,0 +1,31 @@
+package test_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"zeppelin/internal/data"
+	"zeppelin/internal/domain"
+)
+
+func TestSubmissionKeyRepo_CompleteSubmissionKey(t *testing.T) {
+	gormDb, mock := setupMockDb(t)
+	repo := data.NewSubmissionKeyRepo(gormDb)
+
+	quizAnswerID := 12
+	mock.ExpectBegin()
+	mock.ExpectExec(quoteSql(`UPDATE "quiz_submission_key" SET "quiz_answer_id"=$1,"response"=$2 WHERE user_id = $3 AND idempotency_key = $4`)).
+		WithArgs(&quizAnswerID, `{"score":10}`, "student-1", "retry-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.CompleteSubmissionKey(domain.QuizSubmissionKey{
+		UserID:         "student-1",
+		IdempotencyKey: "retry-1",
+		QuizAnswerID:   &quizAnswerID,
+		Response:       `{"score":10}`,
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,51 @@
+package test_test
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"zeppelin/internal/data"
+	"zeppelin/internal/domain"
+)
+
+func TestSurveyRepo_SubmitSurveyResponse(t *testing.T) {
+	response := domain.SurveyResponse{
+		ContentID:   "survey-1",
+		Answers:     map[string]interface{}{"s1": 4},
+		SubmittedAt: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+	}
+	participationSql := quoteSql(`INSERT INTO "survey_participation" ("content_id","user_id") VALUES ($1,$2)`)
+
+	t.Run("Success", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewSurveyRepo(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(participationSql).WithArgs("survey-1", "student-1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(quoteSql(`INSERT INTO "survey_response" ("content_id","user_id","answers","submitted_at") VALUES ($1,$2,$3,$4) RETURNING "survey_response_id"`)).
+			WithArgs("survey-1", nil, `{"s1":4}`, response.SubmittedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"survey_response_id"}).AddRow(1))
+		mock.ExpectExec(quoteSql(`UPDATE "user_content" SET "status_id"=$1 WHERE user_id = $2 AND content_id = $3`)).
+			WithArgs(3, "student-1", "survey-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.SubmitSurveyResponse(response, "student-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already answered", func(t *testing.T) {
+		gormDb, mock := setupMockDb(t)
+		repo := data.NewSurveyRepo(gormDb)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(participationSql).WithArgs("survey-1", "student-1").WillReturnError(errors.New("duplicate key"))
+		mock.ExpectRollback()
+
+		err := repo.SubmitSurveyResponse(response, "student-1")
+		assert.ErrorContains(t, err, "error saving survey participation")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
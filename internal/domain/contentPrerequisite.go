@@ -0,0 +1,46 @@
+package domain
+
+import "errors"
+
+// ErrContentLocked indica que el estudiante no cumple los prerrequisitos del contenido
+var ErrContentLocked = errors.New("content is locked")
+
+// Motivos por los que un contenido sigue bloqueado
+const (
+	LockReasonNotCompleted = "not_completed" // falta completar el contenido requerido
+	LockReasonMinScore     = "min_score"     // falta aprobar el quiz requerido con el puntaje mínimo
+)
+
+// ContentPrerequisite es una condición para desbloquear un contenido: completar RequiredContentID
+// (user_content.status_id = 3) o, si MinScore no es nil, aprobar ese quiz con al menos MinScore por ciento
+type ContentPrerequisite struct {
+	ContentID         string   `json:"content_id" gorm:"column:content_id;primaryKey"`
+	RequiredContentID string   `json:"required_content_id" gorm:"column:required_content_id;primaryKey"`
+	MinScore          *float64 `json:"min_score,omitempty" gorm:"column:min_score"`
+}
+
+func (ContentPrerequisite) TableName() string {
+	return "content_prerequisite"
+}
+
+// PrerequisiteInput structure
+type PrerequisiteInput struct {
+	RequiredContentID string   `json:"required_content_id" validate:"required"`
+	MinScore          *float64 `json:"min_score" validate:"omitempty,gte=0,lte=100"` // solo para quizzes
+}
+
+// SetPrerequisitesInput reemplaza los prerrequisitos de un contenido; una lista vacía lo desbloquea.
+// Los contenidos requeridos deben ser anteriores en el mismo curso.
+type SetPrerequisitesInput struct {
+	ContentID     string              `json:"content_id" validate:"required"`
+	Prerequisites []PrerequisiteInput `json:"prerequisites" validate:"unique=RequiredContentID,dive"`
+}
+
+// ContentLock es un prerrequisito que el estudiante todavía no cumple
+type ContentLock struct {
+	Reason            string   `json:"reason"`
+	RequiredContentID string   `json:"required_content_id"`
+	RequiredTitle     string   `json:"required_title"`
+	MinScore          *float64 `json:"min_score,omitempty"`
+	BestScore         *float64 `json:"best_score,omitempty"` // mejor porcentaje obtenido en el quiz requerido
+}
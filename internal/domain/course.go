@@ -31,6 +31,34 @@ type CourseInput struct {
 	Description string `json:"description"`
 }
 
+// CloneCourseInput son las opciones para duplicar un curso; el título y la fecha vacíos conservan los del original
+type CloneCourseInput struct {
+	Title              string `json:"title" validate:"omitempty,max=100"`
+	StartDate          string `json:"start_date"`
+	ExcludeEnrollments bool   `json:"exclude_enrollments"` // no copia las inscripciones (ni los intentos)
+	ExcludeAttempts    bool   `json:"exclude_attempts"`    // no copia los intentos ni el progreso de los estudiantes
+}
+
+// CloneCourseRequest es la copia que pide un profesor de uno de sus cursos
+type CloneCourseRequest struct {
+	CloneCourseInput
+	SourceCourseID int
+	TeacherID      string
+	QRCode         string
+}
+
+// CourseClone relaciona el curso original con su copia
+type CourseClone struct {
+	SourceCourseID int               `json:"source_course_id"`
+	CourseID       int               `json:"course_id"`
+	QRCode         string            `json:"qr_code"`
+	ModuleIDs      map[int]int       `json:"module_ids"`  // course_content_id original -> copia
+	ContentIDs     map[string]string `json:"content_ids"` // content_id original -> copia
+	Enrollments    int               `json:"enrollments"`
+	Attempts       int               `json:"attempts"`
+	URLs           map[string]string `json:"-"` // URLs de R2 reescritas, original -> copia
+}
+
 type CourseRepo interface {
 	CreateCourse(course CourseDB) error
 	GetCoursesByTeacher(teacherID string) ([]CourseTeacher, error)
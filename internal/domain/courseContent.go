@@ -2,9 +2,13 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrContentHasAttempts indica que el contenido tiene intentos de quiz y no se borra sin confirmación
+var ErrContentHasAttempts = errors.New("content has quiz attempts")
+
 type CourseContentDB struct {
 	CourseContentID int       `json:"course_content_id" gorm:"column:course_content_id;primaryKey;autoIncrement"`
 	CourseID        int       `json:"course_id" gorm:"column:course_id" validate:"required"`
@@ -42,15 +46,17 @@ type CourseContentStudentResult struct {
 }
 
 type ContentWithStatus struct {
-	ContentID       string `json:"content_id"`
-	CourseContentID int    `json:"course_content_id"`
-	ContentTypeID   int    `json:"content_type_id"`
-	Title           string `json:"title"`
-	Url             string `json:"url"`
-	Description     string `json:"description"`
-	SectionIndex    int    `json:"section_index"`
-	StatusID        *int   `json:"status_id,omitempty"`
-	IsActive        bool   `json:"is_active"`
+	ContentID       string        `json:"content_id"`
+	CourseContentID int           `json:"course_content_id"`
+	ContentTypeID   int           `json:"content_type_id"`
+	Title           string        `json:"title"`
+	Url             string        `json:"url"`
+	Description     string        `json:"description"`
+	SectionIndex    int           `json:"section_index"`
+	StatusID        *int          `json:"status_id,omitempty"`
+	IsActive        bool          `json:"is_active"`
+	Locked          bool          `json:"locked"`
+	LockReasons     []ContentLock `json:"lock_reasons,omitempty"` // la URL de un contenido bloqueado no se envía
 }
 
 type CourseContentWithStudentDetails struct {
@@ -64,7 +70,7 @@ type CourseContentWithStudentDetails struct {
 type Content struct {
 	ContentID       string        `json:"content_id" gorm:"column:content_id;primaryKey" validate:"required"`
 	CourseContentID int           `json:"course_content_id" gorm:"column:course_content_id" validate:"required"`
-	ContentTypeID   int           `json:"content_type_id" gorm:"column:content_type_id" validate:"required,oneof=1 2 3"` // 1=video, 2=quiz, 3=text
+	ContentTypeID   int           `json:"content_type_id" gorm:"column:content_type_id" validate:"required,oneof=1 2 3 4"` // 1=video, 2=text, 3=quiz, 4=encuesta
 	Title           string        `json:"title" gorm:"column:title" validate:"required,max=100"`
 	Url             string        `json:"url" gorm:"column:url" validate:"omitempty,url"`
 	Description     string        `json:"description" gorm:"column:description" validate:"omitempty"`
@@ -89,7 +95,7 @@ type AddModuleInput struct {
 
 type AddSectionInput struct {
 	CourseContentID int    `json:"course_content_id" validate:"required"`
-	ContentTypeID   int    `json:"content_type_id" validate:"required,oneof=1 2 3"`
+	ContentTypeID   int    `json:"content_type_id" validate:"required,oneof=1 2 3 4"`
 	Title           string `json:"title" validate:"required,max=100"`
 	Description     string `json:"description" validate:"omitempty"`
 }
@@ -114,13 +120,34 @@ type UpdateModuleTitleInput struct {
 	ModuleTitle     string `json:"module_title" validate:"required,max=100"`
 }
 
+// ReorderModulesInput es el nuevo orden de todos los módulos de un curso
+type ReorderModulesInput struct {
+	CourseID         int   `json:"course_id" validate:"required"`
+	CourseContentIDs []int `json:"course_content_ids" validate:"required,min=1,unique"`
+}
+
+// ReorderSectionsInput es el nuevo orden de todas las secciones de un módulo; puede incluir
+// secciones de otros módulos del mismo curso, que se mueven a este
+type ReorderSectionsInput struct {
+	CourseContentID int      `json:"course_content_id" validate:"required"`
+	ContentIDs      []string `json:"content_ids" validate:"required,min=1,unique,dive,required"`
+}
+
+// DeletedContent describe el contenido borrado (o que se iba a borrar) para limpiar sus archivos en R2
+type DeletedContent struct {
+	CourseID   int      `json:"course_id"`
+	ContentIDs []string `json:"content_ids"`
+	Attempts   int64    `json:"attempts"` // intentos de quiz del contenido
+	AnswerURLs []string `json:"-"`        // archivos de respuestas de los intentos borrados
+}
+
 type UpdateUserContentStatusInput struct {
 	ContentID string `json:"content_id" validate:"required"`
 }
 
 type CourseContentInput struct {
 	Module      string `json:"module" validate:"required,max=100"`
-	ContentType string `json:"content_type" validate:"required,oneof=text video quiz"`
+	ContentType string `json:"content_type" validate:"required,oneof=text video quiz survey"`
 	ModuleIndex int    `json:"module_index"`
 }
 
@@ -139,7 +166,16 @@ type CourseContentRepo interface {
 	UpdateContent(input UpdateContentInput) error
 	UpdateContentStatus(contentID string, isActive bool) error
 	UpdateModuleTitle(courseContentID int, moduleTitle string) error
+	ReorderModules(courseID int, courseContentIDs []int) error
+	ReorderSections(courseContentID int, contentIDs []string) error
+	DeleteSection(contentID, userID string, force bool) (DeletedContent, error)
+	DeleteModule(courseContentID int, userID string, force bool) (DeletedContent, error)
+	CloneCourse(request CloneCourseRequest) (CourseClone, error)
+	SetPrerequisites(input SetPrerequisitesInput, userID string) error
+	GetPrerequisites(contentID, userID string) ([]ContentPrerequisite, error)
+	GetContentLocks(contentID, userID string) ([]ContentLock, error)
 	UpdateUserContentStatus(userID, contentID string, statusID int) error
 	GetContentTypeID(contentID string) (int, error)
 	GetUrlByContentID(contentID string) (string, error)
+	GetCourseIDByContentID(contentID string) (int, error)
 }
@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// Políticas para las entregas después de la fecha límite
+const (
+	LatePolicyNone   = "none"    // se acepta y solo se marca como tardía
+	LatePolicyReject = "reject"  // no se acepta
+	LatePolicyFlat   = "flat"    // se descuenta un porcentaje fijo
+	LatePolicyPerDay = "per_day" // se descuenta un porcentaje por cada día (o fracción) de retraso
+)
+
+// ContentDueDate es la fecha de entrega de un contenido y su política de entregas tardías
+type ContentDueDate struct {
+	ContentID         string    `json:"content_id" gorm:"column:content_id;primaryKey"`
+	DueAt             time.Time `json:"due_at" gorm:"column:due_at"`
+	LatePolicy        string    `json:"late_policy" gorm:"column:late_policy"`
+	PenaltyPercent    float64   `json:"penalty_percent" gorm:"column:penalty_percent"`         // flat: descuento total; per_day: descuento por día
+	MaxPenaltyPercent float64   `json:"max_penalty_percent" gorm:"column:max_penalty_percent"` // per_day: tope del descuento (0 = 100 %)
+	GraceMinutes      int       `json:"grace_minutes" gorm:"column:grace_minutes"`             // tolerancia sin penalización
+	UpdatedAt         time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (ContentDueDate) TableName() string {
+	return "content_due_date"
+}
+
+// DueDateExtension es una prórroga de la fecha de entrega para un estudiante
+type DueDateExtension struct {
+	ContentID string    `json:"content_id" gorm:"column:content_id;primaryKey"`
+	UserID    string    `json:"user_id" gorm:"column:user_id;primaryKey"`
+	DueAt     time.Time `json:"due_at" gorm:"column:due_at"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (DueDateExtension) TableName() string {
+	return "content_due_date_extension"
+}
+
+// DueDateInput structure
+type DueDateInput struct {
+	ContentID         string    `json:"content_id" validate:"required"`
+	DueAt             time.Time `json:"due_at" validate:"required"`
+	LatePolicy        string    `json:"late_policy" validate:"required,oneof=none reject flat per_day"`
+	PenaltyPercent    float64   `json:"penalty_percent" validate:"gte=0,lte=100"`
+	MaxPenaltyPercent float64   `json:"max_penalty_percent" validate:"gte=0,lte=100"`
+	GraceMinutes      int       `json:"grace_minutes" validate:"gte=0"`
+}
+
+// DueDateExtensionInput structure
+type DueDateExtensionInput struct {
+	ContentID string    `json:"content_id" validate:"required"`
+	UserID    string    `json:"user_id" validate:"required"`
+	DueAt     time.Time `json:"due_at" validate:"required"`
+}
+
+// LateSubmission describe si una entrega fue tardía y el descuento aplicado
+type LateSubmission struct {
+	DueAt          time.Time `json:"due_at"`
+	Late           bool      `json:"late"`
+	LateMinutes    int       `json:"late_minutes"`
+	PenaltyPercent float64   `json:"penalty_percent"`
+}
+
+type DueDateRepo interface {
+	GetDueDate(contentID string) (ContentDueDate, error)
+	SaveDueDate(dueDate ContentDueDate) error
+	GetDueDateExtension(contentID, userID string) (DueDateExtension, error)
+	SaveDueDateExtension(extension DueDateExtension) error
+}
@@ -0,0 +1,95 @@
+package domain
+
+import "time"
+
+// Políticas para combinar varios intentos de un mismo quiz
+const (
+	AttemptPolicyBest    = "best"
+	AttemptPolicyLatest  = "latest"
+	AttemptPolicyAverage = "average"
+)
+
+// Fuentes de las calificaciones de una categoría
+const (
+	CategorySourceQuizzes  = "quizzes"  // promedio de los quizzes de la categoría
+	CategorySourceProgress = "progress" // porcentaje de avance del curso
+)
+
+// Gradebook es la configuración del libro de calificaciones de un curso
+type Gradebook struct {
+	CourseID      int                 `json:"course_id" gorm:"column:course_id;primaryKey"`
+	AttemptPolicy string              `json:"attempt_policy" gorm:"column:attempt_policy"`
+	PassThreshold float64             `json:"pass_threshold" gorm:"column:pass_threshold"` // porcentaje mínimo para aprobar
+	Categories    []GradebookCategory `json:"categories" gorm:"column:categories;serializer:json"`
+	LetterScale   []LetterGrade       `json:"letter_scale" gorm:"column:letter_scale;serializer:json"`
+	UpdatedAt     time.Time           `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Gradebook) TableName() string {
+	return "gradebook"
+}
+
+// GradebookCategory agrupa quizzes con un peso sobre la nota final
+type GradebookCategory struct {
+	ID         string   `json:"id" validate:"required"`
+	Name       string   `json:"name" validate:"required"`
+	Weight     float64  `json:"weight" validate:"gt=0"`
+	Source     string   `json:"source,omitempty" validate:"omitempty,oneof=quizzes progress"`
+	ContentIDs []string `json:"content_ids,omitempty"`
+}
+
+// LetterGrade asigna una letra a partir de un porcentaje mínimo
+type LetterGrade struct {
+	Letter     string  `json:"letter" validate:"required"`
+	MinPercent float64 `json:"min_percent" validate:"gte=0,lte=100"`
+}
+
+// GradebookInput structure
+type GradebookInput struct {
+	AttemptPolicy string              `json:"attempt_policy" validate:"required,oneof=best latest average"`
+	PassThreshold float64             `json:"pass_threshold" validate:"gte=0,lte=100"`
+	Categories    []GradebookCategory `json:"categories" validate:"dive"`
+	LetterScale   []LetterGrade       `json:"letter_scale" validate:"dive"`
+}
+
+// GradebookColumn es un quiz del curso dentro de la matriz de calificaciones
+type GradebookColumn struct {
+	ContentID  string `json:"content_id"`
+	Title      string `json:"title"`
+	Module     string `json:"module"`
+	CategoryID string `json:"category_id,omitempty"`
+}
+
+// GradebookCell es la nota de un estudiante en un quiz según la política de intentos
+type GradebookCell struct {
+	Percent     *float64 `json:"percent"` // nil si no hay intentos enviados
+	Attempts    int      `json:"attempts"`
+	NeedsReview bool     `json:"needs_review"`
+}
+
+// GradebookRow es la fila de un estudiante
+type GradebookRow struct {
+	UserID     string                   `json:"user_id"`
+	Name       string                   `json:"name"`
+	Lastname   string                   `json:"lastname"`
+	Email      string                   `json:"email"`
+	Quizzes    map[string]GradebookCell `json:"quizzes"`    // ContentID -> nota
+	Categories map[string]*float64      `json:"categories"` // CategoryID -> porcentaje
+	Progress   float64                  `json:"progress"`
+	Final      *float64                 `json:"final"`
+	Letter     string                   `json:"letter"`
+	Passed     bool                     `json:"passed"`
+}
+
+// GradebookReport es la matriz de estudiantes por quizzes con la nota final de cada uno
+type GradebookReport struct {
+	Settings Gradebook         `json:"settings"`
+	Columns  []GradebookColumn `json:"columns"`
+	Rows     []GradebookRow    `json:"rows"`
+}
+
+type GradebookRepo interface {
+	GetGradebook(courseID int) (Gradebook, error)
+	SaveGradebook(gradebook Gradebook) error
+	GetCourseProgress(courseID int) ([]StudentCourseProgress, error)
+}
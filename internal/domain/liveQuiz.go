@@ -0,0 +1,17 @@
+package domain
+
+// LiveQuizLeaderboardEntry es la posición de un estudiante en un quiz en vivo
+type LiveQuizLeaderboardEntry struct {
+	Rank     int     `json:"rank"`
+	UserID   string  `json:"user_id"`
+	Name     string  `json:"name"`
+	Score    float64 `json:"score"`
+	Correct  int     `json:"correct"`
+	Answered int     `json:"answered"`
+}
+
+// LiveQuizBucket es una barra del histograma de respuestas de la pregunta actual
+type LiveQuizBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
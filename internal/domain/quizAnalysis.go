@@ -0,0 +1,36 @@
+package domain
+
+// QuizItemAnalysis es el reporte psicométrico de un quiz
+type QuizItemAnalysis struct {
+	ContentID          string             `json:"content_id"`
+	CourseID           int                `json:"course_id"`
+	QuizTitle          string             `json:"quiz_title"`
+	Attempts           int                `json:"attempts"`   // primer intento enviado de cada estudiante
+	GroupSize          int                `json:"group_size"` // tamaño de los grupos superior e inferior (27 %)
+	AverageScore       float64            `json:"average_score"`
+	AverageTimeSeconds float64            `json:"average_time_seconds"`
+	Questions          []QuestionAnalysis `json:"questions"`
+}
+
+// QuestionAnalysis son las estadísticas de una pregunta
+type QuestionAnalysis struct {
+	QuestionID     string           `json:"question_id"`
+	Question       string           `json:"question"`
+	Type           string           `json:"type"`
+	Points         int              `json:"points"`
+	Responses      int              `json:"responses"` // intentos calificados en los que apareció la pregunta
+	Pending        int              `json:"pending"`   // respuestas de texto sin revisar
+	Correct        int              `json:"correct"`
+	Difficulty     *float64         `json:"difficulty"`     // fracción media de puntos obtenidos (0 a 1)
+	Discrimination *float64         `json:"discrimination"` // dificultad del grupo superior menos la del inferior
+	Distractors    []DistractorStat `json:"distractors,omitempty"`
+	Omitted        int              `json:"omitted"`
+}
+
+// DistractorStat es la frecuencia con la que se eligió una opción
+type DistractorStat struct {
+	Option    string  `json:"option"`
+	IsCorrect bool    `json:"is_correct"`
+	Count     int     `json:"count"`
+	Ratio     float64 `json:"ratio"`
+}
@@ -1,51 +1,219 @@
 package domain
 
 import (
+	"errors"
+	"math/rand"
+	"slices"
 	"time"
 )
 
+// ErrMaxAttemptsReached indica que el estudiante ya usó todos los intentos permitidos del quiz
+var ErrMaxAttemptsReached = errors.New("maximum quiz attempts reached")
+
 // QuizAnswer model
 type QuizAnswer struct {
 	QuizAnswerID  int        `gorm:"column:quiz_answer_id;primaryKey;autoIncrement"`
 	ContentID     string     `gorm:"column:content_id"`
 	UserID        string     `gorm:"column:user_id"`
 	StartTime     time.Time  `gorm:"column:start_time"`
-	EndTime       time.Time  `gorm:"column:end_time"`
+	EndTime       *time.Time `gorm:"column:end_time"` // nil mientras el intento está en curso
 	Grade         *float64   `gorm:"column:grade"`
 	ReviewedAt    *time.Time `gorm:"column:reviewed_at"`
 	QuizURL       string     `gorm:"column:quiz_url"`
 	QuizAnswerURL string     `gorm:"column:quiz_answer_url"`
 	TotalPoints   *int       `gorm:"column:total_points"`
+	Seed          *int64     `gorm:"column:seed"`            // semilla usada para generar la variante del estudiante
+	VariantURL    string     `gorm:"column:variant_url"`     // quiz del profesor tal como lo vio el estudiante (vacío si no hay variante)
+	QuizVersionID *int       `gorm:"column:quiz_version_id"` // versión del quiz respondida (nil en intentos previos al versionado)
+	RawGrade      *float64   `gorm:"column:raw_grade"`       // nota antes del descuento por entrega tardía
+	DueAt         *time.Time `gorm:"column:due_at"`          // fecha de entrega vigente al enviar (con prórroga)
+	LateMinutes   int        `gorm:"column:late_minutes"`
+	LatePenalty   float64    `gorm:"column:late_penalty"` // porcentaje descontado de la nota
+	// DraftAnswers son las respuestas guardadas automáticamente mientras el intento está en curso
+	DraftAnswers map[string]interface{} `gorm:"column:draft_answers;serializer:json"`
+	AutosavedAt  *time.Time             `gorm:"column:autosaved_at"`
+	// VariableValues son los valores sorteados para las preguntas calculated (pregunta -> variable -> valor)
+	VariableValues map[string]map[string]float64 `gorm:"column:variable_values;serializer:json"`
 }
 
 func (QuizAnswer) TableName() string {
 	return "quiz_answer"
 }
 
+// QuizAnswerItem guarda la calificación de una pregunta dentro de un intento
+type QuizAnswerItem struct {
+	QuizAnswerItemID int           `json:"quiz_answer_item_id" gorm:"column:quiz_answer_item_id;primaryKey;autoIncrement"`
+	QuizAnswerID     int           `json:"quiz_answer_id" gorm:"column:quiz_answer_id;uniqueIndex:idx_quiz_answer_item_question"`
+	QuestionID       string        `json:"question_id" gorm:"column:question_id;uniqueIndex:idx_quiz_answer_item_question"`
+	QuestionType     string        `json:"question_type" gorm:"column:question_type"`
+	Answer           string        `json:"answer" gorm:"column:answer"` // respuesta del estudiante serializada en JSON
+	Points           int           `json:"points" gorm:"column:points"`
+	Score            *float64      `json:"score" gorm:"column:score"` // nil mientras está pendiente de revisión
+	IsCorrect        *bool         `json:"is_correct" gorm:"column:is_correct"`
+	NeedsReview      bool          `json:"needs_review" gorm:"column:needs_review"`
+	ReviewerID       *string       `json:"reviewer_id" gorm:"column:reviewer_id"` // nil si se calificó automáticamente
+	ReviewedAt       *time.Time    `json:"reviewed_at" gorm:"column:reviewed_at"`
+	Feedback         string        `json:"feedback" gorm:"column:feedback"`
+	Rubric           []RubricScore `json:"rubric,omitempty" gorm:"column:rubric;serializer:json"`
+}
+
+func (QuizAnswerItem) TableName() string {
+	return "quiz_answer_item"
+}
+
 // TeacherQuiz structure
 type TeacherQuiz struct {
 	Questions   []TeacherQuizQuestion `json:"questions"`
 	Title       string                `json:"title"`
 	Description string                `json:"description"`
+	Settings    QuizSettings          `json:"settings,omitempty"`
+	Pools       []QuestionPool        `json:"pools,omitempty"`
+}
+
+// QuestionPool es un banco de preguntas del que se sortean Draw preguntas por estudiante
+type QuestionPool struct {
+	ID        string                `json:"id"`
+	Draw      int                   `json:"draw"`
+	Questions []TeacherQuizQuestion `json:"questions"`
+}
+
+// QuizSettings configura los intentos de un quiz. Los valores en cero significan "sin límite".
+type QuizSettings struct {
+	MaxAttempts      int        `json:"maxAttempts,omitempty"`
+	TimeLimitMinutes int        `json:"timeLimitMinutes,omitempty"`
+	OpensAt          *time.Time `json:"opensAt,omitempty"`
+	ClosesAt         *time.Time `json:"closesAt,omitempty"`
+	ShuffleQuestions bool       `json:"shuffleQuestions,omitempty"`
+	ShuffleOptions   bool       `json:"shuffleOptions,omitempty"`
+	ReleaseAnswers   string     `json:"releaseAnswers,omitempty"` // ver ReleaseAnswers*; vacío equivale a ReleaseAnswersNever
 }
 
+// Políticas para mostrar al estudiante las respuestas correctas y las explicaciones
+const (
+	ReleaseAnswersNever           = "never"
+	ReleaseAnswersAfterSubmission = "after_submission" // cuando el estudiante envía su intento
+	ReleaseAnswersAfterClose      = "after_close"      // cuando pasa settings.closesAt
+	ReleaseAnswersAfterRelease    = "after_release"    // cuando el profesor las publica
+)
+
 // TeacherQuizQuestion structure
 type TeacherQuizQuestion struct {
-	ID             string      `json:"id"`
-	Type           string      `json:"type"`
-	Points         int         `json:"points"`
-	CorrectAnswer  interface{} `json:"correctAnswer,omitempty"`
-	CorrectAnswers []string    `json:"correctAnswers,omitempty"`
-	Question       string      `json:"question"`
+	ID              string            `json:"id"`
+	Type            string            `json:"type"`
+	Points          int               `json:"points"`
+	CorrectAnswer   interface{}       `json:"correctAnswer,omitempty"`
+	CorrectAnswers  []string          `json:"correctAnswers,omitempty"`
+	Question        string            `json:"question"`
+	Scoring         string            `json:"scoring,omitempty"` // ver Scoring*; vacío equivale a ScoringAllOrNothing
+	Options         []string          `json:"options,omitempty"`
+	Items           []string          `json:"items,omitempty"`           // matching: elementos de la izquierda
+	CorrectPairs    map[string]string `json:"correctPairs,omitempty"`    // matching: item -> opción
+	AcceptedAnswers []string          `json:"acceptedAnswers,omitempty"` // short
+	Pattern         string            `json:"pattern,omitempty"`         // short: expresión regular opcional
+	Tolerance       float64           `json:"tolerance,omitempty"`       // numeric
+	ToleranceType   string            `json:"toleranceType,omitempty"`   // numeric: "absolute" (por defecto) o "relative"
+	Rubric          []RubricCriterion `json:"rubric,omitempty"`          // text: criterios para la revisión manual
+	Explanation     string            `json:"explanation,omitempty"`     // se muestra al publicar las respuestas
+	// OptionExplanations asigna a cada opción (por su texto) la explicación de por qué es o no correcta
+	OptionExplanations map[string]string  `json:"optionExplanations,omitempty"`
+	Formula            string             `json:"formula,omitempty"`   // calculated: expresión con las variables
+	Variables          []QuestionVariable `json:"variables,omitempty"` // calculated: rangos de las variables
+	Values             map[string]float64 `json:"values,omitempty"`    // calculated: valores sorteados para el estudiante
+}
+
+// QuestionVariable es una variable de una pregunta calculated; a cada estudiante se le sortea un valor
+// entre Min y Max redondeado a Decimals decimales
+type QuestionVariable struct {
+	Name     string  `json:"name"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Decimals int     `json:"decimals"`
+}
+
+// RubricCriterion es un criterio de la rúbrica de una pregunta de texto
+type RubricCriterion struct {
+	ID     string        `json:"id"`
+	Title  string        `json:"title"`
+	Levels []RubricLevel `json:"levels"`
+}
+
+// RubricLevel es un nivel de desempeño dentro de un criterio
+type RubricLevel struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Points      float64 `json:"points"`
 }
 
-// StudentQuizAnswersInput structure
+// RubricScore es el nivel elegido por el profesor en un criterio al revisar una respuesta
+type RubricScore struct {
+	CriterionID string  `json:"criterion_id"`
+	Criterion   string  `json:"criterion"`
+	LevelID     string  `json:"level_id"`
+	Level       string  `json:"level"`
+	Points      float64 `json:"points"`
+	MaxPoints   float64 `json:"max_points"`
+}
+
+// AnswerKeyFields son los campos de una pregunta que se eliminan de la copia del estudiante
+var AnswerKeyFields = []string{"correctAnswer", "correctAnswers", "correctPairs", "acceptedAnswers", "pattern", "tolerance", "toleranceType", "explanation", "optionExplanations", "formula", "variables"}
+
+// ScrambleOrderingOptions baraja las opciones de una pregunta ordering para la copia del estudiante,
+// asegurando que no queden en el orden correcto
+func ScrambleOrderingOptions(options, correctOrder []string) []string {
+	scrambled := slices.Clone(options)
+	rand.Shuffle(len(scrambled), func(i, j int) { scrambled[i], scrambled[j] = scrambled[j], scrambled[i] })
+	if len(scrambled) > 1 && slices.Equal(scrambled, correctOrder) {
+		scrambled = append(scrambled[1:], scrambled[0])
+	}
+	return scrambled
+}
+
+// Políticas de puntuación para preguntas con varias respuestas correctas
+const (
+	ScoringAllOrNothing    = "all_or_nothing"
+	ScoringProportional    = "proportional"
+	ScoringRightMinusWrong = "right_minus_wrong"
+)
+
+// QuestionGrade es el resultado de calificar una pregunta
+type QuestionGrade struct {
+	QuestionID  string  `json:"question_id"`
+	Type        string  `json:"type"`
+	Points      int     `json:"points"`
+	Earned      float64 `json:"earned"`
+	IsCorrect   bool    `json:"is_correct"`
+	NeedsReview bool    `json:"needs_review"`
+}
+
+// QuizGradeResult es el resultado de calificar un quiz completo, con el desglose por pregunta
+type QuizGradeResult struct {
+	Score       float64         `json:"score"`
+	TotalPoints int             `json:"total_points"`
+	NeedsReview bool            `json:"needs_review"`
+	Questions   []QuestionGrade `json:"questions"`
+}
+
+// StartQuizAttemptInput structure
+type StartQuizAttemptInput struct {
+	ContentID string `json:"content_id" validate:"required"`
+}
+
+// StudentQuizAnswersInput structure. StartTime y EndTime se ignoran: el servidor
+// usa la hora de inicio del intento y la hora de recepción del envío.
 type StudentQuizAnswersInput struct {
-	ContentID  string                 `json:"content_id" validate:"required"`
-	StartTime  time.Time              `json:"start_time" validate:"required"`
-	EndTime    time.Time              `json:"end_time" validate:"required"`
-	Answers    map[string]interface{} `json:"answers" validate:"required"`
-	ReviewedAt *time.Time             `json:"reviewed_at,omitempty"`
+	ContentID    string                 `json:"content_id" validate:"required"`
+	QuizAnswerID int                    `json:"quiz_answer_id,omitempty"`
+	StartTime    time.Time              `json:"start_time"`
+	EndTime      time.Time              `json:"end_time"`
+	Answers      map[string]interface{} `json:"answers" validate:"required"`
+	ReviewedAt   *time.Time             `json:"reviewed_at,omitempty"`
+}
+
+// AutosaveQuizInput structure
+type AutosaveQuizInput struct {
+	QuizAnswerID int                    `json:"quiz_answer_id" validate:"required"`
+	Answers      map[string]interface{} `json:"answers" validate:"required"`
 }
 
 // TextAnswerReviewInput structure
@@ -54,6 +222,10 @@ type TextAnswerReviewInput struct {
 	QuestionID    string  `json:"question_id" validate:"required"`
 	IsCorrect     bool    `json:"is_correct"`
 	PointsAwarded float64 `json:"points_awarded" validate:"gte=0"`
+	Feedback      string  `json:"feedback"`
+	// RubricSelections asigna a cada criterio (ID) el nivel elegido (ID). Si la pregunta tiene
+	// rúbrica, los puntos se calculan a partir de ella y se ignora points_awarded.
+	RubricSelections map[string]string `json:"rubric_selections,omitempty"`
 }
 
 // QuizWithAttempts structure
@@ -104,17 +276,55 @@ type QuizAttemptDetail struct {
 	QuizURL       string                 `json:"quiz_url"`
 	QuizAnswerURL string                 `json:"quiz_answer_url"`
 	Answers       map[string]interface{} `json:"answers"`
+	RawGrade      *float64               `json:"raw_grade"`
+	DueAt         *time.Time             `json:"due_at"`
+	Late          bool                   `json:"late"`
+	LateMinutes   int                    `json:"late_minutes"`
+	LatePenalty   float64                `json:"late_penalty"`
+}
+
+// NewQuizAttemptDetail arma el detalle de un intento a partir de la vista, con la nota sin descuento
+// y el descuento por entrega tardía aplicado por el calificador
+func NewQuizAttemptDetail(view QuizAttemptView) QuizAttemptDetail {
+	return QuizAttemptDetail{
+		QuizAnswerID:  view.QuizAnswerID,
+		ContentID:     view.ContentID,
+		CourseID:      view.CourseID,
+		CourseTitle:   view.CourseTitle,
+		QuizTitle:     view.QuizTitle,
+		Description:   view.QuizDescription,
+		Grade:         view.Grade,
+		TotalPoints:   view.TotalPoints,
+		NeedsReview:   view.NeedsReview,
+		ReviewedAt:    view.ReviewedAt,
+		QuizURL:       view.QuizURL,
+		QuizAnswerURL: view.QuizAnswerURL,
+		RawGrade:      view.RawGrade,
+		DueAt:         view.DueAt,
+		Late:          view.LateMinutes > 0,
+		LateMinutes:   view.LateMinutes,
+		LatePenalty:   view.LatePenalty,
+	}
 }
 
 // QuizRepository interface
 type QuizRepository interface {
-	SaveQuizAttempt(attempt QuizAnswer) error
+	SaveQuizAttempt(attempt QuizAnswer, maxAttempts int) (int, error)
+	StartQuizAttempt(attempt QuizAnswer, maxAttempts int) (int, error)
+	CountQuizAttempts(userID, contentID string) (int64, error)
+	FindOpenQuizAttempt(userID, contentID string) (QuizAnswer, error)
 	UpdateQuizAttempt(attempt QuizAnswer) error
+	SaveQuizDraft(quizAnswerID int, answers map[string]interface{}, savedAt time.Time) error
 	FindQuizAttemptByID(quizAnswerID int) (QuizAnswer, error)
 	FindQuizAttemptsByCourse(courseID int) ([]QuizAnswer, error)
 	FindQuizAttemptsByUser(userID string) ([]QuizAnswer, error)
+	FindSubmittedQuizAttemptsByContent(contentID string) ([]QuizAnswer, error)
+	SaveQuizAnswerItems(items []QuizAnswerItem) error
+	FindQuizAnswerItems(quizAnswerID int) ([]QuizAnswerItem, error)
+	FindQuizAnswerItemsByAttempts(quizAnswerIDs []int) ([]QuizAnswerItem, error)
 	GetQuizAttemptsByCourse(courseID int) ([]QuizAttemptView, error)
 	GetQuizAttemptsByStudent(userID string) ([]QuizAttemptView, error)
+	FindPendingReviewItems(filter ReviewQueueFilter) ([]ReviewQueueRow, error)
 }
 
 type QuizAttemptView struct {
@@ -142,6 +352,13 @@ type QuizAttemptView struct {
 	TeacherID         string     `gorm:"column:teacher_id"`
 	NeedsReview       bool       `gorm:"column:needs_review"`
 	TotalQuizzes      int        `gorm:"column:total_quizzes"` // Nuevo campo
+	QuizVersionID     *int       `gorm:"column:quiz_version_id"`
+	QuizVersion       *int       `gorm:"column:quiz_version"`
+	RawGrade          *float64   `gorm:"column:raw_grade"`
+	DueAt             *time.Time `gorm:"column:due_at"`
+	LateMinutes       int        `gorm:"column:late_minutes"`
+	LatePenalty       float64    `gorm:"column:late_penalty"`
+	VariantURL        string     `gorm:"column:variant_url"`
 }
 
 func (QuizAttemptView) TableName() string {
@@ -158,6 +375,7 @@ type Quiz struct {
 	Module          string        `json:"module"`
 	ModuleIndex     *int          `json:"module_index"`
 	Attempts        []QuizAttempt `json:"attempts"`
+	AnswersReleased bool          `json:"answers_released,omitempty"` // solo en la vista del estudiante
 }
 
 // CourseQuizResponse representa la respuesta del endpoint
@@ -170,18 +388,27 @@ type CourseQuizResponse struct {
 }
 
 type QuizAttempt struct {
-	QuizAnswerID    int        `json:"quiz_answer_id"`
-	UserID          string     `json:"user_id"`
-	StudentName     string     `json:"student_name"`
-	StudentLastname string     `json:"student_lastname"`
-	StudentEmail    string     `json:"student_email"`
-	Grade           *float64   `json:"grade"`
-	TotalPoints     *int       `json:"total_points"`
-	NeedsReview     bool       `json:"needs_review"`
-	ReviewedAt      *time.Time `json:"reviewed_at"`
-	QuizAnswerURL   string     `json:"quiz_answer_url"`
-	StartTime       time.Time  `json:"start_time"`
-	EndTime         time.Time  `json:"end_time"`
+	QuizAnswerID    int              `json:"quiz_answer_id"`
+	UserID          string           `json:"user_id"`
+	StudentName     string           `json:"student_name"`
+	StudentLastname string           `json:"student_lastname"`
+	StudentEmail    string           `json:"student_email"`
+	Grade           *float64         `json:"grade"`
+	TotalPoints     *int             `json:"total_points"`
+	NeedsReview     bool             `json:"needs_review"`
+	ReviewedAt      *time.Time       `json:"reviewed_at"`
+	QuizAnswerURL   string           `json:"quiz_answer_url"`
+	StartTime       time.Time        `json:"start_time"`
+	EndTime         time.Time        `json:"end_time"`
+	QuizVersionID   *int             `json:"quiz_version_id"`
+	QuizVersion     *int             `json:"quiz_version"`
+	RawGrade        *float64         `json:"raw_grade"`
+	DueAt           *time.Time       `json:"due_at"`
+	Late            bool             `json:"late"`
+	LateMinutes     int              `json:"late_minutes"`
+	LatePenalty     float64          `json:"late_penalty"`
+	Questions       []QuizAnswerItem `json:"questions,omitempty"` // calificación por pregunta, solo en la vista del estudiante
+	QuizURL         string           `json:"quiz_url,omitempty"`  // variante que respondió el estudiante, solo en su vista
 }
 
 // StudentCoursesQuizResponse representa la respuesta del endpoint para estudiantes, agrupando por curso
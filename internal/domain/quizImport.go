@@ -0,0 +1,35 @@
+package domain
+
+// Formatos de archivo que se pueden importar como quiz
+const (
+	QuizImportMoodleXML = "moodle_xml"
+	QuizImportGIFT      = "gift"
+	QuizImportQTI       = "qti" // IMS QTI 2.1: un assessmentItem en XML o un paquete zip en base64
+)
+
+// QuizImportInput structure
+type QuizImportInput struct {
+	CourseID  int    `json:"course_id" validate:"required"`
+	ContentID string `json:"content_id" validate:"required"`
+	Format    string `json:"format" validate:"required,oneof=moodle_xml gift qti"`
+	Data      string `json:"data" validate:"required"`
+	Title     string `json:"title" validate:"max=100"`
+	DryRun    bool   `json:"dry_run"` // solo convierte y devuelve el resultado, sin publicar el quiz
+}
+
+// QuizImportIssue describe una pregunta del archivo que no se pudo importar
+type QuizImportIssue struct {
+	Position int    `json:"position"` // posición de la pregunta en el archivo, empezando en 1
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"` // tipo de pregunta en el formato de origen
+	Reason   string `json:"reason"`
+}
+
+// QuizImportResult es el quiz convertido junto con las preguntas que quedaron fuera
+type QuizImportResult struct {
+	Quiz        TeacherQuiz       `json:"quiz"`
+	Imported    int               `json:"imported"`
+	Unsupported []QuizImportIssue `json:"unsupported"`
+	Version     int               `json:"version,omitempty"`
+	DryRun      bool              `json:"dry_run"`
+}
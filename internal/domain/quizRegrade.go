@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// Estados de un trabajo de recalificación
+const (
+	RegradeStatusRunning   = "running"
+	RegradeStatusReady     = "ready" // diff calculado, pendiente de confirmar
+	RegradeStatusCommitted = "committed"
+	RegradeStatusFailed    = "failed"
+)
+
+// RegradeJob es un trabajo en segundo plano que recalifica los intentos de un quiz
+type RegradeJob struct {
+	JobID       string           `json:"job_id"`
+	ContentID   string           `json:"content_id"`
+	CourseID    int              `json:"course_id"`
+	TeacherID   string           `json:"-"`
+	Status      string           `json:"status"`
+	Total       int              `json:"total"`
+	Processed   int              `json:"processed"`
+	Changes     []RegradeChange  `json:"changes"`
+	Failures    []RegradeFailure `json:"failures"`
+	Unchanged   int              `json:"unchanged"`
+	Committed   int              `json:"committed"`
+	Skipped     []int            `json:"skipped"` // intentos modificados después del cálculo; no se sobrescriben
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	FinishedAt  *time.Time       `json:"finished_at"`
+	CommittedAt *time.Time       `json:"committed_at"`
+}
+
+// RegradeChange es una fila del reporte de diferencias de una recalificación
+type RegradeChange struct {
+	QuizAnswerID   int             `json:"quiz_answer_id"`
+	UserID         string          `json:"user_id"`
+	OldGrade       *float64        `json:"old_grade"`
+	NewGrade       float64         `json:"new_grade"`
+	NewRawGrade    float64         `json:"new_raw_grade"` // antes del descuento por entrega tardía
+	OldTotalPoints *int            `json:"old_total_points"`
+	NewTotalPoints int             `json:"new_total_points"`
+	NeedsReview    bool            `json:"needs_review"`
+	Questions      []QuestionGrade `json:"questions"`
+}
+
+// RegradeFailure registra un intento que no se pudo recalificar
+type RegradeFailure struct {
+	QuizAnswerID int    `json:"quiz_answer_id"`
+	Error        string `json:"error"`
+}
+
+// RegradeInput structure
+type RegradeInput struct {
+	ContentID string `json:"content_id" validate:"required"`
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// QuizAnswerRelease registra que el profesor publicó las respuestas de un quiz
+// con la política ReleaseAnswersAfterRelease
+type QuizAnswerRelease struct {
+	ContentID  string    `json:"content_id" gorm:"column:content_id;primaryKey"`
+	ReleasedBy string    `json:"released_by" gorm:"column:released_by"`
+	ReleasedAt time.Time `json:"released_at" gorm:"column:released_at"`
+}
+
+func (QuizAnswerRelease) TableName() string {
+	return "quiz_answer_release"
+}
+
+// QuizAnswerReleaseInput structure. Con released=false se retira la publicación.
+type QuizAnswerReleaseInput struct {
+	ContentID string `json:"content_id" validate:"required"`
+	Released  bool   `json:"released"`
+}
+
+type AnswerReleaseRepo interface {
+	GetAnswerRelease(contentID string) (QuizAnswerRelease, error)
+	SaveAnswerRelease(release QuizAnswerRelease) error
+	DeleteAnswerRelease(contentID string) error
+}
@@ -0,0 +1,37 @@
+package domain
+
+// QuizSimilarityReport agrupa por pregunta de texto los pares de respuestas parecidas de un quiz
+type QuizSimilarityReport struct {
+	ContentID string               `json:"content_id"`
+	CourseID  int                  `json:"course_id"`
+	QuizTitle string               `json:"quiz_title"`
+	Threshold float64              `json:"threshold"`
+	Attempts  int                  `json:"attempts"` // último intento enviado de cada estudiante
+	Questions []QuestionSimilarity `json:"questions"`
+}
+
+// QuestionSimilarity son los pares marcados de una pregunta de texto
+type QuestionSimilarity struct {
+	QuestionID string           `json:"question_id"`
+	Question   string           `json:"question"`
+	Compared   int              `json:"compared"` // respuestas con suficientes palabras para compararse
+	Skipped    int              `json:"skipped"`  // respuestas vacías o demasiado cortas
+	Pairs      []SimilarityPair `json:"pairs"`
+}
+
+// SimilarityAnswer es la respuesta de texto de un estudiante a una pregunta
+type SimilarityAnswer struct {
+	QuizAnswerID    int    `json:"quiz_answer_id"`
+	UserID          string `json:"user_id"`
+	StudentName     string `json:"student_name"`
+	StudentLastname string `json:"student_lastname"`
+	Text            string `json:"text"`
+}
+
+// SimilarityPair es un par de respuestas cuya similitud de Jaccard supera el umbral
+type SimilarityPair struct {
+	First          SimilarityAnswer `json:"first"`
+	Second         SimilarityAnswer `json:"second"`
+	Score          float64          `json:"score"`           // similitud de Jaccard entre los shingles de ambas respuestas
+	SharedShingles int              `json:"shared_shingles"` // secuencias de palabras en común
+}
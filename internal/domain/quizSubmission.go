@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// QuizSubmissionKey registra un envío de quiz hecho con la cabecera Idempotency-Key.
+// Response queda vacío mientras el envío se procesa y luego guarda la respuesta original.
+type QuizSubmissionKey struct {
+	UserID         string    `gorm:"column:user_id;primaryKey"`
+	IdempotencyKey string    `gorm:"column:idempotency_key;primaryKey"`
+	ContentID      string    `gorm:"column:content_id"`
+	QuizAnswerID   *int      `gorm:"column:quiz_answer_id"`
+	Response       string    `gorm:"column:response"` // JSON devuelto al estudiante
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (QuizSubmissionKey) TableName() string {
+	return "quiz_submission_key"
+}
+
+type SubmissionKeyRepo interface {
+	// ReserveSubmissionKey devuelve gorm.ErrDuplicatedKey si la clave ya se usó
+	ReserveSubmissionKey(key QuizSubmissionKey) error
+	GetSubmissionKey(userID, idempotencyKey string) (QuizSubmissionKey, error)
+	CompleteSubmissionKey(key QuizSubmissionKey) error
+	DeleteSubmissionKey(userID, idempotencyKey string) error
+}
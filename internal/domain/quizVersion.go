@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// QuizVersion es una versión inmutable del quiz del profesor. Cada edición crea una nueva.
+type QuizVersion struct {
+	QuizVersionID int       `json:"quiz_version_id" gorm:"column:quiz_version_id;primaryKey;autoIncrement"`
+	ContentID     string    `json:"content_id" gorm:"column:content_id;uniqueIndex:idx_quiz_version_content_version"`
+	Version       int       `json:"version" gorm:"column:version;uniqueIndex:idx_quiz_version_content_version"`
+	QuizURL       string    `json:"quiz_url" gorm:"column:quiz_url"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (QuizVersion) TableName() string {
+	return "quiz_version"
+}
+
+type QuizVersionRepo interface {
+	// CreateQuizVersion asigna el siguiente número de versión, llama a publish para subir el quiz
+	// (devuelve su URL) y registra la versión en una sola transacción
+	CreateQuizVersion(contentID string, publish func(version int) (string, error)) (QuizVersion, error)
+	GetLatestQuizVersion(contentID string) (QuizVersion, error)
+}
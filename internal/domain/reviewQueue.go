@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// ReviewQueueFilter filtra las respuestas de texto pendientes de revisión de un profesor.
+// Los filtros vacíos (0 o "") no se aplican. After* es la posición del cursor: se devuelven
+// los ítems posteriores a ella en orden de envío.
+type ReviewQueueFilter struct {
+	TeacherID    string
+	CourseID     int
+	ContentID    string
+	UserID       string
+	AfterEndTime *time.Time
+	AfterItemID  int
+	Limit        int
+}
+
+// ReviewQueueRow es una respuesta pendiente tal como se lee de quiz_answer_item y la vista de intentos
+type ReviewQueueRow struct {
+	QuizAnswerItemID int       `gorm:"column:quiz_answer_item_id"`
+	QuizAnswerID     int       `gorm:"column:quiz_answer_id"`
+	QuestionID       string    `gorm:"column:question_id"`
+	Answer           string    `gorm:"column:answer"`
+	Points           int       `gorm:"column:points"`
+	ContentID        string    `gorm:"column:content_id"`
+	UserID           string    `gorm:"column:user_id"`
+	EndTime          time.Time `gorm:"column:end_time"`
+	CourseID         int       `gorm:"column:course_id"`
+	CourseTitle      string    `gorm:"column:course_title"`
+	QuizTitle        string    `gorm:"column:quiz_title"`
+	StudentName      string    `gorm:"column:student_name"`
+	StudentLastname  string    `gorm:"column:student_lastname"`
+	StudentEmail     string    `gorm:"column:student_email"`
+	QuizURL          string    `gorm:"column:attempt_quiz_url"` // quiz contra el que se calificó el intento
+	VariantURL       string    `gorm:"column:variant_url"`
+}
+
+// ReviewQueueItem es una pregunta de texto pendiente de revisión
+type ReviewQueueItem struct {
+	QuizAnswerItemID int               `json:"quiz_answer_item_id"`
+	QuizAnswerID     int               `json:"quiz_answer_id"`
+	QuestionID       string            `json:"question_id"`
+	Question         string            `json:"question"`
+	Points           int               `json:"points"`
+	Rubric           []RubricCriterion `json:"rubric,omitempty"`
+	Answer           string            `json:"answer"`
+	SubmittedAt      time.Time         `json:"submitted_at"`
+	CourseID         int               `json:"course_id"`
+	CourseTitle      string            `json:"course_title"`
+	ContentID        string            `json:"content_id"`
+	QuizTitle        string            `json:"quiz_title"`
+	// En modo anónimo solo se envía StudentLabel, un alias estable por estudiante y quiz
+	StudentLabel    string `json:"student_label"`
+	UserID          string `json:"user_id,omitempty"`
+	StudentName     string `json:"student_name,omitempty"`
+	StudentLastname string `json:"student_lastname,omitempty"`
+	StudentEmail    string `json:"student_email,omitempty"`
+}
+
+// ReviewQueuePage es una página de la cola de revisión. NextCursor está vacío en la última página.
+type ReviewQueuePage struct {
+	Items      []ReviewQueueItem `json:"items"`
+	NextCursor string            `json:"next_cursor"`
+	Anonymous  bool              `json:"anonymous"`
+}
@@ -0,0 +1,102 @@
+package domain
+
+import "time"
+
+// Tipos de pregunta de una encuesta
+const (
+	SurveyQuestionLikert   = "likert"
+	SurveyQuestionMultiple = "multiple"
+	SurveyQuestionOpen     = "open"
+)
+
+// DefaultLikertScale es el número de puntos de una escala Likert sin scale
+const DefaultLikertScale = 5
+
+// Survey es la definición de una encuesta (content_type_id 4) guardada en R2. No tiene clave de respuestas.
+type Survey struct {
+	Title     string           `json:"title"`
+	Anonymous bool             `json:"anonymous"` // las respuestas no se vinculan con el estudiante
+	Questions []SurveyQuestion `json:"questions"`
+}
+
+// SurveyQuestion es una pregunta de una encuesta
+type SurveyQuestion struct {
+	ID            string   `json:"id"`
+	Type          string   `json:"type"` // likert, multiple u open
+	Question      string   `json:"question"`
+	Required      bool     `json:"required"`
+	Options       []string `json:"options,omitempty"`       // multiple
+	AllowMultiple bool     `json:"allowMultiple,omitempty"` // multiple: se pueden elegir varias opciones
+	Scale         int      `json:"scale,omitempty"`         // likert: número de puntos (por defecto 5)
+	Labels        []string `json:"labels,omitempty"`        // likert: etiqueta de cada punto, opcional
+}
+
+// SurveyResponse es la respuesta de un estudiante a una encuesta
+type SurveyResponse struct {
+	SurveyResponseID int                    `gorm:"column:survey_response_id;primaryKey;autoIncrement"`
+	ContentID        string                 `gorm:"column:content_id"`
+	UserID           *string                `gorm:"column:user_id"` // nil en encuestas anónimas
+	Answers          map[string]interface{} `gorm:"column:answers;serializer:json"`
+	SubmittedAt      time.Time              `gorm:"column:submitted_at"` // en encuestas anónimas solo se guarda el día
+}
+
+func (SurveyResponse) TableName() string {
+	return "survey_response"
+}
+
+// SurveyParticipation registra qué estudiantes respondieron una encuesta sin vincularlos con su respuesta
+type SurveyParticipation struct {
+	ContentID string `gorm:"column:content_id;primaryKey"`
+	UserID    string `gorm:"column:user_id;primaryKey"`
+}
+
+func (SurveyParticipation) TableName() string {
+	return "survey_participation"
+}
+
+// SubmitSurveyInput structure
+type SubmitSurveyInput struct {
+	ContentID string                 `json:"content_id" validate:"required"`
+	Answers   map[string]interface{} `json:"answers" validate:"required"`
+}
+
+// SurveyResults son los resultados agregados de una encuesta; nunca incluyen respuestas individuales
+type SurveyResults struct {
+	ContentID string                 `json:"content_id"`
+	CourseID  int                    `json:"course_id"`
+	Title     string                 `json:"title"`
+	Anonymous bool                   `json:"anonymous"`
+	Responses int                    `json:"responses"`
+	Questions []SurveyQuestionResult `json:"questions"`
+}
+
+// SurveyQuestionResult son los resultados agregados de una pregunta
+type SurveyQuestionResult struct {
+	QuestionID    string              `json:"question_id"`
+	Question      string              `json:"question"`
+	Type          string              `json:"type"`
+	Answered      int                 `json:"answered"`
+	Counts        []SurveyOptionCount `json:"counts,omitempty"`         // likert y multiple
+	Mean          *float64            `json:"mean,omitempty"`           // likert
+	WordFrequency []SurveyWordCount   `json:"word_frequency,omitempty"` // open
+}
+
+// SurveyOptionCount es cuántas respuestas eligieron una opción o un punto de la escala
+type SurveyOptionCount struct {
+	Option string  `json:"option"`
+	Count  int     `json:"count"`
+	Ratio  float64 `json:"ratio"`
+}
+
+// SurveyWordCount es en cuántas respuestas abiertas aparece una palabra
+type SurveyWordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+type SurveyRepo interface {
+	// SubmitSurveyResponse guarda la respuesta, registra la participación del estudiante y marca el contenido
+	// como completado. Devuelve gorm.ErrDuplicatedKey si el estudiante ya respondió la encuesta.
+	SubmitSurveyResponse(response SurveyResponse, userID string) error
+	GetSurveyResponses(contentID string) ([]SurveyResponse, error)
+}
@@ -15,12 +15,16 @@ func DefineCourseContentRoutes(e *echo.Echo) {
 	assignmentRepo := data.NewAssignmentRepo(config.DB)
 	courseRepo := data.NewCourseRepo(config.DB)
 	controller := controller.CourseContentController{
-		Repo:                 repo,
-		RepoAssigment:        assignmentRepo,
-		RepoCourse:           courseRepo,
-		GeneratePresignedURL: config.GeneratePresignedURL,
-		UploadTextFunc:       config.UploadTeacherText,
-		UploadQuizFunc:       config.UploadTeacherQuiz,
+		Repo:                  repo,
+		RepoAssigment:         assignmentRepo,
+		RepoCourse:            courseRepo,
+		GeneratePresignedURL:  config.GeneratePresignedURL,
+		UploadTextFunc:        config.UploadTeacherText,
+		UploadQuizFunc:        config.UploadTeacherQuiz,
+		UploadQuizVersionFunc: config.UploadTeacherQuizVersion,
+		UploadSurveyFunc:      config.UploadTeacherSurvey,
+		DeleteStorageFunc:     config.DeleteR2Prefix,
+		QuizVersionRepo:       data.NewQuizVersionRepo(config.DB),
 	}
 
 	authService, err := services.NewAuthService()
@@ -32,15 +36,24 @@ func DefineCourseContentRoutes(e *echo.Echo) {
 	// GET routes
 	e.GET("/course-content", controller.GetCourseContentTeacher(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.GET("/course-content/student", controller.GetCourseContentForStudent(), middleware.RoleMiddleware(authService, "org:student"))
+	e.GET("/course-content/prerequisites/:contentId", controller.GetPrerequisites(), middleware.RoleMiddleware(authService, "org:teacher"))
 
 	// POST routes
 	e.POST("/course-content/module", controller.AddModule(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.POST("/course-content/section", controller.AddSection(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.POST("/course-content/quiz/import", controller.ImportQuiz(), middleware.RoleMiddleware(authService, "org:teacher"))
 
 	// PUT routes
 	e.PUT("/course-content", controller.UpdateContent(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.PUT("/course-content/status", controller.UpdateContentStatus(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.PUT("/course-content/module-title", controller.UpdateModuleTitle(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/course-content/module-order", controller.ReorderModules(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/course-content/section-order", controller.ReorderSections(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/course-content/prerequisites", controller.SetPrerequisites(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.PUT("/course-content/in_progress", controller.UpdateUserContentStatus(2), middleware.RoleMiddleware(authService, "org:student"))
 	e.PUT("/course-content/completed", controller.UpdateUserContentStatus(3), middleware.RoleMiddleware(authService, "org:student"))
+
+	// DELETE routes
+	e.DELETE("/course-content/section/:contentId", controller.DeleteSection(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.DELETE("/course-content/module/:courseContentId", controller.DeleteModule(), middleware.RoleMiddleware(authService, "org:teacher"))
 }
@@ -12,8 +12,14 @@ import (
 
 func DefineCourseRoutes(e *echo.Echo, authService *services.AuthService, roleMiddlewareProvider func(roles ...string) echo.MiddlewareFunc) {
 	repo := data.NewCourseRepo(config.DB)
-	courseController := controller.CourseController{Repo: repo}
+	courseController := controller.CourseController{
+		Repo:            repo,
+		ContentRepo:     data.NewCourseContentRepo(config.DB, nil),
+		ListStorageFunc: config.ListR2Keys,
+		CopyStorageFunc: config.CopyR2Object,
+	}
 
 	e.POST("/course", courseController.CreateCourse(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.GET("/courses/teacher", courseController.GetCoursesByTeacher(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.POST("/course/:courseId/clone", courseController.CloneCourse(), middleware.RoleMiddleware(authService, "org:teacher"))
 }
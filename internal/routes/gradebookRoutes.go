@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"zeppelin/internal/config"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/data"
+	"zeppelin/internal/middleware"
+	"zeppelin/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+func DefineGradebookRoutes(e *echo.Echo, authService *services.AuthService, roleMiddlewareProvider func(roles ...string) echo.MiddlewareFunc) {
+	gradebookController := controller.GradebookController{
+		GradebookRepo:     data.NewGradebookRepo(config.DB),
+		CourseRepo:        data.NewCourseRepo(config.DB),
+		AssignmentRepo:    data.NewAssignmentRepo(config.DB),
+		CourseContentRepo: data.NewCourseContentRepo(config.DB, controller.GenerateUID),
+		QuizRepo:          data.NewQuizRepository(config.DB),
+	}
+
+	e.GET("/courses/:courseId/gradebook", gradebookController.GetGradebook(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/courses/:courseId/gradebook/export", gradebookController.ExportGradebook(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/courses/:courseId/gradebook/settings", gradebookController.GetGradebookSettings(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/courses/:courseId/gradebook/settings", gradebookController.UpdateGradebookSettings(), middleware.RoleMiddleware(authService, "org:teacher"))
+}
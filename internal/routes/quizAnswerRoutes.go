@@ -19,6 +19,10 @@ func DefineQuizAnswerRoutes(e *echo.Echo, authService *services.AuthService, rol
 
 	Controller := controller.QuizController{
 		QuizRepo:              repo,
+		QuizVersionRepo:       data.NewQuizVersionRepo(config.DB),
+		DueDateRepo:           data.NewDueDateRepo(config.DB),
+		AnswerReleaseRepo:     data.NewAnswerReleaseRepo(config.DB),
+		SubmissionKeyRepo:     data.NewSubmissionKeyRepo(config.DB),
 		AssignmentRepo:        assignmentRepo,
 		CourseContentRepo:     courseContentRepo,
 		CourseRepo:            courseRepo,
@@ -26,10 +30,25 @@ func DefineQuizAnswerRoutes(e *echo.Echo, authService *services.AuthService, rol
 		UploadStudentAnswers:  config.UploadJSONToR2,
 		GetTeacherQuizContent: config.GetR2Object,
 		GeneratePresignedURL:  config.GeneratePresignedURL,
+		RegradeJobs:           controller.NewRegradeJobManager(),
+		QuizCache:             controller.NewQuizVersionCache(),
 	}
 
+	e.POST("/quiz/start", Controller.StartQuizAttempt(), middleware.RoleMiddleware(authService, "org:student"))
+	e.POST("/quiz/autosave", Controller.AutosaveQuiz(), middleware.RoleMiddleware(authService, "org:student"))
 	e.POST("/quiz/submit", Controller.SubmitQuiz(), middleware.RoleMiddleware(authService, "org:student"))
 	e.POST("/quiz/review-text-answer", Controller.ReviewTextAnswer(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/review-queue", Controller.GetReviewQueue(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.GET("/quiz/teacher/courses/:courseId", Controller.GetQuizzesByCourse(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/courses/:courseId/quizzes/:contentId/analysis", Controller.GetQuizItemAnalysis(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/courses/:courseId/quizzes/:contentId/similarity", Controller.GetQuizSimilarity(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/courses/:courseId/quizzes/:contentId/export/qti", Controller.ExportQuizQTI(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/courses/:courseId/quizzes/:contentId/export/attempts", Controller.ExportQuizAttempts(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/quiz/teacher/due-date", Controller.SetDueDate(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/quiz/teacher/due-date/extension", Controller.GrantDueDateExtension(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.PUT("/quiz/teacher/release", Controller.ReleaseQuizAnswers(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.POST("/quiz/teacher/regrade", Controller.StartRegrade(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.GET("/quiz/teacher/regrade/:jobId", Controller.GetRegradeJob(), middleware.RoleMiddleware(authService, "org:teacher"))
+	e.POST("/quiz/teacher/regrade/:jobId/commit", Controller.CommitRegrade(), middleware.RoleMiddleware(authService, "org:teacher"))
 	e.GET("/quiz/student", Controller.GetQuizzesByStudent(), middleware.RoleMiddleware(authService, "org:student", "org:teacher"))
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"zeppelin/internal/config"
+	"zeppelin/internal/controller"
+	"zeppelin/internal/data"
+	"zeppelin/internal/middleware"
+	"zeppelin/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+func DefineSurveyRoutes(e *echo.Echo, authService *services.AuthService, roleMiddlewareProvider func(roles ...string) echo.MiddlewareFunc) {
+	surveyController := controller.SurveyController{
+		SurveyRepo:        data.NewSurveyRepo(config.DB),
+		CourseContentRepo: data.NewCourseContentRepo(config.DB, controller.GenerateUID),
+		CourseRepo:        data.NewCourseRepo(config.DB),
+		AssignmentRepo:    data.NewAssignmentRepo(config.DB),
+		GetSurveyContent:  config.GetR2Object,
+	}
+
+	e.POST("/survey/submit", surveyController.SubmitSurvey(), middleware.RoleMiddleware(authService, "org:student"))
+	e.GET("/survey/teacher/courses/:courseId/surveys/:contentId/results", surveyController.GetSurveyResults(), middleware.RoleMiddleware(authService, "org:teacher"))
+}
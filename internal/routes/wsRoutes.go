@@ -18,6 +18,17 @@ func DefineWebSocketRoutes(e *echo.Echo, authSvc *services.AuthService) {
 
 	// Creamos el ConnectionManager con ambos repos
 	cm := controller.NewConnectionManager(sessionRepo, consentRepo)
+	// Repos para el quiz en vivo: los resultados se guardan como intentos normales en quiz_answer
+	cm.LiveQuiz = &controller.QuizController{
+		QuizRepo:              data.NewQuizRepository(config.DB),
+		QuizVersionRepo:       data.NewQuizVersionRepo(config.DB),
+		CourseContentRepo:     data.NewCourseContentRepo(config.DB, controller.GenerateUID),
+		AssignmentRepo:        data.NewAssignmentRepo(config.DB),
+		CourseRepo:            data.NewCourseRepo(config.DB),
+		UserRepo:              data.NewUserRepo(config.DB),
+		UploadStudentAnswers:  config.UploadJSONToR2,
+		GetTeacherQuizContent: config.GetR2Object,
+	}
 
 	// Ruta WebSocket
 	e.GET("/ws",